@@ -1,78 +1,390 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"mdm/libs/1_domain_methods/handlers"
 	"mdm/libs/1_domain_methods/repositories"
 	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/3_infrastructure/audit"
+	"mdm/libs/3_infrastructure/background"
 	"mdm/libs/3_infrastructure/db_manager"
+	"mdm/libs/3_infrastructure/health"
+	"mdm/libs/3_infrastructure/http_server"
+	"mdm/libs/3_infrastructure/oidc"
+	"mdm/libs/3_infrastructure/rate_limit"
 	"mdm/libs/4_common/auth"
-	"mdm/libs/4_common/env_vars"
+	"mdm/libs/4_common/config"
 	"mdm/libs/4_common/smart_context"
-	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chi_middleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
+// Интервалы фоновой очистки.
+const (
+	// idempotencyCleanupInterval — как часто удаляются истёкшие Idempotency-Key.
+	idempotencyCleanupInterval = time.Hour
+	// rateLimitCleanupInterval — как часто освобождаются вёдра неактивных клиентов.
+	rateLimitCleanupInterval = time.Minute
+	// signingKeyRefreshInterval — как часто перечитываются и ротируются ключи подписи JWT
+	// (ключи, выпущенные другими экземплярами, подхватываются с этой задержкой).
+	signingKeyRefreshInterval = time.Minute
+	// actionExpiryInterval — как часто неподтверждённые запросы на стирание помечаются просроченными.
+	actionExpiryInterval = time.Minute
+	// devicePurgeInterval — как часто удаляются устройства с истёкшим сроком хранения.
+	devicePurgeInterval = time.Hour
+	// telemetrySnapshotCleanupInterval — как часто удаляются устаревшие снимки телеметрии.
+	telemetrySnapshotCleanupInterval = time.Hour
+)
+
 func main() {
-	env_vars.LoadEnvVars()
-	os.Setenv("LOG_LEVEL", "debug")
+	args := os.Args[1:]
+	// backend-api config print [флаги] — вывести итоговую конфигурацию без секретов.
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		cfg := loadConfig(args[2:])
+		fmt.Print(cfg.Redacted())
+		return
+	}
 
+	cfg := loadConfig(args)
+
+	os.Setenv("LOG_LEVEL", cfg.Log.Level)
+	if cfg.Log.ToConsole {
+		os.Setenv("LOG_TO_CONSOLE", "true")
+	}
 	logger := smart_context.NewSmartContext()
+	for _, warning := range cfg.Warnings {
+		logger.Warn(warning)
+	}
+
+	auth.SetJWTSecret(cfg.Auth.JWTSecret)
+	auth.SetDataEncryptionKey(cfg.Auth.DataEncryptionKey)
 
-	dbm, err := db_manager.NewDbManager(logger)
+	dbm, err := db_manager.NewDbManager(logger, cfg.Database)
 	if err != nil {
 		logger.Fatalf("Error connecting to database: %v", err)
 	}
 	logger = logger.WithDbManager(dbm)
 	logger = logger.WithDB(dbm.GetGORM())
 
+	if cfg.Database.AutoMigrate {
+		if err := dbm.Migrate(logger); err != nil {
+			logger.Fatalf("Error applying database migrations: %v", err)
+		}
+	}
+
+	// Без DATA_ENCRYPTION_KEY сохранённые секреты TOTP не расшифровать, и пользователи
+	// с 2FA не смогут войти по коду, поэтому сервер не стартует.
+	if cfg.Auth.DataEncryptionKey == "" {
+		sealed, err := repositories.NewUserRepository().CountTOTPSecrets(logger)
+		if err != nil {
+			logger.Fatalf("Error checking stored two-factor secrets: %v", err)
+		}
+		if sealed > 0 {
+			logger.Fatalf("DATA_ENCRYPTION_KEY is required: %d users have encrypted two-factor secrets", sealed)
+		}
+	}
+
+	// Фоновые воркеры останавливаются вместе с сервером.
+	workers := background.NewManager(logger)
+
+	// Ключи подписи JWT: при RS256/EdDSA хранятся в БД и ротируются по расписанию.
+	auth.Keys.SetAlgorithm(cfg.Auth.JWTAlgorithm)
+	if cfg.Auth.JWTAlgorithm != auth.AlgHS256 {
+		signingKeyRepo := repositories.NewSigningKeyRepository()
+		policy := repositories.KeyRotationPolicy{
+			Algorithm:    cfg.Auth.JWTAlgorithm,
+			RotateEvery:  cfg.Auth.JWTKeyRotation,
+			PublishAhead: cfg.Auth.JWTKeyPublishAhead,
+			TokenTTL:     auth.TokenTTL,
+		}
+		rotateKeys := func(sctx smart_context.ISmartContext) error {
+			keys, err := signingKeyRepo.Rotate(sctx, policy, time.Now())
+			if err != nil {
+				return err
+			}
+			auth.Keys.Replace(keys)
+			return nil
+		}
+		if err := rotateKeys(logger); err != nil {
+			logger.Fatalf("Error loading JWT signing keys: %v", err)
+		}
+		workers.Every("signing_key_rotation", signingKeyRefreshInterval, rotateKeys)
+	}
+
+	// Проверки готовности для /readyz; подсистемы добавляют сюда свои.
+	healthChecks := health.NewRegistry()
+	healthChecks.Register("database", dbm.Ping)
+	healthChecks.Register("migrations", dbm.CheckMigrations)
+	healthChecks.Register("schema", func(ctx context.Context) error {
+		return dbm.CheckSchema(ctx, &model.Device{}, &model.User{}, &model.IdempotencyKey{}, &model.APIKey{}, &model.SigningKey{}, &model.RecoveryCode{}, &model.DeviceCommand{}, &model.DeviceGroup{}, &model.AuditEvent{}, &model.DeviceAction{}, &model.DeviceApp{}, &model.DeviceAppEvent{})
+	})
+	healthChecks.Register("background_workers", workers.Check)
+	if cfg.Auth.JWTAlgorithm != auth.AlgHS256 {
+		healthChecks.Register("signing_key", func(ctx context.Context) error {
+			if auth.Keys.Signing(time.Now()) == nil {
+				return auth.ErrNoSigningKey
+			}
+			return nil
+		})
+	}
+
 	// Инициализация репозитория устройств
-	deviceRepo := repositories.NewDeviceRepository(logger.GetDB())
-	userRepo := repositories.NewUserRepository(logger.GetDB())
+	// (соединение с БД репозитории берут из smart context)
+	deviceRepo := repositories.NewDeviceRepository()
+	userRepo := repositories.NewUserRepository()
+	apiKeyRepo := repositories.NewAPIKeyRepository()
+	commandRepo := repositories.NewCommandRepository()
+	groupRepo := repositories.NewGroupRepository()
+	auditRepo := repositories.NewAuditRepository()
+	actionRepo := repositories.NewActionRepository(commandRepo)
+	appRepo := repositories.NewAppRepository()
 	// Создаем хендлеры
-	h := handlers.NewHandler(deviceRepo, userRepo)
+	h := handlers.NewHandler(deviceRepo, commandRepo, userRepo, repositories.LockoutPolicy{
+		MaxAttempts: cfg.Auth.MaxFailedLogins,
+		Delay:       cfg.Auth.LockoutDelay,
+		MaxDelay:    cfg.Auth.LockoutMaxDelay,
+	}, handlers.TwoFactorPolicy{
+		RequiredRoles: cfg.Auth.TwoFactorRequiredRoles,
+		Issuer:        cfg.Auth.TOTPIssuer,
+	}, handlers.HeartbeatPolicy{
+		Interval: cfg.Devices.HeartbeatInterval,
+		Groups:   groupRepo,
+	}, cfg.Devices.EnrollmentTokenTTL)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+	commandHandler := handlers.NewCommandHandler(commandRepo)
+	appHandler := handlers.NewAppHandler(appRepo)
+	groupHandler := handlers.NewGroupHandler(groupRepo)
+	userHandler := handlers.NewUserHandler(userRepo)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
+	actionHandler := handlers.NewDeviceActionHandler(actionRepo, h, cfg.Devices.WipeApprovalTTL)
+	workers.Every("device_action_expiry", actionExpiryInterval, func(sctx smart_context.ISmartContext) error {
+		expired, err := actionRepo.ExpirePending(sctx, time.Now())
+		if expired > 0 {
+			sctx.Infof("Expired %d device actions awaiting approval", expired)
+		}
+		return err
+	})
+
+	// Ограничители частоты запросов по группам маршрутов (в памяти процесса).
+	loginLimiter := rate_limit.NewLimiter("login", cfg.RateLimit.Login)
+	deviceLimiter := rate_limit.NewLimiter("device", cfg.RateLimit.Device)
+	apiLimiter := rate_limit.NewLimiter("api", cfg.RateLimit.API)
+	workers.Every("rate_limit_cleanup", rateLimitCleanupInterval, func(sctx smart_context.ISmartContext) error {
+		loginLimiter.Cleanup()
+		deviceLimiter.Cleanup()
+		apiLimiter.Cleanup()
+		return nil
+	})
+
+	// Удалённые устройства хранятся в архиве DEVICE_PURGE_RETENTION, затем удаляются с историей.
+	if cfg.Devices.PurgeRetention > 0 {
+		workers.Every("device_purge", devicePurgeInterval, func(sctx smart_context.ISmartContext) error {
+			purged, err := deviceRepo.PurgeDeleted(sctx, time.Now().Add(-cfg.Devices.PurgeRetention))
+			if purged > 0 {
+				sctx.Infof("Purged %d deleted devices", purged)
+			}
+			return err
+		})
+	}
+
+	// Снимки телеметрии, снятые агентами без связи, хранятся DEVICE_TELEMETRY_RETENTION.
+	if cfg.Devices.TelemetryRetention > 0 {
+		workers.Every("telemetry_snapshot_cleanup", telemetrySnapshotCleanupInterval, func(sctx smart_context.ISmartContext) error {
+			deleted, err := deviceRepo.DeleteTelemetrySnapshots(sctx, time.Now().Add(-cfg.Devices.TelemetryRetention))
+			if deleted > 0 {
+				sctx.Infof("Deleted %d telemetry snapshots", deleted)
+			}
+			return err
+		})
+	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "default-secret"
+	// Повторы POST-запросов с Idempotency-Key отдают сохранённый ответ.
+	if cfg.Server.IdempotencyTTL > 0 {
+		idempotencyRepo := repositories.NewIdempotencyRepository()
+		run_processor.SetIdempotencyStore(idempotencyRepo, cfg.Server.IdempotencyTTL, cfg.Server.IdempotencyLease)
+		workers.Every("idempotency_cleanup", idempotencyCleanupInterval, func(sctx smart_context.ISmartContext) error {
+			deleted, err := idempotencyRepo.DeleteExpired(sctx, time.Now())
+			if deleted > 0 {
+				sctx.Infof("Deleted %d expired idempotency keys", deleted)
+			}
+			return err
+		})
 	}
 
 	r := chi.NewRouter()
 
+	if cfg.RateLimit.TrustProxy {
+		// IP клиента для лимитов берётся из X-Forwarded-For / X-Real-IP.
+		r.Use(chi_middleware.RealIP)
+	}
 	r.Use(chi_middleware.Logger)
 	r.Use(chi_middleware.Recoverer)
 
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With", "X-Request-Id", "X-Session-Id", "Apikey", "X-Api-Key"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Requested-With", "X-Request-Id", "X-Session-Id", "Apikey", "X-Api-Key", "If-Match", "Idempotency-Key"},
+		ExposedHeaders:   []string{"Link", "ETag", "Idempotent-Replayed", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Пробы для оркестратора: процесс жив / готов принимать трафик.
+	r.Get("/healthz", health.LivenessHandler())
+	r.Get("/readyz", healthChecks.ReadinessHandler(logger))
+	// Открытые ключи подписи JWT для других сервисов.
+	r.Get("/.well-known/jwks.json", auth.JWKSHandler())
+
 	// Регистрируем маршруты, используя обёртку JSONResponseMiddleware.
-	r.Post("/devices/register", run_processor.JSONResponseMiddleware(logger, h.RegisterDeviceHandler))
-	r.Post("/devices/{id}/heartbeat", run_processor.JSONResponseMiddleware(logger, h.UpdateHeartbeatHandler))
-	r.Get("/devices/{id}/status", run_processor.JSONResponseMiddleware(logger, h.GetDeviceStatusHandler))
+	// Ответы с учётными данными (токены, ключи, секреты 2FA) не сохраняются
+	// для Idempotency-Key: такие маршруты обёрнуты в WithoutIdempotency.
+	r.With(run_processor.WithoutIdempotency, deviceLimiter.Middleware(logger, rate_limit.ByIP, rate_limit.ByJSONField("device_id"))).
+		Post("/devices/register", run_processor.JSONResponseMiddleware(logger, h.RegisterDeviceHandler))
+	r.Group(func(r chi.Router) {
+		r.Use(deviceLimiter.Middleware(logger, rate_limit.ByURLParam("id")))
+		// Агент подтверждает, что он — устройство {id}, токеном, выданным при регистрации.
+		r.Use(auth.DeviceMiddleware(logger, deviceRepo, "id"))
+		r.Post("/devices/{id}/heartbeat", run_processor.JSONResponseMiddleware(logger, h.UpdateHeartbeatHandler))
+		r.Get("/devices/{id}/status", run_processor.JSONResponseMiddleware(logger, h.GetDeviceStatusHandler))
+		r.Post("/devices/{id}/policy-status", run_processor.JSONResponseMiddleware(logger, h.ReportPolicyStatusHandler))
+		r.Post("/devices/{id}/commands/{command_id}/result", run_processor.JSONResponseMiddleware(logger, commandHandler.CommandResultHandler))
+		r.Post("/devices/{id}/apps", run_processor.JSONResponseMiddleware(logger, appHandler.ReportAppsHandler))
+	})
 
 	// Эндпоинт для логина (публичный, для получения JWT-токена)
-	r.Post("/login", run_processor.JSONResponseMiddleware(logger, h.LoginHandler))
+	r.With(run_processor.WithoutIdempotency, loginLimiter.Middleware(logger, rate_limit.ByIP, rate_limit.ByJSONField("username"))).
+		Post("/login", run_processor.JSONResponseMiddleware(logger, h.LoginHandler))
+	// Обязательное подключение 2FA при входе (по mfa_token из ответа /login).
+	r.Route("/login/2fa", func(r chi.Router) {
+		r.Use(run_processor.WithoutIdempotency)
+		r.Use(loginLimiter.Middleware(logger, rate_limit.ByIP))
+		r.Post("/enroll", run_processor.JSONResponseMiddleware(logger, h.LoginEnrollTOTPHandler))
+		r.Post("/confirm", run_processor.JSONResponseMiddleware(logger, h.LoginConfirmTOTPHandler))
+	})
 
-	r.Group(func(r chi.Router) {
-		r.Use(func(next http.Handler) http.Handler {
-			return auth.JWTMiddleware(next, logger)
+	// Вход через корпоративный IdP (OIDC); локальный /login остаётся запасным.
+	if cfg.OIDC.Enabled() {
+		provider, err := oidc.NewProvider(cfg.OIDC)
+		if err != nil {
+			logger.Fatalf("Error configuring OIDC: %v", err)
+		}
+		oidcHandler := handlers.NewOIDCHandler(provider, userRepo, cfg.OIDC.RedirectURL, cfg.OIDC.PostLoginRedirect)
+		r.Route("/auth/oidc", func(r chi.Router) {
+			r.Use(loginLimiter.Middleware(logger, rate_limit.ByIP))
+			r.Get("/login", oidcHandler.LoginHandler(logger))
+			r.Get("/callback", oidcHandler.CallbackHandler(logger))
 		})
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(apiLimiter.Middleware(logger, rate_limit.ByIP))
+		// JWT пользователя или API-ключ; права проверяются по scope одинаково для обоих.
+		r.Use(auth.Middleware(logger, apiKeyRepo))
+		// Изменения через административный API попадают в журнал аудита.
+		r.Use(audit.Middleware(logger, auditRepo))
+
 		// Получение списка всех устройств
-		r.Get("/devices", run_processor.JSONResponseMiddleware(logger, h.GetAllDevicesHandler))
-		r.Post("/devices/{id}/camera", run_processor.JSONResponseMiddleware(logger, h.UpdateCameraHandler))
-		r.Post("/devices/{id}/microphone", run_processor.JSONResponseMiddleware(logger, h.UpdateMicrophoneHandler))
-		r.Post("/devices/{id}/bluetooth", run_processor.JSONResponseMiddleware(logger, h.UpdateBluetoothHandler))
-		r.Post("/devices/{id}/os", run_processor.JSONResponseMiddleware(logger, h.UpdateOsVersionHandler))
-		r.Post("/devices/{id}/battery", run_processor.JSONResponseMiddleware(logger, h.UpdateBatteryLevelHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices", run_processor.JSONResponseMiddleware(logger, h.GetAllDevicesHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices/{id}", run_processor.JSONResponseMiddleware(logger, h.GetDeviceStatusHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices/{id}/telemetry", run_processor.JSONResponseMiddleware(logger, h.TelemetrySnapshotsHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices/{id}/commands", run_processor.JSONResponseMiddleware(logger, commandHandler.ListCommandsHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices/{id}/apps", run_processor.JSONResponseMiddleware(logger, appHandler.ListDeviceAppsHandler))
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/devices/{id}/apps/history", run_processor.JSONResponseMiddleware(logger, appHandler.AppHistoryHandler))
+		// Поиск устройств по установленному приложению и его версии
+		r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+			Get("/apps", run_processor.JSONResponseMiddleware(logger, appHandler.FindAppDevicesHandler))
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeDevicesWrite))
+			r.Post("/devices/{id}/camera", run_processor.JSONResponseMiddleware(logger, h.UpdateCameraHandler))
+			r.Post("/devices/{id}/microphone", run_processor.JSONResponseMiddleware(logger, h.UpdateMicrophoneHandler))
+			r.Post("/devices/{id}/bluetooth", run_processor.JSONResponseMiddleware(logger, h.UpdateBluetoothHandler))
+			r.Post("/devices/{id}/os", run_processor.JSONResponseMiddleware(logger, h.UpdateOsVersionHandler))
+			r.Post("/devices/{id}/battery", run_processor.JSONResponseMiddleware(logger, h.UpdateBatteryLevelHandler))
+			r.Post("/devices/{id}/commands", run_processor.JSONResponseMiddleware(logger, commandHandler.CreateCommandHandler))
+			r.Post("/devices/{id}/group", run_processor.JSONResponseMiddleware(logger, groupHandler.AssignDeviceGroupHandler))
+			r.Post("/devices/{id}/lock", run_processor.JSONResponseMiddleware(logger, actionHandler.LockDeviceHandler))
+			r.Post("/devices/{id}/unlock", run_processor.JSONResponseMiddleware(logger, actionHandler.UnlockDeviceHandler))
+			r.With(run_processor.WithoutIdempotency).
+				Post("/devices/{id}/enrollment-token", run_processor.JSONResponseMiddleware(logger, h.IssueEnrollmentTokenHandler))
+		})
+		// Вывод из эксплуатации и удаление — только администраторам
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireScope(auth.ScopeDevicesRetire))
+			r.Post("/devices/{id}/retire", run_processor.JSONResponseMiddleware(logger, h.RetireDeviceHandler))
+			r.Delete("/devices/{id}", run_processor.JSONResponseMiddleware(logger, h.DeleteDeviceHandler))
+		})
+		r.With(auth.RequireScope(auth.ScopeDevicesWipe)).
+			Post("/devices/{id}/wipe", run_processor.JSONResponseMiddleware(logger, actionHandler.WipeDeviceHandler))
+
+		// Блокировка и стирание: история и подтверждение стирания вторым администратором
+		r.Route("/device-actions", func(r chi.Router) {
+			r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+				Get("/", run_processor.JSONResponseMiddleware(logger, actionHandler.ListActionsHandler))
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireScope(auth.ScopeDevicesWipe))
+				r.Post("/{id}/approve", run_processor.JSONResponseMiddleware(logger, actionHandler.ApproveActionHandler))
+				r.Post("/{id}/reject", run_processor.JSONResponseMiddleware(logger, actionHandler.RejectActionHandler))
+			})
+		})
+
+		// Группы устройств: общий период heartbeat
+		r.Route("/device-groups", func(r chi.Router) {
+			r.With(auth.RequireScope(auth.ScopeDevicesRead)).
+				Get("/", run_processor.JSONResponseMiddleware(logger, groupHandler.ListGroupsHandler))
+			r.Group(func(r chi.Router) {
+				r.Use(auth.RequireScope(auth.ScopeDevicesWrite))
+				r.Post("/", run_processor.JSONResponseMiddleware(logger, groupHandler.CreateGroupHandler))
+				r.Put("/{id}", run_processor.JSONResponseMiddleware(logger, groupHandler.UpdateGroupHandler))
+				r.Delete("/{id}", run_processor.JSONResponseMiddleware(logger, groupHandler.DeleteGroupHandler))
+			})
+		})
+
+		// Управление API-ключами
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(run_processor.WithoutIdempotency)
+			r.Use(auth.RequireScope(auth.ScopeAPIKeysManage))
+			r.Get("/", run_processor.JSONResponseMiddleware(logger, apiKeyHandler.ListAPIKeysHandler))
+			r.Post("/", run_processor.JSONResponseMiddleware(logger, apiKeyHandler.CreateAPIKeyHandler))
+			r.Post("/{id}/rotate", run_processor.JSONResponseMiddleware(logger, apiKeyHandler.RotateAPIKeyHandler))
+			r.Delete("/{id}", run_processor.JSONResponseMiddleware(logger, apiKeyHandler.RevokeAPIKeyHandler))
+		})
+
+		// Пользователи с входом по паролю
+		r.Route("/users", func(r chi.Router) {
+			r.Use(run_processor.WithoutIdempotency)
+			r.Use(auth.RequireScope(auth.ScopeUsersManage))
+			r.Get("/", run_processor.JSONResponseMiddleware(logger, userHandler.ListUsersHandler))
+			r.Post("/", run_processor.JSONResponseMiddleware(logger, userHandler.CreateUserHandler))
+		})
+
+		// Журнал аудита
+		r.With(auth.RequireScope(auth.ScopeAuditRead)).
+			Get("/audit", run_processor.JSONResponseMiddleware(logger, auditHandler.ListAuditHandler))
+
+		// Двухфакторная аутентификация текущего пользователя
+		r.Route("/2fa", func(r chi.Router) {
+			r.Use(run_processor.WithoutIdempotency)
+			r.Get("/", run_processor.JSONResponseMiddleware(logger, h.TwoFactorStatusHandler))
+			r.Post("/totp/enroll", run_processor.JSONResponseMiddleware(logger, h.EnrollTOTPHandler))
+			r.Post("/totp/confirm", run_processor.JSONResponseMiddleware(logger, h.ConfirmTOTPHandler))
+			r.Delete("/totp", run_processor.JSONResponseMiddleware(logger, h.DisableTOTPHandler))
+			r.Post("/recovery-codes", run_processor.JSONResponseMiddleware(logger, h.RegenerateRecoveryCodesHandler))
+		})
 	})
 
 	// r.Post("/devices/{id}/microphone", run_processor.JSONResponseMiddleware(logger, h.UpdateMicrophoneHandler))
@@ -80,7 +392,48 @@ func main() {
 	// r.Post("/devices/{id}/os", run_processor.JSONResponseMiddleware(logger, h.UpdateOsVersionHandler))
 	// r.Post("/devices/{id}/battery", run_processor.JSONResponseMiddleware(logger, h.UpdateBatteryLevelHandler))
 
-	logger.Info("Server listening on port 4000")
-	err = http.ListenAndServe(":4000", r)
-	logger.Fatal(err)
+	server, err := http_server.NewServer(logger, serverConfig(cfg.Server), r)
+	if err != nil {
+		logger.Fatalf("Error configuring server: %v", err)
+	}
+	// Хуки выполняются в обратном порядке: сначала воркеры, затем пул БД.
+	server.OnShutdown(func(ctx context.Context) error {
+		logger.Info("Closing database pool")
+		return dbm.Close()
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		logger.Info("Stopping background workers")
+		return workers.Stop(ctx)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Run(ctx); err != nil {
+		logger.Fatalf("Server error: %v", err)
+	}
+}
+
+// loadConfig загружает конфигурацию и завершает процесс, если она некорректна.
+func loadConfig(args []string) *config.Config {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	return cfg
+}
+
+// serverConfig переводит секцию конфигурации в параметры HTTP-сервера.
+func serverConfig(cfg config.ServerConfig) http_server.Config {
+	return http_server.Config{
+		Addr:              cfg.Addr,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ShutdownTimeout:   cfg.ShutdownTimeout,
+		TLSCertFile:       cfg.TLSCertFile,
+		TLSKeyFile:        cfg.TLSKeyFile,
+	}
 }
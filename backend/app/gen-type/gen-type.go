@@ -1,29 +1,72 @@
 package main
 
 import (
-	"mdm/libs/3_infrastructure/db_manager"
-	"mdm/libs/4_common/env_vars"
+	"mdm/libs/3_infrastructure/migrator"
 	"mdm/libs/4_common/smart_context"
+	"mdm/migration"
 	"os"
+	"time"
 
+	"gorm.io/driver/sqlite"
 	"gorm.io/gen"
+	"gorm.io/gorm"
 )
 
-type Querier interface {
+// UserQuerier — дополнительные запросы к таблице users.
+type UserQuerier interface {
+	// SELECT * FROM @@table WHERE username = @name{{if role != ""}} AND role = @role{{end}}
 	FilterWithNameAndRole(name, role string) ([]gen.T, error)
 }
 
+// DeviceQuerier — поиск и фильтрация устройств.
+type DeviceQuerier interface {
+	// FilterDevices ищет устройства по подстроке device_id (без учёта регистра),
+	// состоянию настроек и времени последнего heartbeat. nil-фильтры не применяются.
+	// archived выбирает выведенные из эксплуатации устройства вместо действующих,
+	// удалённые (deleted_at) показываются только с includeDeleted.
+	// search экранируется символом «!»: обратную косую черту в строке шаблона gen не разбирает.
+	//
+	// SELECT * FROM @@table
+	// {{where}}
+	//   {{if archived}} retired_at IS NOT NULL {{else}} retired_at IS NULL {{end}}
+	//   {{if !includeDeleted}} AND deleted_at IS NULL {{end}}
+	//   {{if search != ""}} AND LOWER(device_id) LIKE LOWER(@search) ESCAPE '!' {{end}}
+	//   {{if cameraEnabled != nil}} AND camera_enabled = @cameraEnabled {{end}}
+	//   {{if microphoneEnabled != nil}} AND microphone_enabled = @microphoneEnabled {{end}}
+	//   {{if bluetoothEnabled != nil}} AND bluetooth_enabled = @bluetoothEnabled {{end}}
+	//   {{if seenSince != nil}} AND last_heartbeat >= @seenSince {{end}}
+	// {{end}}
+	// ORDER BY device_id
+	// {{if limit > 0}} LIMIT @limit OFFSET @offset {{end}}
+	FilterDevices(archived bool, includeDeleted bool, search string, cameraEnabled, microphoneEnabled, bluetoothEnabled *bool, seenSince *time.Time, limit, offset int) ([]*gen.T, error)
+
+	// FindStale возвращает действующие устройства, не присылавшие heartbeat с момента before.
+	//
+	// SELECT * FROM @@table WHERE retired_at IS NULL AND (last_heartbeat IS NULL OR last_heartbeat < @before) ORDER BY last_heartbeat
+	FindStale(before time.Time) ([]*gen.T, error)
+}
+
+// Модели и query-пакет генерируются по схеме, которую дают миграции из
+// backend/migration: они применяются к временной in-memory SQLite, поэтому
+// живая БД и DATABASE_URL не нужны.
 func main() {
-	env_vars.LoadEnvVars()
 	os.Setenv("LOG_LEVEL", "info")
 	logger := smart_context.NewSmartContext()
 
-	dbm, err := db_manager.NewDbManager(logger)
+	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	if err != nil {
-		logger.Fatalf("NewDbManager failed: %v", err)
+		logger.Fatalf("Failed to open throwaway database: %v", err)
 	}
 
-	logger = logger.WithDB(dbm.GetGORM())
+	migrations, err := migrator.Load(migration.FS, db.Dialector.Name())
+	if err != nil {
+		logger.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(logger, db, migrations); err != nil {
+		logger.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	logger = logger.WithDB(db)
 
 	g := gen.NewGenerator(gen.Config{
 		OutPath: "./libs/2_generated_models/query",
@@ -32,9 +75,82 @@ func main() {
 
 	g.UseDB(logger.GetDB())
 
-	g.ApplyBasic(
-		g.GenerateAllTable()...,
-	)
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		logger.Fatalf("Failed to list tables: %v", err)
+	}
+
+	var models []interface{}
+	for _, table := range tables {
+		switch table {
+		case migrator.SchemaMigrationsTable:
+			continue
+		case "device":
+			// Хеши токена устройства и токена перерегистрации не должны попадать в ответы API.
+			g.ApplyInterface(func(DeviceQuerier) {}, g.GenerateModel(table,
+				gen.FieldJSONTag("token_hash", "-"),
+				gen.FieldType("policy_reported_at", "*time.Time"),
+				// Telemetry (model/telemetry.go) читается и пишется как JSON.
+				gen.FieldType("telemetry", "Telemetry"),
+				gen.FieldType("telemetry_at", "*time.Time"),
+				gen.FieldType("retired_at", "*time.Time"),
+				gen.FieldType("deleted_at", "*time.Time"),
+				gen.FieldType("apps_reported_at", "*time.Time"),
+				gen.FieldJSONTag("enrollment_token_hash", "-"),
+				gen.FieldType("enrollment_expires_at", "*time.Time"),
+				gen.FieldJSONTag("legacy_enrollment_until", "-"),
+				gen.FieldType("legacy_enrollment_until", "*time.Time"),
+			))
+		case "api_keys":
+			// Хеш ключа не отдаётся наружу, необязательные даты — nil вместо нулевого времени.
+			models = append(models, g.GenerateModelAs(table, "APIKey",
+				gen.FieldJSONTag("key_hash", "-"),
+				gen.FieldType("expires_at", "*time.Time"),
+				gen.FieldType("last_used_at", "*time.Time"),
+				gen.FieldType("revoked_at", "*time.Time"),
+			))
+		case "signing_keys":
+			// Закрытый ключ (даже зашифрованный) не отдаётся наружу.
+			models = append(models, g.GenerateModel(table,
+				gen.FieldJSONTag("private_key", "-"),
+				gen.FieldType("expires_at", "*time.Time"),
+			))
+		case "users":
+			// Хеш пароля и секрет TOTP не должны попадать в ответы API.
+			g.ApplyInterface(func(UserQuerier) {}, g.GenerateModel(table,
+				gen.FieldJSONTag("password", "-"),
+				gen.FieldJSONTag("totp_secret", "-"),
+				gen.FieldJSONTag("totp_last_step", "-"),
+			))
+		case "recovery_codes":
+			models = append(models, g.GenerateModel(table,
+				gen.FieldJSONTag("code_hash", "-"),
+				gen.FieldType("used_at", "*time.Time"),
+			))
+		case "device_commands":
+			models = append(models, g.GenerateModel(table,
+				gen.FieldType("delivered_at", "*time.Time"),
+				gen.FieldType("completed_at", "*time.Time"),
+			))
+		case "device_actions":
+			models = append(models, g.GenerateModel(table,
+				gen.FieldType("expires_at", "*time.Time"),
+				gen.FieldType("decided_at", "*time.Time"),
+			))
+		case "device_telemetry_snapshots":
+			models = append(models, g.GenerateModel(table,
+				gen.FieldType("telemetry", "Telemetry"),
+			))
+		case "device_apps":
+			// Дата установки известна не для всех пакетных менеджеров.
+			models = append(models, g.GenerateModel(table,
+				gen.FieldType("installed_at", "*time.Time"),
+			))
+		default:
+			models = append(models, g.GenerateModel(table))
+		}
+	}
+	g.ApplyBasic(models...)
 
 	g.Execute()
 }
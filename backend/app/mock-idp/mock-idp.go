@@ -0,0 +1,51 @@
+// mock-idp — локальный OpenID Connect провайдер для проверки входа через SSO
+// без корпоративного IdP. Не для production: пароли не проверяются.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"mdm/libs/3_infrastructure/oidc/mock_idp"
+)
+
+// usersFlag — повторяемый флаг -user username:group1,group2.
+type usersFlag []mock_idp.User
+
+func (u *usersFlag) String() string { return "" }
+
+func (u *usersFlag) Set(value string) error {
+	username, groups, _ := strings.Cut(value, ":")
+	user := mock_idp.User{Subject: "mock-" + username, Username: username, Email: username + "@example.com"}
+	if groups != "" {
+		user.Groups = strings.Split(groups, ",")
+	}
+	*u = append(*u, user)
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:5556", "адрес сервера")
+	issuer := flag.String("issuer", "http://127.0.0.1:5556", "issuer (адрес, по которому IdP доступен backend'у)")
+	clientID := flag.String("client-id", "mdm", "client_id")
+	clientSecret := flag.String("client-secret", "", "client_secret, пустой — публичный клиент")
+	var users usersFlag
+	flag.Var(&users, "user", "пользователь username:group1,group2 (можно повторять)")
+	flag.Parse()
+
+	if len(users) == 0 {
+		users = usersFlag{
+			{Subject: "mock-admin", Username: "sso-admin", Email: "sso-admin@example.com", Groups: []string{"mdm-admins"}},
+			{Subject: "mock-operator", Username: "sso-operator", Email: "sso-operator@example.com", Groups: []string{"mdm-operators"}},
+		}
+	}
+
+	idp, err := mock_idp.New(*issuer, *clientID, *clientSecret, users...)
+	if err != nil {
+		log.Fatalf("Error creating mock IdP: %v", err)
+	}
+	log.Printf("Mock IdP %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, idp))
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+// maxLockMessage — предельная длина сообщения на экране заблокированного устройства.
+const maxLockMessage = 256
+
+// defaultActionListLimit — сколько действий возвращает список без параметра limit.
+const defaultActionListLimit = 50
+
+// PasswordConfirmer повторно проверяет пароль и второй фактор пользователя
+// (реализует Handler.ConfirmPassword).
+type PasswordConfirmer interface {
+	ConfirmPassword(sctx smart_context.ISmartContext, userID string, data map[string]interface{}) error
+}
+
+// DeviceActionHandler — блокировка и стирание устройств.
+type DeviceActionHandler struct {
+	repo      repositories.ActionRepository
+	confirmer PasswordConfirmer
+	// approvalTTL — сколько запрос на стирание ждёт второго администратора.
+	approvalTTL time.Duration
+}
+
+// NewDeviceActionHandler создаёт новый экземпляр DeviceActionHandler.
+func NewDeviceActionHandler(repo repositories.ActionRepository, confirmer PasswordConfirmer, approvalTTL time.Duration) *DeviceActionHandler {
+	return &DeviceActionHandler{repo: repo, confirmer: confirmer, approvalTTL: approvalTTL}
+}
+
+// LockDeviceHandler блокирует устройство "id"; необязательный "message"
+// агент показывает на экране блокировки.
+func (h *DeviceActionHandler) LockDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	return h.lockOrUnlock(sctx, data, repositories.ActionLock)
+}
+
+// UnlockDeviceHandler снимает блокировку с устройства "id".
+func (h *DeviceActionHandler) UnlockDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	return h.lockOrUnlock(sctx, data, repositories.ActionUnlock)
+}
+
+func (h *DeviceActionHandler) lockOrUnlock(sctx smart_context.ISmartContext, data map[string]interface{}, action string) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	payload, err := actionPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	actor := actorOf(sctx)
+	created, err := h.repo.Request(sctx, repositories.ActionRequest{
+		DeviceID:          deviceID,
+		Action:            action,
+		Payload:           payload,
+		RequestedBy:       actor,
+		RequestedByUserID: userIDOf(sctx),
+		ApprovedBy:        actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{StatusCode: http.StatusCreated, Body: created}, nil
+}
+
+// WipeDeviceHandler стирает устройство "id". Пользователь, повторно введший свой
+// пароль в "password" (и при включённой 2FA — "otp" или "recovery_code"), стирает
+// сразу (201); без пароля запрос ждёт подтверждения другим администратором
+// через /device-actions/{id}/approve (202).
+func (h *DeviceActionHandler) WipeDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	payload, err := actionPayload(data)
+	if err != nil {
+		return nil, err
+	}
+	actor := actorOf(sctx)
+	request := repositories.ActionRequest{
+		DeviceID:          deviceID,
+		Action:            repositories.ActionWipe,
+		Payload:           payload,
+		RequestedBy:       actor,
+		RequestedByUserID: userIDOf(sctx),
+		ApprovalTTL:       h.approvalTTL,
+	}
+	if password, _ := data["password"].(string); password != "" {
+		if err := h.verifyPassword(sctx, data); err != nil {
+			return nil, err
+		}
+		request.ApprovedBy = actor
+	}
+	created, err := h.repo.Request(sctx, request)
+	if err != nil {
+		return nil, err
+	}
+	statusCode := http.StatusCreated
+	if created.Status == repositories.ActionStatusPendingApproval {
+		statusCode = http.StatusAccepted
+	}
+	return &run_processor.Response{StatusCode: statusCode, Body: created}, nil
+}
+
+// verifyPassword подтверждает стирание паролем текущего пользователя. API-ключам и
+// пользователям без локального пароля (вход через IdP) остаётся подтверждение вторым администратором.
+func (h *DeviceActionHandler) verifyPassword(sctx smart_context.ISmartContext, data map[string]interface{}) error {
+	principal := auth.PrincipalFromContext(sctx.GetContext())
+	if principal == nil || principal.Kind != auth.PrincipalUser {
+		return fmt.Errorf("%w: password confirmation is only available to users; request approval instead", app_errors.ErrForbidden)
+	}
+	return h.confirmer.ConfirmPassword(sctx, principal.ID, data)
+}
+
+// ListActionsHandler возвращает действия, новые первыми. Параметры: device_id,
+// status (например, pending_approval) и limit.
+func (h *DeviceActionHandler) ListActionsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	var filter repositories.ActionFilter
+	filter.DeviceID, _ = data["device_id"].(string)
+	filter.Status, _ = data["status"].(string)
+	limit, err := optionalInt(data, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultActionListLimit
+	}
+	filter.Limit = limit
+	return h.repo.List(sctx, filter)
+}
+
+// ApproveActionHandler подтверждает действие "id". Подтверждает только
+// пользователь, и не тот, кто запросил действие сам или через свой API-ключ.
+func (h *DeviceActionHandler) ApproveActionHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	principal := auth.PrincipalFromContext(sctx.GetContext())
+	if principal == nil || principal.Kind != auth.PrincipalUser {
+		return nil, fmt.Errorf("%w: actions can only be approved by users", app_errors.ErrForbidden)
+	}
+	return h.repo.Approve(sctx, id, actorOf(sctx), principal.UserID)
+}
+
+// RejectActionHandler отклоняет действие "id", ожидающее подтверждения.
+func (h *DeviceActionHandler) RejectActionHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	return h.repo.Reject(sctx, id, actorOf(sctx))
+}
+
+// actionPayload собирает payload команды из необязательного "message".
+func actionPayload(data map[string]interface{}) (string, error) {
+	payload := map[string]string{}
+	switch message := data["message"].(type) {
+	case nil:
+	case string:
+		if utf8.RuneCountInString(message) > maxLockMessage {
+			return "", fmt.Errorf("%w: message must be at most %d characters", app_errors.ErrBadRequest, maxLockMessage)
+		}
+		if message != "" {
+			payload["message"] = message
+		}
+	default:
+		return "", fmt.Errorf("%w: message must be a string", app_errors.ErrBadRequest)
+	}
+	encoded, err := json.Marshal(payload)
+	return string(encoded), err
+}
+
+// userIDOf возвращает пользователя, от имени которого действует субъект запроса
+// (для API-ключа — его владельца).
+func userIDOf(sctx smart_context.ISmartContext) string {
+	if principal := auth.PrincipalFromContext(sctx.GetContext()); principal != nil {
+		return principal.UserID
+	}
+	return ""
+}
+
+// actorOf возвращает субъекта запроса в виде "user:alice" или "api_key:ci".
+func actorOf(sctx smart_context.ISmartContext) string {
+	if principal := auth.PrincipalFromContext(sctx.GetContext()); principal != nil {
+		return principal.Kind + ":" + principal.Name
+	}
+	return ""
+}
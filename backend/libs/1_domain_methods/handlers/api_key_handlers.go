@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+// APIKeyHandler — управление API-ключами (права api_keys:manage).
+type APIKeyHandler struct {
+	repo repositories.APIKeyRepository
+}
+
+// NewAPIKeyHandler создаёт новый экземпляр APIKeyHandler.
+func NewAPIKeyHandler(repo repositories.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{repo: repo}
+}
+
+// issuedAPIKey — ключ вместе с его открытым значением, которое показывается один раз.
+type issuedAPIKey struct {
+	*model.APIKey
+	Key string `json:"key"`
+}
+
+// ListAPIKeysHandler возвращает все ключи (без значений).
+func (h *APIKeyHandler) ListAPIKeysHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	return h.repo.List(sctx)
+}
+
+// CreateAPIKeyHandler выпускает ключ. Ожидаются "name", "scopes" (список прав)
+// и необязательные "expires_in" (например, "720h") или "expires_at" (RFC 3339).
+// Выдать ключу можно только права, которые есть у самого администратора.
+func (h *APIKeyHandler) CreateAPIKeyHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	name, _ := data["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", app_errors.ErrBadRequest)
+	}
+
+	principal := auth.PrincipalFromContext(sctx.GetContext())
+	scopes, err := parseScopes(data["scopes"], principal)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt, err := parseExpiry(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ключ действует от имени пользователя, который его выпустил; ключ,
+	// выпущенный другим ключом, наследует его владельца.
+	createdBy, ownerID := "", ""
+	if principal != nil {
+		createdBy = principal.Kind + ":" + principal.Name
+		ownerID = principal.UserID
+	}
+	apiKey, key, err := h.repo.Create(sctx, name, scopes, expiresAt, createdBy, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{
+		StatusCode: http.StatusCreated,
+		Body:       &issuedAPIKey{APIKey: apiKey, Key: key},
+	}, nil
+}
+
+// RotateAPIKeyHandler выпускает новое значение ключа "id".
+func (h *APIKeyHandler) RotateAPIKeyHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	apiKey, key, err := h.repo.Rotate(sctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &issuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// RevokeAPIKeyHandler отзывает ключ "id".
+func (h *APIKeyHandler) RevokeAPIKeyHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	return h.repo.Revoke(sctx, id)
+}
+
+// parseScopes проверяет список прав: все должны быть известны и принадлежать выдающему.
+func parseScopes(raw interface{}, principal *auth.Principal) ([]string, error) {
+	items, ok := raw.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("%w: scopes must be a non-empty list", app_errors.ErrBadRequest)
+	}
+	scopes := make([]string, 0, len(items))
+	for _, item := range items {
+		scope, ok := item.(string)
+		if !ok || !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %v", app_errors.ErrBadRequest, item)
+		}
+		if !principal.HasScope(scope) {
+			return nil, fmt.Errorf("%w: cannot grant scope %s", app_errors.ErrForbidden, scope)
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+// parseExpiry разбирает "expires_in" или "expires_at"; без них ключ бессрочный.
+func parseExpiry(data map[string]interface{}) (*time.Time, error) {
+	if raw, ok := data["expires_in"].(string); ok && raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("%w: invalid value for expires_in", app_errors.ErrBadRequest)
+		}
+		expiresAt := time.Now().Add(ttl)
+		return &expiresAt, nil
+	}
+	if raw, ok := data["expires_at"].(string); ok && raw != "" {
+		expiresAt, err := time.Parse(time.RFC3339, raw)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: expires_at must be a future RFC 3339 time", app_errors.ErrBadRequest)
+		}
+		return &expiresAt, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+)
+
+// Ограничения отчёта об установленных приложениях; длины совпадают с колонками device_apps.
+const (
+	maxInventoryApps = 10000
+	maxPackageName   = 255
+	maxAppVersion    = 128
+	maxAppInstaller  = 64
+)
+
+// defaultAppHistoryLimit — сколько событий возвращает история без параметра limit.
+const defaultAppHistoryLimit = 100
+
+// AppHandler — установленные приложения устройств.
+type AppHandler struct {
+	repo repositories.AppRepository
+}
+
+// NewAppHandler создаёт новый экземпляр AppHandler.
+func NewAppHandler(repo repositories.AppRepository) *AppHandler {
+	return &AppHandler{repo: repo}
+}
+
+// appReportResponse — версия инвентаря, от которой агент строит следующий отчёт.
+type appReportResponse struct {
+	AppsVersion int64 `json:"apps_version"`
+}
+
+// ReportAppsHandler принимает от агента отчёт model.AppInventoryReport:
+// полный список ("full": true) или изменения от "base_version" — "installed"
+// и "removed". Отчёт от устаревшей версии отклоняется с 409.
+func (h *AppHandler) ReportAppsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	report, err := appReportFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	version, err := h.repo.Report(sctx, deviceID, *report)
+	if err != nil {
+		return nil, err
+	}
+	return &appReportResponse{AppsVersion: version}, nil
+}
+
+// appReportFromData разбирает и проверяет отчёт агента; прочие поля (id) игнорируются.
+func appReportFromData(data map[string]interface{}) (*model.AppInventoryReport, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var report model.AppInventoryReport
+	if err := json.Unmarshal(encoded, &report); err != nil {
+		return nil, fmt.Errorf("%w: invalid app report: %v", app_errors.ErrBadRequest, err)
+	}
+	if len(report.Installed)+len(report.Removed) > maxInventoryApps {
+		return nil, fmt.Errorf("%w: app report must contain at most %d apps", app_errors.ErrBadRequest, maxInventoryApps)
+	}
+	if report.Full && len(report.Removed) > 0 {
+		return nil, fmt.Errorf("%w: removed is not allowed in a full report", app_errors.ErrBadRequest)
+	}
+	seen := make(map[string]bool, len(report.Installed)+len(report.Removed))
+	checkName := func(name string) error {
+		if name == "" || len(name) > maxPackageName {
+			return fmt.Errorf("%w: package_name must be 1 to %d bytes", app_errors.ErrBadRequest, maxPackageName)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: package %s is listed twice", app_errors.ErrBadRequest, name)
+		}
+		seen[name] = true
+		return nil
+	}
+	for _, app := range report.Installed {
+		if err := checkName(app.PackageName); err != nil {
+			return nil, err
+		}
+		if len(app.Version) > maxAppVersion || len(app.Installer) > maxAppInstaller {
+			return nil, fmt.Errorf("%w: version must be at most %d bytes and installer at most %d", app_errors.ErrBadRequest, maxAppVersion, maxAppInstaller)
+		}
+	}
+	for _, name := range report.Removed {
+		if err := checkName(name); err != nil {
+			return nil, err
+		}
+	}
+	return &report, nil
+}
+
+// ListDeviceAppsHandler возвращает приложения, установленные на устройстве "id".
+func (h *AppHandler) ListDeviceAppsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	return h.repo.List(sctx, deviceID)
+}
+
+// AppHistoryHandler возвращает установки, обновления и удаления приложений
+// устройства "id", новые первыми. Параметры: package и limit.
+func (h *AppHandler) AppHistoryHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	filter := repositories.AppEventFilter{}
+	var ok bool
+	if filter.DeviceID, ok = data["id"].(string); !ok || filter.DeviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	filter.PackageName, _ = data["package"].(string)
+	limit, err := optionalInt(data, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultAppHistoryLimit
+	}
+	filter.Limit = limit
+	return h.repo.History(sctx, filter)
+}
+
+// FindAppDevicesHandler отвечает на вопрос «на каких устройствах стоит приложение
+// package версии ниже below»: возвращает установки на действующих устройствах.
+// Без below возвращаются все установки.
+func (h *AppHandler) FindAppDevicesHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	var filter repositories.AppQuery
+	filter.PackageName, _ = data["package"].(string)
+	if filter.PackageName == "" {
+		return nil, fmt.Errorf("%w: package is required", app_errors.ErrBadRequest)
+	}
+	filter.BelowVersion, _ = data["below"].(string)
+	return h.repo.FindDevices(sctx, filter)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+)
+
+// AuditHandler — журнал изменений (права audit:read).
+type AuditHandler struct {
+	repo repositories.AuditRepository
+}
+
+// NewAuditHandler создаёт новый экземпляр AuditHandler.
+func NewAuditHandler(repo repositories.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+// ListAuditHandler возвращает события журнала по возрастанию времени. Параметры:
+// since (RFC 3339) — только более поздние события, actor ("user:bob") и limit.
+// Без since возвращаются последние limit событий.
+func (h *AuditHandler) ListAuditHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	var filter repositories.AuditFilter
+	if raw, _ := data["since"].(string); raw != "" {
+		since, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value for since", app_errors.ErrBadRequest)
+		}
+		filter.Since = since
+	}
+	filter.Actor, _ = data["actor"].(string)
+	limit, err := optionalInt(data, "limit")
+	if err != nil {
+		return nil, err
+	}
+	filter.Limit = limit
+	return h.repo.List(sctx, filter)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+)
+
+// commandTypePattern — допустимый тип команды. Набор типов не фиксирован:
+// приложения со встроенным агентом обрабатывают и свои команды.
+var commandTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+// reservedCommandTypes выдаются только через действия над устройством
+// (/devices/{id}/lock, /unlock, /wipe), где проверяются права и подтверждение.
+var reservedCommandTypes = map[string]bool{
+	repositories.ActionLock:   true,
+	repositories.ActionUnlock: true,
+	repositories.ActionWipe:   true,
+}
+
+// defaultCommandListLimit — сколько команд возвращает список без параметра limit.
+const defaultCommandListLimit = 50
+
+// CommandHandler — очередь команд устройствам.
+type CommandHandler struct {
+	repo repositories.CommandRepository
+}
+
+// NewCommandHandler создаёт новый экземпляр CommandHandler.
+func NewCommandHandler(repo repositories.CommandRepository) *CommandHandler {
+	return &CommandHandler{repo: repo}
+}
+
+// heartbeatResponse — устройство, команды, ожидающие выполнения, и через сколько
+// секунд агенту прийти снова. Поля устройства сериализуются на верхнем уровне,
+// поэтому ответ совместим с прежним форматом.
+type heartbeatResponse struct {
+	*model.Device
+	Commands                 []*model.DeviceCommand `json:"commands"`
+	HeartbeatIntervalSeconds int64                  `json:"heartbeat_interval_seconds"`
+}
+
+// CreateCommandHandler ставит команду устройству "id". Ожидаются "type" и
+// необязательный "payload" — JSON-объект, который агент получит как есть.
+func (h *CommandHandler) CreateCommandHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	commandType, _ := data["type"].(string)
+	if !commandTypePattern.MatchString(commandType) {
+		return nil, fmt.Errorf("%w: type must match %s", app_errors.ErrBadRequest, commandTypePattern)
+	}
+	if reservedCommandTypes[commandType] {
+		return nil, fmt.Errorf("%w: use POST /devices/{id}/%s for %q", app_errors.ErrBadRequest, commandType, commandType)
+	}
+	payload := "{}"
+	switch raw := data["payload"].(type) {
+	case nil:
+	case map[string]interface{}:
+		encoded, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		payload = string(encoded)
+	default:
+		return nil, fmt.Errorf("%w: payload must be an object", app_errors.ErrBadRequest)
+	}
+
+	command, err := h.repo.Enqueue(sctx, deviceID, commandType, payload, actorOf(sctx))
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{StatusCode: http.StatusCreated, Body: command}, nil
+}
+
+// ListCommandsHandler возвращает команды устройства "id", новые первыми (параметр limit).
+func (h *CommandHandler) ListCommandsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, ok := data["id"].(string)
+	if !ok || deviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	limit, err := optionalInt(data, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultCommandListLimit
+	}
+	return h.repo.List(sctx, deviceID, limit)
+}
+
+// CommandResultHandler принимает от агента результат команды "command_id":
+// "status" ("succeeded" или "failed") и необязательный текст "result".
+func (h *CommandHandler) CommandResultHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	deviceID, _ := data["id"].(string)
+	commandID, _ := data["command_id"].(string)
+	if deviceID == "" || commandID == "" {
+		return nil, fmt.Errorf("%w: id and command_id are required", app_errors.ErrBadRequest)
+	}
+	status, _ := data["status"].(string)
+	if status != repositories.CommandStatusSucceeded && status != repositories.CommandStatusFailed {
+		return nil, fmt.Errorf("%w: status must be %q or %q", app_errors.ErrBadRequest,
+			repositories.CommandStatusSucceeded, repositories.CommandStatusFailed)
+	}
+	result, _ := data["result"].(string)
+	return h.repo.Complete(sctx, deviceID, commandID, status == repositories.CommandStatusSucceeded, result)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+)
+
+const (
+	// maxGroupName — предельная длина имени группы (столбец VARCHAR(64)).
+	maxGroupName = 64
+	// Допустимый период heartbeat группы: чаще нагружает сервер, реже — агент
+	// слишком долго не узнаёт о новой политике и командах.
+	minGroupHeartbeatInterval = 5 * time.Second
+	maxGroupHeartbeatInterval = 24 * time.Hour
+)
+
+// HeartbeatPolicy — период heartbeat, который сервер сообщает агентам.
+type HeartbeatPolicy struct {
+	// Interval — период по умолчанию для устройств без группы или без своего периода у группы.
+	Interval time.Duration
+	Groups   repositories.GroupRepository
+}
+
+// interval возвращает период heartbeat для устройства из группы groupID.
+func (p HeartbeatPolicy) interval(sctx smart_context.ISmartContext, groupID string) (time.Duration, error) {
+	if p.Groups == nil {
+		return p.Interval, nil
+	}
+	return p.Groups.HeartbeatInterval(sctx, groupID, p.Interval)
+}
+
+// GroupHandler — группы устройств.
+type GroupHandler struct {
+	repo repositories.GroupRepository
+}
+
+// NewGroupHandler создаёт новый экземпляр GroupHandler.
+func NewGroupHandler(repo repositories.GroupRepository) *GroupHandler {
+	return &GroupHandler{repo: repo}
+}
+
+// ListGroupsHandler возвращает все группы устройств.
+func (h *GroupHandler) ListGroupsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	return h.repo.List(sctx)
+}
+
+// CreateGroupHandler создаёт группу. Ожидаются "name" и необязательный
+// "heartbeat_interval" — длительность вроде "5m"; пустая или "0s" — период по умолчанию.
+func (h *GroupHandler) CreateGroupHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	name, _ := data["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxGroupName {
+		return nil, fmt.Errorf("%w: name is required and must be at most %d bytes", app_errors.ErrBadRequest, maxGroupName)
+	}
+	interval, err := parseGroupHeartbeatInterval(data)
+	if err != nil {
+		return nil, err
+	}
+	group, err := h.repo.Create(sctx, name, interval)
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{StatusCode: http.StatusCreated, Body: group}, nil
+}
+
+// UpdateGroupHandler меняет период heartbeat группы "id".
+func (h *GroupHandler) UpdateGroupHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	if _, ok := data["heartbeat_interval"]; !ok {
+		return nil, fmt.Errorf("%w: heartbeat_interval is required", app_errors.ErrBadRequest)
+	}
+	interval, err := parseGroupHeartbeatInterval(data)
+	if err != nil {
+		return nil, err
+	}
+	return h.repo.SetHeartbeatInterval(sctx, id, interval)
+}
+
+// DeleteGroupHandler удаляет группу "id"; её устройства остаются без группы.
+func (h *GroupHandler) DeleteGroupHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	return nil, h.repo.Delete(sctx, id)
+}
+
+// AssignDeviceGroupHandler переносит устройство "id" в группу "group_id";
+// пустой "group_id" убирает устройство из группы.
+func (h *GroupHandler) AssignDeviceGroupHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	groupID, ok := data["group_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: group_id is required, empty string removes the device from its group", app_errors.ErrBadRequest)
+	}
+	return deviceResponse(h.repo.AssignDevice(sctx, id, groupID))
+}
+
+// parseGroupHeartbeatInterval разбирает "heartbeat_interval" группы.
+func parseGroupHeartbeatInterval(data map[string]interface{}) (time.Duration, error) {
+	var raw string
+	switch value := data["heartbeat_interval"].(type) {
+	case nil:
+		return 0, nil
+	case string:
+		raw = value
+	default:
+		return 0, fmt.Errorf("%w: heartbeat_interval must be a duration string like \"5m\"", app_errors.ErrBadRequest)
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: heartbeat_interval must be a duration string like \"5m\"", app_errors.ErrBadRequest)
+	}
+	if interval == 0 {
+		return 0, nil
+	}
+	if interval < minGroupHeartbeatInterval || interval > maxGroupHeartbeatInterval || interval%time.Second != 0 {
+		return 0, fmt.Errorf("%w: heartbeat_interval must be whole seconds between %s and %s, or 0 for the default",
+			app_errors.ErrBadRequest, minGroupHeartbeatInterval, maxGroupHeartbeatInterval)
+	}
+	return interval, nil
+}
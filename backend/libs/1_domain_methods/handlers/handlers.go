@@ -1,86 +1,270 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
 	"mdm/libs/4_common/auth"
 	"mdm/libs/4_common/smart_context"
 
-	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// maxTelemetryString — предельная длина строковых полей телеметрии.
+const maxTelemetryString = 256
+
+// maxQueuedTelemetry — сколько снимков телеметрии, снятых без связи, агент может
+// прислать с одним heartbeat (столько же хранит агент).
+const maxQueuedTelemetry = 100
+
 // Handler содержит зависимости для работы с устройствами.
 type Handler struct {
-	deviceRepo repositories.DeviceRepository
-	userRepo   repositories.UserRepository
+	deviceRepo  repositories.DeviceRepository
+	commandRepo repositories.CommandRepository
+	userRepo    repositories.UserRepository
+	lockout     repositories.LockoutPolicy
+	twoFactor   TwoFactorPolicy
+	heartbeat   HeartbeatPolicy
+	// enrollmentTTL — срок действия токена перерегистрации устройства.
+	enrollmentTTL time.Duration
 }
 
 // NewHandler создаёт новый экземпляр Handler.
-func NewHandler(repo repositories.DeviceRepository, userRepo repositories.UserRepository) *Handler {
+func NewHandler(repo repositories.DeviceRepository, commandRepo repositories.CommandRepository, userRepo repositories.UserRepository, lockout repositories.LockoutPolicy, twoFactor TwoFactorPolicy, heartbeat HeartbeatPolicy, enrollmentTTL time.Duration) *Handler {
 	return &Handler{
-		deviceRepo: repo,
-		userRepo:   userRepo,
+		deviceRepo:    repo,
+		commandRepo:   commandRepo,
+		userRepo:      userRepo,
+		lockout:       lockout,
+		twoFactor:     twoFactor,
+		heartbeat:     heartbeat,
+		enrollmentTTL: enrollmentTTL,
 	}
 }
 
+var (
+	// ErrInvalidCredentials не уточняет, что именно неверно: имя или пароль.
+	ErrInvalidCredentials = fmt.Errorf("%w: invalid credentials", app_errors.ErrUnauthorized)
+	// ErrLoginLocked — вход временно заблокирован после серии неудачных попыток.
+	ErrLoginLocked = fmt.Errorf("%w: too many failed login attempts, try again later", app_errors.ErrTooManyRequests)
+	// ErrConfirmationMismatch — пароль или код 2FA, введённые для подтверждения действия, неверны.
+	ErrConfirmationMismatch = fmt.Errorf("%w: password or two-factor code does not match", app_errors.ErrForbidden)
+)
+
+// dummyPasswordHash сравнивается с паролем для несуществующего пользователя,
+// чтобы время ответа не выдавало, существует ли имя.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 // RegisterDeviceHandler обрабатывает регистрацию нового устройства.
-// Ожидается, что в данных будет параметр "device_id".
+// Ожидается, что в данных будет параметр "device_id"; повторная регистрация
+// требует "device_token", выданный при первой, а перерегистрация стёртого
+// устройства — "enrollment_token" от администратора. Новое устройство и
+// перерегистрация отвечают 201, повтор с верным токеном — 200.
 func (h *Handler) RegisterDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
 	deviceID, ok := data["device_id"].(string)
 	if !ok || deviceID == "" {
-		return nil, fmt.Errorf("device_id is required")
+		return nil, fmt.Errorf("%w: device_id is required", app_errors.ErrBadRequest)
+	}
+	deviceToken, _ := data["device_token"].(string)
+	enrollmentToken, _ := data["enrollment_token"].(string)
+
+	registration, err := h.deviceRepo.RegisterDevice(sctx, deviceID, deviceToken, enrollmentToken)
+	if err != nil {
+		return nil, err
 	}
-	return h.deviceRepo.RegisterDevice(sctx, deviceID)
+	statusCode := http.StatusCreated
+	if registration.Registration == repositories.RegistrationExisting {
+		statusCode = http.StatusOK
+	}
+	return &run_processor.Response{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"ETag": deviceETag(registration.Device)},
+		Body:       registration,
+	}, nil
 }
 
-// UpdateHeartbeatHandler обновляет время последнего обновления (heartbeat).
-// Ожидается, что в данных будет параметр "id".
+// UpdateHeartbeatHandler обновляет время последнего обновления (heartbeat)
+// и отдаёт устройству невыполненные команды и период следующего heartbeat.
+// Ожидается, что в данных будет параметр "id" и, необязательно, "telemetry" —
+// объект с полями model.Telemetry (неизвестные поля игнорируются) и "queued_telemetry" —
+// снимки, снятые без связи: [{"collected_at": "<RFC 3339>", "telemetry": {...}}].
 func (h *Handler) UpdateHeartbeatHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
 	id, ok := data["id"].(string)
 	if !ok || id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return h.deviceRepo.UpdateHeartbeat(sctx, id)
+	telemetry, err := telemetryFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	queued, err := queuedTelemetryFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	device, err := h.deviceRepo.UpdateHeartbeat(sctx, id, telemetry, queued)
+	if err != nil {
+		return nil, err
+	}
+	commands, err := h.commandRepo.Deliver(sctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if commands == nil {
+		commands = []*model.DeviceCommand{}
+	}
+	interval, err := h.heartbeat.interval(sctx, device.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeatResponse{
+		Device:                   device,
+		Commands:                 commands,
+		HeartbeatIntervalSeconds: int64(interval / time.Second),
+	}, nil
+}
+
+// telemetryFromData разбирает "telemetry" из тела heartbeat; nil, если её нет.
+func telemetryFromData(data map[string]interface{}) (*model.Telemetry, error) {
+	raw, ok := data["telemetry"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	return parseTelemetry(raw)
+}
+
+// queuedTelemetryFromData разбирает "queued_telemetry" из тела heartbeat.
+func queuedTelemetryFromData(data map[string]interface{}) ([]*model.DeviceTelemetrySnapshot, error) {
+	raw, ok := data["queued_telemetry"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: queued_telemetry must be a list", app_errors.ErrBadRequest)
+	}
+	if len(items) > maxQueuedTelemetry {
+		return nil, fmt.Errorf("%w: queued_telemetry must have at most %d snapshots", app_errors.ErrBadRequest, maxQueuedTelemetry)
+	}
+	snapshots := make([]*model.DeviceTelemetrySnapshot, 0, len(items))
+	for _, item := range items {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: queued_telemetry must be a list of objects", app_errors.ErrBadRequest)
+		}
+		rawTime, _ := entry["collected_at"].(string)
+		collectedAt, err := time.Parse(time.RFC3339Nano, rawTime)
+		if err != nil {
+			return nil, fmt.Errorf("%w: queued_telemetry.collected_at must be an RFC 3339 time", app_errors.ErrBadRequest)
+		}
+		telemetry, err := parseTelemetry(entry["telemetry"])
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &model.DeviceTelemetrySnapshot{Telemetry: *telemetry, CollectedAt: collectedAt})
+	}
+	return snapshots, nil
+}
+
+// parseTelemetry разбирает и проверяет объект телеметрии.
+func parseTelemetry(raw interface{}) (*model.Telemetry, error) {
+	if _, ok := raw.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("%w: telemetry must be an object", app_errors.ErrBadRequest)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var telemetry model.Telemetry
+	if err := json.Unmarshal(encoded, &telemetry); err != nil {
+		return nil, fmt.Errorf("%w: invalid telemetry: %v", app_errors.ErrBadRequest, err)
+	}
+	if level := telemetry.BatteryLevel; level != nil && (*level < 0 || *level > 100) {
+		return nil, fmt.Errorf("%w: telemetry.battery_level must be between 0 and 100", app_errors.ErrBadRequest)
+	}
+	if telemetry.UptimeSeconds != nil && *telemetry.UptimeSeconds < 0 {
+		return nil, fmt.Errorf("%w: telemetry.uptime_seconds must not be negative", app_errors.ErrBadRequest)
+	}
+	for _, value := range []string{telemetry.BatteryStatus, telemetry.OSName, telemetry.OSVersion} {
+		if len(value) > maxTelemetryString {
+			return nil, fmt.Errorf("%w: telemetry strings must be at most %d bytes", app_errors.ErrBadRequest, maxTelemetryString)
+		}
+	}
+	return &telemetry, nil
 }
 
 // GetDeviceStatusHandler возвращает статус устройства.
-// Ожидается, что в данных будет параметр "id".
+// Ожидается, что в данных будет параметр "id"; удалённое устройство
+// возвращается только с include_deleted=true.
 func (h *Handler) GetDeviceStatusHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
 	id, ok := data["id"].(string)
 	if !ok || id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	return h.deviceRepo.GetDevice(sctx, id)
+	includeDeleted, err := optionalBool(data, "include_deleted")
+	if err != nil {
+		return nil, err
+	}
+	return deviceResponse(h.deviceRepo.GetDeviceStatus(sctx, id, includeDeleted != nil && *includeDeleted))
+}
+
+// Пределы выборки снимков телеметрии.
+const (
+	defaultTelemetrySnapshotLimit = 100
+	maxTelemetrySnapshotLimit     = 1000
+)
+
+// TelemetrySnapshotsHandler возвращает снимки телеметрии, которые устройство "id"
+// сняло без связи с сервером, новые первыми. Параметры: since (RFC 3339) — только
+// более поздние снимки, и limit (по умолчанию 100, не больше 1000).
+func (h *Handler) TelemetrySnapshotsHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	filter := repositories.TelemetrySnapshotFilter{}
+	var ok bool
+	if filter.DeviceID, ok = data["id"].(string); !ok || filter.DeviceID == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	if raw, _ := data["since"].(string); raw != "" {
+		since, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value for since", app_errors.ErrBadRequest)
+		}
+		filter.Since = since
+	}
+	limit, err := optionalInt(data, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultTelemetrySnapshotLimit
+	}
+	filter.Limit = min(limit, maxTelemetrySnapshotLimit)
+	return h.deviceRepo.TelemetrySnapshots(sctx, filter)
 }
 
 // UpdateCameraHandler изменяет состояние камеры устройства.
 // Ожидается, что в данных будет параметр "id" и "enabled".
+// Если передан заголовок If-Match, изменение применяется только к указанной версии.
 func (h *Handler) UpdateCameraHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
 	id, ok := data["id"].(string)
 	if !ok || id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-
-	// Попытка извлечь булево значение из поля "enabled"
-	enabled, ok := data["enabled"].(bool)
-	if !ok {
-		// Если значение передано как строка, попробуем преобразовать
-		if strVal, ok := data["enabled"].(string); ok {
-			parsed, err := strconv.ParseBool(strVal)
-			if err != nil {
-				return nil, fmt.Errorf("invalid value for enabled")
-			}
-			enabled = parsed
-		} else {
-			return nil, fmt.Errorf("enabled parameter is required and must be boolean")
-		}
+	enabled, err := parseEnabled(data)
+	if err != nil {
+		return nil, err
 	}
-
-	return h.deviceRepo.SetCameraState(sctx, id, enabled)
+	version, err := expectedVersion(sctx)
+	if err != nil {
+		return nil, err
+	}
+	return deviceResponse(h.deviceRepo.SetCameraState(sctx, id, enabled, version))
 }
 
 func (h *Handler) UpdateMicrophoneHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
@@ -88,20 +272,15 @@ func (h *Handler) UpdateMicrophoneHandler(sctx smart_context.ISmartContext, data
 	if !ok || id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	enabled, ok := data["enabled"].(bool)
-	if !ok {
-		// Если значение передано как строка, попробуем преобразовать
-		if strVal, ok := data["enabled"].(string); ok {
-			parsed, err := strconv.ParseBool(strVal)
-			if err != nil {
-				return nil, fmt.Errorf("invalid value for enabled")
-			}
-			enabled = parsed
-		} else {
-			return nil, fmt.Errorf("enabled parameter is required and must be boolean")
-		}
+	enabled, err := parseEnabled(data)
+	if err != nil {
+		return nil, err
+	}
+	version, err := expectedVersion(sctx)
+	if err != nil {
+		return nil, err
 	}
-	return h.deviceRepo.SetMicrophoneState(sctx, id, enabled)
+	return deviceResponse(h.deviceRepo.SetMicrophoneState(sctx, id, enabled, version))
 }
 
 func (h *Handler) UpdateBluetoothHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
@@ -109,19 +288,15 @@ func (h *Handler) UpdateBluetoothHandler(sctx smart_context.ISmartContext, data
 	if !ok || id == "" {
 		return nil, fmt.Errorf("id is required")
 	}
-	enabled, ok := data["enabled"].(bool)
-	if !ok {
-		if strVal, ok := data["enabled"].(string); ok {
-			parsed, err := strconv.ParseBool(strVal)
-			if err != nil {
-				return nil, fmt.Errorf("invalid value for enabled")
-			}
-			enabled = parsed
-		} else {
-			return nil, fmt.Errorf("enabled parameter is required and must be boolean")
-		}
+	enabled, err := parseEnabled(data)
+	if err != nil {
+		return nil, err
 	}
-	return h.deviceRepo.SetBluetoothState(sctx, id, enabled)
+	version, err := expectedVersion(sctx)
+	if err != nil {
+		return nil, err
+	}
+	return deviceResponse(h.deviceRepo.SetBluetoothState(sctx, id, enabled, version))
 }
 
 func (h *Handler) UpdateOsVersionHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
@@ -149,37 +324,334 @@ func (h *Handler) UpdateBatteryLevelHandler(sctx smart_context.ISmartContext, da
 	return h.deviceRepo.UpdateBatteryLevel(sctx, id, int(levelVal))
 }
 
-// Ожидается JSON: { "username": "...", "password": "..." }
+// ReportPolicyStatusHandler принимает от агента результат применения настроек:
+// "version" — применённая версия и "results" — список { "setting", "enforced", "error" },
+// где непустой "error" означает, что настройку применить не удалось, а "enforced": false
+// без ошибки — что агент только имитировал применение (dry-run). Агенты, которые
+// не присылают "enforced", считаются применившими настройку.
+func (h *Handler) ReportPolicyStatusHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	version, ok := data["version"].(float64)
+	if !ok || version < 1 {
+		return nil, fmt.Errorf("%w: version is required", app_errors.ErrBadRequest)
+	}
+	results, _ := data["results"].([]interface{})
+	var failures []string
+	enforced := true
+	for _, item := range results {
+		result, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: results must be a list of objects", app_errors.ErrBadRequest)
+		}
+		setting, _ := result["setting"].(string)
+		if applyError, _ := result["error"].(string); applyError != "" {
+			failures = append(failures, setting+": "+applyError)
+		} else if applied, present := result["enforced"].(bool); present && !applied {
+			enforced = false
+		}
+	}
+	return h.deviceRepo.ReportPolicyStatus(sctx, id, int64(version), strings.Join(failures, "; "), enforced)
+}
+
+// Ожидается JSON: { "username": "...", "password": "..." }.
+// Если у пользователя включена 2FA, ответ содержит mfa_token, а вход завершается
+// вторым запросом { "mfa_token": "...", "otp": "123456" } (или "recovery_code");
+// код можно передать и сразу вместе с паролем.
 func (h *Handler) LoginHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	if mfaToken, _ := data["mfa_token"].(string); mfaToken != "" {
+		return h.completeTwoFactorLogin(sctx, mfaToken, data)
+	}
+
 	username, ok1 := data["username"].(string)
 	password, ok2 := data["password"].(string)
 	if !ok1 || !ok2 || username == "" || password == "" {
-		return nil, fmt.Errorf("username and password are required")
+		return nil, fmt.Errorf("%w: username and password are required", app_errors.ErrBadRequest)
 	}
 
-	user, err := h.userRepo.GetByUsername(username)
-	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+	user, err := h.userRepo.GetByUsername(sctx, username)
+	// Пользователи из SSO входят только через IdP.
+	if err != nil || user.AuthProvider != repositories.AuthProviderLocal {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	// Пока вход заблокирован, пароль даже не проверяется.
+	if err := checkLoginLock(user); err != nil {
+		return nil, err
 	}
 
 	// Сравнение захешированного пароля
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, h.recordFailedLogin(sctx, user, ErrInvalidCredentials)
+	}
+	return h.finishLogin(sctx, user, data)
+}
+
+// checkLoginLock возвращает ErrLoginLocked, пока вход пользователя заблокирован.
+func checkLoginLock(user *model.User) error {
+	if wait := time.Until(user.LockedUntil); wait > 0 {
+		return app_errors.WithRetryAfter(ErrLoginLocked, wait)
+	}
+	return nil
+}
+
+// recordFailedLogin учитывает неудачный вход (неверный пароль или код 2FA)
+// и возвращает failure или, если политика заблокировала вход, ErrLoginLocked.
+func (h *Handler) recordFailedLogin(sctx smart_context.ISmartContext, user *model.User, failure error) error {
+	lockedUntil, err := h.userRepo.RecordFailedLogin(sctx, user.ID, h.lockout)
+	if err != nil {
+		return err
+	}
+	if !lockedUntil.IsZero() {
+		sctx.Warnf("Login for %s locked until %s", user.Username, lockedUntil.Format(time.RFC3339))
+		return app_errors.WithRetryAfter(ErrLoginLocked, time.Until(lockedUntil))
+	}
+	return failure
+}
+
+// ConfirmPassword повторно проверяет пароль пользователя userID из "password" и,
+// если у него включена 2FA, "otp" или "recovery_code". Неудачи учитываются и
+// блокируют вход так же, как при /login: подтверждение не обходит ограничение попыток.
+func (h *Handler) ConfirmPassword(sctx smart_context.ISmartContext, userID string, data map[string]interface{}) error {
+	user, err := h.userRepo.GetByID(sctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.AuthProvider != repositories.AuthProviderLocal {
+		return fmt.Errorf("%w: password confirmation is only available to local users; request approval instead", app_errors.ErrForbidden)
+	}
+	if err := checkLoginLock(user); err != nil {
+		return err
+	}
+	password, _ := data["password"].(string)
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		sctx.Warnf("Password confirmation failed for %s", user.Username)
+		return h.recordFailedLogin(sctx, user, ErrConfirmationMismatch)
+	}
+	if user.TotpEnabled {
+		ok, err := h.checkSecondFactor(sctx, user, data)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			sctx.Warnf("Two-factor confirmation failed for %s", user.Username)
+			return h.recordFailedLogin(sctx, user, ErrConfirmationMismatch)
+		}
+	}
+	if user.FailedLoginAttempts > 0 {
+		return h.userRepo.ResetFailedLogins(sctx, user.ID)
+	}
+	return nil
+}
+
+// issueToken завершает вход: сбрасывает счётчик неудач и выдаёт JWT.
+func (h *Handler) issueToken(sctx smart_context.ISmartContext, user *model.User) (string, error) {
+	if user.FailedLoginAttempts > 0 {
+		if err := h.userRepo.ResetFailedLogins(sctx, user.ID); err != nil {
+			return "", err
+		}
 	}
 
 	// Генерируем JWT-токен с информацией о пользователе
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"role":     user.Role,
-		"exp":      time.Now().Add(24 * time.Hour).Unix(),
-	})
-	tokenString, err := token.SignedString(auth.JWTSecret)
+	return auth.IssueJWT(user.ID, user.Username, user.Role)
+}
+
+// tokenResponse оборачивает результат issueToken в ответ /login.
+func tokenResponse(token string, err error) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	return map[string]string{"token": tokenString}, nil
+	return map[string]string{"token": token}, nil
 }
 
+// GetAllDevicesHandler возвращает список устройств.
+// Поддерживаются параметры search, camera_enabled, microphone_enabled,
+// bluetooth_enabled, seen_since (RFC 3339), limit и offset; archived=true —
+// архив выведенных из эксплуатации устройств вместо действующих.
 func (h *Handler) GetAllDevicesHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
-	return h.deviceRepo.GetAllDevices(sctx)
+	filter, err := parseDeviceFilter(data)
+	if err != nil {
+		return nil, err
+	}
+	return h.deviceRepo.FilterDevices(sctx, filter)
+}
+
+// RetireDeviceHandler выводит устройство "id" из эксплуатации: токен отзывается,
+// heartbeat и повторная регистрация отклоняются, история остаётся в архиве.
+func (h *Handler) RetireDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	return h.deviceRepo.Retire(sctx, id, actorOf(sctx))
+}
+
+// IssueEnrollmentTokenHandler выдаёт одноразовый токен перерегистрации устройства
+// "id" (201): с ним агент стёртого устройства или устройства, потерявшего
+// токен, регистрируется заново. Открытое значение показывается один раз.
+func (h *Handler) IssueEnrollmentTokenHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	token, err := h.deviceRepo.IssueEnrollmentToken(sctx, id, h.enrollmentTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{StatusCode: http.StatusCreated, Body: token}, nil
+}
+
+// DeleteDeviceHandler мягко удаляет устройство "id" (выводя из эксплуатации,
+// если оно ещё действует). Запись окончательно удаляется после срока хранения.
+func (h *Handler) DeleteDeviceHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	id, ok := data["id"].(string)
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: id is required", app_errors.ErrBadRequest)
+	}
+	_, err := h.deviceRepo.SoftDelete(sctx, id, actorOf(sctx))
+	return nil, err
+}
+
+// parseEnabled извлекает булево поле "enabled", допускается и строковое значение.
+func parseEnabled(data map[string]interface{}) (bool, error) {
+	if enabled, ok := data["enabled"].(bool); ok {
+		return enabled, nil
+	}
+	// Если значение передано как строка, попробуем преобразовать
+	if strVal, ok := data["enabled"].(string); ok {
+		parsed, err := strconv.ParseBool(strVal)
+		if err != nil {
+			return false, fmt.Errorf("%w: invalid value for enabled", app_errors.ErrBadRequest)
+		}
+		return parsed, nil
+	}
+	return false, fmt.Errorf("%w: enabled parameter is required and must be boolean", app_errors.ErrBadRequest)
+}
+
+// deviceETag формирует ETag по версии настроек устройства.
+func deviceETag(device *model.Device) string {
+	return strconv.Quote(strconv.FormatInt(device.Version, 10))
+}
+
+// expectedVersion разбирает заголовок If-Match. Отсутствующий заголовок или "*"
+// означают, что версия не проверяется.
+func expectedVersion(sctx smart_context.ISmartContext) (int64, error) {
+	ifMatch := strings.TrimSpace(run_processor.GetRequestHeader(sctx, "If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return repositories.AnyVersion, nil
+	}
+	ifMatch = strings.TrimPrefix(ifMatch, "W/")
+	unquoted, err := strconv.Unquote(ifMatch)
+	if err != nil {
+		unquoted = ifMatch
+	}
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%w: invalid If-Match header", app_errors.ErrBadRequest)
+	}
+	return version, nil
+}
+
+// deviceResponse отдаёт устройство вместе с заголовком ETag.
+func deviceResponse(device *model.Device, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{
+		Headers: map[string]string{"ETag": deviceETag(device)},
+		Body:    device,
+	}, nil
+}
+
+// parseDeviceFilter собирает фильтр списка устройств из параметров запроса.
+func parseDeviceFilter(data map[string]interface{}) (repositories.DeviceFilter, error) {
+	var filter repositories.DeviceFilter
+	var err error
+
+	if search, ok := data["search"].(string); ok {
+		filter.Search = strings.TrimSpace(search)
+	}
+	archived, err := optionalBool(data, "archived")
+	if err != nil {
+		return filter, err
+	}
+	filter.Archived = archived != nil && *archived
+	includeDeleted, err := optionalBool(data, "include_deleted")
+	if err != nil {
+		return filter, err
+	}
+	filter.IncludeDeleted = includeDeleted != nil && *includeDeleted
+	if filter.CameraEnabled, err = optionalBool(data, "camera_enabled"); err != nil {
+		return filter, err
+	}
+	if filter.MicrophoneEnabled, err = optionalBool(data, "microphone_enabled"); err != nil {
+		return filter, err
+	}
+	if filter.BluetoothEnabled, err = optionalBool(data, "bluetooth_enabled"); err != nil {
+		return filter, err
+	}
+	if raw, ok := data["seen_since"].(string); ok && raw != "" {
+		seenSince, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid value for seen_since", app_errors.ErrBadRequest)
+		}
+		filter.SeenSince = &seenSince
+	}
+	if filter.Limit, err = optionalInt(data, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = optionalInt(data, "offset"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// optionalBool возвращает nil, если параметр не передан.
+func optionalBool(data map[string]interface{}, key string) (*bool, error) {
+	switch value := data[key].(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &value, nil
+	case string:
+		if value == "" {
+			return nil, nil
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value for %s", app_errors.ErrBadRequest, key)
+		}
+		return &parsed, nil
+	default:
+		return nil, fmt.Errorf("%w: invalid value for %s", app_errors.ErrBadRequest, key)
+	}
+}
+
+// optionalInt возвращает 0, если параметр не передан. Отрицательные значения запрещены.
+func optionalInt(data map[string]interface{}, key string) (int, error) {
+	var n int
+	switch value := data[key].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		n = int(value)
+	case string:
+		if value == "" {
+			return 0, nil
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid value for %s", app_errors.ErrBadRequest, key)
+		}
+		n = parsed
+	default:
+		return 0, fmt.Errorf("%w: invalid value for %s", app_errors.ErrBadRequest, key)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%w: %s must not be negative", app_errors.ErrBadRequest, key)
+	}
+	return n, nil
 }
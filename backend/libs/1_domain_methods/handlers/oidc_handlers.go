@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/3_infrastructure/oidc"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+const (
+	// loginStateCookie хранит state, nonce и PKCE verifier между редиректом на IdP и callback.
+	loginStateCookie = "mdm_oidc_login"
+	// loginStateTTL — сколько у пользователя есть на вход в IdP.
+	loginStateTTL = 10 * time.Minute
+)
+
+// ErrInvalidLoginState — callback пришёл без cookie входа, с чужим state или слишком поздно.
+var ErrInvalidLoginState = fmt.Errorf("%w: invalid or expired login state", app_errors.ErrBadRequest)
+
+// OIDCHandler — вход в админ-панель через корпоративный IdP (authorization code + PKCE).
+// Пользователь создаётся в users при первом входе, роль берётся из групп IdP.
+type OIDCHandler struct {
+	provider *oidc.Provider
+	userRepo repositories.UserRepository
+	// postLoginRedirect — страница админ-панели, которой токен передаётся во фрагменте.
+	postLoginRedirect string
+	secureCookie      bool
+}
+
+// NewOIDCHandler создаёт обработчики SSO. redirectURL — адрес callback этого
+// сервера: по его схеме решается, ставить ли cookie с флагом Secure.
+func NewOIDCHandler(provider *oidc.Provider, userRepo repositories.UserRepository, redirectURL, postLoginRedirect string) *OIDCHandler {
+	return &OIDCHandler{
+		provider:          provider,
+		userRepo:          userRepo,
+		postLoginRedirect: postLoginRedirect,
+		secureCookie:      strings.HasPrefix(redirectURL, "https://"),
+	}
+}
+
+// LoginHandler перенаправляет на страницу входа IdP.
+func (h *OIDCHandler) LoginHandler(sctx smart_context.ISmartContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		login, err := oidc.NewLoginState()
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		authURL, err := h.provider.AuthCodeURL(r.Context(), login)
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		// Сервер ничего не хранит: state и verifier подписаны, подменить их клиент не может.
+		value, err := auth.SignValue(loginStatePurpose, login, loginStateTTL)
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		http.SetCookie(w, h.cookie(value, int(loginStateTTL.Seconds())))
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// CallbackHandler принимает код от IdP, создаёт или обновляет пользователя
+// и выдаёт обычный JWT MDM: редиректом на админ-панель или JSON {"token": ...}.
+func (h *OIDCHandler) CallbackHandler(sctx smart_context.ISmartContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Cookie одноразовая независимо от исхода.
+		http.SetCookie(w, h.cookie("", -1))
+
+		q := r.URL.Query()
+		if idpErr := q.Get("error"); idpErr != "" {
+			h.fail(sctx, w, r, fmt.Errorf("%w: identity provider error: %s %s", app_errors.ErrUnauthorized, idpErr, q.Get("error_description")))
+			return
+		}
+		login, err := loginStateFromRequest(r)
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(login.State)) != 1 {
+			h.fail(sctx, w, r, fmt.Errorf("%w: state mismatch", ErrInvalidLoginState))
+			return
+		}
+		code := q.Get("code")
+		if code == "" {
+			h.fail(sctx, w, r, fmt.Errorf("%w: code is required", app_errors.ErrBadRequest))
+			return
+		}
+
+		identity, err := h.provider.Exchange(r.Context(), code, login)
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		user, err := h.userRepo.ProvisionExternalUser(sctx.WithContext(r.Context()), repositories.ExternalIdentity{
+			Provider: repositories.AuthProviderOIDC,
+			Subject:  identity.Subject,
+			Username: identity.Username,
+			Email:    identity.Email,
+			Role:     identity.Role,
+		})
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		token, err := auth.IssueJWT(user.ID, user.Username, user.Role)
+		if err != nil {
+			h.fail(sctx, w, r, err)
+			return
+		}
+		sctx.Infof("SSO login for %s (role %s)", user.Username, user.Role)
+
+		if h.postLoginRedirect != "" {
+			// Фрагмент не уходит на сервер и не попадает в логи прокси.
+			http.Redirect(w, r, h.postLoginRedirect+"#token="+url.QueryEscape(token), http.StatusFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"token": token})
+	}
+}
+
+// fail отдаёт ошибку входа: админ-панели — во фрагменте #error=..., иначе JSON.
+func (h *OIDCHandler) fail(sctx smart_context.ISmartContext, w http.ResponseWriter, r *http.Request, err error) {
+	sctx.Warnf("SSO login failed: %v", err)
+	if h.postLoginRedirect != "" {
+		http.Redirect(w, r, h.postLoginRedirect+"#error="+url.QueryEscape(err.Error()), http.StatusFound)
+		return
+	}
+	writeJSON(w, app_errors.StatusCode(err), map[string]string{"error": err.Error()})
+}
+
+func (h *OIDCHandler) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     loginStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.secureCookie,
+		// Lax: cookie отправляется при возврате с IdP обычным переходом по ссылке.
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// loginStatePurpose — назначение подписи cookie входа (см. auth.SignValue).
+const loginStatePurpose = "oidc-login"
+
+func loginStateFromRequest(r *http.Request) (oidc.LoginState, error) {
+	cookie, err := r.Cookie(loginStateCookie)
+	if err != nil || cookie.Value == "" {
+		return oidc.LoginState{}, fmt.Errorf("%w: login cookie missing", ErrInvalidLoginState)
+	}
+	var login oidc.LoginState
+	if err := auth.VerifyValue(loginStatePurpose, cookie.Value, &login); err != nil {
+		return oidc.LoginState{}, fmt.Errorf("%w: %v", ErrInvalidLoginState, err)
+	}
+	return login, nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
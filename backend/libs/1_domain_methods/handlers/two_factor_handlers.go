@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+// TwoFactorPolicy — требования к двухфакторной аутентификации (TOTP).
+type TwoFactorPolicy struct {
+	// RequiredRoles — роли, которым вход без 2FA запрещён: при входе им
+	// предлагается подключить приложение-аутентификатор.
+	RequiredRoles []string
+	// Issuer — название сервиса в приложении-аутентификаторе.
+	Issuer string
+}
+
+// Required сообщает, обязательна ли 2FA для роли.
+func (p TwoFactorPolicy) Required(role string) bool {
+	return slices.Contains(p.RequiredRoles, role)
+}
+
+const (
+	// Назначения mfa_token: второй шаг входа и подключение 2FA при входе.
+	mfaLoginPurpose  = "mfa-login"
+	mfaEnrollPurpose = "mfa-enroll"
+	// mfaTokenTTL — сколько после проверки пароля ждём второй шаг.
+	mfaTokenTTL = 5 * time.Minute
+	// recoveryCodeCount — сколько кодов восстановления выдаётся за раз.
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidOTP         = fmt.Errorf("%w: invalid two-factor code", app_errors.ErrUnauthorized)
+	ErrInvalidMFAToken    = fmt.Errorf("%w: invalid or expired mfa_token", app_errors.ErrUnauthorized)
+	ErrTOTPAlreadyEnabled = fmt.Errorf("%w: two-factor authentication is already enabled", app_errors.ErrConflict)
+	ErrTOTPNotPending     = fmt.Errorf("%w: two-factor enrollment has not been started", app_errors.ErrConflict)
+	ErrTOTPNotEnabled     = fmt.Errorf("%w: two-factor authentication is not enabled", app_errors.ErrConflict)
+	ErrTwoFactorRequired  = fmt.Errorf("%w: two-factor authentication is required for this role", app_errors.ErrForbidden)
+	// ErrTwoFactorExternal — вторым фактором пользователей SSO управляет IdP.
+	ErrTwoFactorExternal = fmt.Errorf("%w: two-factor authentication of SSO users is managed by the identity provider", app_errors.ErrConflict)
+	// ErrTOTPSecretUnreadable — секрет TOTP не расшифровывается (сменился или не задан
+	// DATA_ENCRYPTION_KEY). Войти можно кодом восстановления.
+	ErrTOTPSecretUnreadable = fmt.Errorf("%w: the two-factor secret cannot be decrypted on the server; sign in with a recovery code and ask an administrator to check DATA_ENCRYPTION_KEY", app_errors.ErrUnprocessable)
+	// ErrTOTPUnavailable — секрет TOTP не зашифровать: на сервере не задан DATA_ENCRYPTION_KEY.
+	ErrTOTPUnavailable = fmt.Errorf("%w: two-factor enrollment is unavailable until an administrator sets DATA_ENCRYPTION_KEY", app_errors.ErrUnprocessable)
+)
+
+// mfaClaims — содержимое mfa_token: пароль пользователя уже проверен.
+type mfaClaims struct {
+	UserID string `json:"uid"`
+}
+
+// mfaChallenge — ответ на верный пароль, когда для входа нужен второй фактор.
+type mfaChallenge struct {
+	// MFARequired — нужно прислать mfa_token с "otp" или "recovery_code" на /login.
+	MFARequired bool `json:"mfa_required,omitempty"`
+	// EnrollmentRequired — роль требует 2FA, но она не подключена:
+	// подключение идёт через /login/2fa/enroll и /login/2fa/confirm.
+	EnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+// totpEnrollment — секрет для ручного ввода и URI для QR-кода.
+type totpEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// twoFactorStatus — ответ GET /2fa.
+type twoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// finishLogin продолжает вход после верного пароля: выдаёт токен или
+// требует второй фактор.
+func (h *Handler) finishLogin(sctx smart_context.ISmartContext, user *model.User, data map[string]interface{}) (interface{}, error) {
+	switch {
+	case user.TotpEnabled && hasSecondFactor(data):
+		if err := h.verifySecondFactor(sctx, user, data); err != nil {
+			return nil, err
+		}
+		return tokenResponse(h.issueToken(sctx, user))
+	case user.TotpEnabled:
+		return newChallenge(user, mfaLoginPurpose)
+	case h.twoFactor.Required(user.Role):
+		return newChallenge(user, mfaEnrollPurpose)
+	}
+	return tokenResponse(h.issueToken(sctx, user))
+}
+
+// completeTwoFactorLogin — второй шаг входа: mfa_token и код.
+func (h *Handler) completeTwoFactorLogin(sctx smart_context.ISmartContext, mfaToken string, data map[string]interface{}) (interface{}, error) {
+	user, err := h.userFromMFAToken(sctx, mfaLoginPurpose, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := h.verifySecondFactor(sctx, user, data); err != nil {
+		return nil, err
+	}
+	return tokenResponse(h.issueToken(sctx, user))
+}
+
+func newChallenge(user *model.User, purpose string) (*mfaChallenge, error) {
+	token, err := auth.SignValue(purpose, mfaClaims{UserID: user.ID}, mfaTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &mfaChallenge{
+		MFARequired:        purpose == mfaLoginPurpose,
+		EnrollmentRequired: purpose == mfaEnrollPurpose,
+		MFAToken:           token,
+		ExpiresIn:          int(mfaTokenTTL.Seconds()),
+	}, nil
+}
+
+// userFromMFAToken проверяет mfa_token и возвращает пользователя, если вход не заблокирован.
+func (h *Handler) userFromMFAToken(sctx smart_context.ISmartContext, purpose, token string) (*model.User, error) {
+	var claims mfaClaims
+	if err := auth.VerifyValue(purpose, token, &claims); err != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	user, err := h.userRepo.GetByID(sctx, claims.UserID)
+	if err != nil || user.AuthProvider != repositories.AuthProviderLocal {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := checkLoginLock(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func hasSecondFactor(data map[string]interface{}) bool {
+	return stringField(data, "otp") != "" || stringField(data, "recovery_code") != ""
+}
+
+// stringField читает строковое поле; код из цифр мог прийти числом.
+func stringField(data map[string]interface{}, name string) string {
+	switch v := data[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%06d", int64(v))
+	}
+	return ""
+}
+
+// verifySecondFactor проверяет "otp" или одноразовый "recovery_code".
+// Неверный код считается неудачной попыткой входа и ведёт к блокировке так же, как пароль.
+func (h *Handler) verifySecondFactor(sctx smart_context.ISmartContext, user *model.User, data map[string]interface{}) error {
+	ok, err := h.checkSecondFactor(sctx, user, data)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return h.recordFailedLogin(sctx, user, ErrInvalidOTP)
+	}
+	return nil
+}
+
+// checkSecondFactor сверяет "otp" или "recovery_code", не учитывая неудачу.
+func (h *Handler) checkSecondFactor(sctx smart_context.ISmartContext, user *model.User, data map[string]interface{}) (bool, error) {
+	otp := stringField(data, "otp")
+	recoveryCode := stringField(data, "recovery_code")
+
+	switch {
+	case otp != "":
+		secret, err := openTOTPSecret(sctx, user)
+		if err != nil {
+			return false, err
+		}
+		step, valid := auth.ValidateTOTP(secret, otp, time.Now(), user.TotpLastStep)
+		if !valid {
+			return false, nil
+		}
+		// Код засчитывается один раз, даже при параллельных запросах.
+		return h.userRepo.UseTOTPStep(sctx, user.ID, step)
+	case recoveryCode != "":
+		ok, err := h.userRepo.UseRecoveryCode(sctx, user.ID, auth.HashRecoveryCode(recoveryCode))
+		if ok {
+			sctx.Warnf("Recovery code used by %s", user.Username)
+		}
+		return ok, err
+	default:
+		return false, fmt.Errorf("%w: otp or recovery_code is required", app_errors.ErrBadRequest)
+	}
+}
+
+// openTOTPSecret расшифровывает секрет TOTP. Причина ошибки пишется только в лог,
+// клиент получает ErrTOTPSecretUnreadable.
+func openTOTPSecret(sctx smart_context.ISmartContext, user *model.User) (string, error) {
+	secret, err := auth.Open(auth.SealPurposeTOTP, user.TotpSecret)
+	if err != nil {
+		sctx.Errorf("Failed to decrypt TOTP secret of %s: %v", user.Username, err)
+		return "", ErrTOTPSecretUnreadable
+	}
+	return string(secret), nil
+}
+
+// startEnrollment создаёт новый секрет; до подтверждения кодом 2FA не включена.
+func (h *Handler) startEnrollment(sctx smart_context.ISmartContext, user *model.User) (*totpEnrollment, error) {
+	if user.TotpEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := auth.Seal(auth.SealPurposeTOTP, []byte(secret))
+	if err != nil {
+		sctx.Errorf("Failed to encrypt TOTP secret of %s: %v", user.Username, err)
+		return nil, ErrTOTPUnavailable
+	}
+	if err := h.userRepo.SetPendingTOTP(sctx, user.ID, sealed); err != nil {
+		return nil, err
+	}
+	return &totpEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(h.twoFactor.Issuer, user.Username, secret),
+	}, nil
+}
+
+// confirmEnrollment включает 2FA по первому коду из приложения и выдаёт коды восстановления.
+func (h *Handler) confirmEnrollment(sctx smart_context.ISmartContext, user *model.User, data map[string]interface{}) ([]string, error) {
+	if user.TotpEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TotpSecret == "" {
+		return nil, ErrTOTPNotPending
+	}
+	otp := stringField(data, "otp")
+	if otp == "" {
+		return nil, fmt.Errorf("%w: otp is required", app_errors.ErrBadRequest)
+	}
+	secret, err := openTOTPSecret(sctx, user)
+	if err != nil {
+		// Кодов восстановления до подтверждения ещё нет: настройку нужно начать заново.
+		return nil, fmt.Errorf("%w: the pending two-factor secret cannot be decrypted, start enrollment again", app_errors.ErrUnprocessable)
+	}
+	step, ok := auth.ValidateTOTP(secret, otp, time.Now(), 0)
+	if !ok {
+		return nil, h.recordFailedLogin(sctx, user, ErrInvalidOTP)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.userRepo.EnableTOTP(sctx, user.ID, step, hashes); err != nil {
+		return nil, err
+	}
+	sctx.Infof("Two-factor authentication enabled for %s", user.Username)
+	return codes, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.NewRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// LoginEnrollTOTPHandler начинает обязательное подключение 2FA при входе.
+// Ожидается "mfa_token" из ответа /login с mfa_enrollment_required.
+func (h *Handler) LoginEnrollTOTPHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	token, _ := data["mfa_token"].(string)
+	user, err := h.userFromMFAToken(sctx, mfaEnrollPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	return h.startEnrollment(sctx, user)
+}
+
+// LoginConfirmTOTPHandler завершает подключение 2FA при входе: ожидаются
+// "mfa_token" и "otp". Возвращает коды восстановления и токен.
+func (h *Handler) LoginConfirmTOTPHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	token, _ := data["mfa_token"].(string)
+	user, err := h.userFromMFAToken(sctx, mfaEnrollPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := h.confirmEnrollment(sctx, user, data)
+	if err != nil {
+		return nil, err
+	}
+	token, err = h.issueToken(sctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"token": token, "recovery_codes": codes}, nil
+}
+
+// currentUser возвращает локального пользователя, выполняющего запрос.
+// Настройки 2FA недоступны API-ключам и пользователям SSO.
+func (h *Handler) currentUser(sctx smart_context.ISmartContext) (*model.User, error) {
+	principal := auth.PrincipalFromContext(sctx.GetContext())
+	if principal == nil || principal.Kind != auth.PrincipalUser {
+		return nil, fmt.Errorf("%w: two-factor settings are available to users only", app_errors.ErrForbidden)
+	}
+	user, err := h.userRepo.GetByID(sctx, principal.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AuthProvider != repositories.AuthProviderLocal {
+		return nil, ErrTwoFactorExternal
+	}
+	return user, nil
+}
+
+// TwoFactorStatusHandler возвращает состояние 2FA текущего пользователя.
+func (h *Handler) TwoFactorStatusHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	user, err := h.currentUser(sctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &twoFactorStatus{Enabled: user.TotpEnabled, Required: h.twoFactor.Required(user.Role)}
+	if user.TotpEnabled {
+		if status.RecoveryCodesRemaining, err = h.userRepo.CountRecoveryCodes(sctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// EnrollTOTPHandler выдаёт новый секрет TOTP текущему пользователю.
+func (h *Handler) EnrollTOTPHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	user, err := h.currentUser(sctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.startEnrollment(sctx, user)
+}
+
+// ConfirmTOTPHandler включает 2FA по коду "otp" и возвращает коды восстановления.
+func (h *Handler) ConfirmTOTPHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	user, err := h.currentUser(sctx)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := h.confirmEnrollment(sctx, user, data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]string{"recovery_codes": codes}, nil
+}
+
+// DisableTOTPHandler отключает 2FA; ожидается "otp" или "recovery_code".
+// Для ролей из TwoFactorPolicy.RequiredRoles отключение запрещено.
+func (h *Handler) DisableTOTPHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	user, err := h.currentUser(sctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	if h.twoFactor.Required(user.Role) {
+		return nil, ErrTwoFactorRequired
+	}
+	if err := h.verifySecondFactor(sctx, user, data); err != nil {
+		return nil, err
+	}
+	if err := h.userRepo.DisableTOTP(sctx, user.ID); err != nil {
+		return nil, err
+	}
+	sctx.Warnf("Two-factor authentication disabled for %s", user.Username)
+	return map[string]bool{"enabled": false}, nil
+}
+
+// RegenerateRecoveryCodesHandler заменяет коды восстановления новыми;
+// ожидается "otp" или "recovery_code".
+func (h *Handler) RegenerateRecoveryCodesHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	user, err := h.currentUser(sctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TotpEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err := h.verifySecondFactor(sctx, user, data); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.userRepo.ReplaceRecoveryCodes(sctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	return map[string][]string{"recovery_codes": codes}, nil
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/3_infrastructure/migrator"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+	"mdm/migration"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const testPassword = "correct horse"
+
+// setupTwoFactorTest создаёт обработчик на in-memory SQLite с администратором
+// "admin": для роли admin 2FA обязательна, вход блокируется после трёх неудач.
+func setupTwoFactorTest(t *testing.T) (*Handler, smart_context.ISmartContext) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open in-memory sqlite: %v", err)
+	}
+	logger := smart_context.NewSmartContext()
+	migrations, err := migrator.Load(migration.FS, db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(logger, db, migrations); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+	sctx := logger.WithDB(db)
+
+	auth.SetJWTSecret("test-secret")
+	auth.SetDataEncryptionKey("test-data-encryption-key")
+
+	userRepo := repositories.NewUserRepository()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+	if _, err := userRepo.CreateLocal(sctx, "admin", string(hash), "admin"); err != nil {
+		t.Fatalf("CreateLocal failed: %v", err)
+	}
+
+	h := NewHandler(repositories.NewDeviceRepository(), repositories.NewCommandRepository(), userRepo,
+		repositories.LockoutPolicy{MaxAttempts: 3, Delay: time.Minute, MaxDelay: time.Hour},
+		TwoFactorPolicy{RequiredRoles: []string{"admin"}, Issuer: "MDM"},
+		HeartbeatPolicy{Interval: time.Minute}, time.Hour)
+	return h, sctx
+}
+
+// totpCode возвращает код секрета для шага, смещённого от текущего на offset.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	return code
+}
+
+// loginChallenge проверяет пароль и возвращает запрос второго шага.
+func loginChallenge(t *testing.T, h *Handler, sctx smart_context.ISmartContext) *mfaChallenge {
+	t.Helper()
+	resp, err := h.LoginHandler(sctx, map[string]interface{}{"username": "admin", "password": testPassword})
+	if err != nil {
+		t.Fatalf("LoginHandler failed: %v", err)
+	}
+	challenge, ok := resp.(*mfaChallenge)
+	if !ok || challenge.MFAToken == "" {
+		t.Fatalf("Expected an mfa challenge, got %#v", resp)
+	}
+	return challenge
+}
+
+// enrollAdmin проходит обязательное подключение 2FA при входе. Подтверждение —
+// кодом предыдущего шага, чтобы код текущего шага остался для входа.
+func enrollAdmin(t *testing.T, h *Handler, sctx smart_context.ISmartContext) (secret string, recoveryCodes []string) {
+	t.Helper()
+	challenge := loginChallenge(t, h, sctx)
+	if !challenge.EnrollmentRequired || challenge.MFARequired {
+		t.Fatalf("Expected enrollment to be required, got %+v", challenge)
+	}
+
+	resp, err := h.LoginEnrollTOTPHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken})
+	if err != nil {
+		t.Fatalf("LoginEnrollTOTPHandler failed: %v", err)
+	}
+	secret = resp.(*totpEnrollment).Secret
+
+	resp, err = h.LoginConfirmTOTPHandler(sctx, map[string]interface{}{
+		"mfa_token": challenge.MFAToken,
+		"otp":       totpCode(t, secret, -1),
+	})
+	if err != nil {
+		t.Fatalf("LoginConfirmTOTPHandler failed: %v", err)
+	}
+	confirmed := resp.(map[string]interface{})
+	if token, _ := confirmed["token"].(string); token == "" {
+		t.Errorf("Expected a token after enrollment, got %#v", confirmed)
+	}
+	recoveryCodes, _ = confirmed["recovery_codes"].([]string)
+	if len(recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %v", recoveryCodeCount, recoveryCodes)
+	}
+	return secret, recoveryCodes
+}
+
+func admin(t *testing.T, h *Handler, sctx smart_context.ISmartContext) *model.User {
+	t.Helper()
+	user, err := h.userRepo.GetByUsername(sctx, "admin")
+	if err != nil {
+		t.Fatalf("GetByUsername failed: %v", err)
+	}
+	return user
+}
+
+// TestLoginForcesEnrollment проверяет, что роли с обязательной 2FA токен без
+// подключения не выдаётся, а токен подключения не подходит для второго шага входа.
+func TestLoginForcesEnrollment(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+
+	challenge := loginChallenge(t, h, sctx)
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": "123456"}); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("Expected the enrollment mfa_token to be rejected for login, got %v", err)
+	}
+	if admin(t, h, sctx).TotpEnabled {
+		t.Fatalf("Expected 2FA to stay disabled before confirmation")
+	}
+
+	enrollAdmin(t, h, sctx)
+	if !admin(t, h, sctx).TotpEnabled {
+		t.Errorf("Expected 2FA to be enabled after confirmation")
+	}
+}
+
+// TestLoginWithSecondFactor проверяет вход в два шага: пароль, затем mfa_token
+// с кодом из приложения или с одноразовым кодом восстановления.
+func TestLoginWithSecondFactor(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+	secret, recoveryCodes := enrollAdmin(t, h, sctx)
+
+	challenge := loginChallenge(t, h, sctx)
+	if !challenge.MFARequired {
+		t.Fatalf("Expected the second factor to be required, got %+v", challenge)
+	}
+	resp, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": totpCode(t, secret, 0)})
+	if err != nil {
+		t.Fatalf("Login with otp failed: %v", err)
+	}
+	if token := resp.(map[string]string)["token"]; token == "" {
+		t.Errorf("Expected a token, got %#v", resp)
+	}
+
+	challenge = loginChallenge(t, h, sctx)
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "recovery_code": recoveryCodes[0]}); err != nil {
+		t.Fatalf("Login with recovery code failed: %v", err)
+	}
+	challenge = loginChallenge(t, h, sctx)
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "recovery_code": recoveryCodes[0]}); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+
+	// Пароль с кодом в одном запросе тоже завершает вход.
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"username": "admin", "password": testPassword, "recovery_code": recoveryCodes[1]}); err != nil {
+		t.Errorf("Login with password and recovery code failed: %v", err)
+	}
+}
+
+// TestTOTPStepReplay проверяет, что принятый код (и коды более ранних шагов)
+// нельзя предъявить повторно.
+func TestTOTPStepReplay(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+	secret, _ := enrollAdmin(t, h, sctx)
+	code := totpCode(t, secret, 0)
+
+	challenge := loginChallenge(t, h, sctx)
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": code}); err != nil {
+		t.Fatalf("Login with otp failed: %v", err)
+	}
+
+	for _, replay := range []string{code, totpCode(t, secret, -1)} {
+		challenge = loginChallenge(t, h, sctx)
+		if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": replay}); !errors.Is(err, ErrInvalidOTP) {
+			t.Errorf("Expected replayed code %s to be rejected, got %v", replay, err)
+		}
+	}
+}
+
+// TestLockoutAfterWrongOTPs проверяет, что неверные коды блокируют вход так же,
+// как неверный пароль: после блокировки не принимается и верный код.
+func TestLockoutAfterWrongOTPs(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+	secret, _ := enrollAdmin(t, h, sctx)
+	challenge := loginChallenge(t, h, sctx)
+
+	// Код далёкого шага не попадает в окно допуска.
+	wrong := totpCode(t, secret, 100)
+	for attempt := 1; attempt <= 3; attempt++ {
+		_, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": wrong})
+		if attempt < 3 && !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("Attempt %d: expected ErrInvalidOTP, got %v", attempt, err)
+		}
+		if attempt == 3 && !errors.Is(err, ErrLoginLocked) {
+			t.Fatalf("Attempt %d: expected ErrLoginLocked, got %v", attempt, err)
+		}
+	}
+
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": totpCode(t, secret, 0)}); !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("Expected a valid code to be rejected while locked, got %v", err)
+	}
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"username": "admin", "password": testPassword}); !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("Expected password login to be locked too, got %v", err)
+	}
+}
+
+// TestConfirmPassword проверяет подтверждение стирания: с 2FA нужен код, а неверные
+// пароль и код учитываются в блокировке входа.
+func TestConfirmPassword(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+	secret, recoveryCodes := enrollAdmin(t, h, sctx)
+	userID := admin(t, h, sctx).ID
+
+	if err := h.ConfirmPassword(sctx, userID, map[string]interface{}{"password": testPassword}); !errors.Is(err, app_errors.ErrBadRequest) {
+		t.Errorf("Expected the second factor to be required, got %v", err)
+	}
+	if err := h.ConfirmPassword(sctx, userID, map[string]interface{}{"password": testPassword, "otp": totpCode(t, secret, 0)}); err != nil {
+		t.Errorf("Expected password and otp to confirm, got %v", err)
+	}
+	if err := h.ConfirmPassword(sctx, userID, map[string]interface{}{"password": testPassword, "recovery_code": recoveryCodes[0]}); err != nil {
+		t.Errorf("Expected password and recovery code to confirm, got %v", err)
+	}
+
+	wrong := []map[string]interface{}{
+		{"password": "wrong", "otp": totpCode(t, secret, 1)},
+		{"password": testPassword, "otp": totpCode(t, secret, 100)},
+		{"password": testPassword, "recovery_code": recoveryCodes[0]},
+	}
+	for attempt, data := range wrong {
+		err := h.ConfirmPassword(sctx, userID, data)
+		if attempt < 2 && !errors.Is(err, ErrConfirmationMismatch) {
+			t.Fatalf("Attempt %d: expected ErrConfirmationMismatch, got %v", attempt+1, err)
+		}
+		if attempt == 2 && !errors.Is(err, ErrLoginLocked) {
+			t.Fatalf("Attempt %d: expected ErrLoginLocked, got %v", attempt+1, err)
+		}
+	}
+	if err := h.ConfirmPassword(sctx, userID, map[string]interface{}{"password": testPassword, "recovery_code": recoveryCodes[1]}); !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("Expected confirmation to be locked, got %v", err)
+	}
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"username": "admin", "password": testPassword}); !errors.Is(err, ErrLoginLocked) {
+		t.Errorf("Expected password login to be locked too, got %v", err)
+	}
+}
+
+// TestUndecryptableTOTPSecret проверяет, что при сменённом DATA_ENCRYPTION_KEY
+// вход по коду даёт понятную ошибку, а код восстановления продолжает работать.
+func TestUndecryptableTOTPSecret(t *testing.T) {
+	h, sctx := setupTwoFactorTest(t)
+	secret, recoveryCodes := enrollAdmin(t, h, sctx)
+	auth.SetDataEncryptionKey("another-data-encryption-key")
+
+	challenge := loginChallenge(t, h, sctx)
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "otp": totpCode(t, secret, 0)}); !errors.Is(err, ErrTOTPSecretUnreadable) {
+		t.Errorf("Expected ErrTOTPSecretUnreadable, got %v", err)
+	}
+	if _, err := h.LoginHandler(sctx, map[string]interface{}{"mfa_token": challenge.MFAToken, "recovery_code": recoveryCodes[0]}); err != nil {
+		t.Errorf("Expected recovery code to work, got %v", err)
+	}
+}
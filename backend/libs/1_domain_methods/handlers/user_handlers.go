@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"mdm/libs/1_domain_methods/repositories"
+	"mdm/libs/1_domain_methods/run_processor"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// minPasswordLength — минимальная длина пароля нового пользователя.
+	minPasswordLength = 8
+	// maxUsernameLength — предельная длина имени пользователя (столбец VARCHAR(255)).
+	maxUsernameLength = 255
+)
+
+// UserHandler — управление пользователями (права users:manage).
+type UserHandler struct {
+	repo repositories.UserRepository
+}
+
+// NewUserHandler создаёт новый экземпляр UserHandler.
+func NewUserHandler(repo repositories.UserRepository) *UserHandler {
+	return &UserHandler{repo: repo}
+}
+
+// ListUsersHandler возвращает всех пользователей (без хешей паролей и секретов 2FA).
+func (h *UserHandler) ListUsersHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	return h.repo.List(sctx)
+}
+
+// CreateUserHandler создаёт пользователя, входящего по паролю. Ожидаются "username",
+// "password" (не короче 8 символов) и "role" — "admin" или "user" (по умолчанию "user").
+func (h *UserHandler) CreateUserHandler(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+	username, _ := data["username"].(string)
+	username = strings.TrimSpace(username)
+	if username == "" || len(username) > maxUsernameLength || strings.ContainsAny(username, " \t\r\n") {
+		return nil, fmt.Errorf("%w: username is required, must be at most %d bytes and contain no spaces", app_errors.ErrBadRequest, maxUsernameLength)
+	}
+	password, _ := data["password"].(string)
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: password must be at least %d characters", app_errors.ErrBadRequest, minPasswordLength)
+	}
+	role, _ := data["role"].(string)
+	if role == "" {
+		role = "user"
+	}
+	if _, ok := auth.RoleScopes[role]; !ok {
+		return nil, fmt.Errorf("%w: unknown role %q", app_errors.ErrBadRequest, role)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.repo.CreateLocal(sctx, username, string(hash), role)
+	if err != nil {
+		return nil, err
+	}
+	return &run_processor.Response{StatusCode: http.StatusCreated, Body: user}, nil
+}
@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Действия над устройством. Тип команды, которую получает агент, совпадает с действием.
+const (
+	ActionLock   = "lock"
+	ActionUnlock = "unlock"
+	ActionWipe   = "wipe"
+)
+
+// Статусы действия.
+const (
+	// ActionStatusPendingApproval — стирание ждёт подтверждения вторым администратором.
+	ActionStatusPendingApproval = "pending_approval"
+	// ActionStatusIssued — команда поставлена в очередь устройства.
+	ActionStatusIssued   = "issued"
+	ActionStatusRejected = "rejected"
+	// ActionStatusExpired — подтверждение не получено вовремя.
+	ActionStatusExpired = "expired"
+)
+
+var (
+	// ErrActionNotPending — действие уже выдано, отклонено или просрочено.
+	ErrActionNotPending = fmt.Errorf("%w: action is not pending approval", app_errors.ErrConflict)
+	// ErrSelfApproval — запросивший стирание не может сам его подтвердить,
+	// в том числе через свой API-ключ.
+	ErrSelfApproval = fmt.Errorf("%w: action must be approved by another administrator", app_errors.ErrForbidden)
+	// ErrRequesterUnknown — запросивший не сводится к пользователю (API-ключ без
+	// владельца), поэтому правило двух лиц проверить нельзя.
+	ErrRequesterUnknown = fmt.Errorf("%w: requester has no owning user, approval cannot be verified", app_errors.ErrForbidden)
+	// ErrApprovalExpired — срок подтверждения истёк.
+	ErrApprovalExpired = fmt.Errorf("%w: approval period has expired", app_errors.ErrConflict)
+	// ErrDeviceWiped — устройство стёрто или стирается; действия недоступны до перерегистрации.
+	ErrDeviceWiped = fmt.Errorf("%w: device is wiped or being wiped", app_errors.ErrConflict)
+)
+
+// ActionRequest — запрос действия над устройством.
+type ActionRequest struct {
+	DeviceID string
+	Action   string
+	// Payload — JSON-объект, который агент получит в команде.
+	Payload     string
+	RequestedBy string
+	// RequestedByUserID — пользователь, запросивший действие сам или через свой API-ключ.
+	RequestedByUserID string
+	// ApprovedBy — кто подтвердил действие; пустой — нужно подтверждение вторым
+	// администратором в течение ApprovalTTL.
+	ApprovedBy  string
+	ApprovalTTL time.Duration
+}
+
+// ActionFilter — параметры выборки действий. Пустые поля не применяются.
+type ActionFilter struct {
+	DeviceID string
+	Status   string
+	// Limit <= 0 означает «без ограничения».
+	Limit int
+}
+
+// ActionRepository — блокировка и стирание устройств с подтверждением.
+type ActionRepository interface {
+	// Request создаёт действие: подтверждённое сразу выдаётся устройству,
+	// остальные ждут Approve.
+	Request(sctx smart_context.ISmartContext, request ActionRequest) (*model.DeviceAction, error)
+	Get(sctx smart_context.ISmartContext, id string) (*model.DeviceAction, error)
+	// List возвращает действия, новые первыми.
+	List(sctx smart_context.ISmartContext, filter ActionFilter) ([]*model.DeviceAction, error)
+	// Approve подтверждает ожидающее действие от имени пользователя approverUserID
+	// и выдаёт его устройству; это должен быть не тот пользователь, что запросил действие.
+	Approve(sctx smart_context.ISmartContext, id, approver, approverUserID string) (*model.DeviceAction, error)
+	// Reject отклоняет ожидающее действие; rejectedBy сохраняется в approved_by
+	// как принявший решение.
+	Reject(sctx smart_context.ISmartContext, id, rejectedBy string) (*model.DeviceAction, error)
+	// ExpirePending помечает просроченными действия, не подтверждённые к моменту now.
+	ExpirePending(sctx smart_context.ISmartContext, now time.Time) (int64, error)
+}
+
+type action_repository struct {
+	commands CommandRepository
+}
+
+// NewActionRepository возвращает репозиторий действий; команды устройствам
+// ставятся через commands.
+func NewActionRepository(commands CommandRepository) ActionRepository {
+	return &action_repository{commands: commands}
+}
+
+func (r *action_repository) Request(sctx smart_context.ISmartContext, request ActionRequest) (*model.DeviceAction, error) {
+	if request.ApprovedBy == "" && request.RequestedByUserID == "" {
+		return nil, ErrRequesterUnknown
+	}
+	var action *model.DeviceAction
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		if _, err := r.activeDevice(tx, request.DeviceID); err != nil {
+			return err
+		}
+		now := time.Now()
+		action = &model.DeviceAction{
+			DeviceID:          request.DeviceID,
+			Action:            request.Action,
+			Payload:           request.Payload,
+			Status:            ActionStatusPendingApproval,
+			RequestedBy:       request.RequestedBy,
+			RequestedByUserID: request.RequestedByUserID,
+			CreatedAt:         now,
+		}
+		if action.Payload == "" {
+			action.Payload = "{}"
+		}
+		if request.ApprovedBy == "" {
+			expiresAt := now.Add(request.ApprovalTTL)
+			action.ExpiresAt = &expiresAt
+		}
+		if err := query.Use(tx.GetDB()).DeviceAction.Create(action); err != nil {
+			return err
+		}
+		if request.ApprovedBy == "" {
+			return nil
+		}
+		return r.issue(tx, action, request.ApprovedBy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("Device action %s (%s) for device %s requested by %s: %s",
+		action.ID, action.Action, action.DeviceID, action.RequestedBy, action.Status)
+	return action, nil
+}
+
+func (r *action_repository) Get(sctx smart_context.ISmartContext, id string) (*model.DeviceAction, error) {
+	a := query.Use(sctx.GetDB()).DeviceAction
+	action, err := a.Where(a.ID.Eq(id)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: action %s", app_errors.ErrNotFound, id)
+	}
+	return action, err
+}
+
+func (r *action_repository) List(sctx smart_context.ISmartContext, filter ActionFilter) ([]*model.DeviceAction, error) {
+	a := query.Use(sctx.GetDB()).DeviceAction
+	do := a.Order(a.CreatedAt.Desc())
+	if filter.DeviceID != "" {
+		do = do.Where(a.DeviceID.Eq(filter.DeviceID))
+	}
+	if filter.Status != "" {
+		do = do.Where(a.Status.Eq(filter.Status))
+	}
+	if filter.Limit > 0 {
+		do = do.Limit(filter.Limit)
+	}
+	return do.Find()
+}
+
+// Approve сверяет пользователей, а не имена субъектов: владелец API-ключа
+// не может подтвердить запрос своего ключа, и наоборот.
+func (r *action_repository) Approve(sctx smart_context.ISmartContext, id, approver, approverUserID string) (*model.DeviceAction, error) {
+	var action *model.DeviceAction
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		var err error
+		if action, err = r.pending(tx, id); err != nil {
+			return err
+		}
+		switch {
+		case action.RequestedByUserID == "":
+			return ErrRequesterUnknown
+		case action.RequestedBy == approver || approverUserID == "" || action.RequestedByUserID == approverUserID:
+			return ErrSelfApproval
+		}
+		if _, err := r.activeDevice(tx, action.DeviceID); err != nil {
+			return err
+		}
+		return r.issue(tx, action, approver)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("Device action %s (%s) for device %s approved by %s", action.ID, action.Action, action.DeviceID, approver)
+	return action, nil
+}
+
+func (r *action_repository) Reject(sctx smart_context.ISmartContext, id, rejectedBy string) (*model.DeviceAction, error) {
+	var action *model.DeviceAction
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		var err error
+		if action, err = r.pending(tx, id); err != nil {
+			return err
+		}
+		return r.decide(tx, action, ActionStatusRejected, rejectedBy, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("Device action %s (%s) for device %s rejected by %s", action.ID, action.Action, action.DeviceID, rejectedBy)
+	return action, nil
+}
+
+func (r *action_repository) ExpirePending(sctx smart_context.ISmartContext, now time.Time) (int64, error) {
+	a := query.Use(sctx.GetDB()).DeviceAction
+	result, err := a.Where(a.Status.Eq(ActionStatusPendingApproval), a.ExpiresAt.Lte(now)).
+		UpdateSimple(a.Status.Value(ActionStatusExpired), a.DecidedAt.Value(now))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// pending возвращает действие, ожидающее подтверждения. Просроченное действие
+// не выдаётся, даже если фоновая задача ещё не отметила его.
+func (r *action_repository) pending(tx smart_context.ISmartContext, id string) (*model.DeviceAction, error) {
+	action, err := r.Get(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != ActionStatusPendingApproval {
+		return nil, fmt.Errorf("%w: action %s is %s", ErrActionNotPending, id, action.Status)
+	}
+	if action.ExpiresAt != nil && !time.Now().Before(*action.ExpiresAt) {
+		return nil, ErrApprovalExpired
+	}
+	return action, nil
+}
+
+// activeDevice возвращает устройство, если оно существует, не стёрто и не выведено из эксплуатации.
+func (r *action_repository) activeDevice(tx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
+	d := query.Use(tx.GetDB()).Device
+	device, err := d.Where(d.DeviceID.Eq(deviceID)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.RetiredAt != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceRetired, deviceID)
+	}
+	if device.Status == DeviceStatusWiped || device.Status == DeviceStatusWipePending {
+		return nil, fmt.Errorf("%w: %s is %s", ErrDeviceWiped, deviceID, device.Status)
+	}
+	return device, nil
+}
+
+// issue ставит команду устройству, меняет его статус и отмечает действие выданным.
+func (r *action_repository) issue(tx smart_context.ISmartContext, action *model.DeviceAction, approver string) error {
+	command, err := r.commands.Enqueue(tx, action.DeviceID, action.Action, action.Payload, action.RequestedBy)
+	if err != nil {
+		return err
+	}
+	status := DeviceStatusActive
+	switch action.Action {
+	case ActionLock:
+		status = DeviceStatusLocked
+	case ActionWipe:
+		status = DeviceStatusWipePending
+	}
+	d := query.Use(tx.GetDB()).Device
+	_, err = d.Where(d.DeviceID.Eq(action.DeviceID)).
+		UpdateSimple(d.Status.Value(status), d.UpdatedAt.Value(time.Now()))
+	if err != nil {
+		return err
+	}
+	return r.decide(tx, action, ActionStatusIssued, approver, command.ID)
+}
+
+// decide переводит ожидающее действие в статус status. Условие на статус в UPDATE
+// не даёт двум параллельным решениям выдать действие дважды.
+func (r *action_repository) decide(tx smart_context.ISmartContext, action *model.DeviceAction, status, decidedBy, commandID string) error {
+	a := query.Use(tx.GetDB()).DeviceAction
+	now := time.Now()
+	result, err := a.Where(a.ID.Eq(action.ID), a.Status.Eq(ActionStatusPendingApproval)).UpdateSimple(
+		a.Status.Value(status),
+		a.ApprovedBy.Value(decidedBy),
+		a.CommandID.Value(commandID),
+		a.DecidedAt.Value(now),
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrActionNotPending
+	}
+	action.Status, action.ApprovedBy, action.CommandID, action.DecidedAt = status, decidedBy, commandID, &now
+	return nil
+}
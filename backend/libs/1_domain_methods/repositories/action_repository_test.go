@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/4_common/app_errors"
+	"testing"
+	"time"
+)
+
+func TestLockAndWipeWorkflow(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	devices := NewDeviceRepository()
+	commands := NewCommandRepository()
+	repo := NewActionRepository(commands)
+
+	if _, err := repo.Request(sctx, ActionRequest{DeviceID: "missing", Action: ActionLock, RequestedBy: "user:alice", ApprovedBy: "user:alice"}); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound for unknown device, got %v", err)
+	}
+	registration, err := devices.RegisterDevice(sctx, "dev-1", "", "")
+	if err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+
+	lock, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionLock, Payload: `{"message":"lost"}`, RequestedBy: "user:alice", ApprovedBy: "user:alice"})
+	if err != nil || lock.Status != ActionStatusIssued || lock.CommandID == "" {
+		t.Fatalf("Expected issued lock, got %+v, %v", lock, err)
+	}
+	if device, _ := devices.GetDevice(sctx, "dev-1"); device.Status != DeviceStatusLocked {
+		t.Errorf("Expected locked device, got %s", device.Status)
+	}
+
+	wipe, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionWipe, RequestedBy: "user:alice", RequestedByUserID: "alice-id", ApprovalTTL: time.Hour})
+	if err != nil || wipe.Status != ActionStatusPendingApproval || wipe.ExpiresAt == nil {
+		t.Fatalf("Expected wipe pending approval, got %+v, %v", wipe, err)
+	}
+	if _, err := repo.Approve(sctx, wipe.ID, "user:alice", "alice-id"); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("Expected ErrSelfApproval, got %v", err)
+	}
+	approved, err := repo.Approve(sctx, wipe.ID, "user:bob", "bob-id")
+	if err != nil || approved.Status != ActionStatusIssued || approved.ApprovedBy != "user:bob" {
+		t.Fatalf("Expected approved wipe, got %+v, %v", approved, err)
+	}
+	if _, err := repo.Approve(sctx, wipe.ID, "user:carol", "carol-id"); !errors.Is(err, ErrActionNotPending) {
+		t.Errorf("Expected ErrActionNotPending on second approval, got %v", err)
+	}
+	if _, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionUnlock, RequestedBy: "user:alice", ApprovedBy: "user:alice"}); !errors.Is(err, ErrDeviceWiped) {
+		t.Errorf("Expected ErrDeviceWiped while wipe is pending, got %v", err)
+	}
+
+	delivered, err := commands.Deliver(sctx, "dev-1")
+	if err != nil || len(delivered) != 2 || delivered[0].Type != ActionLock || delivered[1].Type != ActionWipe {
+		t.Fatalf("Expected lock and wipe commands, got %+v, %v", delivered, err)
+	}
+	if _, err := commands.Complete(sctx, "dev-1", approved.CommandID, true, "wiped"); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	device, err := devices.GetDevice(sctx, "dev-1")
+	if err != nil || device.Status != DeviceStatusWiped {
+		t.Fatalf("Expected wiped device, got %+v, %v", device, err)
+	}
+	if err := devices.AuthenticateDevice(sctx, "dev-1", registration.DeviceToken); !errors.Is(err, app_errors.ErrUnauthorized) {
+		t.Errorf("Expected revoked device token, got %v", err)
+	}
+	// Блокировка, которую агент не успел подтвердить, отменяется вместе со стиранием.
+	history, _ := commands.List(sctx, "dev-1", 0)
+	for _, command := range history {
+		if command.ID == lock.CommandID && command.Status != CommandStatusFailed {
+			t.Errorf("Expected outstanding lock to be cancelled, got %s", command.Status)
+		}
+	}
+
+	enrollment, err := devices.IssueEnrollmentToken(sctx, "dev-1", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueEnrollmentToken failed: %v", err)
+	}
+	again, err := devices.RegisterDevice(sctx, "dev-1", "", enrollment.EnrollmentToken)
+	if err != nil || again.Registration != RegistrationReenrolled {
+		t.Fatalf("Expected wiped device to re-enroll, got %+v, %v", again, err)
+	}
+
+	expiring, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionWipe, RequestedBy: "user:alice", RequestedByUserID: "alice-id", ApprovalTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if expired, err := repo.ExpirePending(sctx, time.Now().Add(2*time.Hour)); err != nil || expired != 1 {
+		t.Fatalf("Expected one expired action, got %d, %v", expired, err)
+	}
+	if _, err := repo.Approve(sctx, expiring.ID, "user:bob", "bob-id"); !errors.Is(err, ErrActionNotPending) {
+		t.Errorf("Expected expired action to stay unapproved, got %v", err)
+	}
+
+	rejected, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionWipe, RequestedBy: "user:alice", RequestedByUserID: "alice-id", ApprovalTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if rejected, err = repo.Reject(sctx, rejected.ID, "user:bob"); err != nil || rejected.Status != ActionStatusRejected {
+		t.Fatalf("Expected rejected action, got %+v, %v", rejected, err)
+	}
+	if device, _ := devices.GetDevice(sctx, "dev-1"); device.Status != DeviceStatusActive {
+		t.Errorf("Expected rejected wipe to leave the device active, got %s", device.Status)
+	}
+
+	pending, err := repo.List(sctx, ActionFilter{DeviceID: "dev-1", Status: ActionStatusIssued})
+	if err != nil || len(pending) != 2 {
+		t.Errorf("Expected 2 issued actions, got %d, %v", len(pending), err)
+	}
+}
+
+// TestWipeApprovalByKeyOwner проверяет правило двух лиц для запросов через
+// API-ключ: владелец ключа не может подтвердить стирание, запрошенное ключом.
+func TestWipeApprovalByKeyOwner(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	devices := NewDeviceRepository()
+	repo := NewActionRepository(NewCommandRepository())
+	if _, err := devices.RegisterDevice(sctx, "dev-1", "", ""); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+
+	if _, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionWipe, RequestedBy: "api_key:legacy", ApprovalTTL: time.Hour}); !errors.Is(err, ErrRequesterUnknown) {
+		t.Errorf("Expected ErrRequesterUnknown for a key without owner, got %v", err)
+	}
+	wipe, err := repo.Request(sctx, ActionRequest{DeviceID: "dev-1", Action: ActionWipe, RequestedBy: "api_key:ci", RequestedByUserID: "alice-id", ApprovalTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if _, err := repo.Approve(sctx, wipe.ID, "user:alice", "alice-id"); !errors.Is(err, ErrSelfApproval) {
+		t.Fatalf("Expected ErrSelfApproval for the key owner, got %v", err)
+	}
+	approved, err := repo.Approve(sctx, wipe.ID, "user:bob", "bob-id")
+	if err != nil || approved.Status != ActionStatusIssued {
+		t.Fatalf("Expected approval by another user, got %+v, %v", approved, err)
+	}
+}
@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository управляет API-ключами и проверяет их при аутентификации.
+type APIKeyRepository interface {
+	auth.APIKeyResolver
+	// Create выпускает ключ; открытое значение возвращается только здесь.
+	// ownerID — пользователь, от имени которого действует ключ.
+	Create(sctx smart_context.ISmartContext, name string, scopes []string, expiresAt *time.Time, createdBy string, ownerID string) (*model.APIKey, string, error)
+	List(sctx smart_context.ISmartContext) ([]*model.APIKey, error)
+	// Rotate выпускает новое значение ключа с теми же правами; старое сразу перестаёт действовать.
+	Rotate(sctx smart_context.ISmartContext, id string) (*model.APIKey, string, error)
+	Revoke(sctx smart_context.ISmartContext, id string) (*model.APIKey, error)
+}
+
+// ErrAPIKeyRevoked возвращается при ротации отозванного ключа.
+var ErrAPIKeyRevoked = fmt.Errorf("%w: api key is revoked", app_errors.ErrConflict)
+
+// apiKeyTouchInterval — как часто обновляется last_used_at, чтобы не писать в БД на каждый запрос.
+const apiKeyTouchInterval = time.Minute
+
+type api_key_repository struct {
+}
+
+func NewAPIKeyRepository() APIKeyRepository {
+	return &api_key_repository{}
+}
+
+func (r *api_key_repository) Create(sctx smart_context.ISmartContext, name string, scopes []string, expiresAt *time.Time, createdBy string, ownerID string) (*model.APIKey, string, error) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	apiKey := &model.APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scope:     strings.Join(scopes, " "),
+		CreatedBy: createdBy,
+		OwnerID:   ownerID,
+		ExpiresAt: expiresAt,
+	}
+	if err := query.Use(sctx.GetDB()).APIKey.Create(apiKey); err != nil {
+		return nil, "", err
+	}
+	sctx.Infof("api key %s (%s) created by %s", apiKey.ID, name, createdBy)
+	return apiKey, key, nil
+}
+
+func (r *api_key_repository) List(sctx smart_context.ISmartContext) ([]*model.APIKey, error) {
+	k := query.Use(sctx.GetDB()).APIKey
+	return k.Order(k.CreatedAt).Find()
+}
+
+func (r *api_key_repository) get(sctx smart_context.ISmartContext, id string) (*model.APIKey, error) {
+	k := query.Use(sctx.GetDB()).APIKey
+	return k.Where(k.ID.Eq(id)).First()
+}
+
+func (r *api_key_repository) Rotate(sctx smart_context.ISmartContext, id string) (*model.APIKey, string, error) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var apiKey *model.APIKey
+	err = sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		existing, err := r.get(tx, id)
+		if err != nil {
+			return err
+		}
+		if existing.RevokedAt != nil {
+			return ErrAPIKeyRevoked
+		}
+
+		k := query.Use(tx.GetDB()).APIKey
+		if _, err := k.Where(k.ID.Eq(id)).UpdateSimple(
+			k.Prefix.Value(prefix),
+			k.KeyHash.Value(hash),
+			k.UpdatedAt.Value(time.Now()),
+		); err != nil {
+			return err
+		}
+		apiKey, err = r.get(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sctx.Infof("api key %s rotated", id)
+	return apiKey, key, nil
+}
+
+func (r *api_key_repository) Revoke(sctx smart_context.ISmartContext, id string) (*model.APIKey, error) {
+	var apiKey *model.APIKey
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		k := query.Use(tx.GetDB()).APIKey
+		now := time.Now()
+		// Повторный отзыв не меняет время первого.
+		if _, err := k.Where(k.ID.Eq(id), k.RevokedAt.IsNull()).UpdateSimple(
+			k.RevokedAt.Value(now),
+			k.UpdatedAt.Value(now),
+		); err != nil {
+			return err
+		}
+		var err error
+		apiKey, err = r.get(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("api key %s revoked", id)
+	return apiKey, nil
+}
+
+// ResolveAPIKey находит действующий ключ по префиксу и сверяет хеш.
+func (r *api_key_repository) ResolveAPIKey(sctx smart_context.ISmartContext, key string) (*auth.Principal, error) {
+	prefix, ok := auth.ParseAPIKeyPrefix(key)
+	if !ok {
+		return nil, auth.ErrInvalidAPIKey
+	}
+
+	k := query.Use(sctx.GetDB()).APIKey
+	apiKey, err := k.Where(k.Prefix.Eq(prefix)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, auth.ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case !auth.TokenMatches(key, apiKey.KeyHash):
+		return nil, auth.ErrInvalidAPIKey
+	case apiKey.RevokedAt != nil:
+		return nil, fmt.Errorf("%w: revoked", auth.ErrInvalidAPIKey)
+	case apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now):
+		return nil, fmt.Errorf("%w: expired", auth.ErrInvalidAPIKey)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if _, err := k.Where(k.ID.Eq(apiKey.ID)).UpdateSimple(k.LastUsedAt.Value(now)); err != nil {
+			sctx.Warnf("Failed to update last_used_at of api key %s: %v", apiKey.ID, err)
+		}
+	}
+
+	return &auth.Principal{
+		Kind:   auth.PrincipalAPIKey,
+		ID:     apiKey.ID,
+		Name:   apiKey.Name,
+		UserID: apiKey.OwnerID,
+		Scopes: strings.Fields(apiKey.Scope),
+	}, nil
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/4_common/auth"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewAPIKeyRepository()
+
+	apiKey, key, err := repo.Create(sctx, "ci", []string{auth.ScopeDevicesRead, auth.ScopeDevicesWrite}, nil, "user:admin", "admin-id")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if apiKey.KeyHash == key || apiKey.Prefix == "" {
+		t.Fatalf("Key must be stored hashed with a prefix: %+v", apiKey)
+	}
+
+	principal, err := repo.ResolveAPIKey(sctx, key)
+	if err != nil {
+		t.Fatalf("ResolveAPIKey failed: %v", err)
+	}
+	if principal.Kind != auth.PrincipalAPIKey || principal.ID != apiKey.ID || principal.UserID != "admin-id" || !principal.HasScope(auth.ScopeDevicesWrite) {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+
+	rotated, newKey, err := repo.Rotate(sctx, apiKey.ID)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if rotated.ID != apiKey.ID || rotated.Scope != apiKey.Scope || newKey == key {
+		t.Errorf("Rotation must keep id and scopes and issue a new value: %+v", rotated)
+	}
+	if _, err := repo.ResolveAPIKey(sctx, key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Expected old key to be rejected after rotation, got: %v", err)
+	}
+	if _, err := repo.ResolveAPIKey(sctx, newKey); err != nil {
+		t.Errorf("Expected rotated key to work, got: %v", err)
+	}
+
+	if _, err := repo.Revoke(sctx, apiKey.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := repo.ResolveAPIKey(sctx, newKey); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Expected revoked key to be rejected, got: %v", err)
+	}
+	if _, _, err := repo.Rotate(sctx, apiKey.ID); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Errorf("Expected rotation of revoked key to fail, got: %v", err)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewAPIKeyRepository()
+
+	expiresAt := time.Now().Add(-time.Minute)
+	_, key, err := repo.Create(sctx, "old", []string{auth.ScopeDevicesRead}, &expiresAt, "user:admin", "admin-id")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := repo.ResolveAPIKey(sctx, key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("Expected expired key to be rejected, got: %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gen/field"
+	"gorm.io/gorm"
+)
+
+// События истории установленных приложений.
+const (
+	AppEventInstalled = "installed"
+	AppEventUpdated   = "updated"
+	AppEventRemoved   = "removed"
+)
+
+// appBatchSize — сколько строк инвентаря вставляется одним запросом.
+const appBatchSize = 100
+
+// ErrInventoryOutOfSync — отчёт с изменениями построен не от текущей версии
+// инвентаря на сервере; агент должен прислать полный список.
+var ErrInventoryOutOfSync = fmt.Errorf("%w: app inventory version mismatch, send a full report", app_errors.ErrConflict)
+
+// AppEventFilter — параметры выборки истории приложений. Пустые поля не применяются.
+type AppEventFilter struct {
+	DeviceID    string
+	PackageName string
+	// Limit <= 0 означает «без ограничения».
+	Limit int
+}
+
+// AppQuery — поиск устройств, на которых установлено приложение.
+type AppQuery struct {
+	PackageName string
+	// BelowVersion — только версии ниже этой (см. CompareVersions); пусто — любые.
+	BelowVersion string
+}
+
+// AppRepository — установленные приложения устройств и история их изменений.
+type AppRepository interface {
+	// Report применяет отчёт агента и возвращает новую версию инвентаря устройства.
+	// Отчёт с изменениями, построенный не от текущей версии, отклоняется с
+	// ErrInventoryOutOfSync.
+	Report(sctx smart_context.ISmartContext, deviceID string, report model.AppInventoryReport) (int64, error)
+	// List возвращает установленные приложения устройства по имени пакета.
+	List(sctx smart_context.ISmartContext, deviceID string) ([]*model.DeviceApp, error)
+	// History возвращает установки, обновления и удаления, новые первыми.
+	History(sctx smart_context.ISmartContext, filter AppEventFilter) ([]*model.DeviceAppEvent, error)
+	// FindDevices возвращает установки приложения на действующих устройствах,
+	// упорядоченные по device_id.
+	FindDevices(sctx smart_context.ISmartContext, filter AppQuery) ([]*model.DeviceApp, error)
+}
+
+type app_repository struct {
+}
+
+func NewAppRepository() AppRepository {
+	return &app_repository{}
+}
+
+// Report сравнивает отчёт с текущим списком и в одной транзакции записывает
+// только изменения и их историю. Условие на apps_version в UPDATE не даёт двум
+// параллельным отчётам от одной версии применить изменения дважды.
+func (r *app_repository) Report(sctx smart_context.ISmartContext, deviceID string, report model.AppInventoryReport) (int64, error) {
+	var version int64
+	var changes int
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		q := query.Use(tx.GetDB())
+		d := q.Device
+		device, err := d.Where(d.DeviceID.Eq(deviceID)).First()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+		}
+		if err != nil {
+			return err
+		}
+		if !report.Full && report.BaseVersion != device.AppsVersion {
+			return fmt.Errorf("%w: device has %d, report is based on %d", ErrInventoryOutOfSync, device.AppsVersion, report.BaseVersion)
+		}
+		now := time.Now()
+		result, err := d.Where(d.ID.Eq(device.ID), d.AppsVersion.Eq(device.AppsVersion)).
+			UpdateSimple(d.AppsVersion.Add(1), d.AppsReportedAt.Value(now))
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return ErrInventoryOutOfSync
+		}
+		version = device.AppsVersion + 1
+
+		a := q.DeviceApp
+		current, err := a.Where(a.DeviceID.Eq(deviceID)).Find()
+		if err != nil {
+			return err
+		}
+		installed := make(map[string]*model.DeviceApp, len(current))
+		for _, app := range current {
+			installed[app.PackageName] = app
+		}
+
+		var created []*model.DeviceApp
+		var events []*model.DeviceAppEvent
+		var removed []string
+		event := func(kind string, app model.InstalledApp, previous string) {
+			events = append(events, &model.DeviceAppEvent{
+				DeviceID:        deviceID,
+				PackageName:     app.PackageName,
+				Event:           kind,
+				Version:         app.Version,
+				PreviousVersion: previous,
+				Installer:       app.Installer,
+				CreatedAt:       now,
+			})
+		}
+		reported := make(map[string]bool, len(report.Installed))
+		for _, app := range report.Installed {
+			reported[app.PackageName] = true
+			existing, ok := installed[app.PackageName]
+			if !ok {
+				created = append(created, &model.DeviceApp{
+					DeviceID:    deviceID,
+					PackageName: app.PackageName,
+					Version:     app.Version,
+					Installer:   app.Installer,
+					InstalledAt: app.InstalledAt,
+					UpdatedAt:   now,
+				})
+				event(AppEventInstalled, app, "")
+				continue
+			}
+			if existing.Version == app.Version && existing.Installer == app.Installer {
+				continue
+			}
+			assign := []field.AssignExpr{a.Version.Value(app.Version), a.Installer.Value(app.Installer), a.UpdatedAt.Value(now)}
+			if app.InstalledAt != nil {
+				assign = append(assign, a.InstalledAt.Value(*app.InstalledAt))
+			}
+			if _, err := a.Where(a.ID.Eq(existing.ID)).UpdateSimple(assign...); err != nil {
+				return err
+			}
+			if existing.Version != app.Version {
+				event(AppEventUpdated, app, existing.Version)
+			}
+		}
+
+		gone := report.Removed
+		if report.Full {
+			gone = nil
+			for name := range installed {
+				if !reported[name] {
+					gone = append(gone, name)
+				}
+			}
+			sort.Strings(gone)
+		}
+		for _, name := range gone {
+			if existing, ok := installed[name]; ok && !reported[name] {
+				removed = append(removed, name)
+				event(AppEventRemoved, model.InstalledApp{PackageName: name, Installer: existing.Installer}, existing.Version)
+			}
+		}
+
+		if len(removed) > 0 {
+			if _, err := a.Where(a.DeviceID.Eq(deviceID), a.PackageName.In(removed...)).Delete(); err != nil {
+				return err
+			}
+		}
+		if len(created) > 0 {
+			if err := a.CreateInBatches(created, appBatchSize); err != nil {
+				return err
+			}
+		}
+		changes = len(events)
+		if changes == 0 {
+			return nil
+		}
+		return q.DeviceAppEvent.CreateInBatches(events, appBatchSize)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if changes > 0 {
+		sctx.Infof("App inventory of device %s updated to version %d: %d changes", deviceID, version, changes)
+	}
+	return version, nil
+}
+
+func (r *app_repository) List(sctx smart_context.ISmartContext, deviceID string) ([]*model.DeviceApp, error) {
+	a := query.Use(sctx.GetDB()).DeviceApp
+	return a.Where(a.DeviceID.Eq(deviceID)).Order(a.PackageName).Find()
+}
+
+func (r *app_repository) History(sctx smart_context.ISmartContext, filter AppEventFilter) ([]*model.DeviceAppEvent, error) {
+	e := query.Use(sctx.GetDB()).DeviceAppEvent
+	do := e.Order(e.CreatedAt.Desc())
+	if filter.DeviceID != "" {
+		do = do.Where(e.DeviceID.Eq(filter.DeviceID))
+	}
+	if filter.PackageName != "" {
+		do = do.Where(e.PackageName.Eq(filter.PackageName))
+	}
+	if filter.Limit > 0 {
+		do = do.Limit(filter.Limit)
+	}
+	return do.Find()
+}
+
+// FindDevices выбирает установки пакета по индексу, а версии сравнивает в Go:
+// порядок версий (CompareVersions) в SQL не выразить.
+func (r *app_repository) FindDevices(sctx smart_context.ISmartContext, filter AppQuery) ([]*model.DeviceApp, error) {
+	q := query.Use(sctx.GetDB())
+	a, d := q.DeviceApp, q.Device
+	apps, err := a.Where(a.PackageName.Eq(filter.PackageName)).
+		Where(a.Columns(a.DeviceID).In(d.Select(d.DeviceID).Where(d.RetiredAt.IsNull()))).
+		Order(a.DeviceID).
+		Find()
+	if err != nil || filter.BelowVersion == "" {
+		return apps, err
+	}
+	outdated := apps[:0]
+	for _, app := range apps {
+		if CompareVersions(app.Version, filter.BelowVersion) < 0 {
+			outdated = append(outdated, app)
+		}
+	}
+	return outdated, nil
+}
+
+// CompareVersions сравнивает версии пакетов и возвращает -1, 0 или 1.
+// Версия разбивается на числовые и буквенные части, остальные символы
+// разделяют их: числа сравниваются как числа (1.10 > 1.9), буквы —
+// лексикографически, число старше букв. Часть после «~» идёт раньше версии
+// без неё, как в dpkg: 2.0~rc1 < 2.0. Эпоха («1:» в начале) сравнивается
+// первой, её отсутствие означает 0: 1:2.0 > 1.5.
+func CompareVersions(a, b string) int {
+	aEpoch, a := splitEpoch(a)
+	bEpoch, b := splitEpoch(b)
+	if c := compareVersionPart(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+	x, y := versionParts(a), versionParts(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		switch {
+		case i >= len(x):
+			if y[i] == "~" {
+				return 1
+			}
+			return -1
+		case i >= len(y):
+			if x[i] == "~" {
+				return -1
+			}
+			return 1
+		}
+		if c := compareVersionPart(x[i], y[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// splitEpoch отделяет эпоху dpkg от остальной версии; без эпохи возвращает "0".
+func splitEpoch(version string) (string, string) {
+	epoch, rest, found := strings.Cut(version, ":")
+	if !found || epoch == "" || strings.TrimFunc(epoch, unicode.IsDigit) != "" {
+		return "0", version
+	}
+	return epoch, rest
+}
+
+// versionParts разбивает версию на числа, слова и «~».
+func versionParts(version string) []string {
+	var parts []string
+	var current strings.Builder
+	var digits bool
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+	for _, c := range version {
+		switch {
+		case c == '~':
+			flush()
+			parts = append(parts, "~")
+		case unicode.IsDigit(c) || unicode.IsLetter(c):
+			if current.Len() > 0 && unicode.IsDigit(c) != digits {
+				flush()
+			}
+			digits = unicode.IsDigit(c)
+			current.WriteRune(c)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
+
+func compareVersionPart(x, y string) int {
+	xTilde, yTilde := x == "~", y == "~"
+	xNum, yNum := isNumber(x), isNumber(y)
+	switch {
+	case xTilde || yTilde:
+		return boolOrder(yTilde) - boolOrder(xTilde)
+	case xNum && yNum:
+		// Без разбора в int: номера сборок бывают длиннее int64.
+		x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+		if len(x) != len(y) {
+			return boolOrder(len(x) > len(y))*2 - 1
+		}
+		return strings.Compare(x, y)
+	case xNum != yNum:
+		return boolOrder(xNum)*2 - 1
+	default:
+		return strings.Compare(x, y)
+	}
+}
+
+func isNumber(part string) bool {
+	return part != "" && unicode.IsDigit([]rune(part)[0])
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
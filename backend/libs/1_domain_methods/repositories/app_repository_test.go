@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/2_generated_models/model"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.9", "1.10", -1},
+		{"1.0", "1.0.1", -1},
+		{"2.0~rc1", "2.0", -1},
+		{"2.0~rc1", "2.0~rc2", -1},
+		{"1.0a", "1.0.1", -1},
+		{"1.2.3-1ubuntu2", "1.2.3-1ubuntu10", -1},
+		{"115.0.2", "115.0", 1},
+		{"007", "7", 0},
+		{"", "0.1", -1},
+		{"1:2.0", "1.5", 1},
+		{"2:8.2", "1:9.18", 1},
+		{"0:1.0", "1.0", 0},
+		{"1:1.0~rc1", "1:1.0", -1},
+	}
+	for _, c := range cases {
+		if got := CompareVersions(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := CompareVersions(c.b, c.a); got != -c.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", c.b, c.a, got, -c.want)
+		}
+	}
+}
+
+func TestAppInventoryReports(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	devices := NewDeviceRepository()
+	repo := NewAppRepository()
+	for _, id := range []string{"dev-1", "dev-2", "dev-3"} {
+		if _, err := devices.RegisterDevice(sctx, id, "", ""); err != nil {
+			t.Fatalf("RegisterDevice failed: %v", err)
+		}
+	}
+
+	version, err := repo.Report(sctx, "dev-1", model.AppInventoryReport{Full: true, Installed: []model.InstalledApp{
+		{PackageName: "firefox", Version: "115.0", Installer: "dpkg"},
+		{PackageName: "vim", Version: "9.0", Installer: "dpkg"},
+	}})
+	if err != nil || version != 1 {
+		t.Fatalf("Expected version 1 after full report, got %d, %v", version, err)
+	}
+	if _, err := repo.Report(sctx, "dev-1", model.AppInventoryReport{BaseVersion: 0}); !errors.Is(err, ErrInventoryOutOfSync) {
+		t.Errorf("Expected ErrInventoryOutOfSync for a stale delta, got %v", err)
+	}
+	version, err = repo.Report(sctx, "dev-1", model.AppInventoryReport{
+		BaseVersion: 1,
+		Installed:   []model.InstalledApp{{PackageName: "firefox", Version: "128.0", Installer: "dpkg"}},
+		Removed:     []string{"vim", "not-installed"},
+	})
+	if err != nil || version != 2 {
+		t.Fatalf("Expected version 2 after delta, got %d, %v", version, err)
+	}
+	apps, err := repo.List(sctx, "dev-1")
+	if err != nil || len(apps) != 1 || apps[0].PackageName != "firefox" || apps[0].Version != "128.0" {
+		t.Fatalf("Expected only firefox 128.0, got %+v, %v", apps, err)
+	}
+	history, err := repo.History(sctx, AppEventFilter{DeviceID: "dev-1"})
+	if err != nil || len(history) != 4 {
+		t.Fatalf("Expected 4 history events, got %d, %v", len(history), err)
+	}
+	events := map[string]*model.DeviceAppEvent{}
+	for _, event := range history {
+		events[event.PackageName+":"+event.Event] = event
+	}
+	if e := events["firefox:updated"]; e == nil || e.PreviousVersion != "115.0" || e.Version != "128.0" {
+		t.Errorf("Expected firefox update from 115.0, got %+v", e)
+	}
+	if e := events["vim:removed"]; e == nil || e.PreviousVersion != "9.0" {
+		t.Errorf("Expected vim removal, got %+v", e)
+	}
+
+	// Полный отчёт заменяет список целиком; неизменённые приложения не попадают в историю.
+	if _, err := repo.Report(sctx, "dev-2", model.AppInventoryReport{Full: true, Installed: []model.InstalledApp{
+		{PackageName: "firefox", Version: "102.9", Installer: "dpkg"},
+		{PackageName: "curl", Version: "8.0", Installer: "dpkg"},
+	}}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if _, err := repo.Report(sctx, "dev-2", model.AppInventoryReport{Full: true, Installed: []model.InstalledApp{
+		{PackageName: "firefox", Version: "102.9", Installer: "dpkg"},
+	}}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+	if history, _ := repo.History(sctx, AppEventFilter{DeviceID: "dev-2"}); len(history) != 3 || history[0].Event != AppEventRemoved {
+		t.Errorf("Expected curl removal on top of 2 installs, got %+v", history)
+	}
+	if _, err := repo.Report(sctx, "dev-3", model.AppInventoryReport{Full: true, Installed: []model.InstalledApp{
+		{PackageName: "firefox", Version: "115.0~esr", Installer: "dpkg"},
+	}}); err != nil {
+		t.Fatalf("Report failed: %v", err)
+	}
+
+	outdated, err := repo.FindDevices(sctx, AppQuery{PackageName: "firefox", BelowVersion: "115.0"})
+	if err != nil || len(outdated) != 2 || outdated[0].DeviceID != "dev-2" || outdated[1].DeviceID != "dev-3" {
+		t.Fatalf("Expected dev-2 and dev-3 below 115.0, got %+v, %v", outdated, err)
+	}
+	if _, err := devices.Retire(sctx, "dev-2", "user:admin"); err != nil {
+		t.Fatalf("Retire failed: %v", err)
+	}
+	all, err := repo.FindDevices(sctx, AppQuery{PackageName: "firefox"})
+	if err != nil || len(all) != 2 || all[0].DeviceID != "dev-1" {
+		t.Errorf("Expected firefox on dev-1 and dev-3 only, got %+v, %v", all, err)
+	}
+}
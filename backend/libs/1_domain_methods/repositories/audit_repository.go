@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/smart_context"
+	"slices"
+	"time"
+)
+
+// Пределы выборки журнала аудита.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditFilter — выборка журнала аудита.
+type AuditFilter struct {
+	// Since — только события после этого момента, по возрастанию времени
+	// (для чтения журнала «хвостом»). Нулевое — последние Limit событий.
+	Since time.Time
+	// Actor — только события субъекта вида "user:bob" или "api_key:ci".
+	Actor string
+	// Limit — не больше стольких событий; 0 — 100, предел — 1000.
+	Limit int
+}
+
+// AuditRepository — журнал изменений через административный API.
+type AuditRepository interface {
+	Record(sctx smart_context.ISmartContext, event *model.AuditEvent) error
+	// List возвращает события по возрастанию времени.
+	List(sctx smart_context.ISmartContext, filter AuditFilter) ([]*model.AuditEvent, error)
+}
+
+type audit_repository struct {
+}
+
+func NewAuditRepository() AuditRepository {
+	return &audit_repository{}
+}
+
+func (r *audit_repository) Record(sctx smart_context.ISmartContext, event *model.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	return query.Use(sctx.GetDB()).AuditEvent.Create(event)
+}
+
+func (r *audit_repository) List(sctx smart_context.ISmartContext, filter AuditFilter) ([]*model.AuditEvent, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	limit = min(limit, maxAuditLimit)
+
+	a := query.Use(sctx.GetDB()).AuditEvent
+	q := a.Limit(limit)
+	if filter.Actor != "" {
+		q = q.Where(a.Actor.Eq(filter.Actor))
+	}
+	if !filter.Since.IsZero() {
+		return q.Where(a.CreatedAt.Gt(filter.Since)).Order(a.CreatedAt, a.ID).Find()
+	}
+	// Последние limit событий, но в хронологическом порядке.
+	events, err := q.Order(a.CreatedAt.Desc(), a.ID.Desc()).Find()
+	slices.Reverse(events)
+	return events, err
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"mdm/libs/2_generated_models/model"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewAuditRepository()
+
+	start := time.Now().Add(-time.Hour)
+	for i, actor := range []string{"user:bob", "api_key:ci", "user:bob"} {
+		event := &model.AuditEvent{
+			Actor:     actor,
+			Method:    "POST",
+			Path:      "/devices/dev-1/camera",
+			Status:    200,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := repo.Record(sctx, event); err != nil || event.ID == "" {
+			t.Fatalf("Record failed: %+v, %v", event, err)
+		}
+	}
+
+	latest, err := repo.List(sctx, AuditFilter{Limit: 2})
+	if err != nil || len(latest) != 2 || latest[0].Actor != "api_key:ci" || latest[1].Actor != "user:bob" {
+		t.Fatalf("Expected the last two events in order, got %+v, %v", latest, err)
+	}
+	after, err := repo.List(sctx, AuditFilter{Since: latest[0].CreatedAt})
+	if err != nil || len(after) != 1 || after[0].ID != latest[1].ID {
+		t.Errorf("Expected only the event after since, got %+v, %v", after, err)
+	}
+	bob, err := repo.List(sctx, AuditFilter{Actor: "user:bob"})
+	if err != nil || len(bob) != 2 {
+		t.Errorf("Expected two events by user:bob, got %d, %v", len(bob), err)
+	}
+}
@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы команды устройству.
+const (
+	CommandStatusPending   = "pending"
+	CommandStatusDelivered = "delivered"
+	CommandStatusSucceeded = "succeeded"
+	CommandStatusFailed    = "failed"
+)
+
+// maxDeliveredCommands — сколько команд отдаётся в одном ответе на heartbeat;
+// остальные уходят со следующими.
+const maxDeliveredCommands = 20
+
+// CommandRepository — очередь команд устройствам.
+type CommandRepository interface {
+	// Enqueue ставит команду в очередь зарегистрированного действующего устройства.
+	Enqueue(sctx smart_context.ISmartContext, deviceID, commandType, payload, createdBy string) (*model.DeviceCommand, error)
+	// List возвращает команды устройства, новые первыми; limit <= 0 — без ограничения.
+	List(sctx smart_context.ISmartContext, deviceID string, limit int) ([]*model.DeviceCommand, error)
+	// Deliver возвращает незавершённые команды устройства (в порядке постановки)
+	// и отмечает новые доставленными. Команда отдаётся, пока агент не сообщит результат.
+	Deliver(sctx smart_context.ISmartContext, deviceID string) ([]*model.DeviceCommand, error)
+	// Complete сохраняет результат выполнения. Повторный отчёт о завершённой
+	// команде ничего не меняет. Успешное стирание (wipe) переводит устройство
+	// в статус wiped и отменяет остальные его команды.
+	Complete(sctx smart_context.ISmartContext, deviceID, commandID string, succeeded bool, result string) (*model.DeviceCommand, error)
+}
+
+type command_repository struct {
+}
+
+func NewCommandRepository() CommandRepository {
+	return &command_repository{}
+}
+
+func (r *command_repository) Enqueue(sctx smart_context.ISmartContext, deviceID, commandType, payload, createdBy string) (*model.DeviceCommand, error) {
+	q := query.Use(sctx.GetDB())
+	device, err := q.Device.Where(q.Device.DeviceID.Eq(deviceID)).First()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+		}
+		return nil, err
+	}
+	if device.RetiredAt != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceRetired, deviceID)
+	}
+
+	command := &model.DeviceCommand{
+		DeviceID:  deviceID,
+		Type:      commandType,
+		Payload:   payload,
+		Status:    CommandStatusPending,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if err := q.DeviceCommand.Create(command); err != nil {
+		return nil, err
+	}
+	sctx.Infof("Command %s (%s) queued for device %s by %s", command.ID, commandType, deviceID, createdBy)
+	return command, nil
+}
+
+func (r *command_repository) List(sctx smart_context.ISmartContext, deviceID string, limit int) ([]*model.DeviceCommand, error) {
+	c := query.Use(sctx.GetDB()).DeviceCommand
+	do := c.Where(c.DeviceID.Eq(deviceID)).Order(c.CreatedAt.Desc())
+	if limit > 0 {
+		do = do.Limit(limit)
+	}
+	return do.Find()
+}
+
+func (r *command_repository) Deliver(sctx smart_context.ISmartContext, deviceID string) ([]*model.DeviceCommand, error) {
+	var commands []*model.DeviceCommand
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		c := query.Use(tx.GetDB()).DeviceCommand
+		var err error
+		commands, err = c.Where(c.DeviceID.Eq(deviceID), c.Status.In(CommandStatusPending, CommandStatusDelivered)).
+			Order(c.CreatedAt).Limit(maxDeliveredCommands).Find()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var pending []string
+		for _, command := range commands {
+			if command.Status == CommandStatusPending {
+				pending = append(pending, command.ID)
+				command.Status = CommandStatusDelivered
+				command.DeliveredAt = &now
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		_, err = c.Where(c.ID.In(pending...), c.Status.Eq(CommandStatusPending)).
+			UpdateSimple(c.Status.Value(CommandStatusDelivered), c.DeliveredAt.Value(now))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+func (r *command_repository) Complete(sctx smart_context.ISmartContext, deviceID, commandID string, succeeded bool, result string) (*model.DeviceCommand, error) {
+	var command *model.DeviceCommand
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		c := query.Use(tx.GetDB()).DeviceCommand
+		var err error
+		command, err = c.Where(c.ID.Eq(commandID), c.DeviceID.Eq(deviceID)).First()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: command %s", app_errors.ErrNotFound, commandID)
+		}
+		if err != nil {
+			return err
+		}
+		if command.CompletedAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		command.Status = CommandStatusFailed
+		if succeeded {
+			command.Status = CommandStatusSucceeded
+		}
+		command.Result = result
+		command.CompletedAt = &now
+		if command.DeliveredAt == nil {
+			command.DeliveredAt = &now
+		}
+		_, err = c.Where(c.ID.Eq(command.ID)).UpdateSimple(
+			c.Status.Value(command.Status),
+			c.Result.Value(result),
+			c.CompletedAt.Value(now),
+			c.DeliveredAt.Value(*command.DeliveredAt),
+		)
+		if err != nil || command.Type != ActionWipe {
+			return err
+		}
+		return completeWipe(tx, command, succeeded, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// completeWipe завершает стирание: после успеха токен устройства отзывается, а
+// невыполненные команды отменяются — стёртому устройству они уже не дойдут.
+// Неудачное стирание возвращает устройство в статус active, чтобы его можно было повторить.
+func completeWipe(tx smart_context.ISmartContext, command *model.DeviceCommand, succeeded bool, now time.Time) error {
+	q := query.Use(tx.GetDB())
+	if !succeeded {
+		d := q.Device
+		_, err := d.Where(d.DeviceID.Eq(command.DeviceID), d.Status.Eq(DeviceStatusWipePending)).
+			UpdateSimple(d.Status.Value(DeviceStatusActive), d.UpdatedAt.Value(now))
+		return err
+	}
+	if _, err := NewDeviceRepository().MarkWiped(tx, command.DeviceID); err != nil {
+		return err
+	}
+	c := q.DeviceCommand
+	_, err := c.Where(c.DeviceID.Eq(command.DeviceID), c.Status.In(CommandStatusPending, CommandStatusDelivered)).
+		UpdateSimple(c.Status.Value(CommandStatusFailed), c.Result.Value("device wiped"), c.CompletedAt.Value(now))
+	if err == nil {
+		tx.Infof("Device %s wiped by command %s", command.DeviceID, command.ID)
+	}
+	return err
+}
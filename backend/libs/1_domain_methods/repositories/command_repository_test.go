@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/4_common/app_errors"
+	"testing"
+)
+
+func TestCommandQueue(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	devices := NewDeviceRepository()
+	repo := NewCommandRepository()
+
+	if _, err := repo.Enqueue(sctx, "missing", "sync", "{}", "test"); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound for unknown device, got %v", err)
+	}
+	if _, err := devices.RegisterDevice(sctx, "dev-1", "", ""); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	first, err := repo.Enqueue(sctx, "dev-1", "sync", "{}", "test")
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if _, err := repo.Enqueue(sctx, "dev-1", "ping", "{}", "test"); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	delivered, err := repo.Deliver(sctx, "dev-1")
+	if err != nil || len(delivered) != 2 || delivered[0].ID != first.ID {
+		t.Fatalf("Expected both commands in order, got %+v, %v", delivered, err)
+	}
+	if delivered[0].Status != CommandStatusDelivered || delivered[0].DeliveredAt == nil {
+		t.Errorf("Expected command to be marked delivered, got %+v", delivered[0])
+	}
+
+	completed, err := repo.Complete(sctx, "dev-1", first.ID, true, "ok")
+	if err != nil || completed.Status != CommandStatusSucceeded || completed.CompletedAt == nil {
+		t.Fatalf("Expected succeeded command, got %+v, %v", completed, err)
+	}
+	// Повторный отчёт не меняет итог.
+	again, err := repo.Complete(sctx, "dev-1", first.ID, false, "late")
+	if err != nil || again.Status != CommandStatusSucceeded || again.Result != "ok" {
+		t.Errorf("Expected repeated report to be ignored, got %+v, %v", again, err)
+	}
+	if _, err := repo.Complete(sctx, "other", first.ID, true, ""); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another device, got %v", err)
+	}
+
+	// Незавершённая команда доставляется снова.
+	delivered, err = repo.Deliver(sctx, "dev-1")
+	if err != nil || len(delivered) != 1 || delivered[0].Type != "ping" {
+		t.Fatalf("Expected the unfinished command to be redelivered, got %+v, %v", delivered, err)
+	}
+
+	all, err := repo.List(sctx, "dev-1", 0)
+	if err != nil || len(all) != 2 {
+		t.Errorf("Expected 2 commands in history, got %d, %v", len(all), err)
+	}
+}
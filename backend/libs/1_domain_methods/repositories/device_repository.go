@@ -2,149 +2,732 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/3_infrastructure/db_manager"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
 	"mdm/libs/4_common/smart_context"
+	"strings"
 	"time"
 
+	"gorm.io/gen/field"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DeviceRepository описывает набор операций над устройствами.
 type DeviceRepository interface {
-	RegisterDevice(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error)
+	// RegisterDevice идемпотентна: повтор с тем же deviceToken возвращает существующее устройство.
+	// enrollmentToken — токен перерегистрации из IssueEnrollmentToken.
+	RegisterDevice(sctx smart_context.ISmartContext, deviceID string, deviceToken string, enrollmentToken string) (*Registration, error)
+	// IssueEnrollmentToken выдаёт одноразовый токен перерегистрации устройства,
+	// действующий ttl; предыдущий токен перерегистрации перестаёт действовать.
+	IssueEnrollmentToken(sctx smart_context.ISmartContext, deviceID string, ttl time.Duration) (*EnrollmentToken, error)
+	// MarkWiped помечает устройство стёртым: токен отзывается, устройство может
+	// пройти регистрацию заново с токеном перерегистрации.
+	MarkWiped(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error)
 	GetDevice(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error)
-	UpdateHeartbeat(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error)
-	SetCameraState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error)
-	SetMicrophoneState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error)
-	SetBluetoothState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error)
+	// GetDeviceStatus — GetDevice для отображения статуса: читает с реплики, если она есть;
+	// includeDeleted возвращает и удалённые устройства, ещё не удалённые окончательно.
+	GetDeviceStatus(sctx smart_context.ISmartContext, deviceID string, includeDeleted bool) (*model.Device, error)
+	// UpdateHeartbeat отмечает heartbeat; telemetry — nil, если агент её не прислал,
+	// queued — снимки телеметрии, которые агент снял без связи с сервером.
+	UpdateHeartbeat(sctx smart_context.ISmartContext, deviceID string, telemetry *model.Telemetry, queued []*model.DeviceTelemetrySnapshot) (*model.Device, error)
+	// TelemetrySnapshots возвращает снимки телеметрии, снятые без связи, новые первыми.
+	TelemetrySnapshots(sctx smart_context.ISmartContext, filter TelemetrySnapshotFilter) ([]*model.DeviceTelemetrySnapshot, error)
+	// DeleteTelemetrySnapshots удаляет снимки, снятые раньше before, и возвращает их число.
+	DeleteTelemetrySnapshots(sctx smart_context.ISmartContext, before time.Time) (int64, error)
+	// AuthenticateDevice возвращает ErrUnauthorized, если token не выдан устройству deviceID.
+	AuthenticateDevice(sctx smart_context.ISmartContext, deviceID string, token string) error
+	// expectedVersion — версия из If-Match; AnyVersion отключает проверку.
+	SetCameraState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error)
+	SetMicrophoneState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error)
+	SetBluetoothState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error)
 	UpdateOsVersion(sctx smart_context.ISmartContext, deviceID string, version string) (*model.Device, error)
 	UpdateBatteryLevel(sctx smart_context.ISmartContext, deviceID string, level int) (*model.Device, error)
-	GetAllDevices(sctx smart_context.ISmartContext) ([]model.Device, error)
+	// ReportPolicyStatus сохраняет результат применения агентом версии настроек version;
+	// applyError пустая, если все настройки применены; enforced — false, если агент
+	// только имитировал применение.
+	ReportPolicyStatus(sctx smart_context.ISmartContext, deviceID string, version int64, applyError string, enforced bool) (*model.Device, error)
+	// GetAllDevices возвращает все записи, включая выведенные из эксплуатации.
+	GetAllDevices(sctx smart_context.ISmartContext) ([]*model.Device, error)
+	FilterDevices(sctx smart_context.ISmartContext, filter DeviceFilter) ([]*model.Device, error)
+	// FindStaleDevices возвращает действующие устройства без heartbeat с момента before.
+	FindStaleDevices(sctx smart_context.ISmartContext, before time.Time) ([]*model.Device, error)
+	// Retire выводит устройство из эксплуатации: токен отзывается, heartbeat и
+	// повторная регистрация отклоняются, история команд и действий сохраняется.
+	// Повторный вызов возвращает устройство без изменений.
+	Retire(sctx smart_context.ISmartContext, deviceID, retiredBy string) (*model.Device, error)
+	// SoftDelete помечает устройство удалённым (выводя из эксплуатации, если нужно);
+	// запись остаётся в архиве до PurgeDeleted.
+	SoftDelete(sctx smart_context.ISmartContext, deviceID, deletedBy string) (*model.Device, error)
+	// PurgeDeleted окончательно удаляет устройства, удалённые раньше before,
+	// вместе с их командами, действиями, приложениями и снимками телеметрии,
+	// и возвращает их число.
+	PurgeDeleted(sctx smart_context.ISmartContext, before time.Time) (int64, error)
 }
 
+// TelemetrySnapshotFilter — выборка снимков телеметрии устройства.
+type TelemetrySnapshotFilter struct {
+	DeviceID string
+	// Since — только снимки, снятые после этого момента; нулевое — без ограничения.
+	Since time.Time
+	// Limit — не больше стольких снимков; 0 — без ограничения.
+	Limit int
+}
+
+// DeviceFilter — параметры поиска устройств. Пустые поля не применяются.
+type DeviceFilter struct {
+	// Search — подстрока device_id, без учёта регистра.
+	Search            string
+	CameraEnabled     *bool
+	MicrophoneEnabled *bool
+	BluetoothEnabled  *bool
+	// SeenSince — только устройства с heartbeat не раньше этого момента.
+	SeenSince *time.Time
+	// Archived — выведенные из эксплуатации устройства вместо действующих.
+	Archived bool
+	// IncludeDeleted — показывать в архиве и удалённые устройства, ещё не удалённые окончательно.
+	IncludeDeleted bool
+	// Limit <= 0 означает «без ограничения»; Offset без Limit не допускается.
+	Limit  int
+	Offset int
+}
+
+// ErrDeviceAlreadyRegistered возвращается при повторной регистрации устройства
+// без его токена.
+var ErrDeviceAlreadyRegistered = fmt.Errorf("%w: device already registered", app_errors.ErrConflict)
+
+// ErrEnrollmentRequired возвращается при регистрации стёртого устройства или
+// устройства без токена без действующего токена перерегистрации.
+var ErrEnrollmentRequired = fmt.Errorf("%w: device must be re-enrolled with an enrollment token issued by an administrator", app_errors.ErrForbidden)
+
+// ErrDeviceRetired возвращается при попытке зарегистрировать или изменить
+// выведенное из эксплуатации устройство.
+var ErrDeviceRetired = fmt.Errorf("%w: device is decommissioned", app_errors.ErrConflict)
+
+// Статусы устройства.
+const (
+	DeviceStatusActive = "active"
+	// DeviceStatusLocked — администратор заблокировал устройство (команда lock).
+	DeviceStatusLocked = "locked"
+	// DeviceStatusWipePending — стирание выдано агенту, ждём его отчёта.
+	DeviceStatusWipePending = "wipe_pending"
+	// DeviceStatusWiped — устройство стёрто и должно зарегистрироваться заново.
+	DeviceStatusWiped = "wiped"
+	// DeviceStatusRetired — устройство выведено из эксплуатации; вернуть его нельзя.
+	DeviceStatusRetired = "retired"
+)
+
+// Статусы применения политики на устройстве.
+const (
+	PolicyStatusPending = "pending"
+	PolicyStatusApplied = "applied"
+	PolicyStatusFailed  = "failed"
+	// PolicyStatusNotEnforced — агент принял версию, но не применял настройки на
+	// устройстве (исполнители dry-run).
+	PolicyStatusNotEnforced = "not_enforced"
+)
+
+// Результаты регистрации.
+const (
+	RegistrationCreated    = "registered"
+	RegistrationExisting   = "already_registered"
+	RegistrationReenrolled = "reenrolled"
+)
+
+// Registration — результат RegisterDevice. Поля устройства сериализуются
+// на верхнем уровне, поэтому ответ совместим с прежним форматом.
+type Registration struct {
+	*model.Device
+	Registration string `json:"registration"`
+	// DeviceToken выдаётся только при первой регистрации и перерегистрации.
+	DeviceToken string `json:"device_token,omitempty"`
+}
+
+// EnrollmentToken — токен перерегистрации; открытое значение показывается один раз.
+type EnrollmentToken struct {
+	DeviceID        string    `json:"device_id"`
+	EnrollmentToken string    `json:"enrollment_token"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// ErrVersionConflict возвращается, если настройки устройства изменились
+// после того, как клиент прочитал версию.
+var ErrVersionConflict = fmt.Errorf("%w: device version is stale", app_errors.ErrConflict)
+
+// AnyVersion отключает проверку версии при обновлении настроек.
+const AnyVersion int64 = 0
+
+// purgeBatchSize — сколько удалённых устройств PurgeDeleted удаляет за одну транзакцию.
+const purgeBatchSize = 100
+
 // repository — реализация DeviceRepository, использующая GORM.
+// Соединение с БД берётся из smart context, поэтому методы репозитория
+// автоматически участвуют в транзакции, открытой через sctx.RunInTx.
 type device_repository struct {
-	db *gorm.DB
 }
 
 // NewDeviceRepository возвращает новый экземпляр репозитория.
-func NewDeviceRepository(db *gorm.DB) DeviceRepository {
-	return &device_repository{db: db}
-}
-
-// RegisterDevice регистрирует устройство, если оно ещё не зарегистрировано.
-func (r *device_repository) RegisterDevice(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
-	// Проверяем, существует ли уже устройство.
-	var existing model.Device
-	err := r.db.Where("device_id = ?", deviceID).First(&existing).Error
-	if err == nil {
-		sctx.Warnf("device already registered")
-		return nil, nil
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+func NewDeviceRepository() DeviceRepository {
+	return &device_repository{}
+}
+
+// RegisterDevice регистрирует устройство. Запись создаётся через
+// INSERT ... ON CONFLICT DO NOTHING, поэтому параллельные регистрации одного
+// device_id не создают дубликатов. Для уже зарегистрированного устройства:
+//   - выведенное из эксплуатации — ErrDeviceRetired;
+//   - с верным токеном — возвращается существующее устройство (already_registered);
+//   - с действующим токеном перерегистрации — выдаётся новый токен (reenrolled),
+//     токен перерегистрации гасится;
+//   - стёртое или зарегистрированное до появления токенов — ErrEnrollmentRequired;
+//   - иначе — ErrDeviceAlreadyRegistered.
+func (r *device_repository) RegisterDevice(sctx smart_context.ISmartContext, deviceID string, deviceToken string, enrollmentToken string) (*Registration, error) {
+	newToken, newHash, err := auth.NewDeviceToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var registration *Registration
+	err = sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		q := query.Use(tx.GetDB())
+		now := time.Now()
+
+		candidate := &model.Device{
+			DeviceID:      deviceID,
+			LastHeartbeat: now,
+			Status:        DeviceStatusActive,
+			TokenHash:     newHash,
+			EnrolledAt:    now,
+		}
+		err := q.Device.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: q.Device.DeviceID.ColumnName().String()}},
+			DoNothing: true,
+		}).Create(candidate)
+		if err != nil {
+			return err
+		}
+
+		device, err := r.findDevice(tx, deviceID)
+		if err != nil {
+			return err
+		}
+		if device.ID == candidate.ID {
+			registration = &Registration{Device: device, Registration: RegistrationCreated, DeviceToken: newToken}
+			return nil
+		}
+
+		switch {
+		case device.RetiredAt != nil:
+			tx.Warnf("registration of decommissioned device %s refused", deviceID)
+			return ErrDeviceRetired
+		case auth.TokenMatches(deviceToken, device.TokenHash):
+			registration = &Registration{Device: device, Registration: RegistrationExisting}
+			return nil
+		case enrollmentToken != "" && device.EnrollmentExpiresAt != nil && now.Before(*device.EnrollmentExpiresAt) &&
+			auth.TokenMatches(enrollmentToken, device.EnrollmentTokenHash):
+			// Условие на хеш в UPDATE не даёт использовать токен дважды параллельно.
+			d := q.Device
+			result, err := d.Where(d.ID.Eq(device.ID), d.EnrollmentTokenHash.Eq(device.EnrollmentTokenHash)).UpdateSimple(
+				d.Status.Value(DeviceStatusActive),
+				d.TokenHash.Value(newHash),
+				d.EnrollmentTokenHash.Value(""),
+				d.EnrollmentExpiresAt.Null(),
+				d.EnrolledAt.Value(now),
+				d.LastHeartbeat.Value(now),
+				d.UpdatedAt.Value(now),
+				d.Version.Add(1),
+			)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected == 0 {
+				return ErrEnrollmentRequired
+			}
+			device, err = r.GetDevice(tx, deviceID)
+			if err != nil {
+				return err
+			}
+			registration = &Registration{Device: device, Registration: RegistrationReenrolled, DeviceToken: newToken}
+			return nil
+		case device.TokenHash == "" && device.Status != DeviceStatusWiped &&
+			device.LegacyEnrollmentUntil != nil && now.Before(*device.LegacyEnrollmentUntil):
+			// Устройство зарегистрировано до появления токенов: в переходный период
+			// (миграция 00019) оно получает токен один раз, без участия администратора.
+			d := q.Device
+			result, err := d.Where(d.ID.Eq(device.ID), d.LegacyEnrollmentUntil.IsNotNull()).UpdateSimple(
+				d.TokenHash.Value(newHash),
+				d.LegacyEnrollmentUntil.Null(),
+				d.EnrolledAt.Value(now),
+				d.LastHeartbeat.Value(now),
+				d.UpdatedAt.Value(now),
+			)
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected == 0 {
+				return ErrEnrollmentRequired
+			}
+			device, err = r.GetDevice(tx, deviceID)
+			if err != nil {
+				return err
+			}
+			tx.Warnf("device %s registered before device tokens got its token during the transition period", deviceID)
+			registration = &Registration{Device: device, Registration: RegistrationReenrolled, DeviceToken: newToken}
+			return nil
+		case device.Status == DeviceStatusWiped || device.TokenHash == "":
+			tx.Warnf("re-enrollment of device %s without a valid enrollment token refused", deviceID)
+			return ErrEnrollmentRequired
+		default:
+			tx.Warnf("device already registered")
+			return ErrDeviceAlreadyRegistered
+		}
+	})
+	if err != nil {
 		return nil, err
 	}
+	sctx.Infof("device %s: %s", deviceID, registration.Registration)
+	return registration, nil
+}
 
-	device := &model.Device{
-		DeviceID:      deviceID,
-		CameraEnabled: false,
-		LastHeartbeat: time.Now(),
+// IssueEnrollmentToken хранит только хеш токена; выведенному из эксплуатации
+// устройству токен не выдаётся.
+func (r *device_repository) IssueEnrollmentToken(sctx smart_context.ISmartContext, deviceID string, ttl time.Duration) (*EnrollmentToken, error) {
+	token, hash, err := auth.NewDeviceToken()
+	if err != nil {
+		return nil, err
 	}
-	if err := r.db.Create(device).Error; err != nil {
+	expiresAt := time.Now().Add(ttl)
+	err = sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		device, err := r.GetDevice(tx, deviceID)
+		if err != nil {
+			return err
+		}
+		if device.RetiredAt != nil {
+			return fmt.Errorf("%w: %s", ErrDeviceRetired, deviceID)
+		}
+		d := query.Use(tx.GetDB()).Device
+		_, err = d.Where(d.ID.Eq(device.ID)).UpdateSimple(
+			d.EnrollmentTokenHash.Value(hash),
+			d.EnrollmentExpiresAt.Value(expiresAt),
+		)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
-	sctx.Infof("device registered")
-	return device, nil
+	sctx.Infof("Enrollment token issued for device %s, valid until %s", deviceID, expiresAt.Format(time.RFC3339))
+	return &EnrollmentToken{DeviceID: deviceID, EnrollmentToken: token, ExpiresAt: expiresAt}, nil
 }
 
-// GetDevice возвращает данные об устройстве по его DeviceID.
+// MarkWiped помечает устройство стёртым и отзывает его токен.
+func (r *device_repository) MarkWiped(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
+	return r.updateColumn(sctx, deviceID, AnyVersion, true, func(q *query.Query) field.AssignExpr {
+		return q.Device.Status.Value(DeviceStatusWiped)
+	}, func(q *query.Query) field.AssignExpr {
+		return q.Device.TokenHash.Value("")
+	})
+}
+
+// GetDevice возвращает данные об устройстве по его DeviceID. Удалённые устройства
+// (SoftDelete) не возвращаются: для API их уже нет, хотя запись ждёт PurgeDeleted.
 func (r *device_repository) GetDevice(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
-	var device model.Device
-	if err := r.db.Where("device_id = ?", deviceID).First(&device).Error; err != nil {
-		return nil, err
+	d := query.Use(sctx.GetDB()).Device
+	return d.Where(d.DeviceID.Eq(deviceID), d.DeletedAt.IsNull()).First()
+}
+
+// findDevice — GetDevice, включая удалённые устройства: регистрация и удаление
+// должны видеть запись, пока она не удалена окончательно.
+func (r *device_repository) findDevice(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
+	d := query.Use(sctx.GetDB()).Device
+	return d.Where(d.DeviceID.Eq(deviceID)).First()
+}
+
+func (r *device_repository) GetDeviceStatus(sctx smart_context.ISmartContext, deviceID string, includeDeleted bool) (*model.Device, error) {
+	d := query.Use(db_manager.ReadReplica(sctx.GetDB())).Device
+	do := d.Where(d.DeviceID.Eq(deviceID))
+	if !includeDeleted {
+		do = do.Where(d.DeletedAt.IsNull())
 	}
-	return &device, nil
+	return do.First()
 }
 
-// UpdateHeartbeat обновляет время последней активности устройства.
-func (r *device_repository) UpdateHeartbeat(sctx smart_context.ISmartContext, deviceID string) (*model.Device, error) {
-	device, err := r.GetDevice(sctx, deviceID)
+// updateColumn обновляет колонки, которые возвращают columns, и updated_at,
+// не трогая остальные, и возвращает актуальное состояние. Если bumpVersion выставлен,
+// версия увеличивается на единицу; при expectedVersion != AnyVersion
+// обновление выполняется только если версия в БД совпадает с ожидаемой.
+// Выведенные из эксплуатации устройства не меняются (ErrDeviceRetired).
+func (r *device_repository) updateColumn(sctx smart_context.ISmartContext, deviceID string, expectedVersion int64, bumpVersion bool, columns ...func(q *query.Query) field.AssignExpr) (*model.Device, error) {
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		q := query.Use(tx.GetDB())
+		d := q.Device
+
+		assign := []field.AssignExpr{d.UpdatedAt.Value(time.Now())}
+		for _, column := range columns {
+			assign = append(assign, column(q))
+		}
+		if bumpVersion {
+			assign = append(assign, d.Version.Add(1))
+		}
+
+		do := d.Where(d.DeviceID.Eq(deviceID), d.RetiredAt.IsNull())
+		if expectedVersion != AnyVersion {
+			do = do.Where(d.Version.Eq(expectedVersion))
+		}
+		result, err := do.UpdateSimple(assign...)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			// Либо устройства нет, либо оно выведено из эксплуатации, либо версия устарела.
+			current, err := r.GetDevice(tx, deviceID)
+			if err != nil {
+				return err
+			}
+			if current.RetiredAt != nil {
+				return ErrDeviceRetired
+			}
+			return ErrVersionConflict
+		}
+
+		device, err = r.GetDevice(tx, deviceID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	device.LastHeartbeat = time.Now()
-	if err := r.db.Save(device).Error; err != nil {
-		return nil, err
-	}
 	return device, nil
 }
 
-// SetCameraState изменяет состояние камеры у устройства.
-func (r *device_repository) SetCameraState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error) {
-	device, err := r.GetDevice(sctx, deviceID)
-	if err != nil {
-		return nil, err
+// UpdateHeartbeat обновляет время последней активности устройства и, если агент
+// прислал телеметрию, сохраняет её; заряд и версия ОС из телеметрии попадают и в
+// battery_level / os_version. Heartbeat не меняет настройки, поэтому версия не увеличивается.
+// Снимки queued записываются в той же транзакции; снимок, уже принятый с прошлым
+// (повторённым агентом) heartbeat, пропускается.
+func (r *device_repository) UpdateHeartbeat(sctx smart_context.ISmartContext, deviceID string, telemetry *model.Telemetry, queued []*model.DeviceTelemetrySnapshot) (*model.Device, error) {
+	now := time.Now()
+	columns := []func(q *query.Query) field.AssignExpr{
+		func(q *query.Query) field.AssignExpr { return q.Device.LastHeartbeat.Value(now) },
+	}
+	if telemetry != nil {
+		columns = append(columns,
+			func(q *query.Query) field.AssignExpr { return q.Device.Telemetry.Value(*telemetry) },
+			func(q *query.Query) field.AssignExpr { return q.Device.TelemetryAt.Value(now) },
+		)
+		if telemetry.BatteryLevel != nil {
+			columns = append(columns, func(q *query.Query) field.AssignExpr {
+				return q.Device.BatteryLevel.Value(*telemetry.BatteryLevel)
+			})
+		}
+		if osVersion := telemetry.OSDisplayName(); osVersion != "" {
+			columns = append(columns, func(q *query.Query) field.AssignExpr {
+				return q.Device.OsVersion.Value(osVersion)
+			})
+		}
 	}
-	device.CameraEnabled = enabled
-	if err := r.db.Save(device).Error; err != nil {
+	if len(queued) == 0 {
+		return r.updateColumn(sctx, deviceID, AnyVersion, false, columns...)
+	}
+
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		var err error
+		device, err = r.updateColumn(tx, deviceID, AnyVersion, false, columns...)
+		if err != nil {
+			return err
+		}
+		for _, snapshot := range queued {
+			snapshot.DeviceID = deviceID
+		}
+		return query.Use(tx.GetDB()).DeviceTelemetrySnapshot.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(queued...)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return device, nil
 }
 
-func (r *device_repository) SetMicrophoneState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error) {
-	device, err := r.GetDevice(sctx, deviceID)
-	if err != nil {
-		return nil, err
+func (r *device_repository) TelemetrySnapshots(sctx smart_context.ISmartContext, filter TelemetrySnapshotFilter) ([]*model.DeviceTelemetrySnapshot, error) {
+	s := query.Use(sctx.GetDB()).DeviceTelemetrySnapshot
+	do := s.Where(s.DeviceID.Eq(filter.DeviceID)).Order(s.CollectedAt.Desc())
+	if !filter.Since.IsZero() {
+		do = do.Where(s.CollectedAt.Gt(filter.Since))
 	}
-	device.MicrophoneEnabled = enabled
-	if err := r.db.Save(device).Error; err != nil {
-		return nil, err
+	if filter.Limit > 0 {
+		do = do.Limit(filter.Limit)
 	}
-	return device, nil
+	return do.Find()
+}
+
+func (r *device_repository) DeleteTelemetrySnapshots(sctx smart_context.ISmartContext, before time.Time) (int64, error) {
+	s := query.Use(sctx.GetDB()).DeviceTelemetrySnapshot
+	result, err := s.Where(s.CollectedAt.Lt(before)).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
 }
 
-func (r *device_repository) SetBluetoothState(sctx smart_context.ISmartContext, deviceID string, enabled bool) (*model.Device, error) {
+// AuthenticateDevice проверяет токен, выданный устройству при регистрации.
+func (r *device_repository) AuthenticateDevice(sctx smart_context.ISmartContext, deviceID string, token string) error {
 	device, err := r.GetDevice(sctx, deviceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: unknown device", app_errors.ErrUnauthorized)
+	}
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if device.RetiredAt != nil {
+		return fmt.Errorf("%w: device is decommissioned", app_errors.ErrUnauthorized)
+	}
+	if !auth.TokenMatches(token, device.TokenHash) {
+		return fmt.Errorf("%w: invalid device token", app_errors.ErrUnauthorized)
+	}
+	return nil
+}
+
+// ReportPolicyStatus сохраняет отчёт агента о применении настроек. Отчёт не меняет
+// сами настройки, поэтому версия не увеличивается. Запоздавший отчёт о версии старше
+// уже принятой не затирает его (ErrVersionConflict), если это не текущая версия
+// устройства; условие проверяется в самом UPDATE.
+func (r *device_repository) ReportPolicyStatus(sctx smart_context.ISmartContext, deviceID string, version int64, applyError string, enforced bool) (*model.Device, error) {
+	status := PolicyStatusApplied
+	switch {
+	case applyError != "":
+		status = PolicyStatusFailed
+	case !enforced:
+		status = PolicyStatusNotEnforced
 	}
-	device.BluetoothEnabled = enabled
-	if err := r.db.Save(device).Error; err != nil {
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		d := query.Use(tx.GetDB()).Device
+		now := time.Now()
+		result, err := d.Where(
+			d.DeviceID.Eq(deviceID),
+			d.RetiredAt.IsNull(),
+			field.Or(d.PolicyVersion.Lte(version), d.Version.Eq(version)),
+		).UpdateSimple(
+			d.PolicyVersion.Value(version),
+			d.PolicyStatus.Value(status),
+			d.PolicyError.Value(applyError),
+			d.PolicyReportedAt.Value(now),
+			d.UpdatedAt.Value(now),
+		)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			current, err := r.GetDevice(tx, deviceID)
+			if err != nil {
+				return err
+			}
+			if current.RetiredAt != nil {
+				return ErrDeviceRetired
+			}
+			return fmt.Errorf("%w: policy version %d is already reported", ErrVersionConflict, current.PolicyVersion)
+		}
+		device, err = r.GetDevice(tx, deviceID)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 	return device, nil
 }
 
+// SetCameraState изменяет состояние камеры у устройства.
+func (r *device_repository) SetCameraState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error) {
+	return r.updateColumn(sctx, deviceID, expectedVersion, true, func(q *query.Query) field.AssignExpr {
+		return q.Device.CameraEnabled.Value(enabled)
+	})
+}
+
+func (r *device_repository) SetMicrophoneState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error) {
+	return r.updateColumn(sctx, deviceID, expectedVersion, true, func(q *query.Query) field.AssignExpr {
+		return q.Device.MicrophoneEnabled.Value(enabled)
+	})
+}
+
+func (r *device_repository) SetBluetoothState(sctx smart_context.ISmartContext, deviceID string, enabled bool, expectedVersion int64) (*model.Device, error) {
+	return r.updateColumn(sctx, deviceID, expectedVersion, true, func(q *query.Query) field.AssignExpr {
+		return q.Device.BluetoothEnabled.Value(enabled)
+	})
+}
+
 func (r *device_repository) UpdateOsVersion(sctx smart_context.ISmartContext, deviceID string, version string) (*model.Device, error) {
-	device, err := r.GetDevice(sctx, deviceID)
+	return r.updateColumn(sctx, deviceID, AnyVersion, false, func(q *query.Query) field.AssignExpr {
+		return q.Device.OsVersion.Value(version)
+	})
+}
+
+func (r *device_repository) UpdateBatteryLevel(sctx smart_context.ISmartContext, deviceID string, level int) (*model.Device, error) {
+	return r.updateColumn(sctx, deviceID, AnyVersion, false, func(q *query.Query) field.AssignExpr {
+		return q.Device.BatteryLevel.Value(int32(level))
+	})
+}
+
+func (r *device_repository) GetAllDevices(sctx smart_context.ISmartContext) ([]*model.Device, error) {
+	devices, err := query.Use(db_manager.ReadReplica(sctx.GetDB())).Device.Find()
 	if err != nil {
 		return nil, err
 	}
-	device.OsVersion = version
-	if err := r.db.Save(device).Error; err != nil {
+
+	sctx.Infof("len(devices) = %d", len(devices))
+	return devices, nil
+}
+
+// FilterDevices ищет устройства по фильтру, упорядочивая по device_id.
+func (r *device_repository) FilterDevices(sctx smart_context.ISmartContext, filter DeviceFilter) ([]*model.Device, error) {
+	// Шаблон применяет OFFSET только вместе с LIMIT.
+	if filter.Offset > 0 && filter.Limit <= 0 {
+		return nil, fmt.Errorf("%w: offset requires limit", app_errors.ErrBadRequest)
+	}
+	search := ""
+	if filter.Search != "" {
+		search = "%" + likeEscaper.Replace(filter.Search) + "%"
+	}
+	return query.Use(db_manager.ReadReplica(sctx.GetDB())).Device.FilterDevices(
+		filter.Archived,
+		filter.IncludeDeleted,
+		search,
+		filter.CameraEnabled,
+		filter.MicrophoneEnabled,
+		filter.BluetoothEnabled,
+		filter.SeenSince,
+		filter.Limit,
+		filter.Offset,
+	)
+}
+
+// likeEscaper экранирует спецсимволы LIKE символом «!» (ESCAPE '!' в FilterDevices),
+// чтобы подстрока поиска сравнивалась буквально.
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+func (r *device_repository) FindStaleDevices(sctx smart_context.ISmartContext, before time.Time) ([]*model.Device, error) {
+	return query.Use(sctx.GetDB()).Device.FindStale(before)
+}
+
+// Retire выводит устройство из эксплуатации в одной транзакции: статус retired,
+// токен отзывается, незавершённые команды отменяются.
+func (r *device_repository) Retire(sctx smart_context.ISmartContext, deviceID, retiredBy string) (*model.Device, error) {
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		var err error
+		device, err = r.retire(tx, deviceID, retiredBy)
+		if err == nil && device.DeletedAt != nil {
+			return fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+		}
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 	return device, nil
 }
 
-func (r *device_repository) UpdateBatteryLevel(sctx smart_context.ISmartContext, deviceID string, level int) (*model.Device, error) {
-	device, err := r.GetDevice(sctx, deviceID)
+func (r *device_repository) retire(tx smart_context.ISmartContext, deviceID, retiredBy string) (*model.Device, error) {
+	device, err := r.findDevice(tx, deviceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+	}
+	if err != nil || device.RetiredAt != nil {
+		return device, err
+	}
+
+	q := query.Use(tx.GetDB())
+	now := time.Now()
+	d := q.Device
+	_, err = d.Where(d.ID.Eq(device.ID), d.RetiredAt.IsNull()).UpdateSimple(
+		d.Status.Value(DeviceStatusRetired),
+		d.TokenHash.Value(""),
+		d.RetiredAt.Value(now),
+		d.RetiredBy.Value(retiredBy),
+		d.UpdatedAt.Value(now),
+	)
 	if err != nil {
 		return nil, err
 	}
-	device.BatteryLevel = int32(level)
-	if err := r.db.Save(device).Error; err != nil {
+	c := q.DeviceCommand
+	_, err = c.Where(c.DeviceID.Eq(deviceID), c.Status.In(CommandStatusPending, CommandStatusDelivered)).
+		UpdateSimple(c.Status.Value(CommandStatusFailed), c.Result.Value("device retired"), c.CompletedAt.Value(now))
+	if err != nil {
 		return nil, err
 	}
-	return device, nil
+	tx.Infof("Device %s retired by %s", deviceID, retiredBy)
+	return r.GetDevice(tx, deviceID)
 }
 
-func (r *device_repository) GetAllDevices(sctx smart_context.ISmartContext) ([]model.Device, error) {
-	var devices []model.Device
-	if err := r.db.Find(&devices).Error; err != nil {
+func (r *device_repository) SoftDelete(sctx smart_context.ISmartContext, deviceID, deletedBy string) (*model.Device, error) {
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		var err error
+		if device, err = r.retire(tx, deviceID, deletedBy); err != nil {
+			return err
+		}
+		if device.DeletedAt != nil {
+			return nil
+		}
+		d := query.Use(tx.GetDB()).Device
+		now := time.Now()
+		if _, err := d.Where(d.ID.Eq(device.ID)).UpdateSimple(d.DeletedAt.Value(now), d.UpdatedAt.Value(now)); err != nil {
+			return err
+		}
+		tx.Infof("Device %s deleted by %s", deviceID, deletedBy)
+		device, err = r.findDevice(tx, deviceID)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return device, nil
+}
 
-	sctx.Infof("len(devices) = %d", len(devices))
-	return devices, nil
+// PurgeDeleted удаляет устройства пачками по одной транзакции, чтобы
+// не держать блокировку на всё время очистки.
+func (r *device_repository) PurgeDeleted(sctx smart_context.ISmartContext, before time.Time) (int64, error) {
+	var purged int64
+	for {
+		var batch []string
+		err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+			q := query.Use(tx.GetDB())
+			d := q.Device
+			devices, err := d.Where(d.DeletedAt.IsNotNull(), d.DeletedAt.Lt(before)).Limit(purgeBatchSize).Find()
+			if err != nil || len(devices) == 0 {
+				return err
+			}
+			batch = make([]string, len(devices))
+			for i, device := range devices {
+				batch[i] = device.DeviceID
+			}
+			if _, err := q.DeviceCommand.Where(q.DeviceCommand.DeviceID.In(batch...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := q.DeviceAction.Where(q.DeviceAction.DeviceID.In(batch...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := q.DeviceApp.Where(q.DeviceApp.DeviceID.In(batch...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := q.DeviceAppEvent.Where(q.DeviceAppEvent.DeviceID.In(batch...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := q.DeviceTelemetrySnapshot.Where(q.DeviceTelemetrySnapshot.DeviceID.In(batch...)).Delete(); err != nil {
+				return err
+			}
+			_, err = d.Where(d.DeviceID.In(batch...)).Delete()
+			return err
+		})
+		if err != nil {
+			return purged, err
+		}
+		purged += int64(len(batch))
+		if len(batch) < purgeBatchSize {
+			return purged, nil
+		}
+	}
 }
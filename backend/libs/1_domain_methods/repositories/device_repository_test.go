@@ -1,7 +1,13 @@
 package repositories
 
 import (
+	"errors"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/3_infrastructure/migrator"
+	"mdm/libs/4_common/app_errors"
 	"mdm/libs/4_common/smart_context"
+	"mdm/migration"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +16,7 @@ import (
 )
 
 func setupTestDB(t *testing.T) (*gorm.DB, smart_context.ISmartContext) {
+	t.Helper()
 	// Используем in-memory SQLite для тестирования
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
@@ -17,59 +24,222 @@ func setupTestDB(t *testing.T) (*gorm.DB, smart_context.ISmartContext) {
 	}
 
 	logger := smart_context.NewSmartContext()
-	// Явное создание таблицы device без дефолтных функций
-	createTableSQL := `
-        CREATE TABLE device (
-            id TEXT PRIMARY KEY,
-            device_id TEXT NOT NULL,
-            camera_enabled BOOLEAN NOT NULL,
-            last_heartbeat DATETIME,
-            created_at DATETIME,
-            updated_at DATETIME
-        );
-    `
-	if err := db.Exec(createTableSQL).Error; err != nil {
-		t.Fatalf("Failed to create table: %v", err)
-	}
-	return db, logger
+	// Схема создаётся теми же миграциями, что и в рабочей БД (вариант для SQLite).
+	migrations, err := migrator.Load(migration.FS, db.Dialector.Name())
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(logger, db, migrations); err != nil {
+		t.Fatalf("Failed to apply migrations: %v", err)
+	}
+	return db, logger.WithDB(db)
 }
 
 func TestRegisterDevice(t *testing.T) {
-	db, sctx := setupTestDB(t)
-	repo := NewDeviceRepository(db)
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
 
 	deviceID := "test-device"
-	device, err := repo.RegisterDevice(sctx, deviceID)
+	registration, err := repo.RegisterDevice(sctx, deviceID, "", "")
 	if err != nil {
 		t.Fatalf("Expected no error on registration, got: %v", err)
 	}
-	if device.DeviceID != deviceID {
-		t.Errorf("Expected device_id %s, got %s", deviceID, device.DeviceID)
+	if registration.DeviceID != deviceID {
+		t.Errorf("Expected device_id %s, got %s", deviceID, registration.DeviceID)
+	}
+	if registration.Registration != RegistrationCreated || registration.DeviceToken == "" {
+		t.Errorf("Expected new registration with token, got %q (token %q)", registration.Registration, registration.DeviceToken)
+	}
+	if registration.Status != DeviceStatusActive {
+		t.Errorf("Expected status %s, got %s", DeviceStatusActive, registration.Status)
 	}
 }
 
 func TestDuplicateRegistration(t *testing.T) {
-	db, sctx := setupTestDB(t)
-	repo := NewDeviceRepository(db)
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
 
 	deviceID := "test-device"
-	_, err := repo.RegisterDevice(sctx, deviceID)
+	first, err := repo.RegisterDevice(sctx, deviceID, "", "")
 	if err != nil {
 		t.Fatalf("Expected first registration to succeed, got: %v", err)
 	}
 
-	_, err = repo.RegisterDevice(sctx, deviceID)
-	if err == nil {
-		t.Errorf("Expected error on duplicate registration, got nil")
+	_, err = repo.RegisterDevice(sctx, deviceID, "", "")
+	if !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Errorf("Expected ErrDeviceAlreadyRegistered without token, got: %v", err)
+	}
+	_, err = repo.RegisterDevice(sctx, deviceID, "wrong-token", "")
+	if !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Errorf("Expected ErrDeviceAlreadyRegistered with wrong token, got: %v", err)
+	}
+
+	// Повтор с выданным токеном идемпотентен.
+	again, err := repo.RegisterDevice(sctx, deviceID, first.DeviceToken, "")
+	if err != nil {
+		t.Fatalf("Expected repeated registration with token to succeed, got: %v", err)
+	}
+	if again.Registration != RegistrationExisting || again.ID != first.ID {
+		t.Errorf("Expected existing device %s, got %q for %s", first.ID, again.Registration, again.ID)
+	}
+	if again.DeviceToken != "" {
+		t.Errorf("Expected no new token on repeated registration")
 	}
 }
 
-func TestUpdateHeartbeat(t *testing.T) {
+func TestConcurrentRegistration(t *testing.T) {
 	db, sctx := setupTestDB(t)
-	repo := NewDeviceRepository(db)
+	repo := NewDeviceRepository()
+	// Одно соединение: in-memory SQLite живёт в пределах соединения.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.RegisterDevice(sctx, "test-device", "", "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrDeviceAlreadyRegistered):
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one successful registration, got %d", created)
+	}
+
+	var count int64
+	db.Model(&model.Device{}).Where("device_id = ?", "test-device").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected one device row, got %d", count)
+	}
+}
+
+func TestReenrollWipedDevice(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	deviceID := "test-device"
+	first, err := repo.RegisterDevice(sctx, deviceID, "", "")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	if _, err := repo.SetCameraState(sctx, deviceID, true, AnyVersion); err != nil {
+		t.Fatalf("SetCameraState failed: %v", err)
+	}
+
+	wiped, err := repo.MarkWiped(sctx, deviceID)
+	if err != nil {
+		t.Fatalf("MarkWiped failed: %v", err)
+	}
+	if wiped.Status != DeviceStatusWiped {
+		t.Errorf("Expected status %s, got %s", DeviceStatusWiped, wiped.Status)
+	}
+
+	// После стирания у устройства нет токена; без токена перерегистрации от
+	// администратора зарегистрировать его нельзя.
+	if _, err := repo.RegisterDevice(sctx, deviceID, "", ""); !errors.Is(err, ErrEnrollmentRequired) {
+		t.Fatalf("Expected ErrEnrollmentRequired without enrollment token, got %v", err)
+	}
+	expired, err := repo.IssueEnrollmentToken(sctx, deviceID, -time.Minute)
+	if err != nil {
+		t.Fatalf("IssueEnrollmentToken failed: %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, deviceID, "", expired.EnrollmentToken); !errors.Is(err, ErrEnrollmentRequired) {
+		t.Fatalf("Expected expired enrollment token to be rejected, got %v", err)
+	}
+	enrollment, err := repo.IssueEnrollmentToken(sctx, deviceID, time.Hour)
+	if err != nil {
+		t.Fatalf("IssueEnrollmentToken failed: %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, deviceID, "", "wrong-token"); !errors.Is(err, ErrEnrollmentRequired) {
+		t.Fatalf("Expected wrong enrollment token to be rejected, got %v", err)
+	}
+	reenrolled, err := repo.RegisterDevice(sctx, deviceID, "", enrollment.EnrollmentToken)
+	if err != nil {
+		t.Fatalf("Re-enrollment failed: %v", err)
+	}
+	if reenrolled.Registration != RegistrationReenrolled || reenrolled.ID != first.ID {
+		t.Errorf("Expected re-enrollment of %s, got %q for %s", first.ID, reenrolled.Registration, reenrolled.ID)
+	}
+	if reenrolled.DeviceToken == "" || reenrolled.DeviceToken == first.DeviceToken {
+		t.Errorf("Expected a new device token")
+	}
+	if reenrolled.Status != DeviceStatusActive || !reenrolled.CameraEnabled {
+		t.Errorf("Expected active device with preserved settings, got %+v", reenrolled.Device)
+	}
+
+	// Старый токен и использованный токен перерегистрации больше не действуют.
+	if _, err := repo.RegisterDevice(sctx, deviceID, first.DeviceToken, ""); !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Errorf("Expected old token to be rejected, got: %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, deviceID, "", enrollment.EnrollmentToken); !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Errorf("Expected used enrollment token to be rejected, got: %v", err)
+	}
+}
+
+// TestLegacyDeviceTransition проверяет переходный период для устройств,
+// зарегистрированных до появления токенов: токен выдаётся один раз и только
+// до legacy_enrollment_until.
+func TestLegacyDeviceTransition(t *testing.T) {
+	db, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	for id, until := range map[string]time.Time{
+		"legacy":  time.Now().Add(time.Hour),
+		"expired": time.Now().Add(-time.Hour),
+	} {
+		if _, err := repo.RegisterDevice(sctx, id, "", ""); err != nil {
+			t.Fatalf("Registration of %s failed: %v", id, err)
+		}
+		// Так выглядит строка после миграции 00019.
+		err := db.Model(&model.Device{}).Where("device_id = ?", id).
+			Updates(map[string]interface{}{"token_hash": "", "legacy_enrollment_until": until}).Error
+		if err != nil {
+			t.Fatalf("Update of %s failed: %v", id, err)
+		}
+	}
+
+	claimed, err := repo.RegisterDevice(sctx, "legacy", "", "")
+	if err != nil {
+		t.Fatalf("Legacy registration failed: %v", err)
+	}
+	if claimed.DeviceToken == "" || claimed.Registration != RegistrationReenrolled {
+		t.Errorf("Expected a device token for the legacy device, got %+v", claimed)
+	}
+	if _, err := repo.RegisterDevice(sctx, "legacy", "", ""); !errors.Is(err, ErrDeviceAlreadyRegistered) {
+		t.Errorf("Expected the transition to be one-time, got %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, "legacy", claimed.DeviceToken, ""); err != nil {
+		t.Errorf("Expected the issued token to work, got %v", err)
+	}
+
+	if _, err := repo.RegisterDevice(sctx, "expired", "", ""); !errors.Is(err, ErrEnrollmentRequired) {
+		t.Errorf("Expected ErrEnrollmentRequired after the transition period, got %v", err)
+	}
+}
+
+func TestUpdateHeartbeat(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
 
 	deviceID := "test-device"
-	device, err := repo.RegisterDevice(sctx, deviceID)
+	device, err := repo.RegisterDevice(sctx, deviceID, "", "")
 	if err != nil {
 		t.Fatalf("Registration failed: %v", err)
 	}
@@ -78,7 +248,7 @@ func TestUpdateHeartbeat(t *testing.T) {
 	// Немного подождём, чтобы время изменилось
 	time.Sleep(1 * time.Second)
 
-	updated, err := repo.UpdateHeartbeat(sctx, deviceID)
+	updated, err := repo.UpdateHeartbeat(sctx, deviceID, nil, nil)
 	if err != nil {
 		t.Fatalf("UpdateHeartbeat failed: %v", err)
 	}
@@ -87,17 +257,128 @@ func TestUpdateHeartbeat(t *testing.T) {
 	}
 }
 
+func TestHeartbeatTelemetry(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	registration, err := repo.RegisterDevice(sctx, "telemetry-device", "", "")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	level, uptime, memory := int32(42), int64(3600), uint64(8<<30)
+	telemetry := &model.Telemetry{BatteryLevel: &level, BatteryStatus: "Charging", OSName: "Ubuntu", OSVersion: "22.04", UptimeSeconds: &uptime, MemoryTotalBytes: &memory}
+
+	updated, err := repo.UpdateHeartbeat(sctx, "telemetry-device", telemetry, nil)
+	if err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	if updated.BatteryLevel != 42 || updated.OsVersion != "Ubuntu 22.04" || updated.TelemetryAt == nil {
+		t.Errorf("Expected battery, OS version and telemetry time from telemetry, got %+v", updated)
+	}
+	if updated.Version != registration.Device.Version {
+		t.Errorf("Expected telemetry not to bump the version, got %d", updated.Version)
+	}
+	stored, err := repo.GetDevice(sctx, "telemetry-device")
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if stored.Telemetry.BatteryStatus != "Charging" || stored.Telemetry.UptimeSeconds == nil || *stored.Telemetry.UptimeSeconds != 3600 || *stored.Telemetry.MemoryTotalBytes != memory {
+		t.Errorf("Telemetry was not stored: %+v", stored.Telemetry)
+	}
+
+	// Heartbeat без телеметрии оставляет последнюю полученную.
+	if _, err := repo.UpdateHeartbeat(sctx, "telemetry-device", nil, nil); err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	stored, _ = repo.GetDevice(sctx, "telemetry-device")
+	if stored.Telemetry.OSName != "Ubuntu" || stored.BatteryLevel != 42 {
+		t.Errorf("Expected previous telemetry to be kept, got %+v", stored.Telemetry)
+	}
+}
+
+// TestQueuedTelemetrySnapshots проверяет, что снимки, снятые без связи, сохраняются
+// с heartbeat, повтор heartbeat их не дублирует, а устаревшие удаляются.
+func TestQueuedTelemetrySnapshots(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+	if _, err := repo.RegisterDevice(sctx, "offline-device", "", ""); err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	collected := time.Now().Add(-time.Hour).UTC()
+	queued := func() []*model.DeviceTelemetrySnapshot {
+		var snapshots []*model.DeviceTelemetrySnapshot
+		for i := range 3 {
+			level := int32(50 - i)
+			snapshots = append(snapshots, &model.DeviceTelemetrySnapshot{
+				Telemetry:   model.Telemetry{BatteryLevel: &level},
+				CollectedAt: collected.Add(time.Duration(i) * time.Minute),
+			})
+		}
+		return snapshots
+	}
+	if _, err := repo.UpdateHeartbeat(sctx, "offline-device", nil, queued()); err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	// Ответ на heartbeat не дошёл до агента, и он прислал те же снимки снова.
+	if _, err := repo.UpdateHeartbeat(sctx, "offline-device", nil, queued()); err != nil {
+		t.Fatalf("Repeated UpdateHeartbeat failed: %v", err)
+	}
+
+	snapshots, err := repo.TelemetrySnapshots(sctx, TelemetrySnapshotFilter{DeviceID: "offline-device"})
+	if err != nil {
+		t.Fatalf("TelemetrySnapshots failed: %v", err)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("Expected 3 snapshots without duplicates, got %d", len(snapshots))
+	}
+	if level := snapshots[0].Telemetry.BatteryLevel; level == nil || *level != 48 {
+		t.Errorf("Expected the newest snapshot first, got %+v", snapshots[0])
+	}
+	since, _ := repo.TelemetrySnapshots(sctx, TelemetrySnapshotFilter{DeviceID: "offline-device", Since: collected})
+	if len(since) != 2 {
+		t.Errorf("Expected 2 snapshots after since, got %d", len(since))
+	}
+
+	deleted, err := repo.DeleteTelemetrySnapshots(sctx, collected.Add(time.Minute))
+	if err != nil || deleted != 1 {
+		t.Errorf("Expected 1 snapshot to be deleted, got %d, %v", deleted, err)
+	}
+}
+
+func TestAuthenticateDevice(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	registration, err := repo.RegisterDevice(sctx, "auth-device", "", "")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	if err := repo.AuthenticateDevice(sctx, "auth-device", registration.DeviceToken); err != nil {
+		t.Errorf("Expected the issued token to be accepted, got %v", err)
+	}
+	for _, tc := range []struct{ deviceID, token string }{
+		{"auth-device", "wrong"},
+		{"auth-device", ""},
+		{"unknown-device", registration.DeviceToken},
+	} {
+		if err := repo.AuthenticateDevice(sctx, tc.deviceID, tc.token); !errors.Is(err, app_errors.ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized for %s/%q, got %v", tc.deviceID, tc.token, err)
+		}
+	}
+}
+
 func TestSetCameraState(t *testing.T) {
-	db, sctx := setupTestDB(t)
-	repo := NewDeviceRepository(db)
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
 
 	deviceID := "test-device"
-	_, err := repo.RegisterDevice(sctx, deviceID)
+	_, err := repo.RegisterDevice(sctx, deviceID, "", "")
 	if err != nil {
 		t.Fatalf("Registration failed: %v", err)
 	}
 
-	updated, err := repo.SetCameraState(sctx, deviceID, true)
+	updated, err := repo.SetCameraState(sctx, deviceID, true, AnyVersion)
 	if err != nil {
 		t.Fatalf("SetCameraState failed: %v", err)
 	}
@@ -105,3 +386,338 @@ func TestSetCameraState(t *testing.T) {
 		t.Errorf("Expected CameraEnabled true, got false")
 	}
 }
+
+func TestSetCameraStateVersionConflict(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	deviceID := "test-device"
+	device, err := repo.RegisterDevice(sctx, deviceID, "", "")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+
+	updated, err := repo.SetCameraState(sctx, deviceID, true, device.Version)
+	if err != nil {
+		t.Fatalf("SetCameraState failed: %v", err)
+	}
+	if updated.Version != device.Version+1 {
+		t.Errorf("Expected version %d, got %d", device.Version+1, updated.Version)
+	}
+
+	// Второй администратор всё ещё держит старую версию.
+	_, err = repo.SetMicrophoneState(sctx, deviceID, true, device.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected version conflict, got: %v", err)
+	}
+
+	// Heartbeat не должен сбрасывать настройки и менять версию.
+	afterHeartbeat, err := repo.UpdateHeartbeat(sctx, deviceID, nil, nil)
+	if err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	if !afterHeartbeat.CameraEnabled || afterHeartbeat.MicrophoneEnabled {
+		t.Errorf("Unexpected settings after heartbeat: %+v", afterHeartbeat)
+	}
+	if afterHeartbeat.Version != updated.Version {
+		t.Errorf("Expected heartbeat to keep version %d, got %d", updated.Version, afterHeartbeat.Version)
+	}
+}
+
+func TestReportPolicyStatus(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	deviceID := "test-device"
+	device, err := repo.RegisterDevice(sctx, deviceID, "", "")
+	if err != nil {
+		t.Fatalf("Registration failed: %v", err)
+	}
+	if device.PolicyStatus != PolicyStatusPending {
+		t.Errorf("Expected pending policy status for a new device, got %q", device.PolicyStatus)
+	}
+
+	reported, err := repo.ReportPolicyStatus(sctx, deviceID, device.Version, "bluetooth: permission denied", true)
+	if err != nil {
+		t.Fatalf("ReportPolicyStatus failed: %v", err)
+	}
+	if reported.PolicyStatus != PolicyStatusFailed || reported.PolicyVersion != device.Version || reported.PolicyReportedAt == nil {
+		t.Errorf("Unexpected policy status: %+v", reported)
+	}
+	if reported.Version != device.Version {
+		t.Errorf("Expected report to keep version %d, got %d", device.Version, reported.Version)
+	}
+
+	reported, err = repo.ReportPolicyStatus(sctx, deviceID, device.Version, "", true)
+	if err != nil || reported.PolicyStatus != PolicyStatusApplied || reported.PolicyError != "" {
+		t.Errorf("Expected applied policy, got %+v, %v", reported, err)
+	}
+	reported, err = repo.ReportPolicyStatus(sctx, deviceID, device.Version, "", false)
+	if err != nil || reported.PolicyStatus != PolicyStatusNotEnforced {
+		t.Errorf("Expected a dry-run report to be not enforced, got %+v, %v", reported, err)
+	}
+
+	// Отчёт о новой версии принимается, запоздавший отчёт о прежней — нет.
+	updated, err := repo.SetCameraState(sctx, deviceID, true, AnyVersion)
+	if err != nil {
+		t.Fatalf("SetCameraState failed: %v", err)
+	}
+	if _, err := repo.ReportPolicyStatus(sctx, deviceID, updated.Version, "", true); err != nil {
+		t.Fatalf("ReportPolicyStatus failed: %v", err)
+	}
+	if _, err := repo.ReportPolicyStatus(sctx, deviceID, device.Version, "camera: busy", true); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for a stale report, got %v", err)
+	}
+	current, err := repo.GetDevice(sctx, deviceID)
+	if err != nil {
+		t.Fatalf("GetDevice failed: %v", err)
+	}
+	if current.PolicyVersion != updated.Version || current.PolicyStatus != PolicyStatusApplied {
+		t.Errorf("Stale report overwrote policy status: %+v", current)
+	}
+}
+
+func TestFilterDevices(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	for _, id := range []string{"Office-1", "office-2", "warehouse-1"} {
+		if _, err := repo.RegisterDevice(sctx, id, "", ""); err != nil {
+			t.Fatalf("Registration of %s failed: %v", id, err)
+		}
+	}
+	if _, err := repo.SetCameraState(sctx, "office-2", true, AnyVersion); err != nil {
+		t.Fatalf("SetCameraState failed: %v", err)
+	}
+
+	devices, err := repo.FilterDevices(sctx, DeviceFilter{Search: "OFFICE"})
+	if err != nil {
+		t.Fatalf("FilterDevices failed: %v", err)
+	}
+	if len(devices) != 2 || devices[0].DeviceID != "Office-1" || devices[1].DeviceID != "office-2" {
+		t.Errorf("Unexpected search result: %+v", devices)
+	}
+
+	enabled := true
+	devices, err = repo.FilterDevices(sctx, DeviceFilter{Search: "office", CameraEnabled: &enabled})
+	if err != nil {
+		t.Fatalf("FilterDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != "office-2" {
+		t.Errorf("Expected only office-2, got: %+v", devices)
+	}
+
+	devices, err = repo.FilterDevices(sctx, DeviceFilter{Limit: 1, Offset: 2})
+	if err != nil {
+		t.Fatalf("FilterDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != "warehouse-1" {
+		t.Errorf("Unexpected page: %+v", devices)
+	}
+}
+
+func TestFilterDevicesLiteralSearch(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	for _, id := range []string{"lab_1", "lab-1", "disk-50%", "disk-500", "hub!a", "huba"} {
+		if _, err := repo.RegisterDevice(sctx, id, "", ""); err != nil {
+			t.Fatalf("Registration of %s failed: %v", id, err)
+		}
+	}
+	for search, want := range map[string]string{"_": "lab_1", "%": "disk-50%", "!": "hub!a"} {
+		devices, err := repo.FilterDevices(sctx, DeviceFilter{Search: search})
+		if err != nil {
+			t.Fatalf("FilterDevices(%q) failed: %v", search, err)
+		}
+		if len(devices) != 1 || devices[0].DeviceID != want {
+			t.Errorf("Search %q: expected only %s, got: %+v", search, want, devices)
+		}
+	}
+
+	if _, err := repo.FilterDevices(sctx, DeviceFilter{Offset: 2}); !errors.Is(err, app_errors.ErrBadRequest) {
+		t.Errorf("Expected ErrBadRequest for offset without limit, got %v", err)
+	}
+}
+
+func TestFindStaleDevices(t *testing.T) {
+	db, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	for _, id := range []string{"fresh", "stale"} {
+		if _, err := repo.RegisterDevice(sctx, id, "", ""); err != nil {
+			t.Fatalf("Registration of %s failed: %v", id, err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := db.Model(&model.Device{}).Where("device_id = ?", "stale").Update("last_heartbeat", old).Error; err != nil {
+		t.Fatalf("Failed to age device: %v", err)
+	}
+
+	devices, err := repo.FindStaleDevices(sctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("FindStaleDevices failed: %v", err)
+	}
+	if len(devices) != 1 || devices[0].DeviceID != "stale" {
+		t.Errorf("Expected only stale device, got: %+v", devices)
+	}
+}
+
+func TestRetireDeleteAndPurge(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+	commands := NewCommandRepository()
+
+	registration, err := repo.RegisterDevice(sctx, "old-laptop", "", "")
+	if err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, "new-laptop", "", ""); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	pending, err := commands.Enqueue(sctx, "old-laptop", "sync", "{}", "test")
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	retired, err := repo.Retire(sctx, "old-laptop", "user:admin")
+	if err != nil || retired.Status != DeviceStatusRetired || retired.RetiredAt == nil || retired.RetiredBy != "user:admin" {
+		t.Fatalf("Expected retired device, got %+v, %v", retired, err)
+	}
+	if again, err := repo.Retire(sctx, "old-laptop", "user:other"); err != nil || again.RetiredBy != "user:admin" {
+		t.Errorf("Expected repeated retire to keep the first record, got %+v, %v", again, err)
+	}
+	if err := repo.AuthenticateDevice(sctx, "old-laptop", registration.DeviceToken); !errors.Is(err, app_errors.ErrUnauthorized) {
+		t.Errorf("Expected heartbeats to be refused, got %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, "old-laptop", "", ""); !errors.Is(err, ErrDeviceRetired) {
+		t.Errorf("Expected re-registration to be refused, got %v", err)
+	}
+	if _, err := repo.SetCameraState(sctx, "old-laptop", true, AnyVersion); !errors.Is(err, ErrDeviceRetired) {
+		t.Errorf("Expected settings of a retired device to be frozen, got %v", err)
+	}
+	if _, err := commands.Enqueue(sctx, "old-laptop", "sync", "{}", "test"); !errors.Is(err, ErrDeviceRetired) {
+		t.Errorf("Expected no new commands for a retired device, got %v", err)
+	}
+	history, err := commands.List(sctx, "old-laptop", 0)
+	if err != nil || len(history) != 1 || history[0].ID != pending.ID || history[0].Status != CommandStatusFailed {
+		t.Errorf("Expected the pending command to be kept as failed, got %+v, %v", history, err)
+	}
+
+	active, _ := repo.FilterDevices(sctx, DeviceFilter{})
+	archived, _ := repo.FilterDevices(sctx, DeviceFilter{Archived: true})
+	if len(active) != 1 || active[0].DeviceID != "new-laptop" || len(archived) != 1 || archived[0].DeviceID != "old-laptop" {
+		t.Errorf("Expected the retired device only in the archive, got active=%d archived=%d", len(active), len(archived))
+	}
+
+	// Удаление действующего устройства сначала выводит его из эксплуатации.
+	deleted, err := repo.SoftDelete(sctx, "new-laptop", "user:admin")
+	if err != nil || deleted.DeletedAt == nil || deleted.RetiredAt == nil {
+		t.Fatalf("Expected soft-deleted device, got %+v, %v", deleted, err)
+	}
+	if _, err := repo.SoftDelete(sctx, "old-laptop", "user:admin"); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if _, err := repo.SoftDelete(sctx, "missing", "user:admin"); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	// Удалённые устройства не видны до запроса include_deleted.
+	if _, err := repo.GetDevice(sctx, "old-laptop"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected deleted device to be hidden, got %v", err)
+	}
+	if _, err := repo.GetDeviceStatus(sctx, "old-laptop", false); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected deleted device status to be hidden, got %v", err)
+	}
+	if device, err := repo.GetDeviceStatus(sctx, "old-laptop", true); err != nil || device.DeletedAt == nil {
+		t.Errorf("Expected deleted device with include_deleted, got %+v, %v", device, err)
+	}
+	if archived, _ := repo.FilterDevices(sctx, DeviceFilter{Archived: true}); len(archived) != 0 {
+		t.Errorf("Expected deleted devices out of the archive, got %d", len(archived))
+	}
+	if archived, _ := repo.FilterDevices(sctx, DeviceFilter{Archived: true, IncludeDeleted: true}); len(archived) != 2 {
+		t.Errorf("Expected 2 deleted devices with include_deleted, got %d", len(archived))
+	}
+	if _, err := repo.Retire(sctx, "old-laptop", "user:admin"); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Errorf("Expected retire of a deleted device to be ErrNotFound, got %v", err)
+	}
+	if _, err := repo.SoftDelete(sctx, "old-laptop", "user:admin"); err != nil {
+		t.Errorf("Expected repeated delete to succeed, got %v", err)
+	}
+	if _, err := repo.RegisterDevice(sctx, "old-laptop", "", ""); !errors.Is(err, ErrDeviceRetired) {
+		t.Errorf("Expected re-registration of a deleted device to be refused, got %v", err)
+	}
+
+	if purged, err := repo.PurgeDeleted(sctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("Expected nothing to purge within retention, got %d, %v", purged, err)
+	}
+	purged, err := repo.PurgeDeleted(sctx, time.Now().Add(time.Second))
+	if err != nil || purged != 2 {
+		t.Fatalf("Expected 2 purged devices, got %d, %v", purged, err)
+	}
+	if _, err := repo.GetDevice(sctx, "old-laptop"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Expected purged device to be gone, got %v", err)
+	}
+	if history, _ := commands.List(sctx, "old-laptop", 0); len(history) != 0 {
+		t.Errorf("Expected purged history to be gone, got %d commands", len(history))
+	}
+	// После окончательного удаления идентификатор можно зарегистрировать заново.
+	if again, err := repo.RegisterDevice(sctx, "old-laptop", "", ""); err != nil || again.Registration != RegistrationCreated {
+		t.Errorf("Expected a fresh registration after purge, got %+v, %v", again, err)
+	}
+}
+
+func TestRunInTxRollback(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	errAbort := errors.New("abort")
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		if _, err := repo.RegisterDevice(tx, "test-device", "", ""); err != nil {
+			return err
+		}
+		if _, err := repo.SetCameraState(tx, "test-device", true, AnyVersion); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected abort error, got: %v", err)
+	}
+
+	if _, err := repo.GetDevice(sctx, "test-device"); err == nil {
+		t.Errorf("Expected device to be rolled back")
+	}
+}
+
+func TestRunInTxNestedSavepoint(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewDeviceRepository()
+
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		if _, err := repo.RegisterDevice(tx, "test-device", "", ""); err != nil {
+			return err
+		}
+		// Ошибка во вложенном шаге откатывает только savepoint.
+		nestedErr := tx.RunInTx(func(nested smart_context.ISmartContext) error {
+			if _, err := repo.SetCameraState(nested, "test-device", true, AnyVersion); err != nil {
+				return err
+			}
+			return errors.New("nested failure")
+		})
+		if nestedErr == nil {
+			t.Errorf("Expected nested error")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx failed: %v", err)
+	}
+
+	device, err := repo.GetDevice(sctx, "test-device")
+	if err != nil {
+		t.Fatalf("Expected device to be committed, got: %v", err)
+	}
+	if device.CameraEnabled {
+		t.Errorf("Expected nested camera change to be rolled back")
+	}
+}
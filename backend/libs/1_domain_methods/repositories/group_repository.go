@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrGroupNameTaken — группа с таким именем уже есть.
+var ErrGroupNameTaken = fmt.Errorf("%w: group name is already taken", app_errors.ErrConflict)
+
+// GroupRepository — группы устройств и их настройки.
+type GroupRepository interface {
+	List(sctx smart_context.ISmartContext) ([]*model.DeviceGroup, error)
+	Get(sctx smart_context.ISmartContext, id string) (*model.DeviceGroup, error)
+	// Create создаёт группу; heartbeatInterval 0 — период по умолчанию.
+	Create(sctx smart_context.ISmartContext, name string, heartbeatInterval time.Duration) (*model.DeviceGroup, error)
+	// SetHeartbeatInterval меняет период heartbeat устройств группы.
+	SetHeartbeatInterval(sctx smart_context.ISmartContext, id string, heartbeatInterval time.Duration) (*model.DeviceGroup, error)
+	// Delete удаляет группу; её устройства остаются вне групп.
+	Delete(sctx smart_context.ISmartContext, id string) error
+	// AssignDevice переносит устройство в группу groupID; пустой groupID — убрать из группы.
+	AssignDevice(sctx smart_context.ISmartContext, deviceID, groupID string) (*model.Device, error)
+	// HeartbeatInterval возвращает период heartbeat группы или fallback, если
+	// устройство вне групп или у группы нет своего периода.
+	HeartbeatInterval(sctx smart_context.ISmartContext, groupID string, fallback time.Duration) (time.Duration, error)
+}
+
+type group_repository struct {
+}
+
+func NewGroupRepository() GroupRepository {
+	return &group_repository{}
+}
+
+func (r *group_repository) List(sctx smart_context.ISmartContext) ([]*model.DeviceGroup, error) {
+	g := query.Use(sctx.GetDB()).DeviceGroup
+	return g.Order(g.Name).Find()
+}
+
+func (r *group_repository) Get(sctx smart_context.ISmartContext, id string) (*model.DeviceGroup, error) {
+	g := query.Use(sctx.GetDB()).DeviceGroup
+	group, err := g.Where(g.ID.Eq(id)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: group %s", app_errors.ErrNotFound, id)
+	}
+	return group, err
+}
+
+func (r *group_repository) Create(sctx smart_context.ISmartContext, name string, heartbeatInterval time.Duration) (*model.DeviceGroup, error) {
+	var group *model.DeviceGroup
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		g := query.Use(tx.GetDB()).DeviceGroup
+		taken, err := g.Where(g.Name.Eq(name)).Count()
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: %s", ErrGroupNameTaken, name)
+		}
+		now := time.Now()
+		group = &model.DeviceGroup{
+			Name:                     name,
+			HeartbeatIntervalSeconds: int32(heartbeatInterval / time.Second),
+			CreatedAt:                now,
+			UpdatedAt:                now,
+		}
+		return g.Create(group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("Device group %s (%s) created", group.Name, group.ID)
+	return group, nil
+}
+
+func (r *group_repository) SetHeartbeatInterval(sctx smart_context.ISmartContext, id string, heartbeatInterval time.Duration) (*model.DeviceGroup, error) {
+	g := query.Use(sctx.GetDB()).DeviceGroup
+	result, err := g.Where(g.ID.Eq(id)).UpdateSimple(
+		g.HeartbeatIntervalSeconds.Value(int32(heartbeatInterval/time.Second)),
+		g.UpdatedAt.Value(time.Now()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: group %s", app_errors.ErrNotFound, id)
+	}
+	return r.Get(sctx, id)
+}
+
+func (r *group_repository) Delete(sctx smart_context.ISmartContext, id string) error {
+	return sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		q := query.Use(tx.GetDB())
+		result, err := q.DeviceGroup.Where(q.DeviceGroup.ID.Eq(id)).Delete()
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: group %s", app_errors.ErrNotFound, id)
+		}
+		_, err = q.Device.Where(q.Device.GroupID.Eq(id)).UpdateSimple(
+			q.Device.GroupID.Value(""),
+			q.Device.UpdatedAt.Value(time.Now()),
+		)
+		return err
+	})
+}
+
+// AssignDevice меняет группу устройства. Группа не относится к настройкам,
+// которые применяет агент, поэтому версия устройства не увеличивается.
+func (r *group_repository) AssignDevice(sctx smart_context.ISmartContext, deviceID, groupID string) (*model.Device, error) {
+	var device *model.Device
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		if groupID != "" {
+			if _, err := r.Get(tx, groupID); err != nil {
+				return err
+			}
+		}
+		d := query.Use(tx.GetDB()).Device
+		result, err := d.Where(d.DeviceID.Eq(deviceID)).UpdateSimple(d.GroupID.Value(groupID), d.UpdatedAt.Value(time.Now()))
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: device %s", app_errors.ErrNotFound, deviceID)
+		}
+		device, err = d.Where(d.DeviceID.Eq(deviceID)).First()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (r *group_repository) HeartbeatInterval(sctx smart_context.ISmartContext, groupID string, fallback time.Duration) (time.Duration, error) {
+	if groupID == "" {
+		return fallback, nil
+	}
+	g := query.Use(sctx.GetDB()).DeviceGroup
+	group, err := g.Where(g.ID.Eq(groupID)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if group.HeartbeatIntervalSeconds <= 0 {
+		return fallback, nil
+	}
+	return time.Duration(group.HeartbeatIntervalSeconds) * time.Second, nil
+}
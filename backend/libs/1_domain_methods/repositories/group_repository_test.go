@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/4_common/app_errors"
+	"testing"
+	"time"
+)
+
+func TestDeviceGroups(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	devices := NewDeviceRepository()
+	repo := NewGroupRepository()
+	const fallback = 10 * time.Second
+
+	group, err := repo.Create(sctx, "kiosks", time.Minute)
+	if err != nil || group.ID == "" || group.HeartbeatIntervalSeconds != 60 {
+		t.Fatalf("Create failed: %+v, %v", group, err)
+	}
+	if _, err := repo.Create(sctx, "kiosks", 0); !errors.Is(err, ErrGroupNameTaken) || !errors.Is(err, app_errors.ErrConflict) {
+		t.Errorf("Expected ErrGroupNameTaken, got %v", err)
+	}
+
+	if _, err := devices.RegisterDevice(sctx, "dev-1", "", ""); err != nil {
+		t.Fatalf("RegisterDevice failed: %v", err)
+	}
+	if _, err := repo.AssignDevice(sctx, "dev-1", "missing"); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown group, got %v", err)
+	}
+	device, err := repo.AssignDevice(sctx, "dev-1", group.ID)
+	if err != nil || device.GroupID != group.ID {
+		t.Fatalf("AssignDevice failed: %+v, %v", device, err)
+	}
+
+	if interval, err := repo.HeartbeatInterval(sctx, device.GroupID, fallback); err != nil || interval != time.Minute {
+		t.Errorf("Expected group interval 1m, got %s, %v", interval, err)
+	}
+	if interval, err := repo.HeartbeatInterval(sctx, "", fallback); err != nil || interval != fallback {
+		t.Errorf("Expected fallback without group, got %s, %v", interval, err)
+	}
+	// Период 0 возвращает группу к значению по умолчанию.
+	if _, err := repo.SetHeartbeatInterval(sctx, group.ID, 0); err != nil {
+		t.Fatalf("SetHeartbeatInterval failed: %v", err)
+	}
+	if interval, err := repo.HeartbeatInterval(sctx, group.ID, fallback); err != nil || interval != fallback {
+		t.Errorf("Expected fallback for group without interval, got %s, %v", interval, err)
+	}
+
+	if err := repo.Delete(sctx, group.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(sctx, group.ID); !errors.Is(err, app_errors.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound on repeated delete, got %v", err)
+	}
+	device, err = devices.GetDevice(sctx, "dev-1")
+	if err != nil || device.GroupID != "" {
+		t.Errorf("Expected device to leave the deleted group, got %+v, %v", device, err)
+	}
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyRepository хранит ответы на запросы с заголовком Idempotency-Key.
+// Ключи принадлежат субъекту запроса principal: одинаковые ключи разных
+// субъектов — разные записи.
+type IdempotencyRepository interface {
+	// Reserve занимает ключ на время аренды lease и возвращает метку аренды.
+	// Если ключ уже занят и не истёк, возвращается существующая запись.
+	Reserve(sctx smart_context.ISmartContext, principal string, key string, requestHash string, lease time.Duration) (*model.IdempotencyKey, string, error)
+	// Complete сохраняет ответ на ttl, если ключ всё ещё занят арендой reservation;
+	// иначе возвращает ErrReservationLost.
+	Complete(sctx smart_context.ISmartContext, principal string, key string, reservation string, statusCode int, headers string, body string, ttl time.Duration) error
+	// Release освобождает ключ, занятый арендой reservation, чтобы запрос можно было повторить.
+	// Ключ, уже переданный другому запросу, не трогается.
+	Release(sctx smart_context.ISmartContext, principal string, key string, reservation string) error
+	// DeleteExpired удаляет записи, срок хранения которых истёк к моменту now.
+	DeleteExpired(sctx smart_context.ISmartContext, now time.Time) (int64, error)
+}
+
+// ErrReservationLost — аренда ключа истекла и ключ занят другим запросом,
+// поэтому ответ не сохранён.
+var ErrReservationLost = fmt.Errorf("%w: idempotency key reservation expired", app_errors.ErrConflict)
+
+type idempotency_repository struct {
+}
+
+func NewIdempotencyRepository() IdempotencyRepository {
+	return &idempotency_repository{}
+}
+
+// Reserve ставит выполняющемуся запросу короткую аренду: если процесс упал, не
+// дописав ответ, ключ освобождается через lease, а не через весь срок хранения.
+// Метка аренды отличает запрос от повтора, занявшего ключ после истечения аренды.
+func (r *idempotency_repository) Reserve(sctx smart_context.ISmartContext, principal string, key string, requestHash string, lease time.Duration) (*model.IdempotencyKey, string, error) {
+	reservation, err := newReservation()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var existing *model.IdempotencyKey
+	err = sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		k := query.Use(tx.GetDB()).IdempotencyKey
+		now := time.Now()
+
+		// Истёкший ключ можно использовать заново.
+		if _, err := k.Where(k.Principal.Eq(principal), k.Key.Eq(key), k.ExpiresAt.Lt(now)).Delete(); err != nil {
+			return err
+		}
+
+		record := &model.IdempotencyKey{
+			Principal:   principal,
+			Key:         key,
+			RequestHash: requestHash,
+			Reservation: reservation,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(lease),
+		}
+		result := tx.GetDB().Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		var err error
+		existing, err = k.Where(k.Principal.Eq(principal), k.Key.Eq(key)).First()
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if existing != nil {
+		return existing, "", nil
+	}
+	return nil, reservation, nil
+}
+
+func (r *idempotency_repository) Complete(sctx smart_context.ISmartContext, principal string, key string, reservation string, statusCode int, headers string, body string, ttl time.Duration) error {
+	k := query.Use(sctx.GetDB()).IdempotencyKey
+	result, err := k.Where(k.Principal.Eq(principal), k.Key.Eq(key), k.Reservation.Eq(reservation)).UpdateSimple(
+		k.StatusCode.Value(int32(statusCode)),
+		k.ResponseHeaders.Value(headers),
+		k.ResponseBody.Value(body),
+		k.ExpiresAt.Value(time.Now().Add(ttl)),
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected == 0 {
+		return ErrReservationLost
+	}
+	return nil
+}
+
+func (r *idempotency_repository) Release(sctx smart_context.ISmartContext, principal string, key string, reservation string) error {
+	k := query.Use(sctx.GetDB()).IdempotencyKey
+	_, err := k.Where(k.Principal.Eq(principal), k.Key.Eq(key), k.Reservation.Eq(reservation)).Delete()
+	return err
+}
+
+func (r *idempotency_repository) DeleteExpired(sctx smart_context.ISmartContext, now time.Time) (int64, error) {
+	k := query.Use(sctx.GetDB()).IdempotencyKey
+	result, err := k.Where(k.ExpiresAt.Lt(now)).Delete()
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// newReservation возвращает случайную метку аренды ключа.
+func newReservation() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
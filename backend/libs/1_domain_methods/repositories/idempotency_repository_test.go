@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyReserve(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewIdempotencyRepository()
+
+	existing, reservation, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Hour)
+	if err != nil || existing != nil || reservation == "" {
+		t.Fatalf("Expected key to be reserved, got %+v, %q, %v", existing, reservation, err)
+	}
+
+	existing, _, err = repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Hour)
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if existing == nil || existing.StatusCode != 0 {
+		t.Fatalf("Expected in-progress record, got %+v", existing)
+	}
+
+	if err := repo.Complete(sctx, "user:1", "key-1", reservation, 201, `{"ETag":"\"1\""}`, `{"ok":true}`, time.Hour); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	existing, _, err = repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Hour)
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if existing == nil || existing.StatusCode != 201 || existing.ResponseBody != `{"ok":true}` {
+		t.Errorf("Expected stored response, got %+v", existing)
+	}
+}
+
+func TestIdempotencyExpiredKey(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewIdempotencyRepository()
+
+	if _, _, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", -time.Minute); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+
+	// Истёкший ключ занимается заново, даже с другим запросом.
+	existing, _, err := repo.Reserve(sctx, "user:1", "key-1", "hash-2", time.Hour)
+	if err != nil || existing != nil {
+		t.Fatalf("Expected expired key to be reserved again, got %+v, %v", existing, err)
+	}
+
+	if _, _, err := repo.Reserve(sctx, "user:1", "key-2", "hash-1", -time.Minute); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	deleted, err := repo.DeleteExpired(sctx, time.Now())
+	if err != nil {
+		t.Fatalf("DeleteExpired failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 expired key to be deleted, got %d", deleted)
+	}
+}
+
+func TestIdempotencyPrincipalAndLease(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewIdempotencyRepository()
+
+	if _, _, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Minute); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	// Тот же ключ другого субъекта — отдельная запись.
+	existing, otherReservation, err := repo.Reserve(sctx, "api_key:7", "key-1", "hash-2", time.Minute)
+	if err != nil || existing != nil {
+		t.Fatalf("Expected key of another principal to be reserved, got %+v, %v", existing, err)
+	}
+
+	// Аренда выполняющегося запроса истекла — ключ свободен.
+	if _, _, err := repo.Reserve(sctx, "user:1", "key-2", "hash-1", -time.Minute); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	existing, reservation, err := repo.Reserve(sctx, "user:1", "key-2", "hash-1", time.Minute)
+	if err != nil || existing != nil {
+		t.Fatalf("Expected abandoned key to be reserved again, got %+v, %v", existing, err)
+	}
+
+	// Готовый ответ хранится ttl, а не срок аренды.
+	if err := repo.Complete(sctx, "user:1", "key-2", reservation, 200, "", `{}`, time.Hour); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	existing, _, err = repo.Reserve(sctx, "user:1", "key-2", "hash-1", time.Minute)
+	if err != nil || existing == nil || existing.StatusCode != 200 || existing.ExpiresAt.Before(time.Now().Add(30*time.Minute)) {
+		t.Errorf("Expected completed response kept for ttl, got %+v, %v", existing, err)
+	}
+	if err := repo.Release(sctx, "api_key:7", "key-1", otherReservation); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if existing, _, _ := repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Minute); existing == nil {
+		t.Errorf("Expected release of another principal's key to keep user:1's record")
+	}
+}
+
+// TestIdempotencyStaleReservation проверяет, что запрос, аренда которого истекла,
+// не сохраняет ответ и не освобождает ключ, уже занятый повтором.
+func TestIdempotencyStaleReservation(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewIdempotencyRepository()
+
+	_, stale, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", -time.Minute)
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	_, retry, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Minute)
+	if err != nil || retry == "" || retry == stale {
+		t.Fatalf("Expected a new reservation for the retry, got %q, %v", retry, err)
+	}
+
+	if err := repo.Complete(sctx, "user:1", "key-1", stale, 200, "", `{"stale":true}`, time.Hour); !errors.Is(err, ErrReservationLost) {
+		t.Errorf("Expected ErrReservationLost for the stale holder, got %v", err)
+	}
+	if err := repo.Release(sctx, "user:1", "key-1", stale); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	existing, _, err := repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Minute)
+	if err != nil || existing == nil || existing.StatusCode != 0 || existing.Reservation != retry {
+		t.Fatalf("Expected the retry's reservation to stay in progress, got %+v, %v", existing, err)
+	}
+
+	if err := repo.Complete(sctx, "user:1", "key-1", retry, 201, "", `{"ok":true}`, time.Hour); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	existing, _, _ = repo.Reserve(sctx, "user:1", "key-1", "hash-1", time.Minute)
+	if existing == nil || existing.StatusCode != 201 || existing.ResponseBody != `{"ok":true}` {
+		t.Errorf("Expected the retry's response to be stored, got %+v", existing)
+	}
+}
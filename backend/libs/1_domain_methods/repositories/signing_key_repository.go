@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+	"sync"
+	"time"
+)
+
+// KeyRotationPolicy — ротация ключей подписи JWT.
+type KeyRotationPolicy struct {
+	Algorithm string
+	// RotateEvery — сколько ключ подписывает токены до замены.
+	RotateEvery time.Duration
+	// PublishAhead — за сколько до активации новый ключ появляется в JWKS.
+	PublishAhead time.Duration
+	// TokenTTL — сколько заменённый ключ остаётся в JWKS: столько живут подписанные им токены.
+	TokenTTL time.Duration
+}
+
+// SigningKeyRepository хранит ключи подписи JWT.
+type SigningKeyRepository interface {
+	// Rotate удаляет истёкшие ключи, выпускает новый, если текущему пора на
+	// замену (или ключа для алгоритма ещё нет), и возвращает действующие ключи.
+	Rotate(sctx smart_context.ISmartContext, policy KeyRotationPolicy, now time.Time) ([]*auth.SigningKey, error)
+}
+
+type signing_key_repository struct {
+	// unreadable — kid ключей, закрытую часть которых не удалось расшифровать
+	// (о каждом предупреждаем один раз).
+	mu         sync.Mutex
+	unreadable map[string]bool
+}
+
+func NewSigningKeyRepository() SigningKeyRepository {
+	return &signing_key_repository{unreadable: make(map[string]bool)}
+}
+
+func (r *signing_key_repository) Rotate(sctx smart_context.ISmartContext, policy KeyRotationPolicy, now time.Time) ([]*auth.SigningKey, error) {
+	var keys []*auth.SigningKey
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		k := query.Use(tx.GetDB()).SigningKey
+		if _, err := k.Where(k.ExpiresAt.Lt(now)).Delete(); err != nil {
+			return err
+		}
+		rows, err := k.Order(k.ActivatesAt).Find()
+		if err != nil {
+			return err
+		}
+
+		keys = make([]*auth.SigningKey, 0, len(rows)+1)
+		var current *auth.SigningKey
+		for _, row := range rows {
+			key, err := r.decode(tx, row)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			if key.PrivateKey != nil && key.Algorithm == policy.Algorithm && key.ExpiresAt == nil {
+				current = key
+			}
+		}
+
+		var activatesAt time.Time
+		switch {
+		case current == nil:
+			activatesAt = now
+		case !now.Before(current.ActivatesAt.Add(policy.RotateEvery - policy.PublishAhead)):
+			activatesAt = current.ActivatesAt.Add(policy.RotateEvery)
+			if earliest := now.Add(policy.PublishAhead); activatesAt.Before(earliest) {
+				activatesAt = earliest
+			}
+		default:
+			return nil
+		}
+
+		// Параллельная ротация на нескольких экземплярах даст лишний ключ,
+		// но не сломает проверку: подписывает самый новый, остальные доживают до expires_at.
+		next, err := auth.GenerateSigningKey(policy.Algorithm, activatesAt)
+		if err != nil {
+			return err
+		}
+		row, err := encode(next)
+		if err != nil {
+			return err
+		}
+		if err := k.Create(row); err != nil {
+			return err
+		}
+
+		expiresAt := activatesAt.Add(policy.TokenTTL)
+		if _, err := k.Where(k.ID.Neq(next.ID), k.ExpiresAt.IsNull()).UpdateSimple(k.ExpiresAt.Value(expiresAt)); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if key.ExpiresAt == nil {
+				key.ExpiresAt = &expiresAt
+			}
+		}
+		keys = append(keys, next)
+		tx.Infof("Signing key %s (%s) created, signs from %s", next.ID, next.Algorithm, activatesAt.Format(time.RFC3339))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// decode восстанавливает ключ из строки таблицы. Если закрытую часть не удалось
+// расшифровать (сменился DATA_ENCRYPTION_KEY), ключ остаётся пригодным только для проверки.
+func (r *signing_key_repository) decode(sctx smart_context.ISmartContext, row *model.SigningKey) (*auth.SigningKey, error) {
+	public, err := auth.ParsePublicKey(row.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key := &auth.SigningKey{
+		ID:          row.ID,
+		Algorithm:   row.Algorithm,
+		PublicKey:   public,
+		ActivatesAt: row.ActivatesAt,
+		ExpiresAt:   row.ExpiresAt,
+	}
+	private, err := auth.OpenPrivateKey(row.PrivateKey)
+	if err != nil {
+		r.mu.Lock()
+		if !r.unreadable[row.ID] {
+			r.unreadable[row.ID] = true
+			sctx.Warnf("Signing key %s can only verify tokens: %v", row.ID, err)
+		}
+		r.mu.Unlock()
+		return key, nil
+	}
+	key.PrivateKey = private
+	return key, nil
+}
+
+func encode(key *auth.SigningKey) (*model.SigningKey, error) {
+	private, err := auth.SealPrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	public, err := auth.MarshalPublicKey(key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SigningKey{
+		ID:          key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  private,
+		PublicKey:   public,
+		ActivatesAt: key.ActivatesAt,
+		ExpiresAt:   key.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"mdm/libs/4_common/auth"
+	"testing"
+	"time"
+)
+
+func TestRotateSigningKeys(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	auth.SetDataEncryptionKey("test-data-key")
+	repo := NewSigningKeyRepository()
+	policy := KeyRotationPolicy{Algorithm: auth.AlgEdDSA, RotateEvery: 24 * time.Hour, PublishAhead: time.Hour, TokenTTL: 2 * time.Hour}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	keys, err := repo.Rotate(sctx, policy, start)
+	if err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	if len(keys) != 1 || !keys[0].ActivatesAt.Equal(start) || keys[0].PrivateKey == nil {
+		t.Fatalf("Expected one key active immediately, got %+v", keys)
+	}
+	first := keys[0].ID
+
+	keys, err = repo.Rotate(sctx, policy, start.Add(time.Hour))
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("Expected no rotation yet, got %d keys, %v", len(keys), err)
+	}
+
+	// За час до конца периода публикуется следующий ключ, текущий продолжает подписывать.
+	keys, err = repo.Rotate(sctx, policy, start.Add(23*time.Hour))
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected rotation, got %d keys, %v", len(keys), err)
+	}
+	ring := &auth.KeyRing{}
+	ring.SetAlgorithm(auth.AlgEdDSA)
+	ring.Replace(keys)
+	if key := ring.Signing(start.Add(23 * time.Hour)); key == nil || key.ID != first {
+		t.Errorf("Expected the old key to sign until the new one activates, got %+v", key)
+	}
+	next := ring.Signing(start.Add(24 * time.Hour))
+	if next == nil || next.ID == first {
+		t.Fatalf("Expected the new key to sign after activation, got %+v", next)
+	}
+	if len(ring.JWKS(start.Add(25*time.Hour)).Keys) != 2 {
+		t.Errorf("Expected both keys in JWKS during overlap")
+	}
+	if ring.Lookup(first, start.Add(26*time.Hour)) != nil {
+		t.Errorf("Expected the old key to expire after TokenTTL")
+	}
+
+	keys, err = repo.Rotate(sctx, policy, start.Add(27*time.Hour))
+	if err != nil || len(keys) != 1 || keys[0].ID != next.ID {
+		t.Fatalf("Expected expired key to be deleted, got %+v, %v", keys, err)
+	}
+
+	// Ключи, которые нельзя расшифровать новым ключом шифрования, только проверяют; выпускается новый.
+	auth.SetDataEncryptionKey("other-data-key")
+	keys, err = repo.Rotate(sctx, policy, start.Add(28*time.Hour))
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("Expected a replacement key, got %d keys, %v", len(keys), err)
+	}
+	for _, key := range keys {
+		if key.ID == next.ID && (key.PrivateKey != nil || key.ExpiresAt == nil) {
+			t.Errorf("Expected unreadable key to be verification-only and expiring, got %+v", key)
+		}
+	}
+}
@@ -2,30 +2,326 @@ package repositories
 
 import (
 	"errors"
+	"fmt"
 	"mdm/libs/2_generated_models/model"
+	"mdm/libs/2_generated_models/query"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	GetByUsername(username string) (*model.User, error)
+	GetByUsername(sctx smart_context.ISmartContext, username string) (*model.User, error)
+	// RecordFailedLogin увеличивает счётчик неудачных входов и, если политика требует,
+	// блокирует вход. Возвращает время окончания блокировки (нулевое — не заблокирован).
+	RecordFailedLogin(sctx smart_context.ISmartContext, userID string, policy LockoutPolicy) (time.Time, error)
+	// ResetFailedLogins сбрасывает счётчик и блокировку после успешного входа.
+	ResetFailedLogins(sctx smart_context.ISmartContext, userID string) error
+	// ProvisionExternalUser находит пользователя внешнего IdP по subject или создаёт
+	// его при первом входе. Роль и email при каждом входе берутся из IdP.
+	ProvisionExternalUser(sctx smart_context.ISmartContext, identity ExternalIdentity) (*model.User, error)
+	// CreateLocal создаёт пользователя, который входит по паролю (passwordHash — bcrypt).
+	CreateLocal(sctx smart_context.ISmartContext, username, passwordHash, role string) (*model.User, error)
+	// List возвращает всех пользователей по имени.
+	List(sctx smart_context.ISmartContext) ([]*model.User, error)
+
+	GetByID(sctx smart_context.ISmartContext, id string) (*model.User, error)
+	// SetPendingTOTP сохраняет (зашифрованный) секрет начатой настройки 2FA.
+	SetPendingTOTP(sctx smart_context.ISmartContext, userID, sealedSecret string) error
+	// EnableTOTP включает 2FA после подтверждения кодом шага step и заменяет коды восстановления.
+	EnableTOTP(sctx smart_context.ISmartContext, userID string, step int64, recoveryCodeHashes []string) error
+	// DisableTOTP выключает 2FA и удаляет секрет и коды восстановления.
+	DisableTOTP(sctx smart_context.ISmartContext, userID string) error
+	// UseTOTPStep атомарно запоминает шаг принятого кода; false — код этого
+	// или более позднего шага уже использован.
+	UseTOTPStep(sctx smart_context.ISmartContext, userID string, step int64) (bool, error)
+	// UseRecoveryCode погашает неиспользованный код восстановления; false — кода нет или он использован.
+	UseRecoveryCode(sctx smart_context.ISmartContext, userID, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(sctx smart_context.ISmartContext, userID string, codeHashes []string) error
+	// CountRecoveryCodes возвращает число неиспользованных кодов восстановления.
+	CountRecoveryCodes(sctx smart_context.ISmartContext, userID string) (int64, error)
+	// CountTOTPSecrets возвращает число пользователей с сохранённым (зашифрованным)
+	// секретом TOTP, включая неподтверждённые.
+	CountTOTPSecrets(sctx smart_context.ISmartContext) (int64, error)
+}
+
+// Источники учётных записей (users.auth_provider).
+const (
+	AuthProviderLocal = "local"
+	AuthProviderOIDC  = "oidc"
+)
+
+// ErrUsernameTaken — имя из IdP уже занято другой учётной записью. Такие записи
+// не объединяются автоматически: иначе IdP мог бы выдать себя за локального администратора.
+var ErrUsernameTaken = fmt.Errorf("%w: username is already used by another account", app_errors.ErrConflict)
+
+// ErrUserExists — пользователь с таким именем уже есть.
+var ErrUserExists = fmt.Errorf("%w: username is already taken", app_errors.ErrConflict)
+
+// ExternalIdentity — пользователь, подтверждённый внешним IdP.
+type ExternalIdentity struct {
+	Provider string
+	Subject  string
+	Username string
+	Email    string
+	Role     string
+}
+
+// LockoutPolicy — блокировка входа после MaxAttempts неудачных попыток подряд:
+// сначала на Delay, затем каждая следующая неудача удваивает паузу, но не больше MaxDelay.
+type LockoutPolicy struct {
+	MaxAttempts int
+	Delay       time.Duration
+	MaxDelay    time.Duration
+}
+
+// LockDuration возвращает длительность блокировки после attempts неудач подряд.
+func (p LockoutPolicy) LockDuration(attempts int) time.Duration {
+	if p.MaxAttempts <= 0 || attempts < p.MaxAttempts || p.Delay <= 0 {
+		return 0
+	}
+	delay := p.Delay
+	for i := p.MaxAttempts; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
 }
 
 type user_repository struct {
-	db *gorm.DB
 }
 
-func NewUserRepository(db *gorm.DB) UserRepository {
-	return &user_repository{db: db}
+func NewUserRepository() UserRepository {
+	return &user_repository{}
 }
 
-func (r *user_repository) GetByUsername(username string) (*model.User, error) {
-	var user model.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+func (r *user_repository) GetByUsername(sctx smart_context.ISmartContext, username string) (*model.User, error) {
+	u := query.Use(sctx.GetDB()).User
+	user, err := u.Where(u.Username.Eq(username)).First()
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
+}
+
+func (r *user_repository) RecordFailedLogin(sctx smart_context.ISmartContext, userID string, policy LockoutPolicy) (time.Time, error) {
+	var lockedUntil time.Time
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		u := query.Use(tx.GetDB()).User
+		// Инкремент в БД, чтобы параллельные попытки не затирали друг друга.
+		if _, err := u.Where(u.ID.Eq(userID)).UpdateSimple(u.FailedLoginAttempts.Add(1)); err != nil {
+			return err
+		}
+		user, err := u.Where(u.ID.Eq(userID)).First()
+		if err != nil {
+			return err
+		}
+
+		lock := policy.LockDuration(int(user.FailedLoginAttempts))
+		if lock == 0 {
+			return nil
+		}
+		lockedUntil = time.Now().Add(lock)
+		_, err = u.Where(u.ID.Eq(userID)).UpdateSimple(u.LockedUntil.Value(lockedUntil))
+		return err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lockedUntil, nil
+}
+
+func (r *user_repository) ResetFailedLogins(sctx smart_context.ISmartContext, userID string) error {
+	u := query.Use(sctx.GetDB()).User
+	_, err := u.Where(u.ID.Eq(userID)).UpdateSimple(
+		u.FailedLoginAttempts.Value(0),
+		u.LockedUntil.Null(),
+	)
+	return err
+}
+
+func (r *user_repository) ProvisionExternalUser(sctx smart_context.ISmartContext, identity ExternalIdentity) (*model.User, error) {
+	var user *model.User
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		u := query.Use(tx.GetDB()).User
+		existing, err := u.Where(u.AuthProvider.Eq(identity.Provider), u.ExternalSubject.Eq(identity.Subject)).First()
+		if err == nil {
+			if existing.Role != identity.Role || existing.Email != identity.Email {
+				if _, err := u.Where(u.ID.Eq(existing.ID)).UpdateSimple(
+					u.Role.Value(identity.Role),
+					u.Email.Value(identity.Email),
+					u.UpdatedAt.Value(time.Now()),
+				); err != nil {
+					return err
+				}
+				existing.Role = identity.Role
+				existing.Email = identity.Email
+			}
+			user = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		taken, err := u.Where(u.Username.Eq(identity.Username)).Count()
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: %s", ErrUsernameTaken, identity.Username)
+		}
+
+		// Пустой пароль не совпадёт ни с одним bcrypt-хешем: войти можно только через IdP.
+		user = &model.User{
+			Username:        identity.Username,
+			Role:            identity.Role,
+			AuthProvider:    identity.Provider,
+			ExternalSubject: identity.Subject,
+			Email:           identity.Email,
+		}
+		return u.Create(user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *user_repository) CreateLocal(sctx smart_context.ISmartContext, username, passwordHash, role string) (*model.User, error) {
+	var user *model.User
+	err := sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		u := query.Use(tx.GetDB()).User
+		taken, err := u.Where(u.Username.Eq(username)).Count()
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: %s", ErrUserExists, username)
+		}
+		now := time.Now()
+		user = &model.User{
+			Username:     username,
+			Password:     passwordHash,
+			Role:         role,
+			AuthProvider: AuthProviderLocal,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		return u.Create(user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sctx.Infof("User %s (%s) created with role %s", user.Username, user.ID, user.Role)
+	return user, nil
+}
+
+func (r *user_repository) List(sctx smart_context.ISmartContext) ([]*model.User, error) {
+	u := query.Use(sctx.GetDB()).User
+	return u.Order(u.Username).Find()
+}
+
+func (r *user_repository) GetByID(sctx smart_context.ISmartContext, id string) (*model.User, error) {
+	u := query.Use(sctx.GetDB()).User
+	user, err := u.Where(u.ID.Eq(id)).First()
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: user %s", app_errors.ErrNotFound, id)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *user_repository) SetPendingTOTP(sctx smart_context.ISmartContext, userID, sealedSecret string) error {
+	u := query.Use(sctx.GetDB()).User
+	_, err := u.Where(u.ID.Eq(userID)).UpdateSimple(
+		u.TotpSecret.Value(sealedSecret),
+		u.TotpEnabled.Value(false),
+		u.UpdatedAt.Value(time.Now()),
+	)
+	return err
+}
+
+func (r *user_repository) EnableTOTP(sctx smart_context.ISmartContext, userID string, step int64, recoveryCodeHashes []string) error {
+	return sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		u := query.Use(tx.GetDB()).User
+		if _, err := u.Where(u.ID.Eq(userID)).UpdateSimple(
+			u.TotpEnabled.Value(true),
+			u.TotpLastStep.Value(step),
+			u.UpdatedAt.Value(time.Now()),
+		); err != nil {
+			return err
+		}
+		return r.ReplaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+func (r *user_repository) DisableTOTP(sctx smart_context.ISmartContext, userID string) error {
+	return sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		q := query.Use(tx.GetDB())
+		u := q.User
+		if _, err := u.Where(u.ID.Eq(userID)).UpdateSimple(
+			u.TotpSecret.Value(""),
+			u.TotpEnabled.Value(false),
+			u.TotpLastStep.Value(0),
+			u.UpdatedAt.Value(time.Now()),
+		); err != nil {
+			return err
+		}
+		_, err := q.RecoveryCode.Where(q.RecoveryCode.UserID.Eq(userID)).Delete()
+		return err
+	})
+}
+
+func (r *user_repository) UseTOTPStep(sctx smart_context.ISmartContext, userID string, step int64) (bool, error) {
+	u := query.Use(sctx.GetDB()).User
+	// Условие в WHERE: из двух параллельных запросов с одним кодом пройдёт один.
+	info, err := u.Where(u.ID.Eq(userID), u.TotpLastStep.Lt(step)).UpdateSimple(u.TotpLastStep.Value(step))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected == 1, nil
+}
+
+func (r *user_repository) UseRecoveryCode(sctx smart_context.ISmartContext, userID, codeHash string) (bool, error) {
+	c := query.Use(sctx.GetDB()).RecoveryCode
+	info, err := c.Where(c.UserID.Eq(userID), c.CodeHash.Eq(codeHash), c.UsedAt.IsNull()).
+		UpdateSimple(c.UsedAt.Value(time.Now()))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected == 1, nil
+}
+
+func (r *user_repository) ReplaceRecoveryCodes(sctx smart_context.ISmartContext, userID string, codeHashes []string) error {
+	return sctx.RunInTx(func(tx smart_context.ISmartContext) error {
+		c := query.Use(tx.GetDB()).RecoveryCode
+		if _, err := c.Where(c.UserID.Eq(userID)).Delete(); err != nil {
+			return err
+		}
+		codes := make([]*model.RecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, &model.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return c.Create(codes...)
+	})
+}
+
+func (r *user_repository) CountRecoveryCodes(sctx smart_context.ISmartContext, userID string) (int64, error) {
+	c := query.Use(sctx.GetDB()).RecoveryCode
+	return c.Where(c.UserID.Eq(userID), c.UsedAt.IsNull()).Count()
+}
+
+func (r *user_repository) CountTOTPSecrets(sctx smart_context.ISmartContext) (int64, error) {
+	u := query.Use(sctx.GetDB()).User
+	return u.Where(u.TotpSecret.Neq("")).Count()
 }
@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"errors"
+	"mdm/libs/2_generated_models/model"
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyLockDuration(t *testing.T) {
+	policy := LockoutPolicy{MaxAttempts: 3, Delay: 30 * time.Second, MaxDelay: 2 * time.Minute}
+	for attempts, want := range map[int]time.Duration{
+		2: 0,
+		3: 30 * time.Second,
+		4: time.Minute,
+		5: 2 * time.Minute,
+		9: 2 * time.Minute,
+	} {
+		if got := policy.LockDuration(attempts); got != want {
+			t.Errorf("LockDuration(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+	if got := (LockoutPolicy{}).LockDuration(100); got != 0 {
+		t.Errorf("Disabled policy must not lock, got %s", got)
+	}
+}
+
+func TestRecordFailedLogin(t *testing.T) {
+	db, sctx := setupTestDB(t)
+	repo := NewUserRepository()
+	user := &model.User{Username: "admin", Password: "hash", Role: "admin"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	policy := LockoutPolicy{MaxAttempts: 2, Delay: time.Minute, MaxDelay: time.Hour}
+
+	lockedUntil, err := repo.RecordFailedLogin(sctx, user.ID, policy)
+	if err != nil || !lockedUntil.IsZero() {
+		t.Fatalf("First failure must not lock, got %v, %v", lockedUntil, err)
+	}
+	lockedUntil, err = repo.RecordFailedLogin(sctx, user.ID, policy)
+	if err != nil {
+		t.Fatalf("RecordFailedLogin failed: %v", err)
+	}
+	if wait := time.Until(lockedUntil); wait <= 0 || wait > time.Minute {
+		t.Errorf("Expected lock for about a minute, got %s", wait)
+	}
+
+	stored, err := repo.GetByUsername(sctx, "admin")
+	if err != nil {
+		t.Fatalf("GetByUsername failed: %v", err)
+	}
+	if stored.FailedLoginAttempts != 2 || !stored.LockedUntil.After(time.Now()) {
+		t.Errorf("Expected 2 attempts and active lock, got %+v", stored)
+	}
+
+	if err := repo.ResetFailedLogins(sctx, user.ID); err != nil {
+		t.Fatalf("ResetFailedLogins failed: %v", err)
+	}
+	stored, err = repo.GetByUsername(sctx, "admin")
+	if err != nil {
+		t.Fatalf("GetByUsername failed: %v", err)
+	}
+	if stored.FailedLoginAttempts != 0 || stored.LockedUntil.After(time.Now()) {
+		t.Errorf("Expected reset counters, got %+v", stored)
+	}
+}
+
+func TestProvisionExternalUser(t *testing.T) {
+	db, sctx := setupTestDB(t)
+	repo := NewUserRepository()
+	identity := ExternalIdentity{Provider: AuthProviderOIDC, Subject: "sub-1", Username: "alice", Email: "alice@example.com", Role: "user"}
+
+	created, err := repo.ProvisionExternalUser(sctx, identity)
+	if err != nil {
+		t.Fatalf("ProvisionExternalUser failed: %v", err)
+	}
+	if created.ID == "" || created.AuthProvider != AuthProviderOIDC || created.Password != "" {
+		t.Fatalf("Unexpected provisioned user: %+v", created)
+	}
+
+	// Повторный вход находит ту же запись и обновляет роль из IdP.
+	identity.Role = "admin"
+	again, err := repo.ProvisionExternalUser(sctx, identity)
+	if err != nil {
+		t.Fatalf("ProvisionExternalUser failed: %v", err)
+	}
+	if again.ID != created.ID || again.Role != "admin" {
+		t.Errorf("Expected the same user with updated role, got %+v", again)
+	}
+	stored, err := repo.GetByUsername(sctx, "alice")
+	if err != nil || stored.Role != "admin" {
+		t.Errorf("Expected stored role admin, got %+v, %v", stored, err)
+	}
+
+	// Локальная учётная запись с тем же именем не перехватывается.
+	if err := db.Create(&model.User{Username: "root", Password: "hash", Role: "admin"}).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	_, err = repo.ProvisionExternalUser(sctx, ExternalIdentity{Provider: AuthProviderOIDC, Subject: "sub-2", Username: "root", Role: "admin"})
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("Expected ErrUsernameTaken, got %v", err)
+	}
+}
+
+func TestCreateLocalUser(t *testing.T) {
+	_, sctx := setupTestDB(t)
+	repo := NewUserRepository()
+
+	user, err := repo.CreateLocal(sctx, "bob", "hash", "user")
+	if err != nil || user.ID == "" || user.AuthProvider != AuthProviderLocal {
+		t.Fatalf("CreateLocal failed: %+v, %v", user, err)
+	}
+	if _, err := repo.CreateLocal(sctx, "bob", "hash", "admin"); !errors.Is(err, ErrUserExists) {
+		t.Errorf("Expected ErrUserExists, got %v", err)
+	}
+	if _, err := repo.CreateLocal(sctx, "alice", "hash", "admin"); err != nil {
+		t.Fatalf("CreateLocal failed: %v", err)
+	}
+	users, err := repo.List(sctx)
+	if err != nil || len(users) != 2 || users[0].Username != "alice" {
+		t.Errorf("Expected users ordered by name, got %+v, %v", users, err)
+	}
+}
+
+func TestTOTPState(t *testing.T) {
+	db, sctx := setupTestDB(t)
+	repo := NewUserRepository()
+	user := &model.User{Username: "admin", Password: "hash", Role: "admin"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if err := repo.SetPendingTOTP(sctx, user.ID, "sealed"); err != nil {
+		t.Fatalf("SetPendingTOTP failed: %v", err)
+	}
+	if err := repo.EnableTOTP(sctx, user.ID, 100, []string{"h1", "h2"}); err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+	stored, err := repo.GetByID(sctx, user.ID)
+	if err != nil || !stored.TotpEnabled || stored.TotpSecret != "sealed" || stored.TotpLastStep != 100 {
+		t.Fatalf("Unexpected TOTP state: %+v, %v", stored, err)
+	}
+
+	// Код того же шага повторно не принимается.
+	if ok, err := repo.UseTOTPStep(sctx, user.ID, 100); err != nil || ok {
+		t.Errorf("Expected step 100 to be rejected, got %v, %v", ok, err)
+	}
+	if ok, err := repo.UseTOTPStep(sctx, user.ID, 101); err != nil || !ok {
+		t.Errorf("Expected step 101 to be accepted, got %v, %v", ok, err)
+	}
+
+	if ok, err := repo.UseRecoveryCode(sctx, user.ID, "h1"); err != nil || !ok {
+		t.Errorf("Expected recovery code to be accepted, got %v, %v", ok, err)
+	}
+	if ok, _ := repo.UseRecoveryCode(sctx, user.ID, "h1"); ok {
+		t.Error("Expected a used recovery code to be rejected")
+	}
+	if left, err := repo.CountRecoveryCodes(sctx, user.ID); err != nil || left != 1 {
+		t.Errorf("Expected 1 recovery code left, got %d, %v", left, err)
+	}
+
+	if err := repo.DisableTOTP(sctx, user.ID); err != nil {
+		t.Fatalf("DisableTOTP failed: %v", err)
+	}
+	stored, _ = repo.GetByID(sctx, user.ID)
+	if stored.TotpEnabled || stored.TotpSecret != "" {
+		t.Errorf("Expected 2FA to be disabled, got %+v", stored)
+	}
+	if left, _ := repo.CountRecoveryCodes(sctx, user.ID); left != 0 {
+		t.Errorf("Expected recovery codes to be deleted, got %d", left)
+	}
+}
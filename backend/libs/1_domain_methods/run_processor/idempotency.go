@@ -0,0 +1,138 @@
+package run_processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+
+	"go.uber.org/zap"
+)
+
+// IdempotencyKeyHeader — заголовок, по которому повтор запроса распознаётся как тот же запрос.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// ReplayedHeader выставляется в ответе, взятом из хранилища, а не выполненном заново.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyKeyReused — ключ уже использован для другого запроса.
+	ErrIdempotencyKeyReused = fmt.Errorf("%w: Idempotency-Key was already used for a different request", app_errors.ErrUnprocessable)
+	// ErrIdempotencyInProgress — запрос с этим ключом ещё выполняется.
+	ErrIdempotencyInProgress = fmt.Errorf("%w: request with this Idempotency-Key is still in progress", app_errors.ErrConflict)
+)
+
+// IdempotencyStore хранит ответы на запросы с Idempotency-Key
+// (реализация — repositories.IdempotencyRepository).
+type IdempotencyStore interface {
+	Reserve(sctx smart_context.ISmartContext, principal string, key string, requestHash string, lease time.Duration) (*model.IdempotencyKey, string, error)
+	Complete(sctx smart_context.ISmartContext, principal string, key string, reservation string, statusCode int, headers string, body string, ttl time.Duration) error
+	Release(sctx smart_context.ISmartContext, principal string, key string, reservation string) error
+}
+
+var idempotencySettings struct {
+	store IdempotencyStore
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// SetIdempotencyStore включает обработку Idempotency-Key: ответы хранятся ttl,
+// а выполняющийся запрос занимает ключ не дольше lease.
+// Без хранилища заголовок игнорируется.
+func SetIdempotencyStore(store IdempotencyStore, ttl, lease time.Duration) {
+	idempotencySettings.store = store
+	idempotencySettings.ttl = ttl
+	idempotencySettings.lease = lease
+}
+
+// WithoutIdempotency отключает Idempotency-Key для маршрутов, ответы которых
+// содержат учётные данные (JWT, токены устройств, API-ключи, секреты 2FA):
+// такие ответы не должны храниться. Заголовок удаляется, и запрос выполняется как обычно.
+func WithoutIdempotency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(IdempotencyKeyHeader)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// idempotencyPrincipal возвращает владельца ключа — субъекта запроса в виде
+// "kind:id", для анонимного запроса — пустую строку.
+func idempotencyPrincipal(r *http.Request) string {
+	p := auth.PrincipalFromContext(r.Context())
+	if p == nil {
+		return ""
+	}
+	return p.Kind + ":" + p.ID
+}
+
+// idempotencyKey возвращает ключ и отпечаток запроса (метод, путь и тело).
+// Для запросов без ключа, не-POST запросов и при выключенном хранилище ключ пустой.
+// Тело читается целиком и подменяется копией, чтобы его можно было разобрать дальше.
+func idempotencyKey(r *http.Request) (string, string, error) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || r.Method != http.MethodPost || idempotencySettings.store == nil {
+		return "", "", nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", "", fmt.Errorf("%w: Idempotency-Key must not exceed %d characters", app_errors.ErrBadRequest, maxIdempotencyKeyLength)
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return "", "", err
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.Path)
+	hash.Write(body)
+	return key, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replayResponse возвращает сохранённый ответ либо ошибку, если ключ
+// использован для другого запроса или запрос ещё выполняется.
+func replayResponse(stored *model.IdempotencyKey, requestHash string, log *zap.Logger) *rawResponse {
+	switch {
+	case stored.RequestHash != requestHash:
+		return errorResponse(ErrIdempotencyKeyReused, log)
+	case stored.StatusCode == 0:
+		return errorResponse(ErrIdempotencyInProgress, log)
+	}
+
+	headers := map[string]string{}
+	if stored.ResponseHeaders != "" {
+		if err := json.Unmarshal([]byte(stored.ResponseHeaders), &headers); err != nil {
+			log.Warn("Failed to decode stored response headers", zap.Error(err))
+		}
+	}
+	headers[ReplayedHeader] = "true"
+	return &rawResponse{
+		statusCode: int(stored.StatusCode),
+		headers:    headers,
+		body:       []byte(stored.ResponseBody),
+	}
+}
+
+func encodeHeaders(headers map[string]string) string {
+	if len(headers) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(headers)
+	return string(encoded)
+}
@@ -0,0 +1,220 @@
+package run_processor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+// memoryIdempotencyStore — IdempotencyStore в памяти для тестов.
+// Записи хранятся по паре (субъект, ключ); canceledWrites считает Complete и
+// Release, вызванные с уже отменённым контекстом.
+type memoryIdempotencyStore struct {
+	mu             sync.Mutex
+	records        map[[2]string]*model.IdempotencyKey
+	reservations   int
+	canceledWrites int
+}
+
+func (s *memoryIdempotencyStore) Reserve(sctx smart_context.ISmartContext, principal string, key string, requestHash string, lease time.Duration) (*model.IdempotencyKey, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[[2]string{principal, key}]; ok {
+		copied := *existing
+		return &copied, "", nil
+	}
+	s.reservations++
+	reservation := strconv.Itoa(s.reservations)
+	s.records[[2]string{principal, key}] = &model.IdempotencyKey{Principal: principal, Key: key, RequestHash: requestHash, Reservation: reservation}
+	return nil, reservation, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(sctx smart_context.ISmartContext, principal string, key string, reservation string, statusCode int, headers string, body string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sctx.GetContext().Err() != nil {
+		s.canceledWrites++
+	}
+	record := s.records[[2]string{principal, key}]
+	if record == nil || record.Reservation != reservation {
+		return errors.New("reservation lost")
+	}
+	record.StatusCode = int32(statusCode)
+	record.ResponseHeaders = headers
+	record.ResponseBody = body
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(sctx smart_context.ISmartContext, principal string, key string, reservation string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sctx.GetContext().Err() != nil {
+		s.canceledWrites++
+	}
+	if record := s.records[[2]string{principal, key}]; record != nil && record.Reservation == reservation {
+		delete(s.records, [2]string{principal, key})
+	}
+	return nil
+}
+
+func useMemoryIdempotencyStore(t *testing.T) *memoryIdempotencyStore {
+	t.Helper()
+	store := &memoryIdempotencyStore{records: map[[2]string]*model.IdempotencyKey{}}
+	SetIdempotencyStore(store, time.Hour, time.Minute)
+	t.Cleanup(func() { SetIdempotencyStore(nil, 0, 0) })
+	return store
+}
+
+func postWithKey(handler http.HandlerFunc, key, body string) *httptest.ResponseRecorder {
+	return postAs(handler, nil, key, body)
+}
+
+// postAs отправляет запрос от имени субъекта principal (nil — анонимно).
+func postAs(handler http.Handler, principal *auth.Principal, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/devices/a/camera", strings.NewReader(body))
+	if principal != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// TestIdempotencyReplay проверяет, что повтор запроса с тем же ключом
+// не вызывает обработчик повторно и возвращает сохранённый ответ.
+func TestIdempotencyReplay(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		calls++
+		return &Response{
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]string{"ETag": `"2"`},
+			Body:       map[string]int{"calls": calls},
+		}, nil
+	})
+
+	first := postWithKey(handler, "key-1", `{"enabled": true}`)
+	second := postWithKey(handler, "key-1", `{"enabled": true}`)
+
+	if calls != 1 {
+		t.Errorf("Expected handler to run once, got %d", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed 201 %q, got %d %q", first.Body.String(), second.Code, second.Body.String())
+	}
+	if second.Header().Get("ETag") != `"2"` || second.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("Unexpected replay headers: %v", second.Header())
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("First response must not be marked as replayed")
+	}
+}
+
+// TestIdempotencyKeyReuse проверяет отказ при повторе ключа с другим телом.
+func TestIdempotencyKeyReuse(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	handler := JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		return data, nil
+	})
+
+	postWithKey(handler, "key-1", `{"enabled": true}`)
+	rr := postWithKey(handler, "key-1", `{"enabled": false}`)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code 422, got %d", rr.Code)
+	}
+}
+
+// TestIdempotencyServerErrorNotStored проверяет, что после ошибки 5xx запрос можно повторить.
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("temporary failure")
+		}
+		return data, nil
+	})
+
+	if rr := postWithKey(handler, "key-1", `{}`); rr.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status code 500, got %d", rr.Code)
+	}
+	if rr := postWithKey(handler, "key-1", `{}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected retry to succeed, got %d", rr.Code)
+	}
+	if calls != 2 {
+		t.Errorf("Expected handler to run twice, got %d", calls)
+	}
+}
+
+// TestIdempotencyKeyBoundToPrincipal проверяет, что ответ на ключ отдаётся
+// только его владельцу: тот же ключ другого субъекта выполняет запрос заново.
+func TestIdempotencyKeyBoundToPrincipal(t *testing.T) {
+	useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		calls++
+		return map[string]int{"calls": calls}, nil
+	})
+	alice := &auth.Principal{Kind: auth.PrincipalUser, ID: "1", Name: "alice"}
+	bob := &auth.Principal{Kind: auth.PrincipalUser, ID: "2", Name: "bob"}
+
+	postAs(handler, alice, "key-1", `{}`)
+	rr := postAs(handler, bob, "key-1", `{}`)
+	if calls != 2 || rr.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("Expected bob's request to run, got %d calls and headers %v", calls, rr.Header())
+	}
+	if rr := postAs(handler, alice, "key-1", `{}`); rr.Header().Get(ReplayedHeader) != "true" || calls != 2 {
+		t.Errorf("Expected alice's retry to be replayed, got %d calls and headers %v", calls, rr.Header())
+	}
+}
+
+// TestWithoutIdempotency проверяет, что маршруты с учётными данными в ответе
+// выполняются при каждом повторе и ничего не сохраняют.
+func TestWithoutIdempotency(t *testing.T) {
+	store := useMemoryIdempotencyStore(t)
+	calls := 0
+	handler := WithoutIdempotency(JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		calls++
+		return map[string]string{"token": "secret"}, nil
+	}))
+
+	postAs(handler, nil, "key-1", `{}`)
+	postAs(handler, nil, "key-1", `{}`)
+	if calls != 2 || len(store.records) != 0 {
+		t.Errorf("Expected 2 calls and no stored responses, got %d calls and %d records", calls, len(store.records))
+	}
+}
+
+// TestIdempotencyStoresAfterCancel проверяет, что ответ сохраняется, даже если
+// клиент отключился, пока выполнялся обработчик.
+func TestIdempotencyStoresAfterCancel(t *testing.T) {
+	store := useMemoryIdempotencyStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := JSONResponseMiddleware(smart_context.NewSmartContext(), func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		cancel()
+		return data, nil
+	})
+
+	req := httptest.NewRequest("POST", "/devices/a/camera", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	handler(httptest.NewRecorder(), req)
+	if store.canceledWrites != 0 {
+		t.Errorf("Expected the response to be stored with a live context, got %d canceled writes", store.canceledWrites)
+	}
+	if record := store.records[[2]string{"", "key-1"}]; record == nil || record.StatusCode != http.StatusOK {
+		t.Errorf("Expected stored 200 response, got %+v", record)
+	}
+}
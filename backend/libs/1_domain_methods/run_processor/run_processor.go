@@ -1,10 +1,14 @@
 package run_processor
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
 
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/app_errors"
 	"mdm/libs/4_common/smart_context"
 
 	"github.com/go-chi/chi/v5"
@@ -14,39 +18,152 @@ import (
 // AppHandler определяет сигнатуру обработчика.
 type AppHandler func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error)
 
+// Response позволяет обработчику задать код ответа и заголовки.
+// Если обработчик возвращает обычное значение, оно отдаётся как тело с кодом 200.
+type Response struct {
+	StatusCode int
+	Headers    map[string]string
+	Body       interface{}
+}
+
+type requestCtxKey struct{}
+
+// GetRequestHeader возвращает заголовок текущего HTTP-запроса.
+// Запрос кладётся в context.Context smart context'а в JSONResponseMiddleware.
+func GetRequestHeader(sctx smart_context.ISmartContext, name string) string {
+	r, ok := sctx.GetContext().Value(requestCtxKey{}).(*http.Request)
+	if !ok {
+		return ""
+	}
+	return r.Header.Get(name)
+}
+
 // JSONResponseMiddleware оборачивает вызов AppHandler в http.HandlerFunc.
+// POST-запросы с заголовком Idempotency-Key выполняются не больше одного раза
+// (см. SetIdempotencyStore).
 func JSONResponseMiddleware(sctx smart_context.ISmartContext, handler AppHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Декодируем JSON-тело запроса и объединяем его с URL-параметрами.
-		data, err := parseJSONBody(r)
+		// Привязываем smart context к запросу: отмена запроса прерывает запросы к БД,
+		// а обработчик может прочитать заголовки через GetRequestHeader.
+		reqCtx := sctx.WithContext(context.WithValue(r.Context(), requestCtxKey{}, r))
+
+		key, requestHash, err := idempotencyKey(r)
 		if err != nil {
-			handleError(w, err, sctx.GetLogger())
+			writeResponse(w, errorResponse(err, sctx.GetLogger()))
 			return
 		}
+		principal := idempotencyPrincipal(r)
+		var reservation string
+		if key != "" {
+			var stored *model.IdempotencyKey
+			stored, reservation, err = idempotencySettings.store.Reserve(reqCtx, principal, key, requestHash, idempotencySettings.lease)
+			if err != nil {
+				writeResponse(w, errorResponse(err, sctx.GetLogger()))
+				return
+			}
+			if stored != nil {
+				writeResponse(w, replayResponse(stored, requestHash, sctx.GetLogger()))
+				return
+			}
+		}
+
+		resp := runHandler(reqCtx, r, handler)
 
-		// Пример: извлекаем параметр "id" из URL и добавляем его в data
-		if id := chi.URLParam(r, "id"); id != "" {
-			data["id"] = id
+		if key != "" {
+			// Клиент мог уже отключиться: запись ответа не должна прерываться вместе с запросом.
+			storeCtx := sctx.WithContext(context.WithoutCancel(reqCtx.GetContext()))
+			// Ответ 5xx не сохраняем: ключ освобождается, и клиент может повторить запрос.
+			// Если аренда истекла и ключ занял повтор, его запись не трогаем.
+			if resp.statusCode >= http.StatusInternalServerError {
+				err = idempotencySettings.store.Release(storeCtx, principal, key, reservation)
+			} else {
+				err = idempotencySettings.store.Complete(storeCtx, principal, key, reservation, resp.statusCode, encodeHeaders(resp.headers), string(resp.body), idempotencySettings.ttl)
+			}
+			if err != nil {
+				sctx.Errorf("Failed to store idempotent response for key %s: %v", key, err)
+			}
 		}
+		writeResponse(w, resp)
+	}
+}
 
-		// Вызываем обработчик с распарсенными данными.
-		response, err := handler(sctx, data)
-		if err != nil {
-			handleError(w, err, sctx.GetLogger())
-			return
+// runHandler разбирает запрос, вызывает обработчик и формирует ответ.
+func runHandler(sctx smart_context.ISmartContext, r *http.Request, handler AppHandler) *rawResponse {
+	// Декодируем JSON-тело запроса и объединяем его с URL-параметрами.
+	data, err := parseJSONBody(r)
+	if err != nil {
+		return errorResponse(err, sctx.GetLogger())
+	}
+
+	// Параметры query string не перекрывают поля тела.
+	for key, values := range r.URL.Query() {
+		if _, exists := data[key]; !exists && len(values) > 0 {
+			data[key] = values[0]
 		}
+	}
 
-		// Отправляем JSON-ответ.
-		w.Header().Set("Content-Type", "application/json")
-		if response != nil {
-			if err := json.NewEncoder(w).Encode(response); err != nil {
-				sctx.Error("Failed to encode JSON response", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// Параметры маршрута ("id", "command_id", ...) перекрывают одноимённые поля тела.
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		for i, key := range rctx.URLParams.Keys {
+			if value := rctx.URLParams.Values[i]; key != "*" && value != "" {
+				data[key] = value
 			}
-		} else {
-			w.WriteHeader(http.StatusNoContent)
 		}
 	}
+
+	// Вызываем обработчик с распарсенными данными.
+	response, err := handler(sctx, data)
+	if err != nil {
+		return errorResponse(err, sctx.GetLogger())
+	}
+
+	resp := &rawResponse{statusCode: http.StatusOK, headers: map[string]string{}}
+	if custom, ok := response.(*Response); ok {
+		for key, value := range custom.Headers {
+			resp.headers[key] = value
+		}
+		if custom.StatusCode != 0 {
+			resp.statusCode = custom.StatusCode
+		}
+		response = custom.Body
+	}
+
+	if response == nil {
+		if resp.statusCode == http.StatusOK {
+			resp.statusCode = http.StatusNoContent
+		}
+		return resp
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		sctx.Error("Failed to encode JSON response", err)
+		return &rawResponse{
+			statusCode: http.StatusInternalServerError,
+			headers:    map[string]string{"Content-Type": "text/plain; charset=utf-8"},
+			body:       []byte("Internal Server Error\n"),
+		}
+	}
+	resp.body = buf.Bytes()
+	return resp
+}
+
+// rawResponse — готовый к отправке ответ; в таком виде он сохраняется для Idempotency-Key.
+type rawResponse struct {
+	statusCode int
+	headers    map[string]string
+	body       []byte
+}
+
+func writeResponse(w http.ResponseWriter, resp *rawResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	for key, value := range resp.headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(resp.statusCode)
+	if len(resp.body) > 0 {
+		_, _ = w.Write(resp.body)
+	}
 }
 
 // parseJSONBody пытается декодировать тело запроса как JSON в map[string]interface{}.
@@ -72,11 +189,17 @@ func parseJSONBody(r *http.Request) (map[string]interface{}, error) {
 	return data, nil
 }
 
-// handleError отправляет JSON-ответ с сообщением об ошибке.
-func handleError(w http.ResponseWriter, err error, log *zap.Logger) {
+// errorResponse формирует JSON-ответ с сообщением об ошибке.
+// Код ответа определяется по виду ошибки (см. app_errors).
+func errorResponse(err error, log *zap.Logger) *rawResponse {
 	log.Error("Handler error", zap.Error(err))
-	w.Header().Set("Content-Type", "application/json")
-	statusCode := http.StatusInternalServerError
-	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	resp := &rawResponse{
+		statusCode: app_errors.StatusCode(err),
+		body:       append(body, '\n'),
+	}
+	if after, ok := app_errors.RetryAfter(err); ok {
+		resp.headers = map[string]string{"Retry-After": app_errors.RetryAfterSeconds(after)}
+	}
+	return resp
 }
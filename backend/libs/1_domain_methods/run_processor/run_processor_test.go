@@ -2,12 +2,14 @@ package run_processor
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"mdm/libs/4_common/app_errors"
 	"mdm/libs/4_common/smart_context"
 )
 
@@ -96,3 +98,72 @@ func TestJSONResponseMiddlewareError(t *testing.T) {
 		t.Errorf("Expected error message containing 'EOF', got %v", respData["error"])
 	}
 }
+
+// TestJSONResponseMiddlewareResponse проверяет заголовки и код из Response,
+// а также доступ к заголовкам запроса из обработчика.
+func TestJSONResponseMiddlewareResponse(t *testing.T) {
+	sctx := smart_context.NewSmartContext()
+	dummyHandler := func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		return &Response{
+			StatusCode: http.StatusCreated,
+			Headers:    map[string]string{"ETag": GetRequestHeader(sctx, "If-Match")},
+			Body:       data,
+		}, nil
+	}
+	handlerFunc := JSONResponseMiddleware(sctx, dummyHandler)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"test": "data"}`))
+	req.Header.Set("If-Match", `"3"`)
+	rr := httptest.NewRecorder()
+
+	handlerFunc(rr, req)
+	res := rr.Result()
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code 201, got %d", res.StatusCode)
+	}
+	if etag := res.Header.Get("ETag"); etag != `"3"` {
+		t.Errorf("Expected ETag header \"3\", got %q", etag)
+	}
+}
+
+// TestJSONResponseMiddlewareConflict проверяет выбор кода ответа по виду ошибки.
+func TestJSONResponseMiddlewareConflict(t *testing.T) {
+	sctx := smart_context.NewSmartContext()
+	dummyHandler := func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("%w: version is stale", app_errors.ErrConflict)
+	}
+	handlerFunc := JSONResponseMiddleware(sctx, dummyHandler)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	rr := httptest.NewRecorder()
+
+	handlerFunc(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected status code 409, got %d", rr.Code)
+	}
+}
+
+// TestJSONResponseMiddlewareQueryParams проверяет, что параметры query string
+// попадают в data, но не перекрывают поля тела.
+func TestJSONResponseMiddlewareQueryParams(t *testing.T) {
+	sctx := smart_context.NewSmartContext()
+	var got map[string]interface{}
+	dummyHandler := func(sctx smart_context.ISmartContext, data map[string]interface{}) (interface{}, error) {
+		got = data
+		return nil, nil
+	}
+	handlerFunc := JSONResponseMiddleware(sctx, dummyHandler)
+
+	req := httptest.NewRequest("POST", "/?search=office&limit=10", strings.NewReader(`{"search": "body"}`))
+	rr := httptest.NewRecorder()
+
+	handlerFunc(rr, req)
+	if got["search"] != "body" {
+		t.Errorf("Expected body value to win, got %v", got["search"])
+	}
+	if got["limit"] != "10" {
+		t.Errorf("Expected limit from query string, got %v", got["limit"])
+	}
+}
@@ -0,0 +1,32 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameAPIKey = "api_keys"
+
+// APIKey mapped from table <api_keys>
+type APIKey struct {
+	ID         string     `gorm:"column:id;primaryKey" json:"id"`
+	Name       string     `gorm:"column:name;not null" json:"name"`
+	Prefix     string     `gorm:"column:prefix;not null" json:"prefix"`
+	KeyHash    string     `gorm:"column:key_hash;not null" json:"-"`
+	Scope      string     `gorm:"column:scope;not null" json:"scope"`
+	CreatedBy  string     `gorm:"column:created_by" json:"created_by"`
+	ExpiresAt  *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedAt  time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	OwnerID    string     `gorm:"column:owner_id;not null" json:"owner_id"`
+}
+
+// TableName APIKey's table name
+func (*APIKey) TableName() string {
+	return TableNameAPIKey
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// InstalledApp — приложение в отчёте агента об установленных приложениях.
+// Файл не генерируется gorm/gen и не перезаписывается при регенерации.
+type InstalledApp struct {
+	// PackageName — имя пакета: org.mozilla.firefox, firefox-esr и т. п.
+	PackageName string `json:"package_name"`
+	Version     string `json:"version"`
+	// Installer — откуда установлено: dpkg, rpm, flatpak, snap, play_store...
+	Installer string `json:"installer,omitempty"`
+	// InstalledAt — время установки, если пакетный менеджер его сообщает.
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+}
+
+// AppInventoryReport — отчёт агента об установленных приложениях.
+// Полный отчёт (Full) заменяет список на сервере целиком. Остальные отчёты
+// содержат только изменения относительно версии инвентаря BaseVersion,
+// которую сервер вернул на прошлый отчёт; при расхождении версий сервер
+// отвечает 409 и агент присылает полный список.
+type AppInventoryReport struct {
+	Full        bool  `json:"full"`
+	BaseVersion int64 `json:"base_version"`
+	// Installed — установленные и обновлённые приложения (в полном отчёте — все).
+	Installed []InstalledApp `json:"installed,omitempty"`
+	// Removed — имена удалённых пакетов; в полном отчёте не используется.
+	Removed []string `json:"removed,omitempty"`
+}
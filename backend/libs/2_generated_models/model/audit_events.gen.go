@@ -0,0 +1,27 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameAuditEvent = "audit_events"
+
+// AuditEvent mapped from table <audit_events>
+type AuditEvent struct {
+	ID         string    `gorm:"column:id;primaryKey" json:"id"`
+	Actor      string    `gorm:"column:actor;not null" json:"actor"`
+	Method     string    `gorm:"column:method;not null" json:"method"`
+	Path       string    `gorm:"column:path;not null" json:"path"`
+	Status     int32     `gorm:"column:status;not null" json:"status"`
+	RemoteAddr string    `gorm:"column:remote_addr;not null" json:"remote_addr"`
+	CreatedAt  time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName AuditEvent's table name
+func (*AuditEvent) TableName() string {
+	return TableNameAuditEvent
+}
@@ -12,16 +12,35 @@ const TableNameDevice = "device"
 
 // Device mapped from table <device>
 type Device struct {
-	ID                string    `gorm:"column:id;primaryKey;default:gen_random_uuid()" json:"id"`
-	DeviceID          string    `gorm:"column:device_id;not null" json:"device_id"`
-	CameraEnabled     bool      `gorm:"column:camera_enabled;not null" json:"camera_enabled"`
-	MicrophoneEnabled bool      `gorm:"column:microphone_enabled;not null" json:"microphone_enabled"`
-	BluetoothEnabled  bool      `gorm:"column:bluetooth_enabled;not null" json:"bluetooth_enabled"`
-	OsVersion         string    `gorm:"column:os_version" json:"os_version"`
-	BatteryLevel      int32     `gorm:"column:battery_level" json:"battery_level"`
-	LastHeartbeat     time.Time `gorm:"column:last_heartbeat" json:"last_heartbeat"`
-	CreatedAt         time.Time `gorm:"column:created_at;default:now()" json:"created_at"`
-	UpdatedAt         time.Time `gorm:"column:updated_at" json:"updated_at"`
+	ID                    string     `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID              string     `gorm:"column:device_id;not null" json:"device_id"`
+	CameraEnabled         bool       `gorm:"column:camera_enabled;not null;default:false" json:"camera_enabled"`
+	MicrophoneEnabled     bool       `gorm:"column:microphone_enabled;not null;default:false" json:"microphone_enabled"`
+	BluetoothEnabled      bool       `gorm:"column:bluetooth_enabled;not null;default:false" json:"bluetooth_enabled"`
+	OsVersion             string     `gorm:"column:os_version" json:"os_version"`
+	BatteryLevel          int32      `gorm:"column:battery_level" json:"battery_level"`
+	LastHeartbeat         time.Time  `gorm:"column:last_heartbeat" json:"last_heartbeat"`
+	CreatedAt             time.Time  `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt             time.Time  `gorm:"column:updated_at" json:"updated_at"`
+	Version               int64      `gorm:"column:version;not null;default:1" json:"version"`
+	Status                string     `gorm:"column:status;not null;default:'active'" json:"status"`
+	TokenHash             string     `gorm:"column:token_hash" json:"-"`
+	EnrolledAt            time.Time  `gorm:"column:enrolled_at" json:"enrolled_at"`
+	PolicyVersion         int64      `gorm:"column:policy_version;not null" json:"policy_version"`
+	PolicyStatus          string     `gorm:"column:policy_status;not null;default:'pending'" json:"policy_status"`
+	PolicyError           string     `gorm:"column:policy_error;not null" json:"policy_error"`
+	PolicyReportedAt      *time.Time `gorm:"column:policy_reported_at" json:"policy_reported_at"`
+	Telemetry             Telemetry  `gorm:"column:telemetry;not null;default:'{}'" json:"telemetry"`
+	TelemetryAt           *time.Time `gorm:"column:telemetry_at" json:"telemetry_at"`
+	GroupID               string     `gorm:"column:group_id;not null" json:"group_id"`
+	RetiredAt             *time.Time `gorm:"column:retired_at" json:"retired_at"`
+	RetiredBy             string     `gorm:"column:retired_by;not null" json:"retired_by"`
+	DeletedAt             *time.Time `gorm:"column:deleted_at" json:"deleted_at"`
+	AppsVersion           int64      `gorm:"column:apps_version;not null" json:"apps_version"`
+	AppsReportedAt        *time.Time `gorm:"column:apps_reported_at" json:"apps_reported_at"`
+	EnrollmentTokenHash   string     `gorm:"column:enrollment_token_hash;not null" json:"-"`
+	EnrollmentExpiresAt   *time.Time `gorm:"column:enrollment_expires_at" json:"enrollment_expires_at"`
+	LegacyEnrollmentUntil *time.Time `gorm:"column:legacy_enrollment_until" json:"-"`
 }
 
 // TableName Device's table name
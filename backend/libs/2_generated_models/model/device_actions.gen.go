@@ -0,0 +1,32 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceAction = "device_actions"
+
+// DeviceAction mapped from table <device_actions>
+type DeviceAction struct {
+	ID                string     `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID          string     `gorm:"column:device_id;not null" json:"device_id"`
+	Action            string     `gorm:"column:action;not null" json:"action"`
+	Payload           string     `gorm:"column:payload;not null;default:'{}'" json:"payload"`
+	Status            string     `gorm:"column:status;not null" json:"status"`
+	RequestedBy       string     `gorm:"column:requested_by;not null" json:"requested_by"`
+	RequestedByUserID string     `gorm:"column:requested_by_user_id;not null" json:"requested_by_user_id"`
+	ApprovedBy        string     `gorm:"column:approved_by;not null" json:"approved_by"`
+	CommandID         string     `gorm:"column:command_id;not null" json:"command_id"`
+	CreatedAt         time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt         *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	DecidedAt         *time.Time `gorm:"column:decided_at" json:"decided_at"`
+}
+
+// TableName DeviceAction's table name
+func (*DeviceAction) TableName() string {
+	return TableNameDeviceAction
+}
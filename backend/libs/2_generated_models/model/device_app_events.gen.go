@@ -0,0 +1,28 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceAppEvent = "device_app_events"
+
+// DeviceAppEvent mapped from table <device_app_events>
+type DeviceAppEvent struct {
+	ID              string    `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID        string    `gorm:"column:device_id;not null" json:"device_id"`
+	PackageName     string    `gorm:"column:package_name;not null" json:"package_name"`
+	Event           string    `gorm:"column:event;not null" json:"event"`
+	Version         string    `gorm:"column:version;not null" json:"version"`
+	PreviousVersion string    `gorm:"column:previous_version;not null" json:"previous_version"`
+	Installer       string    `gorm:"column:installer;not null" json:"installer"`
+	CreatedAt       time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName DeviceAppEvent's table name
+func (*DeviceAppEvent) TableName() string {
+	return TableNameDeviceAppEvent
+}
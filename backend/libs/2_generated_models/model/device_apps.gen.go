@@ -0,0 +1,27 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceApp = "device_apps"
+
+// DeviceApp mapped from table <device_apps>
+type DeviceApp struct {
+	ID          string     `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID    string     `gorm:"column:device_id;not null" json:"device_id"`
+	PackageName string     `gorm:"column:package_name;not null" json:"package_name"`
+	Version     string     `gorm:"column:version;not null" json:"version"`
+	Installer   string     `gorm:"column:installer;not null" json:"installer"`
+	InstalledAt *time.Time `gorm:"column:installed_at" json:"installed_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName DeviceApp's table name
+func (*DeviceApp) TableName() string {
+	return TableNameDeviceApp
+}
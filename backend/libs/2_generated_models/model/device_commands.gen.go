@@ -0,0 +1,30 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceCommand = "device_commands"
+
+// DeviceCommand mapped from table <device_commands>
+type DeviceCommand struct {
+	ID          string     `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID    string     `gorm:"column:device_id;not null" json:"device_id"`
+	Type        string     `gorm:"column:type;not null" json:"type"`
+	Payload     string     `gorm:"column:payload;not null;default:'{}'" json:"payload"`
+	Status      string     `gorm:"column:status;not null;default:'pending'" json:"status"`
+	Result      string     `gorm:"column:result;not null" json:"result"`
+	CreatedBy   string     `gorm:"column:created_by;not null" json:"created_by"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	DeliveredAt *time.Time `gorm:"column:delivered_at" json:"delivered_at"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+}
+
+// TableName DeviceCommand's table name
+func (*DeviceCommand) TableName() string {
+	return TableNameDeviceCommand
+}
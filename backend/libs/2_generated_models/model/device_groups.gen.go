@@ -0,0 +1,25 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceGroup = "device_groups"
+
+// DeviceGroup mapped from table <device_groups>
+type DeviceGroup struct {
+	ID                       string    `gorm:"column:id;primaryKey" json:"id"`
+	Name                     string    `gorm:"column:name;not null" json:"name"`
+	HeartbeatIntervalSeconds int32     `gorm:"column:heartbeat_interval_seconds;not null" json:"heartbeat_interval_seconds"`
+	CreatedAt                time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt                time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName DeviceGroup's table name
+func (*DeviceGroup) TableName() string {
+	return TableNameDeviceGroup
+}
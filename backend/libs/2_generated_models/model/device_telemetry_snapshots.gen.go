@@ -0,0 +1,25 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameDeviceTelemetrySnapshot = "device_telemetry_snapshots"
+
+// DeviceTelemetrySnapshot mapped from table <device_telemetry_snapshots>
+type DeviceTelemetrySnapshot struct {
+	ID          string    `gorm:"column:id;primaryKey" json:"id"`
+	DeviceID    string    `gorm:"column:device_id;not null" json:"device_id"`
+	Telemetry   Telemetry `gorm:"column:telemetry;not null;default:'{}'" json:"telemetry"`
+	CollectedAt time.Time `gorm:"column:collected_at;not null" json:"collected_at"`
+	CreatedAt   time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName DeviceTelemetrySnapshot's table name
+func (*DeviceTelemetrySnapshot) TableName() string {
+	return TableNameDeviceTelemetrySnapshot
+}
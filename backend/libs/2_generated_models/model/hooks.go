@@ -0,0 +1,71 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Первичные ключи генерируются в Go, а не через gen_random_uuid(),
+// чтобы модели одинаково работали на Postgres и SQLite.
+// Файл не генерируется gorm/gen и не перезаписывается при регенерации.
+
+func newID(id *string) {
+	if *id == "" {
+		*id = uuid.NewString()
+	}
+}
+
+func (d *Device) BeforeCreate(tx *gorm.DB) error {
+	newID(&d.ID)
+	return nil
+}
+
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	newID(&u.ID)
+	return nil
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	newID(&k.ID)
+	return nil
+}
+
+func (c *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	newID(&c.ID)
+	return nil
+}
+
+func (c *DeviceCommand) BeforeCreate(tx *gorm.DB) error {
+	newID(&c.ID)
+	return nil
+}
+
+func (g *DeviceGroup) BeforeCreate(tx *gorm.DB) error {
+	newID(&g.ID)
+	return nil
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	newID(&e.ID)
+	return nil
+}
+
+func (a *DeviceAction) BeforeCreate(tx *gorm.DB) error {
+	newID(&a.ID)
+	return nil
+}
+
+func (a *DeviceApp) BeforeCreate(tx *gorm.DB) error {
+	newID(&a.ID)
+	return nil
+}
+
+func (e *DeviceAppEvent) BeforeCreate(tx *gorm.DB) error {
+	newID(&e.ID)
+	return nil
+}
+
+func (s *DeviceTelemetrySnapshot) BeforeCreate(tx *gorm.DB) error {
+	newID(&s.ID)
+	return nil
+}
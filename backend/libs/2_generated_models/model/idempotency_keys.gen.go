@@ -0,0 +1,29 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameIdempotencyKey = "idempotency_keys"
+
+// IdempotencyKey mapped from table <idempotency_keys>
+type IdempotencyKey struct {
+	Principal       string    `gorm:"column:principal;primaryKey" json:"principal"`
+	Key             string    `gorm:"column:key;primaryKey" json:"key"`
+	RequestHash     string    `gorm:"column:request_hash;not null" json:"request_hash"`
+	StatusCode      int32     `gorm:"column:status_code;not null" json:"status_code"`
+	ResponseHeaders string    `gorm:"column:response_headers" json:"response_headers"`
+	ResponseBody    string    `gorm:"column:response_body" json:"response_body"`
+	CreatedAt       time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	ExpiresAt       time.Time `gorm:"column:expires_at;not null" json:"expires_at"`
+	Reservation     string    `gorm:"column:reservation;not null" json:"reservation"`
+}
+
+// TableName IdempotencyKey's table name
+func (*IdempotencyKey) TableName() string {
+	return TableNameIdempotencyKey
+}
@@ -0,0 +1,25 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameRecoveryCode = "recovery_codes"
+
+// RecoveryCode mapped from table <recovery_codes>
+type RecoveryCode struct {
+	ID        string     `gorm:"column:id;primaryKey" json:"id"`
+	UserID    string     `gorm:"column:user_id;not null" json:"user_id"`
+	CodeHash  string     `gorm:"column:code_hash;not null" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	CreatedAt time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName RecoveryCode's table name
+func (*RecoveryCode) TableName() string {
+	return TableNameRecoveryCode
+}
@@ -0,0 +1,27 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+const TableNameSigningKey = "signing_keys"
+
+// SigningKey mapped from table <signing_keys>
+type SigningKey struct {
+	ID          string     `gorm:"column:id;primaryKey" json:"id"`
+	Algorithm   string     `gorm:"column:algorithm;not null" json:"algorithm"`
+	PrivateKey  string     `gorm:"column:private_key;not null" json:"-"`
+	PublicKey   string     `gorm:"column:public_key;not null" json:"public_key"`
+	ActivatesAt time.Time  `gorm:"column:activates_at;not null" json:"activates_at"`
+	ExpiresAt   *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// TableName SigningKey's table name
+func (*SigningKey) TableName() string {
+	return TableNameSigningKey
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Telemetry — показатели устройства, которые агент присылает с heartbeat.
+// Показатель, который агент не смог собрать, отсутствует (nil или пустая строка).
+// Хранится в колонке device.telemetry как JSON.
+// Файл не генерируется gorm/gen и не перезаписывается при регенерации.
+type Telemetry struct {
+	// BatteryLevel — заряд в процентах (0–100).
+	BatteryLevel *int32 `json:"battery_level,omitempty"`
+	// BatteryStatus — состояние из power_supply: Charging, Discharging, Full и т. п.
+	BatteryStatus string `json:"battery_status,omitempty"`
+	OSName        string `json:"os_name,omitempty"`
+	OSVersion     string `json:"os_version,omitempty"`
+	UptimeSeconds *int64 `json:"uptime_seconds,omitempty"`
+	// DiskTotalBytes и DiskFreeBytes — корневая файловая система.
+	DiskTotalBytes       *uint64 `json:"disk_total_bytes,omitempty"`
+	DiskFreeBytes        *uint64 `json:"disk_free_bytes,omitempty"`
+	MemoryTotalBytes     *uint64 `json:"memory_total_bytes,omitempty"`
+	MemoryAvailableBytes *uint64 `json:"memory_available_bytes,omitempty"`
+}
+
+// OSDisplayName — имя и версия ОС одной строкой, например "Ubuntu 22.04".
+func (t Telemetry) OSDisplayName() string {
+	switch {
+	case t.OSName == "":
+		return t.OSVersion
+	case t.OSVersion == "":
+		return t.OSName
+	default:
+		return t.OSName + " " + t.OSVersion
+	}
+}
+
+// Value сохраняет телеметрию как JSON.
+func (t Telemetry) Value() (driver.Value, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan читает телеметрию из JSON; NULL и пустая строка дают пустую телеметрию.
+func (t *Telemetry) Scan(value interface{}) error {
+	*t = Telemetry{}
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported telemetry value %T", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, t)
+}
@@ -12,12 +12,20 @@ const TableNameUser = "users"
 
 // User mapped from table <users>
 type User struct {
-	ID        string    `gorm:"column:id;primaryKey;default:gen_random_uuid()" json:"id"`
-	Username  string    `gorm:"column:username;not null" json:"username"`
-	Password  string    `gorm:"column:password;not null" json:"password"`
-	Role      string    `gorm:"column:role;not null" json:"role"`
-	CreatedAt time.Time `gorm:"column:created_at;not null;default:now()" json:"created_at"`
-	UpdatedAt time.Time `gorm:"column:updated_at;not null;default:now()" json:"updated_at"`
+	ID                  string    `gorm:"column:id;primaryKey" json:"id"`
+	Username            string    `gorm:"column:username;not null" json:"username"`
+	Password            string    `gorm:"column:password;not null" json:"-"`
+	Role                string    `gorm:"column:role;not null" json:"role"`
+	CreatedAt           time.Time `gorm:"column:created_at;not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt           time.Time `gorm:"column:updated_at;not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	FailedLoginAttempts int32     `gorm:"column:failed_login_attempts;not null" json:"failed_login_attempts"`
+	LockedUntil         time.Time `gorm:"column:locked_until" json:"locked_until"`
+	AuthProvider        string    `gorm:"column:auth_provider;not null;default:'local'" json:"auth_provider"`
+	ExternalSubject     string    `gorm:"column:external_subject;not null" json:"external_subject"`
+	Email               string    `gorm:"column:email;not null" json:"email"`
+	TotpSecret          string    `gorm:"column:totp_secret;not null" json:"-"`
+	TotpEnabled         bool      `gorm:"column:totp_enabled;not null;default:false" json:"totp_enabled"`
+	TotpLastStep        int64     `gorm:"column:totp_last_step;not null" json:"-"`
 }
 
 // TableName User's table name
@@ -6,6 +6,7 @@ package query
 
 import (
 	"context"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -13,10 +14,13 @@ import (
 
 	"gorm.io/gen"
 	"gorm.io/gen/field"
+	"gorm.io/gen/helper"
 
 	"gorm.io/plugin/dbresolver"
 
 	"mdm/libs/2_generated_models/model"
+
+	"time"
 )
 
 func newDevice(db *gorm.DB, opts ...gen.DOOption) device {
@@ -37,6 +41,25 @@ func newDevice(db *gorm.DB, opts ...gen.DOOption) device {
 	_device.LastHeartbeat = field.NewTime(tableName, "last_heartbeat")
 	_device.CreatedAt = field.NewTime(tableName, "created_at")
 	_device.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_device.Version = field.NewInt64(tableName, "version")
+	_device.Status = field.NewString(tableName, "status")
+	_device.TokenHash = field.NewString(tableName, "token_hash")
+	_device.EnrolledAt = field.NewTime(tableName, "enrolled_at")
+	_device.PolicyVersion = field.NewInt64(tableName, "policy_version")
+	_device.PolicyStatus = field.NewString(tableName, "policy_status")
+	_device.PolicyError = field.NewString(tableName, "policy_error")
+	_device.PolicyReportedAt = field.NewTime(tableName, "policy_reported_at")
+	_device.Telemetry = field.NewField(tableName, "telemetry")
+	_device.TelemetryAt = field.NewTime(tableName, "telemetry_at")
+	_device.GroupID = field.NewString(tableName, "group_id")
+	_device.RetiredAt = field.NewTime(tableName, "retired_at")
+	_device.RetiredBy = field.NewString(tableName, "retired_by")
+	_device.DeletedAt = field.NewTime(tableName, "deleted_at")
+	_device.AppsVersion = field.NewInt64(tableName, "apps_version")
+	_device.AppsReportedAt = field.NewTime(tableName, "apps_reported_at")
+	_device.EnrollmentTokenHash = field.NewString(tableName, "enrollment_token_hash")
+	_device.EnrollmentExpiresAt = field.NewTime(tableName, "enrollment_expires_at")
+	_device.LegacyEnrollmentUntil = field.NewTime(tableName, "legacy_enrollment_until")
 
 	_device.fillFieldMap()
 
@@ -46,17 +69,36 @@ func newDevice(db *gorm.DB, opts ...gen.DOOption) device {
 type device struct {
 	deviceDo
 
-	ALL               field.Asterisk
-	ID                field.String
-	DeviceID          field.String
-	CameraEnabled     field.Bool
-	MicrophoneEnabled field.Bool
-	BluetoothEnabled  field.Bool
-	OsVersion         field.String
-	BatteryLevel      field.Int32
-	LastHeartbeat     field.Time
-	CreatedAt         field.Time
-	UpdatedAt         field.Time
+	ALL                   field.Asterisk
+	ID                    field.String
+	DeviceID              field.String
+	CameraEnabled         field.Bool
+	MicrophoneEnabled     field.Bool
+	BluetoothEnabled      field.Bool
+	OsVersion             field.String
+	BatteryLevel          field.Int32
+	LastHeartbeat         field.Time
+	CreatedAt             field.Time
+	UpdatedAt             field.Time
+	Version               field.Int64
+	Status                field.String
+	TokenHash             field.String
+	EnrolledAt            field.Time
+	PolicyVersion         field.Int64
+	PolicyStatus          field.String
+	PolicyError           field.String
+	PolicyReportedAt      field.Time
+	Telemetry             field.Field
+	TelemetryAt           field.Time
+	GroupID               field.String
+	RetiredAt             field.Time
+	RetiredBy             field.String
+	DeletedAt             field.Time
+	AppsVersion           field.Int64
+	AppsReportedAt        field.Time
+	EnrollmentTokenHash   field.String
+	EnrollmentExpiresAt   field.Time
+	LegacyEnrollmentUntil field.Time
 
 	fieldMap map[string]field.Expr
 }
@@ -83,6 +125,25 @@ func (d *device) updateTableName(table string) *device {
 	d.LastHeartbeat = field.NewTime(table, "last_heartbeat")
 	d.CreatedAt = field.NewTime(table, "created_at")
 	d.UpdatedAt = field.NewTime(table, "updated_at")
+	d.Version = field.NewInt64(table, "version")
+	d.Status = field.NewString(table, "status")
+	d.TokenHash = field.NewString(table, "token_hash")
+	d.EnrolledAt = field.NewTime(table, "enrolled_at")
+	d.PolicyVersion = field.NewInt64(table, "policy_version")
+	d.PolicyStatus = field.NewString(table, "policy_status")
+	d.PolicyError = field.NewString(table, "policy_error")
+	d.PolicyReportedAt = field.NewTime(table, "policy_reported_at")
+	d.Telemetry = field.NewField(table, "telemetry")
+	d.TelemetryAt = field.NewTime(table, "telemetry_at")
+	d.GroupID = field.NewString(table, "group_id")
+	d.RetiredAt = field.NewTime(table, "retired_at")
+	d.RetiredBy = field.NewString(table, "retired_by")
+	d.DeletedAt = field.NewTime(table, "deleted_at")
+	d.AppsVersion = field.NewInt64(table, "apps_version")
+	d.AppsReportedAt = field.NewTime(table, "apps_reported_at")
+	d.EnrollmentTokenHash = field.NewString(table, "enrollment_token_hash")
+	d.EnrollmentExpiresAt = field.NewTime(table, "enrollment_expires_at")
+	d.LegacyEnrollmentUntil = field.NewTime(table, "legacy_enrollment_until")
 
 	d.fillFieldMap()
 
@@ -99,7 +160,7 @@ func (d *device) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (d *device) fillFieldMap() {
-	d.fieldMap = make(map[string]field.Expr, 10)
+	d.fieldMap = make(map[string]field.Expr, 29)
 	d.fieldMap["id"] = d.ID
 	d.fieldMap["device_id"] = d.DeviceID
 	d.fieldMap["camera_enabled"] = d.CameraEnabled
@@ -110,6 +171,25 @@ func (d *device) fillFieldMap() {
 	d.fieldMap["last_heartbeat"] = d.LastHeartbeat
 	d.fieldMap["created_at"] = d.CreatedAt
 	d.fieldMap["updated_at"] = d.UpdatedAt
+	d.fieldMap["version"] = d.Version
+	d.fieldMap["status"] = d.Status
+	d.fieldMap["token_hash"] = d.TokenHash
+	d.fieldMap["enrolled_at"] = d.EnrolledAt
+	d.fieldMap["policy_version"] = d.PolicyVersion
+	d.fieldMap["policy_status"] = d.PolicyStatus
+	d.fieldMap["policy_error"] = d.PolicyError
+	d.fieldMap["policy_reported_at"] = d.PolicyReportedAt
+	d.fieldMap["telemetry"] = d.Telemetry
+	d.fieldMap["telemetry_at"] = d.TelemetryAt
+	d.fieldMap["group_id"] = d.GroupID
+	d.fieldMap["retired_at"] = d.RetiredAt
+	d.fieldMap["retired_by"] = d.RetiredBy
+	d.fieldMap["deleted_at"] = d.DeletedAt
+	d.fieldMap["apps_version"] = d.AppsVersion
+	d.fieldMap["apps_reported_at"] = d.AppsReportedAt
+	d.fieldMap["enrollment_token_hash"] = d.EnrollmentTokenHash
+	d.fieldMap["enrollment_expires_at"] = d.EnrollmentExpiresAt
+	d.fieldMap["legacy_enrollment_until"] = d.LegacyEnrollmentUntil
 }
 
 func (d device) clone(db *gorm.DB) device {
@@ -183,6 +263,95 @@ type IDeviceDo interface {
 	Returning(value interface{}, columns ...string) IDeviceDo
 	UnderlyingDB() *gorm.DB
 	schema.Tabler
+
+	FilterDevices(archived bool, includeDeleted bool, search string, cameraEnabled *bool, microphoneEnabled *bool, bluetoothEnabled *bool, seenSince *time.Time, limit int, offset int) (result []*model.Device, err error)
+	FindStale(before time.Time) (result []*model.Device, err error)
+}
+
+// FilterDevices ищет устройства по подстроке device_id (без учёта регистра),
+// состоянию настроек и времени последнего heartbeat. nil-фильтры не применяются.
+// archived выбирает выведенные из эксплуатации устройства вместо действующих,
+// удалённые (deleted_at) показываются только с includeDeleted.
+// search экранируется символом «!»: обратную косую черту в строке шаблона gen не разбирает.
+//
+// SELECT * FROM @@table
+// {{where}}
+//
+//	{{if archived}} retired_at IS NOT NULL {{else}} retired_at IS NULL {{end}}
+//	{{if !includeDeleted}} AND deleted_at IS NULL {{end}}
+//	{{if search != ""}} AND LOWER(device_id) LIKE LOWER(@search) ESCAPE '!' {{end}}
+//	{{if cameraEnabled != nil}} AND camera_enabled = @cameraEnabled {{end}}
+//	{{if microphoneEnabled != nil}} AND microphone_enabled = @microphoneEnabled {{end}}
+//	{{if bluetoothEnabled != nil}} AND bluetooth_enabled = @bluetoothEnabled {{end}}
+//	{{if seenSince != nil}} AND last_heartbeat >= @seenSince {{end}}
+//
+// {{end}}
+// ORDER BY device_id
+// {{if limit > 0}} LIMIT @limit OFFSET @offset {{end}}
+func (d deviceDo) FilterDevices(archived bool, includeDeleted bool, search string, cameraEnabled *bool, microphoneEnabled *bool, bluetoothEnabled *bool, seenSince *time.Time, limit int, offset int) (result []*model.Device, err error) {
+	var params []interface{}
+
+	var generateSQL strings.Builder
+	generateSQL.WriteString("SELECT * FROM device ")
+	var whereSQL0 strings.Builder
+	if archived {
+		whereSQL0.WriteString("retired_at IS NOT NULL ")
+	} else {
+		whereSQL0.WriteString("retired_at IS NULL ")
+	}
+	if !includeDeleted {
+		whereSQL0.WriteString("AND deleted_at IS NULL ")
+	}
+	if search != "" {
+		params = append(params, search)
+		whereSQL0.WriteString("AND LOWER(device_id) LIKE LOWER(?) ESCAPE '!' ")
+	}
+	if cameraEnabled != nil {
+		params = append(params, cameraEnabled)
+		whereSQL0.WriteString("AND camera_enabled = ? ")
+	}
+	if microphoneEnabled != nil {
+		params = append(params, microphoneEnabled)
+		whereSQL0.WriteString("AND microphone_enabled = ? ")
+	}
+	if bluetoothEnabled != nil {
+		params = append(params, bluetoothEnabled)
+		whereSQL0.WriteString("AND bluetooth_enabled = ? ")
+	}
+	if seenSince != nil {
+		params = append(params, seenSince)
+		whereSQL0.WriteString("AND last_heartbeat >= ? ")
+	}
+	helper.JoinWhereBuilder(&generateSQL, whereSQL0)
+	generateSQL.WriteString("ORDER BY device_id ")
+	if limit > 0 {
+		params = append(params, limit)
+		params = append(params, offset)
+		generateSQL.WriteString("LIMIT ? OFFSET ? ")
+	}
+
+	var executeSQL *gorm.DB
+	executeSQL = d.UnderlyingDB().Raw(generateSQL.String(), params...).Find(&result) // ignore_security_alert
+	err = executeSQL.Error
+
+	return
+}
+
+// FindStale возвращает действующие устройства, не присылавшие heartbeat с момента before.
+//
+// SELECT * FROM @@table WHERE retired_at IS NULL AND (last_heartbeat IS NULL OR last_heartbeat < @before) ORDER BY last_heartbeat
+func (d deviceDo) FindStale(before time.Time) (result []*model.Device, err error) {
+	var params []interface{}
+
+	var generateSQL strings.Builder
+	params = append(params, before)
+	generateSQL.WriteString("SELECT * FROM device WHERE retired_at IS NULL AND (last_heartbeat IS NULL OR last_heartbeat < ?) ORDER BY last_heartbeat ")
+
+	var executeSQL *gorm.DB
+	executeSQL = d.UnderlyingDB().Raw(generateSQL.String(), params...).Find(&result) // ignore_security_alert
+	err = executeSQL.Error
+
+	return
 }
 
 func (d deviceDo) Debug() IDeviceDo {
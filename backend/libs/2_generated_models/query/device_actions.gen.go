@@ -0,0 +1,424 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newDeviceAction(db *gorm.DB, opts ...gen.DOOption) deviceAction {
+	_deviceAction := deviceAction{}
+
+	_deviceAction.deviceActionDo.UseDB(db, opts...)
+	_deviceAction.deviceActionDo.UseModel(&model.DeviceAction{})
+
+	tableName := _deviceAction.deviceActionDo.TableName()
+	_deviceAction.ALL = field.NewAsterisk(tableName)
+	_deviceAction.ID = field.NewString(tableName, "id")
+	_deviceAction.DeviceID = field.NewString(tableName, "device_id")
+	_deviceAction.Action = field.NewString(tableName, "action")
+	_deviceAction.Payload = field.NewString(tableName, "payload")
+	_deviceAction.Status = field.NewString(tableName, "status")
+	_deviceAction.RequestedBy = field.NewString(tableName, "requested_by")
+	_deviceAction.RequestedByUserID = field.NewString(tableName, "requested_by_user_id")
+	_deviceAction.ApprovedBy = field.NewString(tableName, "approved_by")
+	_deviceAction.CommandID = field.NewString(tableName, "command_id")
+	_deviceAction.CreatedAt = field.NewTime(tableName, "created_at")
+	_deviceAction.ExpiresAt = field.NewTime(tableName, "expires_at")
+	_deviceAction.DecidedAt = field.NewTime(tableName, "decided_at")
+
+	_deviceAction.fillFieldMap()
+
+	return _deviceAction
+}
+
+type deviceAction struct {
+	deviceActionDo
+
+	ALL               field.Asterisk
+	ID                field.String
+	DeviceID          field.String
+	Action            field.String
+	Payload           field.String
+	Status            field.String
+	RequestedBy       field.String
+	RequestedByUserID field.String
+	ApprovedBy        field.String
+	CommandID         field.String
+	CreatedAt         field.Time
+	ExpiresAt         field.Time
+	DecidedAt         field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (d deviceAction) Table(newTableName string) *deviceAction {
+	d.deviceActionDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d deviceAction) As(alias string) *deviceAction {
+	d.deviceActionDo.DO = *(d.deviceActionDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *deviceAction) updateTableName(table string) *deviceAction {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewString(table, "id")
+	d.DeviceID = field.NewString(table, "device_id")
+	d.Action = field.NewString(table, "action")
+	d.Payload = field.NewString(table, "payload")
+	d.Status = field.NewString(table, "status")
+	d.RequestedBy = field.NewString(table, "requested_by")
+	d.RequestedByUserID = field.NewString(table, "requested_by_user_id")
+	d.ApprovedBy = field.NewString(table, "approved_by")
+	d.CommandID = field.NewString(table, "command_id")
+	d.CreatedAt = field.NewTime(table, "created_at")
+	d.ExpiresAt = field.NewTime(table, "expires_at")
+	d.DecidedAt = field.NewTime(table, "decided_at")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *deviceAction) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *deviceAction) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 12)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["device_id"] = d.DeviceID
+	d.fieldMap["action"] = d.Action
+	d.fieldMap["payload"] = d.Payload
+	d.fieldMap["status"] = d.Status
+	d.fieldMap["requested_by"] = d.RequestedBy
+	d.fieldMap["requested_by_user_id"] = d.RequestedByUserID
+	d.fieldMap["approved_by"] = d.ApprovedBy
+	d.fieldMap["command_id"] = d.CommandID
+	d.fieldMap["created_at"] = d.CreatedAt
+	d.fieldMap["expires_at"] = d.ExpiresAt
+	d.fieldMap["decided_at"] = d.DecidedAt
+}
+
+func (d deviceAction) clone(db *gorm.DB) deviceAction {
+	d.deviceActionDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d deviceAction) replaceDB(db *gorm.DB) deviceAction {
+	d.deviceActionDo.ReplaceDB(db)
+	return d
+}
+
+type deviceActionDo struct{ gen.DO }
+
+type IDeviceActionDo interface {
+	gen.SubQuery
+	Debug() IDeviceActionDo
+	WithContext(ctx context.Context) IDeviceActionDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDeviceActionDo
+	WriteDB() IDeviceActionDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDeviceActionDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDeviceActionDo
+	Not(conds ...gen.Condition) IDeviceActionDo
+	Or(conds ...gen.Condition) IDeviceActionDo
+	Select(conds ...field.Expr) IDeviceActionDo
+	Where(conds ...gen.Condition) IDeviceActionDo
+	Order(conds ...field.Expr) IDeviceActionDo
+	Distinct(cols ...field.Expr) IDeviceActionDo
+	Omit(cols ...field.Expr) IDeviceActionDo
+	Join(table schema.Tabler, on ...field.Expr) IDeviceActionDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceActionDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDeviceActionDo
+	Group(cols ...field.Expr) IDeviceActionDo
+	Having(conds ...gen.Condition) IDeviceActionDo
+	Limit(limit int) IDeviceActionDo
+	Offset(offset int) IDeviceActionDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceActionDo
+	Unscoped() IDeviceActionDo
+	Create(values ...*model.DeviceAction) error
+	CreateInBatches(values []*model.DeviceAction, batchSize int) error
+	Save(values ...*model.DeviceAction) error
+	First() (*model.DeviceAction, error)
+	Take() (*model.DeviceAction, error)
+	Last() (*model.DeviceAction, error)
+	Find() ([]*model.DeviceAction, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceAction, err error)
+	FindInBatches(result *[]*model.DeviceAction, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DeviceAction) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDeviceActionDo
+	Assign(attrs ...field.AssignExpr) IDeviceActionDo
+	Joins(fields ...field.RelationField) IDeviceActionDo
+	Preload(fields ...field.RelationField) IDeviceActionDo
+	FirstOrInit() (*model.DeviceAction, error)
+	FirstOrCreate() (*model.DeviceAction, error)
+	FindByPage(offset int, limit int) (result []*model.DeviceAction, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDeviceActionDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d deviceActionDo) Debug() IDeviceActionDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d deviceActionDo) WithContext(ctx context.Context) IDeviceActionDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d deviceActionDo) ReadDB() IDeviceActionDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d deviceActionDo) WriteDB() IDeviceActionDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d deviceActionDo) Session(config *gorm.Session) IDeviceActionDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d deviceActionDo) Clauses(conds ...clause.Expression) IDeviceActionDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d deviceActionDo) Returning(value interface{}, columns ...string) IDeviceActionDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d deviceActionDo) Not(conds ...gen.Condition) IDeviceActionDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d deviceActionDo) Or(conds ...gen.Condition) IDeviceActionDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d deviceActionDo) Select(conds ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d deviceActionDo) Where(conds ...gen.Condition) IDeviceActionDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d deviceActionDo) Order(conds ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d deviceActionDo) Distinct(cols ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d deviceActionDo) Omit(cols ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d deviceActionDo) Join(table schema.Tabler, on ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d deviceActionDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d deviceActionDo) RightJoin(table schema.Tabler, on ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d deviceActionDo) Group(cols ...field.Expr) IDeviceActionDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d deviceActionDo) Having(conds ...gen.Condition) IDeviceActionDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d deviceActionDo) Limit(limit int) IDeviceActionDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d deviceActionDo) Offset(offset int) IDeviceActionDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d deviceActionDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceActionDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d deviceActionDo) Unscoped() IDeviceActionDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d deviceActionDo) Create(values ...*model.DeviceAction) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d deviceActionDo) CreateInBatches(values []*model.DeviceAction, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d deviceActionDo) Save(values ...*model.DeviceAction) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d deviceActionDo) First() (*model.DeviceAction, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAction), nil
+	}
+}
+
+func (d deviceActionDo) Take() (*model.DeviceAction, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAction), nil
+	}
+}
+
+func (d deviceActionDo) Last() (*model.DeviceAction, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAction), nil
+	}
+}
+
+func (d deviceActionDo) Find() ([]*model.DeviceAction, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DeviceAction), err
+}
+
+func (d deviceActionDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceAction, err error) {
+	buf := make([]*model.DeviceAction, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d deviceActionDo) FindInBatches(result *[]*model.DeviceAction, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d deviceActionDo) Attrs(attrs ...field.AssignExpr) IDeviceActionDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d deviceActionDo) Assign(attrs ...field.AssignExpr) IDeviceActionDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d deviceActionDo) Joins(fields ...field.RelationField) IDeviceActionDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d deviceActionDo) Preload(fields ...field.RelationField) IDeviceActionDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d deviceActionDo) FirstOrInit() (*model.DeviceAction, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAction), nil
+	}
+}
+
+func (d deviceActionDo) FirstOrCreate() (*model.DeviceAction, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAction), nil
+	}
+}
+
+func (d deviceActionDo) FindByPage(offset int, limit int) (result []*model.DeviceAction, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d deviceActionDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d deviceActionDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d deviceActionDo) Delete(models ...*model.DeviceAction) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *deviceActionDo) withDO(do gen.Dao) *deviceActionDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
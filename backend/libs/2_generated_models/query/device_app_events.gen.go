@@ -0,0 +1,408 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newDeviceAppEvent(db *gorm.DB, opts ...gen.DOOption) deviceAppEvent {
+	_deviceAppEvent := deviceAppEvent{}
+
+	_deviceAppEvent.deviceAppEventDo.UseDB(db, opts...)
+	_deviceAppEvent.deviceAppEventDo.UseModel(&model.DeviceAppEvent{})
+
+	tableName := _deviceAppEvent.deviceAppEventDo.TableName()
+	_deviceAppEvent.ALL = field.NewAsterisk(tableName)
+	_deviceAppEvent.ID = field.NewString(tableName, "id")
+	_deviceAppEvent.DeviceID = field.NewString(tableName, "device_id")
+	_deviceAppEvent.PackageName = field.NewString(tableName, "package_name")
+	_deviceAppEvent.Event = field.NewString(tableName, "event")
+	_deviceAppEvent.Version = field.NewString(tableName, "version")
+	_deviceAppEvent.PreviousVersion = field.NewString(tableName, "previous_version")
+	_deviceAppEvent.Installer = field.NewString(tableName, "installer")
+	_deviceAppEvent.CreatedAt = field.NewTime(tableName, "created_at")
+
+	_deviceAppEvent.fillFieldMap()
+
+	return _deviceAppEvent
+}
+
+type deviceAppEvent struct {
+	deviceAppEventDo
+
+	ALL             field.Asterisk
+	ID              field.String
+	DeviceID        field.String
+	PackageName     field.String
+	Event           field.String
+	Version         field.String
+	PreviousVersion field.String
+	Installer       field.String
+	CreatedAt       field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (d deviceAppEvent) Table(newTableName string) *deviceAppEvent {
+	d.deviceAppEventDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d deviceAppEvent) As(alias string) *deviceAppEvent {
+	d.deviceAppEventDo.DO = *(d.deviceAppEventDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *deviceAppEvent) updateTableName(table string) *deviceAppEvent {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewString(table, "id")
+	d.DeviceID = field.NewString(table, "device_id")
+	d.PackageName = field.NewString(table, "package_name")
+	d.Event = field.NewString(table, "event")
+	d.Version = field.NewString(table, "version")
+	d.PreviousVersion = field.NewString(table, "previous_version")
+	d.Installer = field.NewString(table, "installer")
+	d.CreatedAt = field.NewTime(table, "created_at")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *deviceAppEvent) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *deviceAppEvent) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 8)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["device_id"] = d.DeviceID
+	d.fieldMap["package_name"] = d.PackageName
+	d.fieldMap["event"] = d.Event
+	d.fieldMap["version"] = d.Version
+	d.fieldMap["previous_version"] = d.PreviousVersion
+	d.fieldMap["installer"] = d.Installer
+	d.fieldMap["created_at"] = d.CreatedAt
+}
+
+func (d deviceAppEvent) clone(db *gorm.DB) deviceAppEvent {
+	d.deviceAppEventDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d deviceAppEvent) replaceDB(db *gorm.DB) deviceAppEvent {
+	d.deviceAppEventDo.ReplaceDB(db)
+	return d
+}
+
+type deviceAppEventDo struct{ gen.DO }
+
+type IDeviceAppEventDo interface {
+	gen.SubQuery
+	Debug() IDeviceAppEventDo
+	WithContext(ctx context.Context) IDeviceAppEventDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDeviceAppEventDo
+	WriteDB() IDeviceAppEventDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDeviceAppEventDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDeviceAppEventDo
+	Not(conds ...gen.Condition) IDeviceAppEventDo
+	Or(conds ...gen.Condition) IDeviceAppEventDo
+	Select(conds ...field.Expr) IDeviceAppEventDo
+	Where(conds ...gen.Condition) IDeviceAppEventDo
+	Order(conds ...field.Expr) IDeviceAppEventDo
+	Distinct(cols ...field.Expr) IDeviceAppEventDo
+	Omit(cols ...field.Expr) IDeviceAppEventDo
+	Join(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo
+	Group(cols ...field.Expr) IDeviceAppEventDo
+	Having(conds ...gen.Condition) IDeviceAppEventDo
+	Limit(limit int) IDeviceAppEventDo
+	Offset(offset int) IDeviceAppEventDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceAppEventDo
+	Unscoped() IDeviceAppEventDo
+	Create(values ...*model.DeviceAppEvent) error
+	CreateInBatches(values []*model.DeviceAppEvent, batchSize int) error
+	Save(values ...*model.DeviceAppEvent) error
+	First() (*model.DeviceAppEvent, error)
+	Take() (*model.DeviceAppEvent, error)
+	Last() (*model.DeviceAppEvent, error)
+	Find() ([]*model.DeviceAppEvent, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceAppEvent, err error)
+	FindInBatches(result *[]*model.DeviceAppEvent, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DeviceAppEvent) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDeviceAppEventDo
+	Assign(attrs ...field.AssignExpr) IDeviceAppEventDo
+	Joins(fields ...field.RelationField) IDeviceAppEventDo
+	Preload(fields ...field.RelationField) IDeviceAppEventDo
+	FirstOrInit() (*model.DeviceAppEvent, error)
+	FirstOrCreate() (*model.DeviceAppEvent, error)
+	FindByPage(offset int, limit int) (result []*model.DeviceAppEvent, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDeviceAppEventDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d deviceAppEventDo) Debug() IDeviceAppEventDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d deviceAppEventDo) WithContext(ctx context.Context) IDeviceAppEventDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d deviceAppEventDo) ReadDB() IDeviceAppEventDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d deviceAppEventDo) WriteDB() IDeviceAppEventDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d deviceAppEventDo) Session(config *gorm.Session) IDeviceAppEventDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d deviceAppEventDo) Clauses(conds ...clause.Expression) IDeviceAppEventDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d deviceAppEventDo) Returning(value interface{}, columns ...string) IDeviceAppEventDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d deviceAppEventDo) Not(conds ...gen.Condition) IDeviceAppEventDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d deviceAppEventDo) Or(conds ...gen.Condition) IDeviceAppEventDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d deviceAppEventDo) Select(conds ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d deviceAppEventDo) Where(conds ...gen.Condition) IDeviceAppEventDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d deviceAppEventDo) Order(conds ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d deviceAppEventDo) Distinct(cols ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d deviceAppEventDo) Omit(cols ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d deviceAppEventDo) Join(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d deviceAppEventDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d deviceAppEventDo) RightJoin(table schema.Tabler, on ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d deviceAppEventDo) Group(cols ...field.Expr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d deviceAppEventDo) Having(conds ...gen.Condition) IDeviceAppEventDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d deviceAppEventDo) Limit(limit int) IDeviceAppEventDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d deviceAppEventDo) Offset(offset int) IDeviceAppEventDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d deviceAppEventDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceAppEventDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d deviceAppEventDo) Unscoped() IDeviceAppEventDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d deviceAppEventDo) Create(values ...*model.DeviceAppEvent) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d deviceAppEventDo) CreateInBatches(values []*model.DeviceAppEvent, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d deviceAppEventDo) Save(values ...*model.DeviceAppEvent) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d deviceAppEventDo) First() (*model.DeviceAppEvent, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAppEvent), nil
+	}
+}
+
+func (d deviceAppEventDo) Take() (*model.DeviceAppEvent, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAppEvent), nil
+	}
+}
+
+func (d deviceAppEventDo) Last() (*model.DeviceAppEvent, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAppEvent), nil
+	}
+}
+
+func (d deviceAppEventDo) Find() ([]*model.DeviceAppEvent, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DeviceAppEvent), err
+}
+
+func (d deviceAppEventDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceAppEvent, err error) {
+	buf := make([]*model.DeviceAppEvent, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d deviceAppEventDo) FindInBatches(result *[]*model.DeviceAppEvent, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d deviceAppEventDo) Attrs(attrs ...field.AssignExpr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d deviceAppEventDo) Assign(attrs ...field.AssignExpr) IDeviceAppEventDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d deviceAppEventDo) Joins(fields ...field.RelationField) IDeviceAppEventDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d deviceAppEventDo) Preload(fields ...field.RelationField) IDeviceAppEventDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d deviceAppEventDo) FirstOrInit() (*model.DeviceAppEvent, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAppEvent), nil
+	}
+}
+
+func (d deviceAppEventDo) FirstOrCreate() (*model.DeviceAppEvent, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceAppEvent), nil
+	}
+}
+
+func (d deviceAppEventDo) FindByPage(offset int, limit int) (result []*model.DeviceAppEvent, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d deviceAppEventDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d deviceAppEventDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d deviceAppEventDo) Delete(models ...*model.DeviceAppEvent) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *deviceAppEventDo) withDO(do gen.Dao) *deviceAppEventDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
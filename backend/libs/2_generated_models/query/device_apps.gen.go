@@ -0,0 +1,404 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newDeviceApp(db *gorm.DB, opts ...gen.DOOption) deviceApp {
+	_deviceApp := deviceApp{}
+
+	_deviceApp.deviceAppDo.UseDB(db, opts...)
+	_deviceApp.deviceAppDo.UseModel(&model.DeviceApp{})
+
+	tableName := _deviceApp.deviceAppDo.TableName()
+	_deviceApp.ALL = field.NewAsterisk(tableName)
+	_deviceApp.ID = field.NewString(tableName, "id")
+	_deviceApp.DeviceID = field.NewString(tableName, "device_id")
+	_deviceApp.PackageName = field.NewString(tableName, "package_name")
+	_deviceApp.Version = field.NewString(tableName, "version")
+	_deviceApp.Installer = field.NewString(tableName, "installer")
+	_deviceApp.InstalledAt = field.NewTime(tableName, "installed_at")
+	_deviceApp.UpdatedAt = field.NewTime(tableName, "updated_at")
+
+	_deviceApp.fillFieldMap()
+
+	return _deviceApp
+}
+
+type deviceApp struct {
+	deviceAppDo
+
+	ALL         field.Asterisk
+	ID          field.String
+	DeviceID    field.String
+	PackageName field.String
+	Version     field.String
+	Installer   field.String
+	InstalledAt field.Time
+	UpdatedAt   field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (d deviceApp) Table(newTableName string) *deviceApp {
+	d.deviceAppDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d deviceApp) As(alias string) *deviceApp {
+	d.deviceAppDo.DO = *(d.deviceAppDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *deviceApp) updateTableName(table string) *deviceApp {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewString(table, "id")
+	d.DeviceID = field.NewString(table, "device_id")
+	d.PackageName = field.NewString(table, "package_name")
+	d.Version = field.NewString(table, "version")
+	d.Installer = field.NewString(table, "installer")
+	d.InstalledAt = field.NewTime(table, "installed_at")
+	d.UpdatedAt = field.NewTime(table, "updated_at")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *deviceApp) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *deviceApp) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 7)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["device_id"] = d.DeviceID
+	d.fieldMap["package_name"] = d.PackageName
+	d.fieldMap["version"] = d.Version
+	d.fieldMap["installer"] = d.Installer
+	d.fieldMap["installed_at"] = d.InstalledAt
+	d.fieldMap["updated_at"] = d.UpdatedAt
+}
+
+func (d deviceApp) clone(db *gorm.DB) deviceApp {
+	d.deviceAppDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d deviceApp) replaceDB(db *gorm.DB) deviceApp {
+	d.deviceAppDo.ReplaceDB(db)
+	return d
+}
+
+type deviceAppDo struct{ gen.DO }
+
+type IDeviceAppDo interface {
+	gen.SubQuery
+	Debug() IDeviceAppDo
+	WithContext(ctx context.Context) IDeviceAppDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDeviceAppDo
+	WriteDB() IDeviceAppDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDeviceAppDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDeviceAppDo
+	Not(conds ...gen.Condition) IDeviceAppDo
+	Or(conds ...gen.Condition) IDeviceAppDo
+	Select(conds ...field.Expr) IDeviceAppDo
+	Where(conds ...gen.Condition) IDeviceAppDo
+	Order(conds ...field.Expr) IDeviceAppDo
+	Distinct(cols ...field.Expr) IDeviceAppDo
+	Omit(cols ...field.Expr) IDeviceAppDo
+	Join(table schema.Tabler, on ...field.Expr) IDeviceAppDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceAppDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDeviceAppDo
+	Group(cols ...field.Expr) IDeviceAppDo
+	Having(conds ...gen.Condition) IDeviceAppDo
+	Limit(limit int) IDeviceAppDo
+	Offset(offset int) IDeviceAppDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceAppDo
+	Unscoped() IDeviceAppDo
+	Create(values ...*model.DeviceApp) error
+	CreateInBatches(values []*model.DeviceApp, batchSize int) error
+	Save(values ...*model.DeviceApp) error
+	First() (*model.DeviceApp, error)
+	Take() (*model.DeviceApp, error)
+	Last() (*model.DeviceApp, error)
+	Find() ([]*model.DeviceApp, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceApp, err error)
+	FindInBatches(result *[]*model.DeviceApp, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DeviceApp) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDeviceAppDo
+	Assign(attrs ...field.AssignExpr) IDeviceAppDo
+	Joins(fields ...field.RelationField) IDeviceAppDo
+	Preload(fields ...field.RelationField) IDeviceAppDo
+	FirstOrInit() (*model.DeviceApp, error)
+	FirstOrCreate() (*model.DeviceApp, error)
+	FindByPage(offset int, limit int) (result []*model.DeviceApp, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDeviceAppDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d deviceAppDo) Debug() IDeviceAppDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d deviceAppDo) WithContext(ctx context.Context) IDeviceAppDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d deviceAppDo) ReadDB() IDeviceAppDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d deviceAppDo) WriteDB() IDeviceAppDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d deviceAppDo) Session(config *gorm.Session) IDeviceAppDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d deviceAppDo) Clauses(conds ...clause.Expression) IDeviceAppDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d deviceAppDo) Returning(value interface{}, columns ...string) IDeviceAppDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d deviceAppDo) Not(conds ...gen.Condition) IDeviceAppDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d deviceAppDo) Or(conds ...gen.Condition) IDeviceAppDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d deviceAppDo) Select(conds ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d deviceAppDo) Where(conds ...gen.Condition) IDeviceAppDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d deviceAppDo) Order(conds ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d deviceAppDo) Distinct(cols ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d deviceAppDo) Omit(cols ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d deviceAppDo) Join(table schema.Tabler, on ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d deviceAppDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d deviceAppDo) RightJoin(table schema.Tabler, on ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d deviceAppDo) Group(cols ...field.Expr) IDeviceAppDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d deviceAppDo) Having(conds ...gen.Condition) IDeviceAppDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d deviceAppDo) Limit(limit int) IDeviceAppDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d deviceAppDo) Offset(offset int) IDeviceAppDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d deviceAppDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceAppDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d deviceAppDo) Unscoped() IDeviceAppDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d deviceAppDo) Create(values ...*model.DeviceApp) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d deviceAppDo) CreateInBatches(values []*model.DeviceApp, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d deviceAppDo) Save(values ...*model.DeviceApp) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d deviceAppDo) First() (*model.DeviceApp, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceApp), nil
+	}
+}
+
+func (d deviceAppDo) Take() (*model.DeviceApp, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceApp), nil
+	}
+}
+
+func (d deviceAppDo) Last() (*model.DeviceApp, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceApp), nil
+	}
+}
+
+func (d deviceAppDo) Find() ([]*model.DeviceApp, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DeviceApp), err
+}
+
+func (d deviceAppDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceApp, err error) {
+	buf := make([]*model.DeviceApp, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d deviceAppDo) FindInBatches(result *[]*model.DeviceApp, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d deviceAppDo) Attrs(attrs ...field.AssignExpr) IDeviceAppDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d deviceAppDo) Assign(attrs ...field.AssignExpr) IDeviceAppDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d deviceAppDo) Joins(fields ...field.RelationField) IDeviceAppDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d deviceAppDo) Preload(fields ...field.RelationField) IDeviceAppDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d deviceAppDo) FirstOrInit() (*model.DeviceApp, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceApp), nil
+	}
+}
+
+func (d deviceAppDo) FirstOrCreate() (*model.DeviceApp, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceApp), nil
+	}
+}
+
+func (d deviceAppDo) FindByPage(offset int, limit int) (result []*model.DeviceApp, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d deviceAppDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d deviceAppDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d deviceAppDo) Delete(models ...*model.DeviceApp) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *deviceAppDo) withDO(do gen.Dao) *deviceAppDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
@@ -0,0 +1,416 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newDeviceCommand(db *gorm.DB, opts ...gen.DOOption) deviceCommand {
+	_deviceCommand := deviceCommand{}
+
+	_deviceCommand.deviceCommandDo.UseDB(db, opts...)
+	_deviceCommand.deviceCommandDo.UseModel(&model.DeviceCommand{})
+
+	tableName := _deviceCommand.deviceCommandDo.TableName()
+	_deviceCommand.ALL = field.NewAsterisk(tableName)
+	_deviceCommand.ID = field.NewString(tableName, "id")
+	_deviceCommand.DeviceID = field.NewString(tableName, "device_id")
+	_deviceCommand.Type = field.NewString(tableName, "type")
+	_deviceCommand.Payload = field.NewString(tableName, "payload")
+	_deviceCommand.Status = field.NewString(tableName, "status")
+	_deviceCommand.Result = field.NewString(tableName, "result")
+	_deviceCommand.CreatedBy = field.NewString(tableName, "created_by")
+	_deviceCommand.CreatedAt = field.NewTime(tableName, "created_at")
+	_deviceCommand.DeliveredAt = field.NewTime(tableName, "delivered_at")
+	_deviceCommand.CompletedAt = field.NewTime(tableName, "completed_at")
+
+	_deviceCommand.fillFieldMap()
+
+	return _deviceCommand
+}
+
+type deviceCommand struct {
+	deviceCommandDo
+
+	ALL         field.Asterisk
+	ID          field.String
+	DeviceID    field.String
+	Type        field.String
+	Payload     field.String
+	Status      field.String
+	Result      field.String
+	CreatedBy   field.String
+	CreatedAt   field.Time
+	DeliveredAt field.Time
+	CompletedAt field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (d deviceCommand) Table(newTableName string) *deviceCommand {
+	d.deviceCommandDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d deviceCommand) As(alias string) *deviceCommand {
+	d.deviceCommandDo.DO = *(d.deviceCommandDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *deviceCommand) updateTableName(table string) *deviceCommand {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewString(table, "id")
+	d.DeviceID = field.NewString(table, "device_id")
+	d.Type = field.NewString(table, "type")
+	d.Payload = field.NewString(table, "payload")
+	d.Status = field.NewString(table, "status")
+	d.Result = field.NewString(table, "result")
+	d.CreatedBy = field.NewString(table, "created_by")
+	d.CreatedAt = field.NewTime(table, "created_at")
+	d.DeliveredAt = field.NewTime(table, "delivered_at")
+	d.CompletedAt = field.NewTime(table, "completed_at")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *deviceCommand) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *deviceCommand) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 10)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["device_id"] = d.DeviceID
+	d.fieldMap["type"] = d.Type
+	d.fieldMap["payload"] = d.Payload
+	d.fieldMap["status"] = d.Status
+	d.fieldMap["result"] = d.Result
+	d.fieldMap["created_by"] = d.CreatedBy
+	d.fieldMap["created_at"] = d.CreatedAt
+	d.fieldMap["delivered_at"] = d.DeliveredAt
+	d.fieldMap["completed_at"] = d.CompletedAt
+}
+
+func (d deviceCommand) clone(db *gorm.DB) deviceCommand {
+	d.deviceCommandDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d deviceCommand) replaceDB(db *gorm.DB) deviceCommand {
+	d.deviceCommandDo.ReplaceDB(db)
+	return d
+}
+
+type deviceCommandDo struct{ gen.DO }
+
+type IDeviceCommandDo interface {
+	gen.SubQuery
+	Debug() IDeviceCommandDo
+	WithContext(ctx context.Context) IDeviceCommandDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDeviceCommandDo
+	WriteDB() IDeviceCommandDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDeviceCommandDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDeviceCommandDo
+	Not(conds ...gen.Condition) IDeviceCommandDo
+	Or(conds ...gen.Condition) IDeviceCommandDo
+	Select(conds ...field.Expr) IDeviceCommandDo
+	Where(conds ...gen.Condition) IDeviceCommandDo
+	Order(conds ...field.Expr) IDeviceCommandDo
+	Distinct(cols ...field.Expr) IDeviceCommandDo
+	Omit(cols ...field.Expr) IDeviceCommandDo
+	Join(table schema.Tabler, on ...field.Expr) IDeviceCommandDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceCommandDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDeviceCommandDo
+	Group(cols ...field.Expr) IDeviceCommandDo
+	Having(conds ...gen.Condition) IDeviceCommandDo
+	Limit(limit int) IDeviceCommandDo
+	Offset(offset int) IDeviceCommandDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceCommandDo
+	Unscoped() IDeviceCommandDo
+	Create(values ...*model.DeviceCommand) error
+	CreateInBatches(values []*model.DeviceCommand, batchSize int) error
+	Save(values ...*model.DeviceCommand) error
+	First() (*model.DeviceCommand, error)
+	Take() (*model.DeviceCommand, error)
+	Last() (*model.DeviceCommand, error)
+	Find() ([]*model.DeviceCommand, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceCommand, err error)
+	FindInBatches(result *[]*model.DeviceCommand, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DeviceCommand) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDeviceCommandDo
+	Assign(attrs ...field.AssignExpr) IDeviceCommandDo
+	Joins(fields ...field.RelationField) IDeviceCommandDo
+	Preload(fields ...field.RelationField) IDeviceCommandDo
+	FirstOrInit() (*model.DeviceCommand, error)
+	FirstOrCreate() (*model.DeviceCommand, error)
+	FindByPage(offset int, limit int) (result []*model.DeviceCommand, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDeviceCommandDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d deviceCommandDo) Debug() IDeviceCommandDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d deviceCommandDo) WithContext(ctx context.Context) IDeviceCommandDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d deviceCommandDo) ReadDB() IDeviceCommandDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d deviceCommandDo) WriteDB() IDeviceCommandDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d deviceCommandDo) Session(config *gorm.Session) IDeviceCommandDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d deviceCommandDo) Clauses(conds ...clause.Expression) IDeviceCommandDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d deviceCommandDo) Returning(value interface{}, columns ...string) IDeviceCommandDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d deviceCommandDo) Not(conds ...gen.Condition) IDeviceCommandDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d deviceCommandDo) Or(conds ...gen.Condition) IDeviceCommandDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d deviceCommandDo) Select(conds ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d deviceCommandDo) Where(conds ...gen.Condition) IDeviceCommandDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d deviceCommandDo) Order(conds ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d deviceCommandDo) Distinct(cols ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d deviceCommandDo) Omit(cols ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d deviceCommandDo) Join(table schema.Tabler, on ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d deviceCommandDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d deviceCommandDo) RightJoin(table schema.Tabler, on ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d deviceCommandDo) Group(cols ...field.Expr) IDeviceCommandDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d deviceCommandDo) Having(conds ...gen.Condition) IDeviceCommandDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d deviceCommandDo) Limit(limit int) IDeviceCommandDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d deviceCommandDo) Offset(offset int) IDeviceCommandDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d deviceCommandDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceCommandDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d deviceCommandDo) Unscoped() IDeviceCommandDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d deviceCommandDo) Create(values ...*model.DeviceCommand) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d deviceCommandDo) CreateInBatches(values []*model.DeviceCommand, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d deviceCommandDo) Save(values ...*model.DeviceCommand) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d deviceCommandDo) First() (*model.DeviceCommand, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceCommand), nil
+	}
+}
+
+func (d deviceCommandDo) Take() (*model.DeviceCommand, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceCommand), nil
+	}
+}
+
+func (d deviceCommandDo) Last() (*model.DeviceCommand, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceCommand), nil
+	}
+}
+
+func (d deviceCommandDo) Find() ([]*model.DeviceCommand, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DeviceCommand), err
+}
+
+func (d deviceCommandDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceCommand, err error) {
+	buf := make([]*model.DeviceCommand, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d deviceCommandDo) FindInBatches(result *[]*model.DeviceCommand, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d deviceCommandDo) Attrs(attrs ...field.AssignExpr) IDeviceCommandDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d deviceCommandDo) Assign(attrs ...field.AssignExpr) IDeviceCommandDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d deviceCommandDo) Joins(fields ...field.RelationField) IDeviceCommandDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d deviceCommandDo) Preload(fields ...field.RelationField) IDeviceCommandDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d deviceCommandDo) FirstOrInit() (*model.DeviceCommand, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceCommand), nil
+	}
+}
+
+func (d deviceCommandDo) FirstOrCreate() (*model.DeviceCommand, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceCommand), nil
+	}
+}
+
+func (d deviceCommandDo) FindByPage(offset int, limit int) (result []*model.DeviceCommand, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d deviceCommandDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d deviceCommandDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d deviceCommandDo) Delete(models ...*model.DeviceCommand) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *deviceCommandDo) withDO(do gen.Dao) *deviceCommandDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
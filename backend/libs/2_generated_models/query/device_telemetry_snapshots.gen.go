@@ -0,0 +1,396 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newDeviceTelemetrySnapshot(db *gorm.DB, opts ...gen.DOOption) deviceTelemetrySnapshot {
+	_deviceTelemetrySnapshot := deviceTelemetrySnapshot{}
+
+	_deviceTelemetrySnapshot.deviceTelemetrySnapshotDo.UseDB(db, opts...)
+	_deviceTelemetrySnapshot.deviceTelemetrySnapshotDo.UseModel(&model.DeviceTelemetrySnapshot{})
+
+	tableName := _deviceTelemetrySnapshot.deviceTelemetrySnapshotDo.TableName()
+	_deviceTelemetrySnapshot.ALL = field.NewAsterisk(tableName)
+	_deviceTelemetrySnapshot.ID = field.NewString(tableName, "id")
+	_deviceTelemetrySnapshot.DeviceID = field.NewString(tableName, "device_id")
+	_deviceTelemetrySnapshot.Telemetry = field.NewField(tableName, "telemetry")
+	_deviceTelemetrySnapshot.CollectedAt = field.NewTime(tableName, "collected_at")
+	_deviceTelemetrySnapshot.CreatedAt = field.NewTime(tableName, "created_at")
+
+	_deviceTelemetrySnapshot.fillFieldMap()
+
+	return _deviceTelemetrySnapshot
+}
+
+type deviceTelemetrySnapshot struct {
+	deviceTelemetrySnapshotDo
+
+	ALL         field.Asterisk
+	ID          field.String
+	DeviceID    field.String
+	Telemetry   field.Field
+	CollectedAt field.Time
+	CreatedAt   field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (d deviceTelemetrySnapshot) Table(newTableName string) *deviceTelemetrySnapshot {
+	d.deviceTelemetrySnapshotDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d deviceTelemetrySnapshot) As(alias string) *deviceTelemetrySnapshot {
+	d.deviceTelemetrySnapshotDo.DO = *(d.deviceTelemetrySnapshotDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *deviceTelemetrySnapshot) updateTableName(table string) *deviceTelemetrySnapshot {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewString(table, "id")
+	d.DeviceID = field.NewString(table, "device_id")
+	d.Telemetry = field.NewField(table, "telemetry")
+	d.CollectedAt = field.NewTime(table, "collected_at")
+	d.CreatedAt = field.NewTime(table, "created_at")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *deviceTelemetrySnapshot) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *deviceTelemetrySnapshot) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 5)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["device_id"] = d.DeviceID
+	d.fieldMap["telemetry"] = d.Telemetry
+	d.fieldMap["collected_at"] = d.CollectedAt
+	d.fieldMap["created_at"] = d.CreatedAt
+}
+
+func (d deviceTelemetrySnapshot) clone(db *gorm.DB) deviceTelemetrySnapshot {
+	d.deviceTelemetrySnapshotDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d deviceTelemetrySnapshot) replaceDB(db *gorm.DB) deviceTelemetrySnapshot {
+	d.deviceTelemetrySnapshotDo.ReplaceDB(db)
+	return d
+}
+
+type deviceTelemetrySnapshotDo struct{ gen.DO }
+
+type IDeviceTelemetrySnapshotDo interface {
+	gen.SubQuery
+	Debug() IDeviceTelemetrySnapshotDo
+	WithContext(ctx context.Context) IDeviceTelemetrySnapshotDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDeviceTelemetrySnapshotDo
+	WriteDB() IDeviceTelemetrySnapshotDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDeviceTelemetrySnapshotDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDeviceTelemetrySnapshotDo
+	Not(conds ...gen.Condition) IDeviceTelemetrySnapshotDo
+	Or(conds ...gen.Condition) IDeviceTelemetrySnapshotDo
+	Select(conds ...field.Expr) IDeviceTelemetrySnapshotDo
+	Where(conds ...gen.Condition) IDeviceTelemetrySnapshotDo
+	Order(conds ...field.Expr) IDeviceTelemetrySnapshotDo
+	Distinct(cols ...field.Expr) IDeviceTelemetrySnapshotDo
+	Omit(cols ...field.Expr) IDeviceTelemetrySnapshotDo
+	Join(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo
+	Group(cols ...field.Expr) IDeviceTelemetrySnapshotDo
+	Having(conds ...gen.Condition) IDeviceTelemetrySnapshotDo
+	Limit(limit int) IDeviceTelemetrySnapshotDo
+	Offset(offset int) IDeviceTelemetrySnapshotDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceTelemetrySnapshotDo
+	Unscoped() IDeviceTelemetrySnapshotDo
+	Create(values ...*model.DeviceTelemetrySnapshot) error
+	CreateInBatches(values []*model.DeviceTelemetrySnapshot, batchSize int) error
+	Save(values ...*model.DeviceTelemetrySnapshot) error
+	First() (*model.DeviceTelemetrySnapshot, error)
+	Take() (*model.DeviceTelemetrySnapshot, error)
+	Last() (*model.DeviceTelemetrySnapshot, error)
+	Find() ([]*model.DeviceTelemetrySnapshot, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceTelemetrySnapshot, err error)
+	FindInBatches(result *[]*model.DeviceTelemetrySnapshot, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DeviceTelemetrySnapshot) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDeviceTelemetrySnapshotDo
+	Assign(attrs ...field.AssignExpr) IDeviceTelemetrySnapshotDo
+	Joins(fields ...field.RelationField) IDeviceTelemetrySnapshotDo
+	Preload(fields ...field.RelationField) IDeviceTelemetrySnapshotDo
+	FirstOrInit() (*model.DeviceTelemetrySnapshot, error)
+	FirstOrCreate() (*model.DeviceTelemetrySnapshot, error)
+	FindByPage(offset int, limit int) (result []*model.DeviceTelemetrySnapshot, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDeviceTelemetrySnapshotDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d deviceTelemetrySnapshotDo) Debug() IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d deviceTelemetrySnapshotDo) WithContext(ctx context.Context) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d deviceTelemetrySnapshotDo) ReadDB() IDeviceTelemetrySnapshotDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d deviceTelemetrySnapshotDo) WriteDB() IDeviceTelemetrySnapshotDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d deviceTelemetrySnapshotDo) Session(config *gorm.Session) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d deviceTelemetrySnapshotDo) Clauses(conds ...clause.Expression) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Returning(value interface{}, columns ...string) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d deviceTelemetrySnapshotDo) Not(conds ...gen.Condition) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Or(conds ...gen.Condition) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Select(conds ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Where(conds ...gen.Condition) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Order(conds ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Distinct(cols ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d deviceTelemetrySnapshotDo) Omit(cols ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d deviceTelemetrySnapshotDo) Join(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d deviceTelemetrySnapshotDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d deviceTelemetrySnapshotDo) RightJoin(table schema.Tabler, on ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d deviceTelemetrySnapshotDo) Group(cols ...field.Expr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d deviceTelemetrySnapshotDo) Having(conds ...gen.Condition) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d deviceTelemetrySnapshotDo) Limit(limit int) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d deviceTelemetrySnapshotDo) Offset(offset int) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d deviceTelemetrySnapshotDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d deviceTelemetrySnapshotDo) Unscoped() IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d deviceTelemetrySnapshotDo) Create(values ...*model.DeviceTelemetrySnapshot) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d deviceTelemetrySnapshotDo) CreateInBatches(values []*model.DeviceTelemetrySnapshot, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d deviceTelemetrySnapshotDo) Save(values ...*model.DeviceTelemetrySnapshot) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d deviceTelemetrySnapshotDo) First() (*model.DeviceTelemetrySnapshot, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceTelemetrySnapshot), nil
+	}
+}
+
+func (d deviceTelemetrySnapshotDo) Take() (*model.DeviceTelemetrySnapshot, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceTelemetrySnapshot), nil
+	}
+}
+
+func (d deviceTelemetrySnapshotDo) Last() (*model.DeviceTelemetrySnapshot, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceTelemetrySnapshot), nil
+	}
+}
+
+func (d deviceTelemetrySnapshotDo) Find() ([]*model.DeviceTelemetrySnapshot, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DeviceTelemetrySnapshot), err
+}
+
+func (d deviceTelemetrySnapshotDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DeviceTelemetrySnapshot, err error) {
+	buf := make([]*model.DeviceTelemetrySnapshot, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d deviceTelemetrySnapshotDo) FindInBatches(result *[]*model.DeviceTelemetrySnapshot, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d deviceTelemetrySnapshotDo) Attrs(attrs ...field.AssignExpr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d deviceTelemetrySnapshotDo) Assign(attrs ...field.AssignExpr) IDeviceTelemetrySnapshotDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d deviceTelemetrySnapshotDo) Joins(fields ...field.RelationField) IDeviceTelemetrySnapshotDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d deviceTelemetrySnapshotDo) Preload(fields ...field.RelationField) IDeviceTelemetrySnapshotDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d deviceTelemetrySnapshotDo) FirstOrInit() (*model.DeviceTelemetrySnapshot, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceTelemetrySnapshot), nil
+	}
+}
+
+func (d deviceTelemetrySnapshotDo) FirstOrCreate() (*model.DeviceTelemetrySnapshot, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DeviceTelemetrySnapshot), nil
+	}
+}
+
+func (d deviceTelemetrySnapshotDo) FindByPage(offset int, limit int) (result []*model.DeviceTelemetrySnapshot, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d deviceTelemetrySnapshotDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d deviceTelemetrySnapshotDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d deviceTelemetrySnapshotDo) Delete(models ...*model.DeviceTelemetrySnapshot) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *deviceTelemetrySnapshotDo) withDO(do gen.Dao) *deviceTelemetrySnapshotDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
@@ -16,39 +16,94 @@ import (
 )
 
 var (
-	Q      = new(Query)
-	Device *device
-	User   *user
+	Q                       = new(Query)
+	APIKey                  *aPIKey
+	AuditEvent              *auditEvent
+	Device                  *device
+	DeviceAction            *deviceAction
+	DeviceApp               *deviceApp
+	DeviceAppEvent          *deviceAppEvent
+	DeviceCommand           *deviceCommand
+	DeviceGroup             *deviceGroup
+	DeviceTelemetrySnapshot *deviceTelemetrySnapshot
+	IdempotencyKey          *idempotencyKey
+	RecoveryCode            *recoveryCode
+	SigningKey              *signingKey
+	User                    *user
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
+	APIKey = &Q.APIKey
+	AuditEvent = &Q.AuditEvent
 	Device = &Q.Device
+	DeviceAction = &Q.DeviceAction
+	DeviceApp = &Q.DeviceApp
+	DeviceAppEvent = &Q.DeviceAppEvent
+	DeviceCommand = &Q.DeviceCommand
+	DeviceGroup = &Q.DeviceGroup
+	DeviceTelemetrySnapshot = &Q.DeviceTelemetrySnapshot
+	IdempotencyKey = &Q.IdempotencyKey
+	RecoveryCode = &Q.RecoveryCode
+	SigningKey = &Q.SigningKey
 	User = &Q.User
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:     db,
-		Device: newDevice(db, opts...),
-		User:   newUser(db, opts...),
+		db:                      db,
+		APIKey:                  newAPIKey(db, opts...),
+		AuditEvent:              newAuditEvent(db, opts...),
+		Device:                  newDevice(db, opts...),
+		DeviceAction:            newDeviceAction(db, opts...),
+		DeviceApp:               newDeviceApp(db, opts...),
+		DeviceAppEvent:          newDeviceAppEvent(db, opts...),
+		DeviceCommand:           newDeviceCommand(db, opts...),
+		DeviceGroup:             newDeviceGroup(db, opts...),
+		DeviceTelemetrySnapshot: newDeviceTelemetrySnapshot(db, opts...),
+		IdempotencyKey:          newIdempotencyKey(db, opts...),
+		RecoveryCode:            newRecoveryCode(db, opts...),
+		SigningKey:              newSigningKey(db, opts...),
+		User:                    newUser(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Device device
-	User   user
+	APIKey                  aPIKey
+	AuditEvent              auditEvent
+	Device                  device
+	DeviceAction            deviceAction
+	DeviceApp               deviceApp
+	DeviceAppEvent          deviceAppEvent
+	DeviceCommand           deviceCommand
+	DeviceGroup             deviceGroup
+	DeviceTelemetrySnapshot deviceTelemetrySnapshot
+	IdempotencyKey          idempotencyKey
+	RecoveryCode            recoveryCode
+	SigningKey              signingKey
+	User                    user
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:     db,
-		Device: q.Device.clone(db),
-		User:   q.User.clone(db),
+		db:                      db,
+		APIKey:                  q.APIKey.clone(db),
+		AuditEvent:              q.AuditEvent.clone(db),
+		Device:                  q.Device.clone(db),
+		DeviceAction:            q.DeviceAction.clone(db),
+		DeviceApp:               q.DeviceApp.clone(db),
+		DeviceAppEvent:          q.DeviceAppEvent.clone(db),
+		DeviceCommand:           q.DeviceCommand.clone(db),
+		DeviceGroup:             q.DeviceGroup.clone(db),
+		DeviceTelemetrySnapshot: q.DeviceTelemetrySnapshot.clone(db),
+		IdempotencyKey:          q.IdempotencyKey.clone(db),
+		RecoveryCode:            q.RecoveryCode.clone(db),
+		SigningKey:              q.SigningKey.clone(db),
+		User:                    q.User.clone(db),
 	}
 }
 
@@ -62,21 +117,54 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:     db,
-		Device: q.Device.replaceDB(db),
-		User:   q.User.replaceDB(db),
+		db:                      db,
+		APIKey:                  q.APIKey.replaceDB(db),
+		AuditEvent:              q.AuditEvent.replaceDB(db),
+		Device:                  q.Device.replaceDB(db),
+		DeviceAction:            q.DeviceAction.replaceDB(db),
+		DeviceApp:               q.DeviceApp.replaceDB(db),
+		DeviceAppEvent:          q.DeviceAppEvent.replaceDB(db),
+		DeviceCommand:           q.DeviceCommand.replaceDB(db),
+		DeviceGroup:             q.DeviceGroup.replaceDB(db),
+		DeviceTelemetrySnapshot: q.DeviceTelemetrySnapshot.replaceDB(db),
+		IdempotencyKey:          q.IdempotencyKey.replaceDB(db),
+		RecoveryCode:            q.RecoveryCode.replaceDB(db),
+		SigningKey:              q.SigningKey.replaceDB(db),
+		User:                    q.User.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Device IDeviceDo
-	User   IUserDo
+	APIKey                  IAPIKeyDo
+	AuditEvent              IAuditEventDo
+	Device                  IDeviceDo
+	DeviceAction            IDeviceActionDo
+	DeviceApp               IDeviceAppDo
+	DeviceAppEvent          IDeviceAppEventDo
+	DeviceCommand           IDeviceCommandDo
+	DeviceGroup             IDeviceGroupDo
+	DeviceTelemetrySnapshot IDeviceTelemetrySnapshotDo
+	IdempotencyKey          IIdempotencyKeyDo
+	RecoveryCode            IRecoveryCodeDo
+	SigningKey              ISigningKeyDo
+	User                    IUserDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Device: q.Device.WithContext(ctx),
-		User:   q.User.WithContext(ctx),
+		APIKey:                  q.APIKey.WithContext(ctx),
+		AuditEvent:              q.AuditEvent.WithContext(ctx),
+		Device:                  q.Device.WithContext(ctx),
+		DeviceAction:            q.DeviceAction.WithContext(ctx),
+		DeviceApp:               q.DeviceApp.WithContext(ctx),
+		DeviceAppEvent:          q.DeviceAppEvent.WithContext(ctx),
+		DeviceCommand:           q.DeviceCommand.WithContext(ctx),
+		DeviceGroup:             q.DeviceGroup.WithContext(ctx),
+		DeviceTelemetrySnapshot: q.DeviceTelemetrySnapshot.WithContext(ctx),
+		IdempotencyKey:          q.IdempotencyKey.WithContext(ctx),
+		RecoveryCode:            q.RecoveryCode.WithContext(ctx),
+		SigningKey:              q.SigningKey.WithContext(ctx),
+		User:                    q.User.WithContext(ctx),
 	}
 }
 
@@ -0,0 +1,404 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"mdm/libs/2_generated_models/model"
+)
+
+func newSigningKey(db *gorm.DB, opts ...gen.DOOption) signingKey {
+	_signingKey := signingKey{}
+
+	_signingKey.signingKeyDo.UseDB(db, opts...)
+	_signingKey.signingKeyDo.UseModel(&model.SigningKey{})
+
+	tableName := _signingKey.signingKeyDo.TableName()
+	_signingKey.ALL = field.NewAsterisk(tableName)
+	_signingKey.ID = field.NewString(tableName, "id")
+	_signingKey.Algorithm = field.NewString(tableName, "algorithm")
+	_signingKey.PrivateKey = field.NewString(tableName, "private_key")
+	_signingKey.PublicKey = field.NewString(tableName, "public_key")
+	_signingKey.ActivatesAt = field.NewTime(tableName, "activates_at")
+	_signingKey.ExpiresAt = field.NewTime(tableName, "expires_at")
+	_signingKey.CreatedAt = field.NewTime(tableName, "created_at")
+
+	_signingKey.fillFieldMap()
+
+	return _signingKey
+}
+
+type signingKey struct {
+	signingKeyDo
+
+	ALL         field.Asterisk
+	ID          field.String
+	Algorithm   field.String
+	PrivateKey  field.String
+	PublicKey   field.String
+	ActivatesAt field.Time
+	ExpiresAt   field.Time
+	CreatedAt   field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (s signingKey) Table(newTableName string) *signingKey {
+	s.signingKeyDo.UseTable(newTableName)
+	return s.updateTableName(newTableName)
+}
+
+func (s signingKey) As(alias string) *signingKey {
+	s.signingKeyDo.DO = *(s.signingKeyDo.As(alias).(*gen.DO))
+	return s.updateTableName(alias)
+}
+
+func (s *signingKey) updateTableName(table string) *signingKey {
+	s.ALL = field.NewAsterisk(table)
+	s.ID = field.NewString(table, "id")
+	s.Algorithm = field.NewString(table, "algorithm")
+	s.PrivateKey = field.NewString(table, "private_key")
+	s.PublicKey = field.NewString(table, "public_key")
+	s.ActivatesAt = field.NewTime(table, "activates_at")
+	s.ExpiresAt = field.NewTime(table, "expires_at")
+	s.CreatedAt = field.NewTime(table, "created_at")
+
+	s.fillFieldMap()
+
+	return s
+}
+
+func (s *signingKey) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := s.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (s *signingKey) fillFieldMap() {
+	s.fieldMap = make(map[string]field.Expr, 7)
+	s.fieldMap["id"] = s.ID
+	s.fieldMap["algorithm"] = s.Algorithm
+	s.fieldMap["private_key"] = s.PrivateKey
+	s.fieldMap["public_key"] = s.PublicKey
+	s.fieldMap["activates_at"] = s.ActivatesAt
+	s.fieldMap["expires_at"] = s.ExpiresAt
+	s.fieldMap["created_at"] = s.CreatedAt
+}
+
+func (s signingKey) clone(db *gorm.DB) signingKey {
+	s.signingKeyDo.ReplaceConnPool(db.Statement.ConnPool)
+	return s
+}
+
+func (s signingKey) replaceDB(db *gorm.DB) signingKey {
+	s.signingKeyDo.ReplaceDB(db)
+	return s
+}
+
+type signingKeyDo struct{ gen.DO }
+
+type ISigningKeyDo interface {
+	gen.SubQuery
+	Debug() ISigningKeyDo
+	WithContext(ctx context.Context) ISigningKeyDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() ISigningKeyDo
+	WriteDB() ISigningKeyDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) ISigningKeyDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) ISigningKeyDo
+	Not(conds ...gen.Condition) ISigningKeyDo
+	Or(conds ...gen.Condition) ISigningKeyDo
+	Select(conds ...field.Expr) ISigningKeyDo
+	Where(conds ...gen.Condition) ISigningKeyDo
+	Order(conds ...field.Expr) ISigningKeyDo
+	Distinct(cols ...field.Expr) ISigningKeyDo
+	Omit(cols ...field.Expr) ISigningKeyDo
+	Join(table schema.Tabler, on ...field.Expr) ISigningKeyDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) ISigningKeyDo
+	RightJoin(table schema.Tabler, on ...field.Expr) ISigningKeyDo
+	Group(cols ...field.Expr) ISigningKeyDo
+	Having(conds ...gen.Condition) ISigningKeyDo
+	Limit(limit int) ISigningKeyDo
+	Offset(offset int) ISigningKeyDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) ISigningKeyDo
+	Unscoped() ISigningKeyDo
+	Create(values ...*model.SigningKey) error
+	CreateInBatches(values []*model.SigningKey, batchSize int) error
+	Save(values ...*model.SigningKey) error
+	First() (*model.SigningKey, error)
+	Take() (*model.SigningKey, error)
+	Last() (*model.SigningKey, error)
+	Find() ([]*model.SigningKey, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SigningKey, err error)
+	FindInBatches(result *[]*model.SigningKey, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.SigningKey) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) ISigningKeyDo
+	Assign(attrs ...field.AssignExpr) ISigningKeyDo
+	Joins(fields ...field.RelationField) ISigningKeyDo
+	Preload(fields ...field.RelationField) ISigningKeyDo
+	FirstOrInit() (*model.SigningKey, error)
+	FirstOrCreate() (*model.SigningKey, error)
+	FindByPage(offset int, limit int) (result []*model.SigningKey, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) ISigningKeyDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (s signingKeyDo) Debug() ISigningKeyDo {
+	return s.withDO(s.DO.Debug())
+}
+
+func (s signingKeyDo) WithContext(ctx context.Context) ISigningKeyDo {
+	return s.withDO(s.DO.WithContext(ctx))
+}
+
+func (s signingKeyDo) ReadDB() ISigningKeyDo {
+	return s.Clauses(dbresolver.Read)
+}
+
+func (s signingKeyDo) WriteDB() ISigningKeyDo {
+	return s.Clauses(dbresolver.Write)
+}
+
+func (s signingKeyDo) Session(config *gorm.Session) ISigningKeyDo {
+	return s.withDO(s.DO.Session(config))
+}
+
+func (s signingKeyDo) Clauses(conds ...clause.Expression) ISigningKeyDo {
+	return s.withDO(s.DO.Clauses(conds...))
+}
+
+func (s signingKeyDo) Returning(value interface{}, columns ...string) ISigningKeyDo {
+	return s.withDO(s.DO.Returning(value, columns...))
+}
+
+func (s signingKeyDo) Not(conds ...gen.Condition) ISigningKeyDo {
+	return s.withDO(s.DO.Not(conds...))
+}
+
+func (s signingKeyDo) Or(conds ...gen.Condition) ISigningKeyDo {
+	return s.withDO(s.DO.Or(conds...))
+}
+
+func (s signingKeyDo) Select(conds ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Select(conds...))
+}
+
+func (s signingKeyDo) Where(conds ...gen.Condition) ISigningKeyDo {
+	return s.withDO(s.DO.Where(conds...))
+}
+
+func (s signingKeyDo) Order(conds ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Order(conds...))
+}
+
+func (s signingKeyDo) Distinct(cols ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Distinct(cols...))
+}
+
+func (s signingKeyDo) Omit(cols ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Omit(cols...))
+}
+
+func (s signingKeyDo) Join(table schema.Tabler, on ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Join(table, on...))
+}
+
+func (s signingKeyDo) LeftJoin(table schema.Tabler, on ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.LeftJoin(table, on...))
+}
+
+func (s signingKeyDo) RightJoin(table schema.Tabler, on ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.RightJoin(table, on...))
+}
+
+func (s signingKeyDo) Group(cols ...field.Expr) ISigningKeyDo {
+	return s.withDO(s.DO.Group(cols...))
+}
+
+func (s signingKeyDo) Having(conds ...gen.Condition) ISigningKeyDo {
+	return s.withDO(s.DO.Having(conds...))
+}
+
+func (s signingKeyDo) Limit(limit int) ISigningKeyDo {
+	return s.withDO(s.DO.Limit(limit))
+}
+
+func (s signingKeyDo) Offset(offset int) ISigningKeyDo {
+	return s.withDO(s.DO.Offset(offset))
+}
+
+func (s signingKeyDo) Scopes(funcs ...func(gen.Dao) gen.Dao) ISigningKeyDo {
+	return s.withDO(s.DO.Scopes(funcs...))
+}
+
+func (s signingKeyDo) Unscoped() ISigningKeyDo {
+	return s.withDO(s.DO.Unscoped())
+}
+
+func (s signingKeyDo) Create(values ...*model.SigningKey) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Create(values)
+}
+
+func (s signingKeyDo) CreateInBatches(values []*model.SigningKey, batchSize int) error {
+	return s.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (s signingKeyDo) Save(values ...*model.SigningKey) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Save(values)
+}
+
+func (s signingKeyDo) First() (*model.SigningKey, error) {
+	if result, err := s.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SigningKey), nil
+	}
+}
+
+func (s signingKeyDo) Take() (*model.SigningKey, error) {
+	if result, err := s.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SigningKey), nil
+	}
+}
+
+func (s signingKeyDo) Last() (*model.SigningKey, error) {
+	if result, err := s.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SigningKey), nil
+	}
+}
+
+func (s signingKeyDo) Find() ([]*model.SigningKey, error) {
+	result, err := s.DO.Find()
+	return result.([]*model.SigningKey), err
+}
+
+func (s signingKeyDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SigningKey, err error) {
+	buf := make([]*model.SigningKey, 0, batchSize)
+	err = s.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (s signingKeyDo) FindInBatches(result *[]*model.SigningKey, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return s.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (s signingKeyDo) Attrs(attrs ...field.AssignExpr) ISigningKeyDo {
+	return s.withDO(s.DO.Attrs(attrs...))
+}
+
+func (s signingKeyDo) Assign(attrs ...field.AssignExpr) ISigningKeyDo {
+	return s.withDO(s.DO.Assign(attrs...))
+}
+
+func (s signingKeyDo) Joins(fields ...field.RelationField) ISigningKeyDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Joins(_f))
+	}
+	return &s
+}
+
+func (s signingKeyDo) Preload(fields ...field.RelationField) ISigningKeyDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Preload(_f))
+	}
+	return &s
+}
+
+func (s signingKeyDo) FirstOrInit() (*model.SigningKey, error) {
+	if result, err := s.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SigningKey), nil
+	}
+}
+
+func (s signingKeyDo) FirstOrCreate() (*model.SigningKey, error) {
+	if result, err := s.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SigningKey), nil
+	}
+}
+
+func (s signingKeyDo) FindByPage(offset int, limit int) (result []*model.SigningKey, count int64, err error) {
+	result, err = s.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = s.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (s signingKeyDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = s.Count()
+	if err != nil {
+		return
+	}
+
+	err = s.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (s signingKeyDo) Scan(result interface{}) (err error) {
+	return s.DO.Scan(result)
+}
+
+func (s signingKeyDo) Delete(models ...*model.SigningKey) (result gen.ResultInfo, err error) {
+	return s.DO.Delete(models)
+}
+
+func (s *signingKeyDo) withDO(do gen.Dao) *signingKeyDo {
+	s.DO = *do.(*gen.DO)
+	return s
+}
@@ -6,6 +6,7 @@ package query
 
 import (
 	"context"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -33,6 +34,14 @@ func newUser(db *gorm.DB, opts ...gen.DOOption) user {
 	_user.Role = field.NewString(tableName, "role")
 	_user.CreatedAt = field.NewTime(tableName, "created_at")
 	_user.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_user.FailedLoginAttempts = field.NewInt32(tableName, "failed_login_attempts")
+	_user.LockedUntil = field.NewTime(tableName, "locked_until")
+	_user.AuthProvider = field.NewString(tableName, "auth_provider")
+	_user.ExternalSubject = field.NewString(tableName, "external_subject")
+	_user.Email = field.NewString(tableName, "email")
+	_user.TotpSecret = field.NewString(tableName, "totp_secret")
+	_user.TotpEnabled = field.NewBool(tableName, "totp_enabled")
+	_user.TotpLastStep = field.NewInt64(tableName, "totp_last_step")
 
 	_user.fillFieldMap()
 
@@ -42,13 +51,21 @@ func newUser(db *gorm.DB, opts ...gen.DOOption) user {
 type user struct {
 	userDo
 
-	ALL       field.Asterisk
-	ID        field.String
-	Username  field.String
-	Password  field.String
-	Role      field.String
-	CreatedAt field.Time
-	UpdatedAt field.Time
+	ALL                 field.Asterisk
+	ID                  field.String
+	Username            field.String
+	Password            field.String
+	Role                field.String
+	CreatedAt           field.Time
+	UpdatedAt           field.Time
+	FailedLoginAttempts field.Int32
+	LockedUntil         field.Time
+	AuthProvider        field.String
+	ExternalSubject     field.String
+	Email               field.String
+	TotpSecret          field.String
+	TotpEnabled         field.Bool
+	TotpLastStep        field.Int64
 
 	fieldMap map[string]field.Expr
 }
@@ -71,6 +88,14 @@ func (u *user) updateTableName(table string) *user {
 	u.Role = field.NewString(table, "role")
 	u.CreatedAt = field.NewTime(table, "created_at")
 	u.UpdatedAt = field.NewTime(table, "updated_at")
+	u.FailedLoginAttempts = field.NewInt32(table, "failed_login_attempts")
+	u.LockedUntil = field.NewTime(table, "locked_until")
+	u.AuthProvider = field.NewString(table, "auth_provider")
+	u.ExternalSubject = field.NewString(table, "external_subject")
+	u.Email = field.NewString(table, "email")
+	u.TotpSecret = field.NewString(table, "totp_secret")
+	u.TotpEnabled = field.NewBool(table, "totp_enabled")
+	u.TotpLastStep = field.NewInt64(table, "totp_last_step")
 
 	u.fillFieldMap()
 
@@ -87,13 +112,21 @@ func (u *user) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (u *user) fillFieldMap() {
-	u.fieldMap = make(map[string]field.Expr, 6)
+	u.fieldMap = make(map[string]field.Expr, 14)
 	u.fieldMap["id"] = u.ID
 	u.fieldMap["username"] = u.Username
 	u.fieldMap["password"] = u.Password
 	u.fieldMap["role"] = u.Role
 	u.fieldMap["created_at"] = u.CreatedAt
 	u.fieldMap["updated_at"] = u.UpdatedAt
+	u.fieldMap["failed_login_attempts"] = u.FailedLoginAttempts
+	u.fieldMap["locked_until"] = u.LockedUntil
+	u.fieldMap["auth_provider"] = u.AuthProvider
+	u.fieldMap["external_subject"] = u.ExternalSubject
+	u.fieldMap["email"] = u.Email
+	u.fieldMap["totp_secret"] = u.TotpSecret
+	u.fieldMap["totp_enabled"] = u.TotpEnabled
+	u.fieldMap["totp_last_step"] = u.TotpLastStep
 }
 
 func (u user) clone(db *gorm.DB) user {
@@ -167,6 +200,27 @@ type IUserDo interface {
 	Returning(value interface{}, columns ...string) IUserDo
 	UnderlyingDB() *gorm.DB
 	schema.Tabler
+
+	FilterWithNameAndRole(name string, role string) (result []model.User, err error)
+}
+
+// SELECT * FROM @@table WHERE username = @name{{if role != ""}} AND role = @role{{end}}
+func (u userDo) FilterWithNameAndRole(name string, role string) (result []model.User, err error) {
+	var params []interface{}
+
+	var generateSQL strings.Builder
+	params = append(params, name)
+	generateSQL.WriteString("SELECT * FROM users WHERE username = ? ")
+	if role != "" {
+		params = append(params, role)
+		generateSQL.WriteString("AND role = ? ")
+	}
+
+	var executeSQL *gorm.DB
+	executeSQL = u.UnderlyingDB().Raw(generateSQL.String(), params...).Find(&result) // ignore_security_alert
+	err = executeSQL.Error
+
+	return
 }
 
 func (u userDo) Debug() IUserDo {
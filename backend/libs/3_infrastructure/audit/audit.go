@@ -0,0 +1,69 @@
+// Package audit записывает в журнал изменения, сделанные через административный API.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+
+	chi_middleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// Recorder сохраняет событие журнала (реализация — repositories.AuditRepository).
+type Recorder interface {
+	Record(sctx smart_context.ISmartContext, event *model.AuditEvent) error
+}
+
+// Middleware записывает каждый изменяющий запрос (не GET, HEAD и OPTIONS): субъект
+// из контекста (ставится auth.Middleware раньше), метод, путь, код ответа и адрес
+// клиента. Тело запроса не сохраняется. Ошибка записи только логируется: ответ уже отдан.
+func Middleware(sctx smart_context.ISmartContext, recorder Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := chi_middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			event := &model.AuditEvent{
+				Actor:      Actor(auth.PrincipalFromContext(r.Context())),
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				Status:     int32(status),
+				RemoteAddr: clientIP(r),
+				CreatedAt:  time.Now(),
+			}
+			// Клиент мог уже отключиться, а запись всё равно нужна.
+			if err := recorder.Record(sctx.WithContext(context.WithoutCancel(r.Context())), event); err != nil {
+				sctx.Errorf("Failed to record audit event %s %s: %v", event.Method, event.Path, err)
+			}
+		})
+	}
+}
+
+// Actor — субъект в журнале: "user:<имя>", "api_key:<название>" или "anonymous".
+func Actor(principal *auth.Principal) string {
+	if principal == nil {
+		return "anonymous"
+	}
+	return principal.Kind + ":" + principal.Name
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mdm/libs/2_generated_models/model"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/smart_context"
+)
+
+type recorderFunc func(event *model.AuditEvent) error
+
+func (f recorderFunc) Record(sctx smart_context.ISmartContext, event *model.AuditEvent) error {
+	return f(event)
+}
+
+func TestMiddleware(t *testing.T) {
+	var events []*model.AuditEvent
+	recorder := recorderFunc(func(event *model.AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	handler := Middleware(smart_context.NewSmartContext(), recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+
+	principal := &auth.Principal{Kind: auth.PrincipalUser, Name: "bob"}
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/devices", nil),
+		httptest.NewRequest(http.MethodPost, "/devices/dev-1/camera", nil),
+		httptest.NewRequest(http.MethodDelete, "/missing", nil),
+	} {
+		req.RemoteAddr = "10.0.0.1:5555"
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(auth.WithPrincipal(req.Context(), principal)))
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected only changing requests to be recorded, got %d", len(events))
+	}
+	if e := events[0]; e.Actor != "user:bob" || e.Method != http.MethodPost || e.Path != "/devices/dev-1/camera" || e.Status != http.StatusOK || e.RemoteAddr != "10.0.0.1" {
+		t.Errorf("Unexpected event: %+v", e)
+	}
+	if events[1].Status != http.StatusNotFound {
+		t.Errorf("Expected the response status to be recorded, got %d", events[1].Status)
+	}
+	if Actor(nil) != "anonymous" {
+		t.Errorf("Expected anonymous actor without a principal")
+	}
+}
@@ -0,0 +1,128 @@
+package background
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+)
+
+// WorkerFunc — тело фонового воркера. Воркер должен завершаться,
+// когда отменяется sctx.GetContext().
+type WorkerFunc func(sctx smart_context.ISmartContext) error
+
+// Manager запускает фоновые воркеры и останавливает их при завершении сервера.
+type Manager struct {
+	sctx    smart_context.ISmartContext
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewManager создаёт менеджер воркеров. Воркеры получают копию sctx
+// с контекстом, который отменяется в Stop.
+func NewManager(sctx smart_context.ISmartContext) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		sctx:    sctx,
+		ctx:     ctx,
+		cancel:  cancel,
+		running: make(map[string]bool),
+	}
+}
+
+// Go запускает воркер в отдельной горутине.
+func (m *Manager) Go(name string, fn WorkerFunc) {
+	m.mu.Lock()
+	m.running[name] = true
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() {
+			m.mu.Lock()
+			m.running[name] = false
+			m.mu.Unlock()
+		}()
+
+		sctx := m.sctx.LogField("worker", name).WithContext(m.ctx)
+		sctx.Infof("background worker %s started", name)
+		if err := fn(sctx); err != nil && !errors.Is(err, context.Canceled) {
+			sctx.Errorf("background worker %s failed: %v", name, err)
+			return
+		}
+		sctx.Infof("background worker %s stopped", name)
+	}()
+}
+
+// Every запускает воркер, который вызывает fn с заданным интервалом.
+// Ошибка одного запуска логируется и не останавливает воркер.
+func (m *Manager) Every(name string, interval time.Duration, fn WorkerFunc) {
+	m.Go(name, func(sctx smart_context.ISmartContext) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sctx.GetContext().Done():
+				return nil
+			case <-ticker.C:
+				if err := fn(sctx); err != nil {
+					sctx.Errorf("background worker %s iteration failed: %v", name, err)
+				}
+			}
+		}
+	})
+}
+
+// Running возвращает состояние воркеров: имя -> запущен ли он сейчас.
+func (m *Manager) Running() map[string]bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result := make(map[string]bool, len(m.running))
+	for name, running := range m.running {
+		result[name] = running
+	}
+	return result
+}
+
+// Check возвращает ошибку, если какой-либо из запущенных воркеров завершился.
+// Используется в проверке готовности.
+func (m *Manager) Check(ctx context.Context) error {
+	var stopped []string
+	for name, running := range m.Running() {
+		if !running {
+			stopped = append(stopped, name)
+		}
+	}
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return fmt.Errorf("background workers are not running: %s", strings.Join(stopped, ", "))
+	}
+	return nil
+}
+
+// Stop отменяет контекст воркеров и ждёт их завершения, но не дольше ctx.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,44 +1,224 @@
 package db_manager
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"mdm/libs/3_infrastructure/migrator"
+	"mdm/libs/4_common/config"
 	"mdm/libs/4_common/smart_context"
-	"os"
+	"mdm/migration"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 )
 
 type DbManager struct {
 	db *gorm.DB
 }
 
-func NewDbManager(sctx smart_context.ISmartContext) (*DbManager, error) {
-	databaseUrl, ok := os.LookupEnv("DATABASE_URL")
-	if !ok {
+// Границы паузы между попытками подключения при старте.
+const (
+	connectRetryMinDelay = 500 * time.Millisecond
+	connectRetryMaxDelay = 10 * time.Second
+)
+
+// NewDbManager подключается к БД. Пока БД недоступна (например, контейнер
+// Postgres ещё стартует), попытки повторяются с растущей паузой в течение
+// cfg.ConnectTimeout.
+func NewDbManager(sctx smart_context.ISmartContext, cfg config.DatabaseConfig) (*DbManager, error) {
+	databaseUrl := cfg.URL
+	if databaseUrl == "" {
 		return nil, errors.New("DATABASE_URL is not set")
 	}
-	sctx.Debugf("DATABASE_URL: %s", databaseUrl)
 
 	if sctx == nil {
 		sctx = smart_context.NewSmartContext()
 	}
 
+	ctx, cancel := context.WithTimeout(sctx.GetContext(), cfg.ConnectTimeout)
+	defer cancel()
+
+	delay := connectRetryMinDelay
+	for attempt := 1; ; attempt++ {
+		db, err := open(sctx, cfg)
+		if err == nil {
+			sctx.Infof("Connected to database (attempt %d, %d replica(s))", attempt, len(cfg.ReplicaURLs))
+			return &DbManager{db: db}, nil
+		}
+
+		sctx.Warnf("Database is not available (attempt %d): %v; retrying in %s", attempt, err, delay)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("database is not available after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, connectRetryMaxDelay)
+	}
+}
+
+// openDialector выбирает драйвер по схеме строки подключения:
+// sqlite://path, sqlite:path и file:path — SQLite, всё остальное — Postgres.
+func openDialector(databaseUrl string) gorm.Dialector {
+	var path string
+	switch {
+	case strings.HasPrefix(databaseUrl, "sqlite://"):
+		path = strings.TrimPrefix(databaseUrl, "sqlite://")
+	case strings.HasPrefix(databaseUrl, "sqlite:"):
+		path = strings.TrimPrefix(databaseUrl, "sqlite:")
+	case strings.HasPrefix(databaseUrl, "file:"):
+		path = databaseUrl
+	default:
+		return postgres.Open(databaseUrl)
+	}
+
+	if path == ":memory:" {
+		// Общий кеш, чтобы все соединения пула видели одну и ту же БД.
+		path = "file::memory:?cache=shared"
+	}
+	// Внешние ключи, ожидание блокировки вместо мгновенной ошибки и BEGIN IMMEDIATE,
+	// чтобы параллельные транзакции не упирались в "database is locked" при записи.
+	pragmas := "_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"
+	if !strings.Contains(path, "memory") {
+		pragmas += "&_journal_mode=WAL"
+	}
+	if strings.Contains(path, "?") {
+		return sqlite.Open(path + "&" + pragmas)
+	}
+	return sqlite.Open(path + "?" + pragmas)
+}
+
+// ReplicaResolver — имя, под которым в dbresolver подключены реплики. Запросы
+// по умолчанию идут в основную БД, на реплики — только через ReadReplica.
+const ReplicaResolver = "read_replicas"
+
+// ReadReplica направляет SELECT вне транзакции на реплику (без реплик — в основную БД).
+// Только для чтений, которым не страшно отставание реплики: списки и статус устройств.
+// Проверки токенов, ключей, блокировок входа и идемпотентности читают основную БД.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(ReplicaResolver), dbresolver.Read)
+}
+
+// open открывает пул соединений, настраивает его и подключает реплики для чтения.
+func open(sctx smart_context.ISmartContext, cfg config.DatabaseConfig) (*gorm.DB, error) {
 	db, err := gorm.Open(
-		postgres.Open(databaseUrl),
-		&gorm.Config{},
+		openDialector(cfg.URL),
+		&gorm.Config{Logger: newSctxLogger(sctx, cfg.SlowQueryThreshold)},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	result := &DbManager{
-		db: db,
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
 	}
+	configurePool(sqlDB, cfg)
 
-	return result, nil
+	if len(cfg.ReplicaURLs) > 0 {
+		replicas := make([]gorm.Dialector, 0, len(cfg.ReplicaURLs))
+		for _, replicaURL := range cfg.ReplicaURLs {
+			replicas = append(replicas, openDialector(replicaURL))
+		}
+		// Реплики подключены под именем, а не глобально: без ReadReplica запрос
+		// идёт в основную БД, внутри sctx.RunInTx — всегда в основную.
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: replicas,
+			Policy:   dbresolver.RandomPolicy{},
+		}, ReplicaResolver).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetMaxIdleConns(cfg.MaxIdleConns).
+			SetConnMaxLifetime(cfg.ConnMaxLifetime).
+			SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		if err := db.Use(resolver); err != nil {
+			_ = sqlDB.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func configurePool(sqlDB *sql.DB, cfg config.DatabaseConfig) {
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// Dialect возвращает имя диалекта БД ("postgres" или "sqlite").
+func (dbmanager *DbManager) Dialect() string {
+	return dbmanager.db.Dialector.Name()
+}
+
+// Migrate применяет неприменённые миграции из migration.FS.
+func (dbmanager *DbManager) Migrate(sctx smart_context.ISmartContext) error {
+	migrations, err := migrator.Load(migration.FS, dbmanager.Dialect())
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(sctx, dbmanager.db, migrations)
+	if err != nil {
+		return err
+	}
+	sctx.Infof("Database migrations: %d applied, %d total", applied, len(migrations))
+	return nil
+}
+
+// CheckMigrations возвращает ошибку, если есть неприменённые миграции.
+func (dbmanager *DbManager) CheckMigrations(ctx context.Context) error {
+	migrations, err := migrator.Load(migration.FS, dbmanager.Dialect())
+	if err != nil {
+		return err
+	}
+	return migrator.Check(ctx, dbmanager.db, migrations)
 }
 
 func (dbmanager *DbManager) GetGORM() *gorm.DB {
 	return dbmanager.db.Session(&gorm.Session{NewDB: true})
 }
+
+// Close закрывает пул соединений с БД.
+func (dbmanager *DbManager) Close() error {
+	sqlDB, err := dbmanager.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping проверяет, что БД доступна.
+func (dbmanager *DbManager) Ping(ctx context.Context) error {
+	sqlDB, err := dbmanager.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckSchema проверяет, что для переданных моделей в БД есть таблицы и все колонки,
+// то есть миграции, под которые сгенерированы модели, применены.
+func (dbmanager *DbManager) CheckSchema(ctx context.Context, models ...interface{}) error {
+	db := dbmanager.db.WithContext(ctx)
+	migrator := db.Migrator()
+	for _, m := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(table) {
+			return fmt.Errorf("table %s does not exist", table)
+		}
+		for _, column := range stmt.Schema.DBNames {
+			if !migrator.HasColumn(m, column) {
+				return fmt.Errorf("column %s.%s does not exist", table, column)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package db_manager
+
+import (
+	"path/filepath"
+	"testing"
+
+	"mdm/libs/4_common/config"
+	"mdm/libs/4_common/smart_context"
+
+	"gorm.io/gorm"
+)
+
+// TestReadReplicaOptIn проверяет, что без ReadReplica чтение идёт в основную БД,
+// а с ReadReplica — на реплику, кроме чтений внутри транзакции.
+func TestReadReplicaOptIn(t *testing.T) {
+	dir := t.TempDir()
+	primaryURL := "sqlite://" + filepath.Join(dir, "primary.db")
+	replicaURL := "sqlite://" + filepath.Join(dir, "replica.db")
+	for url, name := range map[string]string{primaryURL: "primary", replicaURL: "replica"} {
+		db, err := gorm.Open(openDialector(url))
+		if err != nil {
+			t.Fatalf("Open %s failed: %v", name, err)
+		}
+		if err := db.Exec("CREATE TABLE source (name TEXT)").Error; err != nil {
+			t.Fatalf("CREATE TABLE failed: %v", err)
+		}
+		if err := db.Exec("INSERT INTO source (name) VALUES (?)", name).Error; err != nil {
+			t.Fatalf("INSERT failed: %v", err)
+		}
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}
+
+	db, err := open(smart_context.NewSmartContext(), config.DatabaseConfig{
+		URL:          primaryURL,
+		ReplicaURLs:  []string{replicaURL},
+		MaxOpenConns: 2,
+		MaxIdleConns: 2,
+	})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	source := func(db *gorm.DB) string {
+		var name string
+		if err := db.Table("source").Select("name").Scan(&name).Error; err != nil {
+			t.Fatalf("SELECT failed: %v", err)
+		}
+		return name
+	}
+
+	if got := source(db); got != "primary" {
+		t.Errorf("Expected reads to use the primary by default, got %s", got)
+	}
+	if got := source(ReadReplica(db)); got != "replica" {
+		t.Errorf("Expected ReadReplica to use the replica, got %s", got)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if got := source(ReadReplica(tx)); got != "primary" {
+			t.Errorf("Expected reads inside a transaction to use the primary, got %s", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+}
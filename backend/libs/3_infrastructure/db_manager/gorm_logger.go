@@ -0,0 +1,73 @@
+package db_manager
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sctxLogger направляет логи GORM в ISmartContext: ошибки запросов — как Error,
+// запросы дольше slowThreshold — как Warn, остальные — как Debug.
+type sctxLogger struct {
+	sctx          smart_context.ISmartContext
+	slowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+var _ logger.Interface = (*sctxLogger)(nil)
+
+func newSctxLogger(sctx smart_context.ISmartContext, slowThreshold time.Duration) *sctxLogger {
+	return &sctxLogger{
+		sctx:          sctx.LogField("component", "gorm"),
+		slowThreshold: slowThreshold,
+		level:         logger.Info,
+	}
+}
+
+func (l *sctxLogger) LogMode(level logger.LogLevel) logger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *sctxLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		l.sctx.Infof(msg, args...)
+	}
+}
+
+func (l *sctxLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		l.sctx.Warnf(msg, args...)
+	}
+}
+
+func (l *sctxLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		l.sctx.Errorf(msg, args...)
+	}
+}
+
+func (l *sctxLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		sql, rows := fc()
+		l.sctx.Errorf("query failed after %s: %v [rows:%d] %s", elapsed, err, rows, sql)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.sctx.Warnf("slow query: %s (threshold %s) [rows:%d] %s", elapsed, l.slowThreshold, rows, sql)
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.sctx.Debugf("query: %s [rows:%d] %s", elapsed, rows, sql)
+	}
+}
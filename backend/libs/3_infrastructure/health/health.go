@@ -0,0 +1,151 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	// DefaultTimeout — время на одну проверку, если при регистрации не задано иное.
+	DefaultTimeout = 2 * time.Second
+)
+
+// CheckFunc — проверка готовности подсистемы. Должна уважать отмену ctx.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	fn      CheckFunc
+	timeout time.Duration
+}
+
+// CheckResult — результат одной проверки. Error не попадает в ответ /readyz:
+// текст ошибок БД содержит адреса и фрагменты строки подключения, он только логируется.
+type CheckResult struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"-"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report — сводный результат всех проверок.
+type Report struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks"`
+}
+
+// Registry хранит проверки готовности. Подсистемы регистрируют в нём свои
+// проверки при старте, /readyz выполняет их все.
+type Registry struct {
+	mu     sync.RWMutex
+	checks []check
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register добавляет проверку с таймаутом по умолчанию.
+func (r *Registry) Register(name string, fn CheckFunc) {
+	r.RegisterWithTimeout(name, DefaultTimeout, fn)
+}
+
+// RegisterWithTimeout добавляет проверку с собственным таймаутом.
+func (r *Registry) RegisterWithTimeout(name string, timeout time.Duration, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, fn: fn, timeout: timeout})
+}
+
+// Run выполняет все проверки параллельно. Результаты идут в порядке регистрации.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make([]CheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+			break
+		}
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Name:       c.name,
+		Status:     StatusOK,
+		DurationMs: float64(time.Since(started).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler отвечает на /healthz: процесс жив и обслуживает HTTP.
+func LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	}
+}
+
+// ReadinessHandler отвечает на /readyz: 200, если все проверки прошли, иначе 503.
+// Причины неудачных проверок пишутся в лог sctx, в ответе только статусы.
+func (r *Registry) ReadinessHandler(sctx smart_context.ISmartContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+			for _, result := range report.Checks {
+				if result.Status != StatusOK {
+					sctx.Warnf("Readiness check %s failed: %s", result.Name, result.Error)
+				}
+			}
+		}
+		writeJSON(w, status, report)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
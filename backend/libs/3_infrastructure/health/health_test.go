@@ -0,0 +1,64 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+)
+
+// TestReadinessHandler проверяет код ответа и статус каждой проверки; текст
+// ошибки наружу не отдаётся.
+func TestReadinessHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("database", func(ctx context.Context) error { return nil })
+	registry.Register("migrations", func(ctx context.Context) error { return errors.New("dial tcp db.internal:5432: connection refused") })
+
+	rr := httptest.NewRecorder()
+	registry.ReadinessHandler(smart_context.NewSmartContext())(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	if strings.Contains(rr.Body.String(), "db.internal") {
+		t.Errorf("Expected error details to stay out of the response, got %s", rr.Body.String())
+	}
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code 503, got %d", rr.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode report: %v", err)
+	}
+	if report.Status != StatusFail || len(report.Checks) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if report.Checks[0].Name != "database" || report.Checks[0].Status != StatusOK {
+		t.Errorf("Expected database check to pass, got %+v", report.Checks[0])
+	}
+	if report.Checks[1].Status != StatusFail {
+		t.Errorf("Expected migrations check to fail, got %+v", report.Checks[1])
+	}
+}
+
+// TestCheckTimeout проверяет, что зависшая проверка не блокирует /readyz.
+func TestCheckTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterWithTimeout("slow", 50*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	started := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected check to time out quickly, took %s", elapsed)
+	}
+	if report.Status != StatusFail {
+		t.Errorf("Expected timed out check to fail, got %+v", report)
+	}
+}
@@ -0,0 +1,96 @@
+package http_server
+
+import (
+	"crypto/tls"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+
+	"go.uber.org/zap"
+)
+
+// certCheckInterval ограничивает частоту проверки файлов сертификата.
+const certCheckInterval = 10 * time.Second
+
+// certReloader отдаёт TLS-сертификат и перечитывает его, когда меняются файлы
+// (например, после продления сертификата certbot'ом).
+type certReloader struct {
+	sctx     smart_context.ISmartContext
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(sctx smart_context.ISmartContext, certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{sctx: sctx, certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// GetCertificate используется как tls.Config.GetCertificate.
+// При ошибке перечитывания продолжает отдавать прежний сертификат.
+func (r *certReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= certCheckInterval {
+		r.checkedAt = time.Now()
+		modTime, err := r.latestModTime()
+		if err != nil {
+			r.sctx.Warnf("Failed to stat TLS certificate: %v", err)
+		} else if modTime.After(r.modTime) {
+			if err := r.reload(); err != nil {
+				r.sctx.Errorf("Failed to reload TLS certificate: %v", err)
+			} else {
+				r.sctx.Infof("TLS certificate reloaded from %s", r.certFile)
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// zapErrorLog направляет внутренние ошибки net/http в логгер smart context.
+func zapErrorLog(sctx smart_context.ISmartContext) *log.Logger {
+	logger, err := zap.NewStdLogAt(sctx.GetLogger(), zap.WarnLevel)
+	if err != nil {
+		return nil
+	}
+	return logger
+}
@@ -0,0 +1,150 @@
+package http_server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+)
+
+// Config описывает параметры HTTP-сервера.
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout — сколько ждать завершения активных запросов после сигнала остановки.
+	ShutdownTimeout time.Duration
+
+	// Если заданы оба файла, сервер слушает HTTPS. Сертификат перечитывается
+	// при изменении файла, перезапуск не нужен.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// ShutdownHook вызывается после остановки приёма запросов (воркеры, пул БД и т.п.).
+type ShutdownHook func(ctx context.Context) error
+
+// Server — HTTP-сервер с корректным завершением работы.
+type Server struct {
+	sctx   smart_context.ISmartContext
+	cfg    Config
+	server *http.Server
+
+	mu    sync.Mutex
+	hooks []ShutdownHook
+}
+
+// NewServer создаёт сервер для handler. Если указан TLS, сертификат загружается сразу,
+// чтобы ошибка конфигурации обнаружилась до начала приёма запросов.
+func NewServer(sctx smart_context.ISmartContext, cfg Config, handler http.Handler) (*Server, error) {
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          zapErrorLog(sctx),
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		if cfg.TLSCertFile == "" || cfg.TLSKeyFile == "" {
+			return nil, errors.New("both TLS cert and key files must be set")
+		}
+		reloader, err := newCertReloader(sctx, cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	return &Server{sctx: sctx, cfg: cfg, server: server}, nil
+}
+
+// OnShutdown регистрирует хук, выполняемый после того, как сервер перестал принимать
+// запросы и дождался активных. Хуки выполняются в обратном порядке регистрации.
+func (s *Server) OnShutdown(hook ShutdownHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Run слушает адрес из конфигурации до отмены ctx, после чего дожидается
+// завершения активных запросов и выполняет хуки остановки.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve работает как Run, но на уже открытом listener.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.server.TLSConfig != nil {
+			s.sctx.Infof("Server listening on %s (TLS)", listener.Addr())
+			serveErr <- s.server.ServeTLS(listener, "", "")
+		} else {
+			s.sctx.Infof("Server listening on %s", listener.Addr())
+			serveErr <- s.server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		// Сервер остановился сам (например, listener закрыт): воркеры и пул БД
+		// всё равно останавливаются хуками.
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		if err != nil {
+			s.sctx.Errorf("Server stopped unexpectedly: %v", err)
+		}
+		return errors.Join(err, s.shutdown())
+	case <-ctx.Done():
+	}
+
+	return s.shutdown()
+}
+
+func (s *Server) shutdown() error {
+	timeout := s.cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	s.sctx.Infof("Shutting down server, waiting up to %s for in-flight requests", timeout)
+	var errs []error
+	if err := s.server.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	s.mu.Lock()
+	hooks := s.hooks
+	s.mu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	s.sctx.Info("Server stopped")
+	return nil
+}
@@ -0,0 +1,102 @@
+package http_server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+)
+
+// TestServerDrainsInFlightRequests проверяет, что после отмены контекста сервер
+// дожидается активного запроса и только потом вызывает хуки остановки.
+func TestServerDrainsInFlightRequests(t *testing.T) {
+	sctx := smart_context.NewSmartContext()
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})
+
+	server, err := NewServer(sctx, Config{ShutdownTimeout: 5 * time.Second}, handler)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+
+	var order []string
+	server.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "db")
+		return nil
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "workers")
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- server.Serve(ctx, listener) }()
+
+	respCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			respCh <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respCh <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	if body := <-respCh; body != "done" {
+		t.Errorf("Expected in-flight request to complete, got %q", body)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "workers" || order[1] != "db" {
+		t.Errorf("Expected hooks in reverse order [workers db], got %v", order)
+	}
+}
+
+// TestServerRunsHooksOnServeError проверяет, что хуки остановки выполняются
+// и тогда, когда сервер завершился с ошибкой, а не по отмене контекста.
+func TestServerRunsHooksOnServeError(t *testing.T) {
+	sctx := smart_context.NewSmartContext()
+	server, err := NewServer(sctx, Config{ShutdownTimeout: time.Second}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	hookCalled := false
+	server.OnShutdown(func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	listener.Close()
+
+	if err := server.Serve(context.Background(), listener); err == nil {
+		t.Error("Expected Serve to return the listener error")
+	}
+	if !hookCalled {
+		t.Error("Expected shutdown hooks to run after a serve error")
+	}
+}
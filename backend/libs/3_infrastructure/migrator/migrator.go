@@ -0,0 +1,170 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mdm/libs/4_common/smart_context"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigrationsTable хранит применённые миграции.
+const SchemaMigrationsTable = "schema_migrations"
+
+// Migration — одна SQL-миграция для конкретного диалекта.
+type Migration struct {
+	Version int
+	Name    string
+	File    string
+	SQL     string
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"column:version;primaryKey"`
+	Name      string    `gorm:"column:name;not null"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+func (*schemaMigration) TableName() string {
+	return SchemaMigrationsTable
+}
+
+// Load читает миграции из fsys для диалекта (имя gorm.Dialector: "postgres", "sqlite").
+// Файл NNNNN_name.<dialect>.sql заменяет общий NNNNN_name.sql; файлы других
+// диалектов пропускаются.
+func Load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]Migration)
+	specific := make(map[int]bool)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || path.Ext(fileName) != ".sql" {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		fileDialect := ""
+		if dot := strings.LastIndex(base, "."); dot >= 0 {
+			fileDialect = base[dot+1:]
+			base = base[:dot]
+		}
+		if fileDialect != "" && fileDialect != dialect {
+			continue
+		}
+
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNNN_name.sql", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", fileName, err)
+		}
+
+		if existing, ok := byVersion[version]; ok {
+			if existing.Name != name {
+				return nil, fmt.Errorf("migrations %s and %s share version %d", existing.File, fileName, version)
+			}
+			if specific[version] || fileDialect == "" {
+				continue
+			}
+		}
+
+		content, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+		byVersion[version] = Migration{Version: version, Name: name, File: fileName, SQL: string(content)}
+		specific[version] = fileDialect != ""
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Pending возвращает миграции, которые ещё не применены.
+func Pending(ctx context.Context, db *gorm.DB, migrations []Migration) ([]Migration, error) {
+	db = db.WithContext(ctx)
+	if !db.Migrator().HasTable(SchemaMigrationsTable) {
+		return migrations, nil
+	}
+
+	var applied []schemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Up применяет неприменённые миграции по порядку, каждую в своей транзакции.
+func Up(sctx smart_context.ISmartContext, db *gorm.DB, migrations []Migration) (int, error) {
+	ctx := sctx.GetContext()
+	if err := db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS ` + SchemaMigrationsTable + ` (
+    version INT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`).Error; err != nil {
+		return 0, err
+	}
+
+	pending, err := Pending(ctx, db, migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, m := range pending {
+		sctx.Infof("Applying migration %s", m.File)
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.SQL).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("migration %s: %w", m.File, err)
+		}
+	}
+	return len(pending), nil
+}
+
+// Check возвращает ошибку, если есть неприменённые миграции. Используется в /readyz.
+func Check(ctx context.Context, db *gorm.DB, migrations []Migration) error {
+	pending, err := Pending(ctx, db, migrations)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		files := make([]string, 0, len(pending))
+		for _, m := range pending {
+			files = append(files, m.File)
+		}
+		return fmt.Errorf("%d migration(s) not applied: %s", len(pending), strings.Join(files, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package migrator
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"mdm/libs/4_common/smart_context"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testFS = fstest.MapFS{
+	"00001_init.sql":             {Data: []byte("CREATE TABLE t (id TEXT PRIMARY KEY DEFAULT gen_random_uuid());")},
+	"00001_init.sqlite.sql":      {Data: []byte("CREATE TABLE t (id TEXT PRIMARY KEY);")},
+	"00002_more.sql":             {Data: []byte("ALTER TABLE t ADD COLUMN name TEXT;")},
+	"00003_pg_only.postgres.sql": {Data: []byte("SELECT 1;")},
+}
+
+// TestLoadDialectOverride проверяет выбор файла под диалект.
+func TestLoadDialectOverride(t *testing.T) {
+	migrations, err := Load(testFS, "sqlite")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("Expected 2 migrations for sqlite, got %d", len(migrations))
+	}
+	if migrations[0].File != "00001_init.sqlite.sql" || migrations[1].File != "00002_more.sql" {
+		t.Errorf("Unexpected migration files: %s, %s", migrations[0].File, migrations[1].File)
+	}
+
+	migrations, err = Load(testFS, "postgres")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(migrations) != 3 || migrations[0].File != "00001_init.sql" {
+		t.Errorf("Unexpected postgres migrations: %+v", migrations)
+	}
+}
+
+// TestUpIsIdempotent проверяет, что повторный запуск ничего не применяет.
+func TestUpIsIdempotent(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open in-memory sqlite: %v", err)
+	}
+	sctx := smart_context.NewSmartContext()
+
+	migrations, err := Load(testFS, "sqlite")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if applied, err := Up(sctx, db, migrations); err != nil || applied != 2 {
+		t.Fatalf("Expected 2 applied migrations, got %d (%v)", applied, err)
+	}
+	if applied, err := Up(sctx, db, migrations); err != nil || applied != 0 {
+		t.Fatalf("Expected no migrations on second run, got %d (%v)", applied, err)
+	}
+	if err := Check(context.Background(), db, migrations); err != nil {
+		t.Errorf("Expected no pending migrations, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+// Package mock_idp — минимальный OpenID Connect провайдер для тестов и локальной
+// разработки: discovery, JWKS, authorization code с обязательным PKCE (S256)
+// и ID-токены RS256. Пароли не спрашивает: пользователь выбирается на странице
+// входа или параметром login_hint.
+package mock_idp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Сроки жизни кода авторизации и ID-токена.
+const (
+	codeTTL    = time.Minute
+	idTokenTTL = time.Hour
+)
+
+// User — учётная запись в IdP.
+type User struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// Server — IdP; реализует http.Handler. Issuer должен совпадать с адресом,
+// по которому сервер доступен клиенту (для httptest — srv.URL).
+type Server struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	keyID string
+	users []User
+
+	mu    sync.Mutex
+	codes map[string]grant
+	mux   *http.ServeMux
+}
+
+// grant — выданный, но ещё не обменянный код авторизации.
+type grant struct {
+	user        User
+	redirectURI string
+	challenge   string
+	nonce       string
+	expiresAt   time.Time
+}
+
+// New создаёт IdP для клиента clientID. Пустой clientSecret — публичный клиент.
+func New(issuer, clientID, clientSecret string, users ...User) (*Server, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		keyID:        randomString(8),
+		users:        users,
+		codes:        make(map[string]grant),
+		mux:          http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	s.mux.HandleFunc("GET /jwks", s.jwks)
+	s.mux.HandleFunc("GET /authorize", s.authorize)
+	s.mux.HandleFunc("POST /token", s.token)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.Issuer,
+		"authorization_endpoint":                s.Issuer + "/authorize",
+		"token_endpoint":                        s.Issuer + "/token",
+		"jwks_uri":                              s.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email", "groups"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": s.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html><head><title>Mock IdP</title></head><body>
+<h1>Mock IdP: choose a user</h1>
+<ul>{{range .}}<li><a href="{{.URL}}">{{.User.Username}}</a> {{.User.Groups}}</li>{{end}}</ul>
+</body></html>`))
+
+// authorize выдаёт код пользователю из login_hint; без него показывает список пользователей.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	switch {
+	case q.Get("client_id") != s.ClientID:
+		writeOAuthError(w, http.StatusBadRequest, "unauthorized_client", "unknown client_id")
+		return
+	case q.Get("response_type") != "code" || redirectURI == "":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "response_type=code and redirect_uri are required")
+		return
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "PKCE with S256 is required")
+		return
+	}
+
+	user, ok := s.findUser(q.Get("login_hint"))
+	if !ok {
+		if q.Get("login_hint") != "" || len(s.users) != 1 {
+			s.renderLogin(w, r)
+			return
+		}
+		user = s.users[0]
+	}
+
+	code := randomString(24)
+	s.mu.Lock()
+	s.codes[code] = grant{
+		user:        user,
+		redirectURI: redirectURI,
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		expiresAt:   time.Now().Add(codeTTL),
+	}
+	s.mu.Unlock()
+
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "invalid redirect_uri")
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *Server) renderLogin(w http.ResponseWriter, r *http.Request) {
+	type option struct {
+		User User
+		URL  string
+	}
+	options := make([]option, 0, len(s.users))
+	for _, user := range s.users {
+		q := r.URL.Query()
+		q.Set("login_hint", user.Username)
+		options = append(options, option{User: user, URL: "/authorize?" + q.Encode()})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = loginPage.Execute(w, options)
+}
+
+func (s *Server) findUser(hint string) (User, bool) {
+	for _, user := range s.users {
+		if hint != "" && (user.Username == hint || user.Subject == hint) {
+			return user, true
+		}
+	}
+	return User{}, false
+}
+
+// token обменивает код на ID-токен, проверяя клиента, redirect_uri и PKCE.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.ClientSecret)) != 1 {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	g, found := s.codes[code]
+	// Код одноразовый.
+	delete(s.codes, code)
+	s.mu.Unlock()
+	if !found || time.Now().After(g.expiresAt) || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired code")
+		return
+	}
+	if err := verifyPKCE(r.PostForm.Get("code_verifier"), g.challenge); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+
+	idToken, err := s.signIDToken(g)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(24),
+		"token_type":   "Bearer",
+		"expires_in":   int(idTokenTTL.Seconds()),
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) signIDToken(g grant) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                s.Issuer,
+		"sub":                g.user.Subject,
+		"aud":                s.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(idTokenTTL).Unix(),
+		"preferred_username": g.user.Username,
+		"email":              g.user.Email,
+		"groups":             g.user.Groups,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.key)
+}
+
+// verifyPKCE сверяет code_verifier с code_challenge (S256).
+func verifyPKCE(verifier, challenge string) error {
+	if verifier == "" {
+		return errors.New("code_verifier is required")
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		return errors.New("code_verifier does not match code_challenge")
+	}
+	return nil
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func randomString(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(fmt.Sprintf("crypto/rand: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
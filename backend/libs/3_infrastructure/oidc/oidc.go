@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/auth"
+	"mdm/libs/4_common/config"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// httpTimeout ограничивает запросы к IdP (discovery, JWKS, обмен кода).
+const httpTimeout = 10 * time.Second
+
+var (
+	// ErrNoRole — ни одна группа пользователя не сопоставлена роли MDM и роль по умолчанию не задана.
+	ErrNoRole = fmt.Errorf("%w: no MDM role is mapped to the user's groups", app_errors.ErrForbidden)
+	// ErrInvalidIDToken — IdP вернул ID-токен, который не прошёл проверку.
+	ErrInvalidIDToken = fmt.Errorf("%w: invalid id_token", app_errors.ErrUnauthorized)
+)
+
+// Identity — пользователь, подтверждённый IdP, с ролью MDM по его группам.
+type Identity struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+	Role     string
+}
+
+// Provider — клиент OIDC (authorization code + PKCE) к одному IdP.
+// Discovery выполняется при первом входе и повторяется, пока не удастся:
+// недоступный IdP не мешает запуску сервера и локальному входу.
+type Provider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *gooidc.IDTokenVerifier
+}
+
+// NewProvider проверяет соответствие групп ролям; к IdP не обращается.
+func NewProvider(cfg config.OIDCConfig) (*Provider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("issuer, client id and redirect url are required")
+	}
+	roles := make([]string, 0, len(cfg.RoleMapping)+1)
+	for _, role := range cfg.RoleMapping {
+		roles = append(roles, role)
+	}
+	if cfg.DefaultRole != "" {
+		roles = append(roles, cfg.DefaultRole)
+	}
+	for _, role := range roles {
+		if _, ok := auth.RoleScopes[role]; !ok {
+			return nil, fmt.Errorf("unknown role %q in OIDC role mapping", role)
+		}
+	}
+	return &Provider{cfg: cfg, client: &http.Client{Timeout: httpTimeout}}, nil
+}
+
+// discover загружает метаданные IdP (.well-known/openid-configuration).
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *gooidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := gooidc.NewProvider(gooidc.ClientContext(ctx, p.client), p.cfg.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: oidc discovery: %v", app_errors.ErrBadGateway, err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.cfg.Scopes,
+	}
+	p.verifier = provider.Verifier(&gooidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// LoginState — одноразовые значения одного входа: state защищает callback от
+// CSRF, nonce привязывает ID-токен к входу, verifier — секрет PKCE.
+type LoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// NewLoginState генерирует значения для нового входа.
+func NewLoginState() (LoginState, error) {
+	state, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+	return LoginState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа IdP.
+func (p *Provider) AuthCodeURL(ctx context.Context, login LoginState) (string, error) {
+	oauth, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauth.AuthCodeURL(login.State,
+		oauth2.S256ChallengeOption(login.Verifier),
+		gooidc.Nonce(login.Nonce),
+	), nil
+}
+
+// Exchange обменивает код авторизации на токены, проверяет ID-токен
+// (подпись, issuer, audience, срок, nonce) и определяет роль пользователя.
+func (p *Provider) Exchange(ctx context.Context, code string, login LoginState) (*Identity, error) {
+	oauth, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = gooidc.ClientContext(ctx, p.client)
+
+	token, err := oauth.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: code exchange failed: %v", app_errors.ErrUnauthorized, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	identity := &Identity{
+		Subject: idToken.Subject,
+		Email:   stringClaim(claims, "email"),
+		Groups:  stringsClaim(claims, p.cfg.GroupsClaim),
+	}
+	// Имя пользователя MDM: preferred_username, иначе email, иначе subject.
+	identity.Username = stringClaim(claims, "preferred_username")
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Subject
+	}
+
+	identity.Role = p.roleFor(identity.Groups)
+	if identity.Role == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoRole, identity.Username)
+	}
+	return identity, nil
+}
+
+// roleFor выбирает по группам самую сильную роль (с наибольшим набором прав),
+// без сопоставленных групп — роль по умолчанию.
+func (p *Provider) roleFor(groups []string) string {
+	best := ""
+	for _, group := range groups {
+		role, ok := p.cfg.RoleMapping[group]
+		if ok && (best == "" || len(auth.RoleScopes[role]) > len(auth.RoleScopes[best])) {
+			best = role
+		}
+	}
+	if best == "" {
+		return p.cfg.DefaultRole
+	}
+	return best
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// stringsClaim читает claim-список; IdP с одной группой иногда отдают строку.
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				items = append(items, s)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,143 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"mdm/libs/3_infrastructure/oidc/mock_idp"
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/config"
+)
+
+const redirectURL = "http://mdm.test/auth/oidc/callback"
+
+func setupIdP(t *testing.T) (*Provider, *mock_idp.Server) {
+	t.Helper()
+	idp, err := mock_idp.New("", "mdm", "secret",
+		mock_idp.User{Subject: "u-1", Username: "alice", Email: "alice@example.com", Groups: []string{"staff", "mdm-admins"}},
+		mock_idp.User{Subject: "u-2", Username: "bob", Groups: []string{"mdm-operators"}},
+		mock_idp.User{Subject: "u-3", Username: "eve", Groups: []string{"staff"}},
+	)
+	if err != nil {
+		t.Fatalf("mock_idp.New failed: %v", err)
+	}
+	srv := httptest.NewServer(idp)
+	t.Cleanup(srv.Close)
+	idp.Issuer = srv.URL
+
+	provider, err := NewProvider(config.OIDCConfig{
+		IssuerURL:    srv.URL,
+		ClientID:     "mdm",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "profile", "email"},
+		GroupsClaim:  "groups",
+		RoleMapping:  map[string]string{"mdm-admins": "admin", "mdm-operators": "user", "staff": "user"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider failed: %v", err)
+	}
+	return provider, idp
+}
+
+// authorize проходит страницу входа IdP и возвращает параметры редиректа на callback.
+func authorize(t *testing.T, provider *Provider, login LoginState, username string) url.Values {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), login)
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL + "&login_hint=" + username)
+	if err != nil {
+		t.Fatalf("Authorize request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from IdP, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+	return location.Query()
+}
+
+func TestExchangeMapsGroupsToRole(t *testing.T) {
+	provider, _ := setupIdP(t)
+	login, err := NewLoginState()
+	if err != nil {
+		t.Fatalf("NewLoginState failed: %v", err)
+	}
+
+	params := authorize(t, provider, login, "alice")
+	if params.Get("state") != login.State {
+		t.Fatalf("State was not echoed back: %v", params)
+	}
+	identity, err := provider.Exchange(context.Background(), params.Get("code"), login)
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	// Из двух сопоставленных ролей выбирается более сильная.
+	if identity.Subject != "u-1" || identity.Username != "alice" || identity.Email != "alice@example.com" || identity.Role != "admin" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+
+	// Код одноразовый.
+	if _, err := provider.Exchange(context.Background(), params.Get("code"), login); !errors.Is(err, app_errors.ErrUnauthorized) {
+		t.Errorf("Expected reused code to be rejected, got %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifierAndNonce(t *testing.T) {
+	provider, _ := setupIdP(t)
+	login, _ := NewLoginState()
+
+	params := authorize(t, provider, login, "bob")
+	wrongVerifier := login
+	wrongVerifier.Verifier = "not-the-verifier-not-the-verifier-not-the-verifier"
+	if _, err := provider.Exchange(context.Background(), params.Get("code"), wrongVerifier); !errors.Is(err, app_errors.ErrUnauthorized) {
+		t.Errorf("Expected PKCE failure, got %v", err)
+	}
+
+	params = authorize(t, provider, login, "bob")
+	wrongNonce := login
+	wrongNonce.Nonce = "other"
+	if _, err := provider.Exchange(context.Background(), params.Get("code"), wrongNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("Expected nonce mismatch, got %v", err)
+	}
+}
+
+func TestExchangeWithoutMappedRole(t *testing.T) {
+	provider, _ := setupIdP(t)
+	provider.cfg.RoleMapping = map[string]string{"mdm-admins": "admin"}
+	login, _ := NewLoginState()
+
+	params := authorize(t, provider, login, "eve")
+	if _, err := provider.Exchange(context.Background(), params.Get("code"), login); !errors.Is(err, ErrNoRole) {
+		t.Errorf("Expected ErrNoRole, got %v", err)
+	}
+
+	provider.cfg.DefaultRole = "user"
+	params = authorize(t, provider, login, "eve")
+	identity, err := provider.Exchange(context.Background(), params.Get("code"), login)
+	if err != nil || identity.Role != "user" {
+		t.Errorf("Expected default role, got %+v, %v", identity, err)
+	}
+}
+
+func TestNewProviderRejectsUnknownRole(t *testing.T) {
+	_, err := NewProvider(config.OIDCConfig{
+		IssuerURL:   "http://idp.test",
+		ClientID:    "mdm",
+		RedirectURL: redirectURL,
+		RoleMapping: map[string]string{"admins": "superuser"},
+	})
+	if err == nil {
+		t.Error("Expected unknown role to be rejected")
+	}
+}
@@ -0,0 +1,236 @@
+package rate_limit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/config"
+	"mdm/libs/4_common/smart_context"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Заголовки с состоянием лимита.
+const (
+	HeaderLimit     = "X-RateLimit-Limit"
+	HeaderRemaining = "X-RateLimit-Remaining"
+	HeaderReset     = "X-RateLimit-Reset"
+)
+
+// KeyFunc возвращает ключ, по которому считается лимит (IP, имя пользователя,
+// device id). Пустая строка — ключ для запроса не определён и не проверяется.
+// Ошибка отклоняет запрос со статусом по app_errors.StatusCode.
+type KeyFunc func(r *http.Request) (string, error)
+
+// maxJSONKeyBody — сколько байт тела читает ByJSONField. Тела запросов входа и
+// регистрации намного меньше, а без ограничения ключ лимита можно было бы
+// заставить читать в память тело любого размера.
+const maxJSONKeyBody = 64 << 10
+
+// Limiter — token bucket в памяти процесса: у каждого ключа своё ведро на
+// rate.Requests запросов, которое равномерно пополняется за rate.Per.
+type Limiter struct {
+	name    string
+	rate    config.Rate
+	now     func() time.Time
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Result — результат проверки одного ключа.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset — через сколько ведро наполнится полностью.
+	Reset time.Duration
+	// RetryAfter — через сколько появится следующий токен (для отказа).
+	RetryAfter time.Duration
+}
+
+// NewLimiter создаёт ограничитель; name попадает в логи.
+func NewLimiter(name string, rate config.Rate) *Limiter {
+	return &Limiter{
+		name:    name,
+		rate:    rate,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// perToken — за сколько пополняется один токен.
+func (l *Limiter) perToken() time.Duration {
+	return l.rate.Per / time.Duration(l.rate.Requests)
+}
+
+// Allow расходует токен ключа, если он есть.
+func (l *Limiter) Allow(key string) Result {
+	if l.rate.Unlimited() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.rate.Requests)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	} else {
+		elapsed := now.Sub(b.updated)
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(l.perToken()))
+		b.updated = now
+	}
+
+	result := Result{Limit: l.rate.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(l.perToken()))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration((capacity - b.tokens) * float64(l.perToken()))
+	return result
+}
+
+// Cleanup удаляет вёдра, которые успели наполниться, — они не отличаются от новых.
+// Возвращает число удалённых вёдер.
+func (l *Limiter) Cleanup() int {
+	if l.rate.Unlimited() {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	removed := 0
+	for key, b := range l.buckets {
+		missing := float64(l.rate.Requests) - b.tokens
+		if now.Sub(b.updated) >= time.Duration(missing*float64(l.perToken())) {
+			delete(l.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Middleware пропускает запрос, только если лимит не исчерпан ни по одному из ключей.
+// Заголовки X-RateLimit-* описывают самый строгий из проверенных ключей,
+// при отказе отвечает 429 с Retry-After.
+func (l *Limiter) Middleware(sctx smart_context.ISmartContext, keys ...KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l.rate.Unlimited() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var strictest *Result
+			for _, keyFunc := range keys {
+				key, err := keyFunc(r)
+				if err != nil {
+					sctx.Warnf("Rate limit %s rejected request: %v", l.name, err)
+					writeError(w, app_errors.StatusCode(err), err.Error())
+					return
+				}
+				if key == "" {
+					continue
+				}
+				result := l.Allow(key)
+				if !result.Allowed {
+					sctx.Warnf("Rate limit %s exceeded for %s", l.name, key)
+					writeHeaders(w, result)
+					w.Header().Set("Retry-After", app_errors.RetryAfterSeconds(result.RetryAfter))
+					writeError(w, http.StatusTooManyRequests, "too many requests: rate limit exceeded")
+					return
+				}
+				if strictest == nil || result.Remaining < strictest.Remaining {
+					strictest = &result
+				}
+			}
+			if strictest != nil {
+				writeHeaders(w, *strictest)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func writeHeaders(w http.ResponseWriter, result Result) {
+	w.Header().Set(HeaderLimit, strconv.Itoa(result.Limit))
+	w.Header().Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+	w.Header().Set(HeaderReset, app_errors.RetryAfterSeconds(result.Reset))
+}
+
+// ByIP — ключ по IP клиента. За доверенным прокси RemoteAddr должен быть
+// заранее подменён (chi middleware.RealIP).
+func ByIP(r *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host, nil
+}
+
+// ByURLParam — ключ по параметру маршрута chi (например, {id} устройства).
+func ByURLParam(name string) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		if value := chi.URLParam(r, name); value != "" {
+			return name + ":" + value, nil
+		}
+		return "", nil
+	}
+}
+
+// ByJSONField — ключ по строковому полю JSON-тела (например, username).
+// Тело подменяется копией, чтобы обработчик мог прочитать его снова.
+// Тело больше maxJSONKeyBody отклоняется с ErrPayloadTooLarge (413).
+func ByJSONField(name string) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		if r.Body == nil {
+			return "", nil
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxJSONKeyBody))
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return "", fmt.Errorf("%w: request body must not exceed %d bytes", app_errors.ErrPayloadTooLarge, tooLarge.Limit)
+		}
+		if err != nil {
+			return "", nil
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", nil
+		}
+		if value, ok := data[name].(string); ok && value != "" {
+			return name + ":" + value, nil
+		}
+		return "", nil
+	}
+}
@@ -0,0 +1,123 @@
+package rate_limit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mdm/libs/4_common/config"
+	"mdm/libs/4_common/smart_context"
+)
+
+// fakeClock позволяет двигать время limiter'а вручную.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestLimiter(rate config.Rate) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	l := NewLimiter("test", rate)
+	l.now = clock.Now
+	return l, clock
+}
+
+// TestAllowRefill проверяет расход и равномерное пополнение токенов.
+func TestAllowRefill(t *testing.T) {
+	l, clock := newTestLimiter(config.Rate{Requests: 2, Per: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if result := l.Allow("ip:1"); !result.Allowed {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+	denied := l.Allow("ip:1")
+	if denied.Allowed {
+		t.Fatalf("Third request should be denied")
+	}
+	if denied.RetryAfter != 30*time.Second {
+		t.Errorf("Expected RetryAfter 30s, got %s", denied.RetryAfter)
+	}
+	if other := l.Allow("ip:2"); !other.Allowed {
+		t.Errorf("Other key must have its own bucket")
+	}
+
+	clock.now = clock.now.Add(30 * time.Second)
+	if result := l.Allow("ip:1"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Expected one refilled token, got %+v", result)
+	}
+}
+
+// TestCleanup проверяет, что наполнившиеся вёдра удаляются.
+func TestCleanup(t *testing.T) {
+	l, clock := newTestLimiter(config.Rate{Requests: 2, Per: time.Minute})
+	l.Allow("ip:1")
+	l.Allow("ip:2")
+	l.Allow("ip:2")
+
+	clock.now = clock.now.Add(30 * time.Second)
+	if removed := l.Cleanup(); removed != 1 {
+		t.Errorf("Expected 1 bucket removed, got %d", removed)
+	}
+	clock.now = clock.now.Add(30 * time.Second)
+	if removed := l.Cleanup(); removed != 1 {
+		t.Errorf("Expected remaining bucket removed, got %d", removed)
+	}
+}
+
+// TestMiddleware проверяет заголовки X-RateLimit-*, ответ 429 и сохранность тела запроса.
+func TestMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(config.Rate{Requests: 1, Per: time.Minute})
+	var gotBody string
+	handler := l.Middleware(smart_context.NewSmartContext(), ByIP, ByJSONField("username"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+		}))
+
+	send := func(remoteAddr, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("10.0.0.1:1234", `{"username": "admin"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code 200, got %d", rr.Code)
+	}
+	if gotBody != `{"username": "admin"}` {
+		t.Errorf("Handler must see the original body, got %q", gotBody)
+	}
+	if rr.Header().Get(HeaderLimit) != "1" || rr.Header().Get(HeaderRemaining) != "0" {
+		t.Errorf("Unexpected rate limit headers: %v", rr.Header())
+	}
+
+	// Другой IP, но тот же пользователь — лимит по имени исчерпан.
+	rr = send("10.0.0.2:1234", `{"username": "admin"}`)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", rr.Header().Get("Retry-After"))
+	}
+}
+
+// TestByJSONFieldBodyLimit проверяет, что слишком большое тело не читается
+// целиком, а отклоняется с 413.
+func TestByJSONFieldBodyLimit(t *testing.T) {
+	l, _ := newTestLimiter(config.Rate{Requests: 10, Per: time.Minute})
+	called := false
+	handler := l.Middleware(smart_context.NewSmartContext(), ByJSONField("username"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+
+	body := `{"username": "admin", "padding": "` + strings.Repeat("x", maxJSONKeyBody) + `"}`
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/login", strings.NewReader(body)))
+	if rr.Code != http.StatusRequestEntityTooLarge || called {
+		t.Errorf("Expected 413 without calling the handler, got %d (called=%v)", rr.Code, called)
+	}
+}
@@ -0,0 +1,90 @@
+package app_errors
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Базовые виды ошибок. Конкретные ошибки оборачивают их через fmt.Errorf("%w: ...", ...),
+// а run_processor по ним выбирает HTTP-статус ответа.
+var (
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized — не переданы или неверны учётные данные.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden — у субъекта нет нужных прав.
+	ErrForbidden = errors.New("forbidden")
+	ErrNotFound  = errors.New("not found")
+	ErrConflict  = errors.New("conflict")
+	// ErrPayloadTooLarge — тело запроса больше допустимого.
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrUnprocessable — запрос корректен синтаксически, но не может быть выполнен.
+	ErrUnprocessable = errors.New("unprocessable entity")
+	// ErrTooManyRequests — превышен лимит запросов или вход временно заблокирован.
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrBadGateway — внешний сервис (например, IdP) недоступен или ответил ошибкой.
+	ErrBadGateway = errors.New("bad gateway")
+)
+
+// retryAfterError добавляет к ошибке время, через которое запрос можно повторить.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// WithRetryAfter помечает ошибку временем ожидания; run_processor отдаёт его
+// в заголовке Retry-After.
+func WithRetryAfter(err error, after time.Duration) error {
+	return &retryAfterError{err: err, after: after}
+}
+
+// RetryAfter возвращает время ожидания, если оно задано через WithRetryAfter.
+func RetryAfter(err error) (time.Duration, bool) {
+	var target *retryAfterError
+	if errors.As(err, &target) {
+		return target.after, true
+	}
+	return 0, false
+}
+
+// StatusCode возвращает HTTP-статус, соответствующий ошибке.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrPayloadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnprocessable):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrBadGateway):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RetryAfterSeconds форматирует время ожидания для заголовка Retry-After
+// (целые секунды, с округлением вверх).
+func RetryAfterSeconds(after time.Duration) string {
+	seconds := int64((after + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix отличает API-ключи от JWT и позволяет находить их в логах и конфигах.
+// Формат ключа: mdm_<prefix>_<secret>; prefix хранится открыто и служит для поиска,
+// от ключа целиком в БД хранится только хеш.
+const APIKeyPrefix = "mdm_"
+
+const (
+	apiKeyPrefixBytes = 4
+	apiKeySecretBytes = 24
+)
+
+// NewAPIKey генерирует API-ключ. Возвращает сам ключ (отдаётся один раз),
+// его открытый префикс и хеш для хранения.
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	prefixBuf := make([]byte, apiKeyPrefixBytes)
+	secretBuf := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(prefixBuf); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secretBuf); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(prefixBuf)
+	key = APIKeyPrefix + prefix + "_" + hex.EncodeToString(secretBuf)
+	return key, prefix, HashToken(key), nil
+}
+
+// ParseAPIKeyPrefix извлекает открытый префикс из ключа.
+func ParseAPIKeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 2*apiKeyPrefixBytes || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
@@ -1,44 +1,200 @@
 package auth
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"mdm/libs/4_common/smart_context"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-var JWTSecret = []byte("your-secret") // по умолчанию, замените на значение из ENV
+// JWTSecret — секрет подписи JWT. Устанавливается при старте из конфигурации (JWT_SECRET).
+var JWTSecret []byte
 
-// SetJWTSecret позволяет установить секрет из внешнего источника (например, из переменной окружения).
+// SetJWTSecret позволяет установить секрет из внешнего источника (например, из конфигурации).
 func SetJWTSecret(secret string) {
 	JWTSecret = []byte(secret)
 }
 
-// JWTMiddleware проверяет заголовок Authorization и валидирует JWT-токен.
-func JWTMiddleware(next http.Handler, sctx smart_context.ISmartContext) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-			return
-		}
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
-			return
-		}
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return JWTSecret, nil
+// TokenTTL — срок действия JWT пользователя.
+const TokenTTL = 24 * time.Hour
+
+// ErrNoSigningKey — для алгоритма из JWT_ALGORITHM ещё нет активного ключа.
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// IssueJWT выдаёт JWT пользователю — после входа по паролю или через SSO.
+// Токен подписывается текущим ключом из Keys (kid в заголовке) или, при
+// JWT_ALGORITHM=HS256, общим секретом.
+func IssueJWT(userID, username, role string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":      userID,
+		"username": username,
+		"role":     role,
+		"iat":      now.Unix(),
+		"exp":      now.Add(TokenTTL).Unix(),
+	}
+	if Keys.Algorithm() == AlgHS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(JWTSecret)
+	}
+
+	key := Keys.Signing(now)
+	if key == nil {
+		return "", ErrNoSigningKey
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// ErrInvalidAPIKey — ключ не найден, отозван или истёк.
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKeyResolver находит субъекта по API-ключу (реализация — repositories.APIKeyRepository).
+type APIKeyResolver interface {
+	ResolveAPIKey(sctx smart_context.ISmartContext, key string) (*Principal, error)
+}
+
+// Middleware аутентифицирует запрос по JWT (Authorization: Bearer <jwt>) или
+// API-ключу (X-Api-Key, Apikey или Authorization: Bearer mdm_...) и кладёт
+// Principal в контекст запроса. Без apiKeys принимаются только JWT.
+func Middleware(sctx smart_context.ISmartContext, apiKeys APIKeyResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			credential, isAPIKey, err := credentialFromRequest(r)
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			var principal *Principal
+			if isAPIKey {
+				if apiKeys == nil {
+					writeError(w, http.StatusUnauthorized, "API keys are not accepted")
+					return
+				}
+				principal, err = apiKeys.ResolveAPIKey(sctx.WithContext(r.Context()), credential)
+				if err != nil {
+					sctx.Warnf("API key rejected: %v", err)
+					writeError(w, http.StatusUnauthorized, "Invalid API key")
+					return
+				}
+			} else {
+				principal, err = ParseJWT(credential)
+				if err != nil {
+					sctx.Warnf("Invalid JWT token: %v", err)
+					writeError(w, http.StatusUnauthorized, "Invalid token")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequireScope пропускает запрос, только если у субъекта есть право scope.
+// Ставится после Middleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !PrincipalFromContext(r.Context()).HasScope(scope) {
+				writeError(w, http.StatusForbidden, "Missing scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
 		})
-		if err != nil || !token.Valid {
-			sctx.Errorf("Invalid JWT token", err)
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+	}
+}
+
+// credentialFromRequest извлекает JWT или API-ключ из заголовков.
+func credentialFromRequest(r *http.Request) (string, bool, error) {
+	for _, header := range []string{"X-Api-Key", "Apikey"} {
+		if key := r.Header.Get(header); key != "" {
+			return key, true, nil
+		}
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", false, errors.New("Authorization header missing")
+	}
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false, errors.New("Invalid Authorization header format")
+	}
+	return parts[1], strings.HasPrefix(parts[1], APIKeyPrefix), nil
+}
+
+// ParseJWT проверяет JWT и возвращает пользователя из его claims.
+func ParseJWT(tokenStr string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token is not valid")
+	}
+
+	username, _ := claims["username"].(string)
+	role, _ := claims["role"].(string)
+	subject, _ := claims["sub"].(string)
+	return &Principal{
+		Kind:   PrincipalUser,
+		ID:     subject,
+		Name:   username,
+		UserID: subject,
+		Role:   role,
+		Scopes: RoleScopes[role],
+	}, nil
+}
+
+// verificationKey выбирает ключ проверки. После перехода на асимметричную
+// подпись токены HS256 принимаются только в отсрочку KeyRing.HS256GraceUntil
+// и только если срок токена кончается до её конца: дальше общий секрет
+// больше не даёт права выпускать токены.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if Keys.Algorithm() != AlgHS256 && !withinHS256Grace(token, time.Now()) {
+			return nil, errors.New("HS256 tokens are not accepted")
 		}
-		// При необходимости можно извлечь claims и положить их в контекст запроса.
-		next.ServeHTTP(w, r)
-	})
+		return JWTSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key := Keys.Lookup(kid, time.Now())
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.PublicKey, nil
+}
+
+// withinHS256Grace сообщает, что токен HS256 можно принять после перехода на
+// асимметричную подпись.
+func withinHS256Grace(token *jwt.Token, now time.Time) bool {
+	graceUntil := Keys.HS256GraceUntil()
+	if !now.Before(graceUntil) {
+		return false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	exp, ok := claims["exp"].(float64)
+	return ok && int64(exp) <= graceUntil.Unix()
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
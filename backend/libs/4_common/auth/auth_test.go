@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// staticResolver принимает один ключ.
+type staticResolver struct {
+	key       string
+	principal *Principal
+}
+
+func (r staticResolver) ResolveAPIKey(sctx smart_context.ISmartContext, key string) (*Principal, error) {
+	if key != r.key {
+		return nil, ErrInvalidAPIKey
+	}
+	return r.principal, nil
+}
+
+func signedJWT(t *testing.T, role string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":      "user-1",
+		"username": "alice",
+		"role":     role,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString(JWTSecret)
+	if err != nil {
+		t.Fatalf("SignedString failed: %v", err)
+	}
+	return signed
+}
+
+// TestMiddlewareScopes проверяет, что JWT и API-ключ дают одинаковую модель прав.
+func TestMiddlewareScopes(t *testing.T) {
+	SetJWTSecret("test-secret")
+	resolver := staticResolver{
+		key:       "mdm_0011aabb_secret",
+		principal: &Principal{Kind: PrincipalAPIKey, ID: "key-1", Name: "ci", Scopes: []string{ScopeDevicesRead}},
+	}
+
+	var seen *Principal
+	handler := Middleware(smart_context.NewSmartContext(), resolver)(
+		RequireScope(ScopeDevicesWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = PrincipalFromContext(r.Context())
+		})))
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"user jwt", "Authorization", "Bearer " + signedJWT(t, "user"), http.StatusOK},
+		{"unknown role", "Authorization", "Bearer " + signedJWT(t, "guest"), http.StatusForbidden},
+		{"bad jwt", "Authorization", "Bearer garbage", http.StatusUnauthorized},
+		{"api key without scope", "X-Api-Key", "mdm_0011aabb_secret", http.StatusForbidden},
+		{"api key as bearer", "Authorization", "Bearer mdm_0011aabb_secret", http.StatusForbidden},
+		{"wrong api key", "X-Api-Key", "mdm_0011aabb_other", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/devices/a/camera", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Errorf("Expected status code %d, got %d", tc.want, rr.Code)
+			}
+		})
+	}
+
+	if seen == nil || seen.Kind != PrincipalUser || seen.ID != "user-1" || seen.Name != "alice" {
+		t.Errorf("Unexpected principal in context: %+v", seen)
+	}
+}
+
+// TestParseAPIKeyPrefix проверяет формат ключа.
+func TestParseAPIKeyPrefix(t *testing.T) {
+	key, prefix, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("NewAPIKey failed: %v", err)
+	}
+	parsed, ok := ParseAPIKeyPrefix(key)
+	if !ok || parsed != prefix {
+		t.Errorf("Expected prefix %s, got %s (%v)", prefix, parsed, ok)
+	}
+	if !TokenMatches(key, hash) {
+		t.Errorf("Expected key to match its hash")
+	}
+	for _, invalid := range []string{"", "mdm_", "mdm_short_x", "jwt.token.value", "mdm_0011aabb_"} {
+		if _, ok := ParseAPIKeyPrefix(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+// TestIssueJWTWithKeyRing проверяет подпись ключами из KeyRing.
+func TestIssueJWTWithKeyRing(t *testing.T) {
+	SetJWTSecret("test-secret")
+	defer func() {
+		Keys.SetAlgorithm(AlgHS256)
+		Keys.Replace(nil)
+	}()
+	legacy := signedJWT(t, "admin")
+
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		key, err := GenerateSigningKey(alg, time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatalf("GenerateSigningKey(%s) failed: %v", alg, err)
+		}
+		Keys.SetAlgorithm(alg)
+		Keys.Replace([]*SigningKey{key})
+
+		token, err := IssueJWT("user-1", "alice", "admin")
+		if err != nil {
+			t.Fatalf("IssueJWT(%s) failed: %v", alg, err)
+		}
+		principal, err := ParseJWT(token)
+		if err != nil || principal.Name != "alice" || !principal.HasScope(ScopeAPIKeysManage) {
+			t.Errorf("ParseJWT(%s) = %+v, %v", alg, principal, err)
+		}
+		// Сразу после перехода выданные ранее токены HS256 ещё принимаются.
+		if _, err := ParseJWT(legacy); err != nil {
+			t.Errorf("Expected HS256 token to be accepted during the grace period with %s, got %v", alg, err)
+		}
+
+		jwks := Keys.JWKS(time.Now())
+		if len(jwks.Keys) != 1 || jwks.Keys[0].Kid != key.ID || jwks.Keys[0].Alg != alg {
+			t.Errorf("Unexpected JWKS: %+v", jwks)
+		}
+
+		// Токен неизвестного ключа (ключ удалён из набора) не принимается.
+		Keys.Replace(nil)
+		if _, err := ParseJWT(token); err == nil {
+			t.Errorf("Expected token of a removed key to be rejected")
+		}
+	}
+}
+
+// TestHS256GracePeriod проверяет, что после перехода на асимметричную подпись
+// токены HS256 принимаются TokenTTL с момента перехода и не дольше.
+func TestHS256GracePeriod(t *testing.T) {
+	SetJWTSecret("test-secret")
+	defer func() {
+		Keys.SetAlgorithm(AlgHS256)
+		Keys.Replace(nil)
+	}()
+	hs256 := func(exp time.Time) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "user-1", "username": "alice", "role": "admin", "exp": exp.Unix(),
+		}).SignedString(JWTSecret)
+		if err != nil {
+			t.Fatalf("SignedString failed: %v", err)
+		}
+		return signed
+	}
+
+	switchedAt := time.Now().Add(-time.Hour)
+	first, err := GenerateSigningKey(AlgEdDSA, switchedAt)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	Keys.SetAlgorithm(AlgEdDSA)
+	Keys.Replace([]*SigningKey{first})
+
+	if _, err := ParseJWT(hs256(switchedAt.Add(TokenTTL - time.Minute))); err != nil {
+		t.Errorf("Expected HS256 token expiring within the grace period to be accepted, got %v", err)
+	}
+	if _, err := ParseJWT(hs256(switchedAt.Add(TokenTTL + time.Hour))); err == nil {
+		t.Error("Expected HS256 token outliving the grace period to be rejected")
+	}
+
+	// Отсрочка считается от первого ключа, а не от последнего выпущенного.
+	next, err := GenerateSigningKey(AlgEdDSA, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	Keys.Replace([]*SigningKey{next, first})
+	if got := Keys.HS256GraceUntil(); !got.Equal(switchedAt.Add(TokenTTL)) {
+		t.Errorf("Expected grace until %s, got %s", switchedAt.Add(TokenTTL), got)
+	}
+
+	// Переход был больше TokenTTL назад — HS256 больше не принимается.
+	old, err := GenerateSigningKey(AlgEdDSA, time.Now().Add(-TokenTTL-time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	Keys.Replace([]*SigningKey{next, old})
+	if _, err := ParseJWT(hs256(time.Now().Add(time.Minute))); err == nil {
+		t.Error("Expected HS256 token to be rejected after the grace period")
+	}
+}
+
+func TestSealPrivateKey(t *testing.T) {
+	SetDataEncryptionKey("test-data-key")
+	key, err := GenerateSigningKey(AlgEdDSA, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateSigningKey failed: %v", err)
+	}
+	sealed, err := SealPrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatalf("SealPrivateKey failed: %v", err)
+	}
+	if _, err := OpenPrivateKey(sealed); err != nil {
+		t.Errorf("OpenPrivateKey failed: %v", err)
+	}
+	SetJWTSecret("other-secret")
+	if _, err := OpenPrivateKey(sealed); err != nil {
+		t.Errorf("Expected JWT_SECRET rotation to keep sealed keys readable, got %v", err)
+	}
+	SetDataEncryptionKey("other-data-key")
+	if _, err := OpenPrivateKey(sealed); err == nil {
+		t.Errorf("Expected a different data key to fail decryption")
+	}
+}
+
+// staticDevices принимает токен "tok" устройства "dev-1".
+type staticDevices struct{}
+
+func (staticDevices) AuthenticateDevice(sctx smart_context.ISmartContext, deviceID string, token string) error {
+	if deviceID != "dev-1" || token != "tok" {
+		return fmt.Errorf("%w: invalid device token", app_errors.ErrUnauthorized)
+	}
+	return nil
+}
+
+// TestDeviceMiddleware проверяет, что токен устройства подходит только к своему устройству.
+func TestDeviceMiddleware(t *testing.T) {
+	var seen *Principal
+	router := chi.NewRouter()
+	router.With(DeviceMiddleware(smart_context.NewSmartContext(), staticDevices{}, "id")).
+		Post("/devices/{id}/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+			seen = PrincipalFromContext(r.Context())
+		})
+
+	cases := []struct {
+		name     string
+		deviceID string
+		header   string
+		want     int
+	}{
+		{"valid token", "dev-1", "Bearer tok", http.StatusOK},
+		{"no token", "dev-1", "", http.StatusUnauthorized},
+		{"wrong token", "dev-1", "Bearer other", http.StatusUnauthorized},
+		{"token of another device", "dev-2", "Bearer tok", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/devices/"+tc.deviceID+"/heartbeat", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tc.want {
+				t.Errorf("Expected status code %d, got %d", tc.want, rr.Code)
+			}
+		})
+	}
+
+	if seen == nil || seen.Kind != PrincipalDevice || seen.ID != "dev-1" {
+		t.Errorf("Unexpected principal in context: %+v", seen)
+	}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"mdm/libs/4_common/app_errors"
+	"mdm/libs/4_common/smart_context"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// NewDeviceToken генерирует токен, который выдаётся устройству при регистрации.
+// Сам токен отдаётся устройству один раз, в БД хранится только его хеш.
+func NewDeviceToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+// HashToken возвращает hex(SHA-256) токена (токена устройства или API-ключа).
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenMatches сравнивает токен с сохранённым хешем за постоянное время.
+func TokenMatches(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashToken(token)), []byte(hash)) == 1
+}
+
+// DeviceAuthenticator проверяет токен устройства (реализация — repositories.DeviceRepository).
+type DeviceAuthenticator interface {
+	AuthenticateDevice(sctx smart_context.ISmartContext, deviceID string, token string) error
+}
+
+// DeviceMiddleware пропускает запросы агента, только если Authorization: Bearer
+// содержит токен, выданный при регистрации устройству из URL-параметра param.
+// В контекст кладётся Principal вида PrincipalDevice.
+func DeviceMiddleware(sctx smart_context.ISmartContext, devices DeviceAuthenticator, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deviceID := chi.URLParam(r, param)
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				writeError(w, http.StatusUnauthorized, "Device token missing")
+				return
+			}
+			err := devices.AuthenticateDevice(sctx.WithContext(r.Context()), deviceID, token)
+			if errors.Is(err, app_errors.ErrUnauthorized) {
+				sctx.Warnf("Device %q rejected: %v", deviceID, err)
+				writeError(w, http.StatusUnauthorized, "Invalid device token")
+				return
+			}
+			if err != nil {
+				sctx.Errorf("Device %q authentication failed: %v", deviceID, err)
+				writeError(w, http.StatusInternalServerError, "Internal server error")
+				return
+			}
+			principal := &Principal{Kind: PrincipalDevice, ID: deviceID, Name: deviceID}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Алгоритмы подписи JWT. HS256 — общий секрет JWT_SECRET (без JWKS),
+// RS256 и EdDSA — ключи из KeyRing, открытые части публикуются в JWKS.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// rsaKeyBits — размер RSA-ключей для RS256.
+const rsaKeyBits = 2048
+
+// SigningKey — ключ подписи JWT. ID — kid в заголовке токена.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// PrivateKey — nil, если закрытую часть не удалось расшифровать
+	// (сменился DATA_ENCRYPTION_KEY): таким ключом можно только проверять.
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	// ActivatesAt — с этого момента ключ подписывает новые токены.
+	ActivatesAt time.Time
+	// ExpiresAt — когда ключ убирается из JWKS; nil — ключ ещё не заменён.
+	ExpiresAt *time.Time
+}
+
+// GenerateSigningKey создаёт ключ алгоритма RS256 или EdDSA.
+func GenerateSigningKey(alg string, activatesAt time.Time) (*SigningKey, error) {
+	var private crypto.Signer
+	switch alg {
+	case AlgRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	case AlgEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &SigningKey{
+		ID:          hex.EncodeToString(id),
+		Algorithm:   alg,
+		PrivateKey:  private,
+		PublicKey:   private.Public(),
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// method возвращает метод golang-jwt для алгоритма ключа.
+func (k *SigningKey) method() jwt.SigningMethod {
+	if k.Algorithm == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// activeAt сообщает, что ключ можно использовать для проверки в момент now.
+func (k *SigningKey) activeAt(now time.Time) bool {
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// KeyRing — набор ключей подписи: самый новый активированный подписывает,
+// остальные неистёкшие проверяют токены и публикуются в JWKS.
+type KeyRing struct {
+	mu        sync.RWMutex
+	algorithm string
+	keys      []*SigningKey
+}
+
+// Keys — ключи подписи процесса. Алгоритм задаётся из конфигурации (JWT_ALGORITHM),
+// набор ключей периодически перечитывается из БД.
+var Keys = &KeyRing{algorithm: AlgHS256}
+
+// SetAlgorithm задаёт алгоритм подписи новых токенов.
+func (r *KeyRing) SetAlgorithm(alg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.algorithm = alg
+}
+
+// Algorithm возвращает алгоритм подписи новых токенов.
+func (r *KeyRing) Algorithm() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.algorithm
+}
+
+// Replace заменяет набор ключей.
+func (r *KeyRing) Replace(keys []*SigningKey) {
+	sorted := append([]*SigningKey(nil), keys...)
+	// Сначала самые новые.
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ActivatesAt.After(sorted[j].ActivatesAt) })
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = sorted
+}
+
+// Signing возвращает ключ, которым подписываются новые токены.
+func (r *KeyRing) Signing(now time.Time) *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.PrivateKey != nil && key.Algorithm == r.algorithm && !key.ActivatesAt.After(now) && key.activeAt(now) {
+			return key
+		}
+	}
+	return nil
+}
+
+// Lookup возвращает неистёкший ключ по kid.
+func (r *KeyRing) Lookup(kid string, now time.Time) *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, key := range r.keys {
+		if key.ID == kid && key.activeAt(now) {
+			return key
+		}
+	}
+	return nil
+}
+
+// HS256GraceUntil возвращает, до какого момента после перехода с HS256 ещё
+// принимаются токены HS256: выданные до перехода токены доживают свой TokenTTL,
+// и пользователям не нужно входить заново. Моментом перехода считается активация
+// самого старого ключа набора: первый ключ заменяется не раньше чем через
+// JWT_KEY_ROTATION и остаётся в наборе ещё TokenTTL после замены, так что всё
+// время отсрочки он самый старый. Без ключей возвращается нулевое время.
+func (r *KeyRing) HS256GraceUntil() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.keys) == 0 {
+		return time.Time{}
+	}
+	// Ключи отсортированы от новых к старым.
+	return r.keys[len(r.keys)-1].ActivatesAt.Add(TokenTTL)
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519 (OKP)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKSet — ответ /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS возвращает открытые части неистёкших ключей, включая ещё не активированные:
+// сервисы с закешированным JWKS заранее узнают о следующем ключе.
+func (r *KeyRing) JWKS(now time.Time) JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if !key.activeAt(now) {
+			continue
+		}
+		jwk := JWK{Use: "sig", Alg: key.Algorithm, Kid: key.ID}
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// jwksMaxAge — сколько проверяющие сервисы могут кешировать JWKS;
+// должен быть меньше JWT_KEY_PUBLISH_AHEAD.
+const jwksMaxAge = 5 * time.Minute
+
+// JWKSHandler отдаёт /.well-known/jwks.json для сервисов, проверяющих токены MDM.
+func JWKSHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+		_ = json.NewEncoder(w).Encode(Keys.JWKS(time.Now()))
+	}
+}
+
+// MarshalPublicKey кодирует открытый ключ в PEM (PKIX).
+func MarshalPublicKey(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// ParsePublicKey разбирает PEM из MarshalPublicKey.
+func ParsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// sealPurposeSigningKeys — назначение шифрования закрытых ключей подписи.
+const sealPurposeSigningKeys = "signing-keys"
+
+// SealPrivateKey шифрует закрытый ключ (PKCS#8), чтобы дамп БД не раскрывал ключи подписи.
+func SealPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return Seal(sealPurposeSigningKeys, der)
+}
+
+// OpenPrivateKey расшифровывает ключ из SealPrivateKey.
+func OpenPrivateKey(sealed string) (crypto.Signer, error) {
+	der, err := Open(sealPurposeSigningKeys, sealed)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt private key: %w", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key is not a signer")
+	}
+	return signer, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Виды субъектов запроса.
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+	PrincipalDevice = "device"
+)
+
+// Права доступа. Пользователь получает их по роли, API-ключ — явным списком.
+const (
+	ScopeDevicesRead  = "devices:read"
+	ScopeDevicesWrite = "devices:write"
+	// ScopeDevicesWipe — стирание устройств и подтверждение чужих запросов на стирание.
+	ScopeDevicesWipe = "devices:wipe"
+	// ScopeDevicesRetire — вывод устройств из эксплуатации и их удаление.
+	ScopeDevicesRetire = "devices:retire"
+	ScopeAPIKeysManage = "api_keys:manage"
+	ScopeUsersManage   = "users:manage"
+	ScopeAuditRead     = "audit:read"
+)
+
+// KnownScopes — все права, которые можно выдать API-ключу.
+var KnownScopes = []string{ScopeDevicesRead, ScopeDevicesWrite, ScopeDevicesWipe, ScopeDevicesRetire, ScopeAPIKeysManage, ScopeUsersManage, ScopeAuditRead}
+
+// RoleScopes — права пользователей по роли.
+var RoleScopes = map[string][]string{
+	"admin": KnownScopes,
+	"user":  {ScopeDevicesRead, ScopeDevicesWrite},
+}
+
+// Principal — аутентифицированный субъект запроса: пользователь (JWT), API-ключ
+// или устройство (токен устройства).
+type Principal struct {
+	Kind string `json:"kind"`
+	// ID — id пользователя или API-ключа.
+	ID string `json:"id"`
+	// Name — имя пользователя или название ключа.
+	Name string `json:"name"`
+	// UserID — пользователь, от имени которого действует субъект: сам пользователь
+	// или владелец API-ключа; пусто для устройств и ключей без известного владельца.
+	UserID string   `json:"user_id,omitempty"`
+	Role   string   `json:"role,omitempty"`
+	Scopes []string `json:"scopes"`
+}
+
+// HasScope сообщает, есть ли у субъекта право scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+type principalCtxKey struct{}
+
+// WithPrincipal кладёт субъекта в контекст запроса.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, p)
+}
+
+// PrincipalFromContext возвращает субъекта запроса или nil для анонимного запроса.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalCtxKey{}).(*Principal)
+	return p
+}
+
+// ValidScope сообщает, что scope входит в KnownScopes.
+func ValidScope(scope string) bool {
+	return slices.Contains(KnownScopes, scope)
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Секреты, которые сервер должен уметь прочитать (ключи подписи, секреты TOTP),
+// хранятся в БД зашифрованными ключом из DATA_ENCRYPTION_KEY, а короткоживущие
+// значения (состояние входа через SSO, токен второго шага входа) подписываются
+// ключом из JWT_SECRET. Так смена JWT_SECRET не делает сохранённые секреты
+// нечитаемыми. Ключи выводятся отдельно для каждого назначения (purpose), так что
+// значение одного назначения нельзя подставить вместо другого.
+
+// DataEncryptionKey — ключ шифрования секретов в БД. Устанавливается при старте
+// из конфигурации (DATA_ENCRYPTION_KEY).
+var DataEncryptionKey []byte
+
+// SetDataEncryptionKey устанавливает ключ шифрования секретов в БД.
+func SetDataEncryptionKey(key string) {
+	DataEncryptionKey = []byte(key)
+}
+
+var (
+	// ErrInvalidSignedValue — подпись не сходится, значение повреждено или истекло.
+	ErrInvalidSignedValue = errors.New("invalid or expired signed value")
+	// ErrNoDataEncryptionKey — DATA_ENCRYPTION_KEY не задан, секреты в БД не зашифровать и не прочитать.
+	ErrNoDataEncryptionKey = errors.New("DATA_ENCRYPTION_KEY is not set")
+)
+
+// deriveKey выводит ключ назначения purpose из секрета secret.
+func deriveKey(secret []byte, purpose string) []byte {
+	sum := sha256.Sum256(append([]byte("mdm-"+purpose+":"), secret...))
+	return sum[:]
+}
+
+func sealingAEAD(purpose string) (cipher.AEAD, error) {
+	if len(DataEncryptionKey) == 0 {
+		return nil, ErrNoDataEncryptionKey
+	}
+	block, err := aes.NewCipher(deriveKey(DataEncryptionKey, purpose))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal шифрует данные AES-256-GCM ключом назначения purpose, выведенным из DATA_ENCRYPTION_KEY.
+func Seal(purpose string, plaintext []byte) (string, error) {
+	aead, err := sealingAEAD(purpose)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open расшифровывает данные из Seal. Ошибка означает, что значение повреждено
+// или зашифровано другим ключом.
+func Open(purpose, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := sealingAEAD(purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("sealed value is too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+// signedEnvelope — подписываемое значение со сроком действия.
+type signedEnvelope struct {
+	Value     json.RawMessage `json:"v"`
+	ExpiresAt int64           `json:"exp"`
+}
+
+// SignValue сериализует value в JSON и подписывает HMAC-SHA256 на ключе назначения
+// purpose. Значение не шифруется: в него нельзя класть секреты, которые не должен видеть клиент.
+func SignValue(purpose string, value interface{}, ttl time.Duration) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(signedEnvelope{Value: raw, ExpiresAt: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac, err := signatureOf(purpose, encoded)
+	if err != nil {
+		return "", err
+	}
+	return encoded + "." + mac, nil
+}
+
+// VerifyValue проверяет подпись и срок значения из SignValue и разбирает его в value.
+func VerifyValue(purpose, signed string, value interface{}) error {
+	encoded, mac, ok := strings.Cut(signed, ".")
+	if !ok {
+		return ErrInvalidSignedValue
+	}
+	expected, err := signatureOf(purpose, encoded)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(mac), []byte(expected)) {
+		return ErrInvalidSignedValue
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrInvalidSignedValue
+	}
+	var envelope signedEnvelope
+	if err := json.Unmarshal(payload, &envelope); err != nil || time.Now().Unix() > envelope.ExpiresAt {
+		return ErrInvalidSignedValue
+	}
+	if err := json.Unmarshal(envelope.Value, value); err != nil {
+		return ErrInvalidSignedValue
+	}
+	return nil
+}
+
+func signatureOf(purpose, encoded string) (string, error) {
+	if len(JWTSecret) == 0 {
+		return "", errors.New("JWT secret is not set")
+	}
+	mac := hmac.New(sha256.New, deriveKey(JWTSecret, "signed-"+purpose))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP (RFC 6238) — значения по умолчанию, которые понимают все
+// приложения-аутентификаторы: HMAC-SHA1, 6 цифр, шаг 30 секунд.
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew — сколько соседних шагов принимается из-за расхождения часов.
+	totpSkew = 1
+	// totpSecretBytes — длина секрета (160 бит, как рекомендует RFC 4226).
+	totpSecretBytes = 20
+)
+
+// SealPurposeTOTP — назначение шифрования секретов TOTP в БД (см. Seal).
+const SealPurposeTOTP = "totp"
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret генерирует секрет TOTP в base32, как его вводят в приложение вручную.
+func NewTOTPSecret() (string, error) {
+	buf := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI возвращает otpauth:// URI для QR-кода приложения-аутентификатора.
+func TOTPProvisioningURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{
+		"secret":    {secret},
+		"issuer":    {issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(int(totpPeriod.Seconds()))},
+	}
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep возвращает номер 30-секундного шага для момента t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// TOTPCode вычисляет код для шага step (RFC 4226, dynamic truncation).
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000), nil
+}
+
+// ValidateTOTP проверяет код с допуском ±totpSkew шагов. Шаги не позже lastStep
+// не принимаются: однажды использованный код нельзя предъявить повторно.
+// Возвращает шаг принятого кода.
+func ValidateTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	current := TOTPStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// NewRecoveryCodes генерирует n одноразовых кодов восстановления вида XXXXX-XXXXX (50 бит).
+func NewRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := totpEncoding.EncodeToString(buf)[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode нормализует код (регистр, дефисы, пробелы) и хеширует его для хранения.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashToken(normalized)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// TestTOTPCodeRFC6238 сверяет коды с тестовыми векторами RFC 6238 (SHA1, 8 цифр → последние 6).
+func TestTOTPCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	for unix, want := range map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	} {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("TOTPCode at %d = %s, %v; want %s", unix, got, err, want)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("NewTOTPSecret failed: %v", err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	code, _ := TOTPCode(secret, TOTPStep(now.Add(-30*time.Second)))
+
+	step, ok := ValidateTOTP(secret, code, now, 0)
+	if !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("Expected previous step to be accepted, got %d, %v", step, ok)
+	}
+	if _, ok := ValidateTOTP(secret, code, now, step); ok {
+		t.Error("Expected a used code to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(2*time.Minute), 0); ok {
+		t.Error("Expected an old code to be rejected")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now, 0); ok {
+		t.Error("Expected a short code to be rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("NewRecoveryCodes = %v, %v", codes, err)
+	}
+	if len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Errorf("Unexpected code format %q", codes[0])
+	}
+	if HashRecoveryCode(codes[0]) != HashRecoveryCode(" "+codes[0][:5]+codes[0][6:]+" ") {
+		t.Error("Expected hyphens and spaces to be ignored")
+	}
+}
+
+func TestSignValue(t *testing.T) {
+	SetJWTSecret("test-secret")
+	signed, err := SignValue("test", map[string]string{"user": "u-1"}, time.Minute)
+	if err != nil {
+		t.Fatalf("SignValue failed: %v", err)
+	}
+	var value map[string]string
+	if err := VerifyValue("test", signed, &value); err != nil || value["user"] != "u-1" {
+		t.Errorf("VerifyValue = %v, %v", value, err)
+	}
+	if err := VerifyValue("other", signed, &value); err == nil {
+		t.Error("Expected a value of another purpose to be rejected")
+	}
+	expired, _ := SignValue("test", "x", -time.Second)
+	if err := VerifyValue("test", expired, &value); err == nil {
+		t.Error("Expected an expired value to be rejected")
+	}
+}
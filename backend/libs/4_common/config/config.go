@@ -0,0 +1,296 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config — типизированная конфигурация backend'а.
+// Значения берутся (по возрастанию приоритета) из тегов default, файла
+// конфигурации, переменных окружения (тег env) и флагов командной строки (тег flag).
+// Поля с тегом secret скрываются в Redacted.
+type Config struct {
+	// Env не имеет значения по умолчанию: послабления режима разработки (случайный
+	// JWT_SECRET) включаются только явным APP_ENV=development.
+	Env string `env:"APP_ENV" flag:"env" desc:"режим работы: development или production"`
+
+	Log       LogConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
+	OIDC      OIDCConfig
+	Devices   DevicesConfig
+
+	// Warnings — замечания, найденные при загрузке; не мешают запуску.
+	Warnings []string `config:"-"`
+}
+
+type LogConfig struct {
+	Level     string `env:"LOG_LEVEL" flag:"log-level" default:"info" desc:"уровень логирования"`
+	ToConsole bool   `env:"LOG_TO_CONSOLE" flag:"log-to-console" desc:"человекочитаемый вывод логов вместо JSON"`
+}
+
+type ServerConfig struct {
+	Addr              string        `env:"BC_PORT" flag:"addr" default:":4000" desc:"адрес HTTP-сервера"`
+	ReadTimeout       time.Duration `env:"HTTP_READ_TIMEOUT" default:"15s"`
+	ReadHeaderTimeout time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s"`
+	WriteTimeout      time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout       time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s"`
+	ShutdownTimeout   time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" default:"30s" desc:"сколько ждать активные запросы после SIGTERM"`
+	TLSCertFile       string        `env:"TLS_CERT_FILE" flag:"tls-cert" desc:"файл сертификата, включает HTTPS"`
+	TLSKeyFile        string        `env:"TLS_KEY_FILE" flag:"tls-key" desc:"файл ключа сертификата"`
+	// IdempotencyTTL — сколько хранятся ответы на POST-запросы с Idempotency-Key.
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" default:"24h" desc:"срок хранения ответов для Idempotency-Key, 0 — отключить"`
+	// IdempotencyLease — сколько ключ занят выполняющимся запросом; если процесс
+	// упал, не дописав ответ, повтор возможен через это время.
+	IdempotencyLease time.Duration `env:"IDEMPOTENCY_LEASE" default:"2m" desc:"аренда Idempotency-Key на время выполнения запроса"`
+}
+
+type DatabaseConfig struct {
+	// postgresql://... — Postgres, sqlite://path/to/mdm.db — SQLite (локальная разработка без Docker).
+	URL string `env:"DATABASE_URL" flag:"database-url" secret:"url" desc:"строка подключения к БД"`
+	// AutoMigrate применяет миграции из backend/migration при старте.
+	AutoMigrate bool `env:"DB_AUTO_MIGRATE" flag:"migrate" default:"true" desc:"применять миграции при старте"`
+	// Реплики для чтения: списки и статусы идут на них, запись и транзакции — на основную БД.
+	ReplicaURLs []string `env:"DATABASE_REPLICA_URLS" secret:"url" desc:"строки подключения к репликам через запятую"`
+
+	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"10"`
+	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" default:"5m"`
+
+	SlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" default:"200ms" desc:"запросы дольше порога логируются как медленные, 0 — отключить"`
+	ConnectTimeout     time.Duration `env:"DB_CONNECT_TIMEOUT" default:"60s" desc:"сколько пытаться подключиться к БД при старте"`
+}
+
+type AuthConfig struct {
+	JWTSecret string `env:"JWT_SECRET" secret:"true" desc:"секрет подписи HS256 и короткоживущих значений (SSO, mfa_token)"`
+	// DataEncryptionKey шифрует секреты, которые хранятся в БД (секреты TOTP, ключи подписи JWT).
+	// Не выводится из JWT_SECRET, чтобы смена секрета JWT не делала их нечитаемыми.
+	// Обязателен, если в БД уже есть зашифрованные записи (проверяется при старте).
+	DataEncryptionKey string `env:"DATA_ENCRYPTION_KEY" secret:"true" desc:"ключ шифрования секретов в БД (TOTP, ключи подписи JWT)"`
+	// JWTAlgorithm — RS256 или EdDSA: токены подписываются ключами из БД (kid в заголовке),
+	// открытые ключи публикуются в /.well-known/jwks.json. HS256 — подпись общим секретом;
+	// остаётся по умолчанию, чтобы обновление не обрывало выданные ранее токены.
+	JWTAlgorithm string `env:"JWT_ALGORITHM" default:"HS256" desc:"алгоритм подписи JWT: RS256, EdDSA или HS256"`
+	// JWTKeyRotation — как часто выпускается новый ключ подписи.
+	JWTKeyRotation time.Duration `env:"JWT_KEY_ROTATION" default:"720h" desc:"период ротации ключа подписи JWT"`
+	// JWTKeyPublishAhead — за сколько до начала подписи новый ключ появляется в JWKS;
+	// должен превышать время кеширования JWKS у проверяющих сервисов.
+	JWTKeyPublishAhead time.Duration `env:"JWT_KEY_PUBLISH_AHEAD" default:"1h" desc:"за сколько до активации новый ключ публикуется в JWKS"`
+
+	// После MaxFailedLogins неудачных попыток подряд вход блокируется на LockoutDelay,
+	// каждая следующая неудача удваивает паузу, но не больше LockoutMaxDelay.
+	MaxFailedLogins int           `env:"LOGIN_MAX_FAILED_ATTEMPTS" default:"5" desc:"неудачных входов до блокировки, 0 — не блокировать"`
+	LockoutDelay    time.Duration `env:"LOGIN_LOCKOUT_DELAY" default:"30s" desc:"начальная длительность блокировки входа"`
+	LockoutMaxDelay time.Duration `env:"LOGIN_LOCKOUT_MAX_DELAY" default:"1h" desc:"максимальная длительность блокировки входа"`
+
+	// TwoFactorRequiredRoles — роли, которым вход по паролю без TOTP запрещён: без
+	// настроенной 2FA вход завершается только после её настройки. Пусто — 2FA по желанию.
+	TwoFactorRequiredRoles []string `env:"LOGIN_2FA_REQUIRED_ROLES" default:"admin" desc:"роли, для которых 2FA обязательна, через запятую"`
+	// TOTPIssuer — название сервиса в приложении-аутентификаторе.
+	TOTPIssuer string `env:"TOTP_ISSUER" default:"MDM" desc:"название сервиса в приложении-аутентификаторе"`
+}
+
+// RateLimitConfig — ограничения частоты запросов по группам маршрутов,
+// в формате "N/период" ("0" — без ограничения).
+type RateLimitConfig struct {
+	// Login — на IP и на имя пользователя для POST /login.
+	Login Rate `env:"RATE_LIMIT_LOGIN" default:"10/1m" desc:"лимит запросов /login на IP и на пользователя"`
+	// Device — на устройство для регистрации, heartbeat и статуса.
+	Device Rate `env:"RATE_LIMIT_DEVICE" default:"60/1m" desc:"лимит запросов устройства"`
+	// API — на IP для остальных (административных) запросов.
+	API Rate `env:"RATE_LIMIT_API" default:"300/1m" desc:"лимит запросов административного API на IP"`
+	// TrustProxy — брать IP клиента из X-Forwarded-For / X-Real-IP (только за доверенным прокси).
+	TrustProxy bool `env:"RATE_LIMIT_TRUST_PROXY" desc:"доверять X-Forwarded-For при определении IP"`
+}
+
+// OIDCConfig — вход в админ-панель через корпоративный IdP (OpenID Connect,
+// authorization code + PKCE). Пустой OIDC_ISSUER_URL отключает SSO; локальный
+// вход по паролю работает в любом случае.
+type OIDCConfig struct {
+	IssuerURL    string `env:"OIDC_ISSUER_URL" flag:"oidc-issuer" desc:"issuer IdP, включает вход через SSO"`
+	ClientID     string `env:"OIDC_CLIENT_ID" desc:"client_id приложения в IdP"`
+	ClientSecret string `env:"OIDC_CLIENT_SECRET" secret:"true" desc:"client_secret; для публичного клиента не нужен"`
+	// RedirectURL — адрес /auth/oidc/callback этого сервера, как он зарегистрирован в IdP.
+	RedirectURL string   `env:"OIDC_REDIRECT_URL" desc:"адрес callback, зарегистрированный в IdP"`
+	Scopes      []string `env:"OIDC_SCOPES" default:"openid,profile,email" desc:"запрашиваемые scope через запятую"`
+	// GroupsClaim — claim ID-токена со списком групп пользователя.
+	GroupsClaim string `env:"OIDC_GROUPS_CLAIM" default:"groups" desc:"claim с группами пользователя"`
+	// RoleMapping — группа IdP → роль MDM: "mdm-admins=admin,mdm-operators=user".
+	// Если пользователь состоит в нескольких группах, берётся самая сильная роль.
+	RoleMapping map[string]string `env:"OIDC_ROLE_MAPPING" desc:"соответствие групп IdP ролям MDM: группа=роль через запятую"`
+	// DefaultRole — роль пользователя без сопоставленных групп; пустая — такой вход запрещён.
+	DefaultRole string `env:"OIDC_DEFAULT_ROLE" desc:"роль для пользователей без сопоставленных групп"`
+	// PostLoginRedirect — страница админ-панели, куда после входа передаётся
+	// токен (во фрагменте #token=...). Пустой — callback отвечает JSON.
+	PostLoginRedirect string `env:"OIDC_POST_LOGIN_REDIRECT" desc:"страница админ-панели для возврата после входа"`
+}
+
+// DevicesConfig — параметры работы агентов на устройствах.
+type DevicesConfig struct {
+	// HeartbeatInterval — период heartbeat, который сервер сообщает агентам в ответе;
+	// группы устройств могут задать свой.
+	HeartbeatInterval time.Duration `env:"DEVICE_HEARTBEAT_INTERVAL" default:"10s" desc:"период heartbeat агентов по умолчанию"`
+	// WipeApprovalTTL — сколько запрос на стирание ждёт подтверждения вторым администратором.
+	WipeApprovalTTL time.Duration `env:"DEVICE_WIPE_APPROVAL_TTL" default:"24h" desc:"срок подтверждения стирания вторым администратором"`
+	// EnrollmentTokenTTL — сколько действует токен перерегистрации, выданный администратором.
+	EnrollmentTokenTTL time.Duration `env:"DEVICE_ENROLLMENT_TOKEN_TTL" default:"24h" desc:"срок действия токена перерегистрации устройства"`
+	// PurgeRetention — сколько удалённые устройства хранятся в архиве с историей
+	// до окончательного удаления; 0 — не удалять.
+	PurgeRetention time.Duration `env:"DEVICE_PURGE_RETENTION" default:"2160h" desc:"срок хранения удалённых устройств (0 — хранить всегда)"`
+	// TelemetryRetention — сколько хранятся снимки телеметрии, снятые агентами без связи;
+	// 0 — не удалять.
+	TelemetryRetention time.Duration `env:"DEVICE_TELEMETRY_RETENTION" default:"720h" desc:"срок хранения снимков телеметрии, снятых без связи (0 — хранить всегда)"`
+}
+
+// Enabled сообщает, настроен ли вход через OIDC.
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// minProductionSecretLength — минимальная длина JWT_SECRET в production
+// и DATA_ENCRYPTION_KEY в любом окружении.
+const minProductionSecretLength = 32
+
+// IsProduction сообщает, запущен ли сервис в production-режиме.
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// IsDevelopment сообщает, что режим разработки задан явно (APP_ENV=development).
+func (c *Config) IsDevelopment() bool {
+	return c.Env == EnvDevelopment
+}
+
+// Validate проверяет конфигурацию. В production обязательны секреты,
+// при явном APP_ENV=development недостающий JWT_SECRET заменяется случайным,
+// без APP_ENV он обязателен.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Env {
+	case "", EnvDevelopment, EnvProduction:
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("BC_PORT must not be empty"))
+	} else if !strings.Contains(c.Server.Addr, ":") {
+		// Допускаем форму "4000" наравне с ":4000".
+		c.Server.Addr = ":" + c.Server.Addr
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together"))
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.Server.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", c.Server.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.Server.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout},
+		{"IDEMPOTENCY_TTL", c.Server.IdempotencyTTL},
+		{"IDEMPOTENCY_LEASE", c.Server.IdempotencyLease},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", timeout.name))
+		}
+	}
+
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("DATABASE_URL is required"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS"))
+	}
+
+	switch c.Auth.JWTAlgorithm {
+	case "HS256":
+	case "RS256", "EdDSA":
+		if c.Auth.JWTKeyRotation <= 0 || c.Auth.JWTKeyPublishAhead < 0 || c.Auth.JWTKeyPublishAhead >= c.Auth.JWTKeyRotation {
+			errs = append(errs, errors.New("JWT_KEY_ROTATION must be positive and exceed JWT_KEY_PUBLISH_AHEAD"))
+		}
+		// Закрытые ключи подписи хранятся в БД зашифрованными этим ключом.
+		if c.Auth.DataEncryptionKey == "" {
+			errs = append(errs, fmt.Errorf("DATA_ENCRYPTION_KEY is required when JWT_ALGORITHM=%s", c.Auth.JWTAlgorithm))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("JWT_ALGORITHM must be RS256, EdDSA or HS256, got %q", c.Auth.JWTAlgorithm))
+	}
+	if c.Auth.MaxFailedLogins < 0 {
+		errs = append(errs, errors.New("LOGIN_MAX_FAILED_ATTEMPTS must not be negative"))
+	}
+	if c.Auth.LockoutDelay < 0 || c.Auth.LockoutMaxDelay < c.Auth.LockoutDelay {
+		errs = append(errs, errors.New("LOGIN_LOCKOUT_DELAY must not be negative or exceed LOGIN_LOCKOUT_MAX_DELAY"))
+	}
+
+	if c.Devices.HeartbeatInterval < time.Second {
+		errs = append(errs, errors.New("DEVICE_HEARTBEAT_INTERVAL must be at least 1s"))
+	}
+	if c.Devices.WipeApprovalTTL <= 0 {
+		errs = append(errs, errors.New("DEVICE_WIPE_APPROVAL_TTL must be positive"))
+	}
+	if c.Devices.EnrollmentTokenTTL <= 0 {
+		errs = append(errs, errors.New("DEVICE_ENROLLMENT_TOKEN_TTL must be positive"))
+	}
+	if c.Devices.PurgeRetention < 0 {
+		errs = append(errs, errors.New("DEVICE_PURGE_RETENTION must not be negative"))
+	}
+	if c.Devices.TelemetryRetention < 0 {
+		errs = append(errs, errors.New("DEVICE_TELEMETRY_RETENTION must not be negative"))
+	}
+
+	if c.OIDC.Enabled() {
+		if c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "" {
+			errs = append(errs, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC_ISSUER_URL is set"))
+		}
+		if len(c.OIDC.RoleMapping) == 0 && c.OIDC.DefaultRole == "" {
+			errs = append(errs, errors.New("OIDC_ROLE_MAPPING or OIDC_DEFAULT_ROLE is required when OIDC_ISSUER_URL is set"))
+		}
+	}
+
+	switch {
+	case c.IsProduction() && c.Auth.JWTSecret == "":
+		errs = append(errs, errors.New("JWT_SECRET is required in production"))
+	case c.IsProduction() && len(c.Auth.JWTSecret) < minProductionSecretLength:
+		errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d characters in production", minProductionSecretLength))
+	case !c.IsDevelopment() && c.Auth.JWTSecret == "":
+		errs = append(errs, errors.New("JWT_SECRET is required unless APP_ENV=development is set"))
+	}
+	if c.Auth.DataEncryptionKey != "" && len(c.Auth.DataEncryptionKey) < minProductionSecretLength {
+		errs = append(errs, fmt.Errorf("DATA_ENCRYPTION_KEY must be at least %d characters", minProductionSecretLength))
+	}
+
+	return errors.Join(errs...)
+}
+
+// applyDevelopmentDefaults подставляет значения, допустимые только при явном APP_ENV=development.
+func (c *Config) applyDevelopmentDefaults() error {
+	if !c.IsDevelopment() || c.Auth.JWTSecret != "" {
+		return nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	c.Auth.JWTSecret = hex.EncodeToString(buf)
+	c.Warnings = append(c.Warnings, "JWT_SECRET is not set, using a random secret: tokens will not survive a restart")
+	return nil
+}
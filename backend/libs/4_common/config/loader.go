@@ -0,0 +1,346 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// DefaultConfigFile — файл, который подхватывается из текущей директории,
+// если путь к конфигурации не задан явно. Его отсутствие не является ошибкой.
+const DefaultConfigFile = ".env"
+
+const redactedValue = "<redacted>"
+
+// field описывает одно поле конфигурации, найденное по тегам.
+type field struct {
+	env    string
+	flag   string
+	def    string
+	secret string
+	desc   string
+	value  reflect.Value
+}
+
+// Load собирает конфигурацию из окружения процесса. args — аргументы командной
+// строки без имени программы; помимо флагов полей поддерживается --config <file>.
+func Load(args []string) (*Config, error) {
+	return LoadFrom(args, os.LookupEnv)
+}
+
+// LoadFrom работает как Load, но берёт переменные окружения из lookupEnv.
+func LoadFrom(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := &Config{}
+	fields := collectFields(reflect.ValueOf(cfg).Elem())
+
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := setValue(f, f.def); err != nil {
+			return nil, err
+		}
+	}
+
+	fs := flag.NewFlagSet("backend-api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", "", "путь к файлу конфигурации (формат .env)")
+	flagValues := make(map[string]*flagValue)
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		fv := &flagValue{isBool: f.value.Kind() == reflect.Bool}
+		flagValues[f.flag] = fv
+		fs.Var(fv, f.flag, f.desc)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	fileValues, err := readConfigFile(*configFile, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value, ok := fileValues[f.env]; ok {
+			if err := setValue(f, value); err != nil {
+				return nil, err
+			}
+		}
+		if value, ok := lookupEnv(f.env); ok {
+			if err := setValue(f, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, f := range fields {
+		if fv, ok := flagValues[f.flag]; ok && fv.set {
+			if err := setValue(f, fv.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyDevelopmentDefaults(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfigFile читает файл конфигурации: путь из --config, затем CONFIG_FILE,
+// затем ENV_PATH (для совместимости) и, наконец, необязательный .env в текущей директории.
+func readConfigFile(path string, lookupEnv func(string) (string, bool)) (map[string]string, error) {
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path == "" {
+		path, _ = lookupEnv("ENV_PATH")
+	}
+	if path == "" {
+		if _, err := os.Stat(DefaultConfigFile); err != nil {
+			return nil, nil
+		}
+		path = DefaultConfigFile
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func collectFields(v reflect.Value) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Tag.Get("config") == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct && !isScalar(sf.Type) {
+			fields = append(fields, collectFields(fv)...)
+			continue
+		}
+		fields = append(fields, field{
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret"),
+			desc:   sf.Tag.Get("desc"),
+			value:  fv,
+		})
+	}
+	return fields
+}
+
+// isScalar сообщает, что поле-структура задаётся одной строкой (например, config.Rate).
+func isScalar(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem())
+}
+
+func setValue(f field, raw string) error {
+	v := f.value
+	invalid := func(err error) error {
+		return fmt.Errorf("%s: invalid value %q: %w", f.env, raw, err)
+	}
+
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(raw)); err != nil {
+			return invalid(err)
+		}
+		return nil
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid(err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return invalid(errors.New("unsupported slice type"))
+		}
+		items := splitList(raw)
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return invalid(errors.New("unsupported map type"))
+		}
+		m := make(map[string]string)
+		for _, item := range splitList(raw) {
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return invalid(errors.New("expected key=value pairs"))
+			}
+			m[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return invalid(fmt.Errorf("unsupported type %s", v.Type()))
+	}
+	return nil
+}
+
+// splitList разбирает список значений, разделённых запятыми.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return time.Duration(v.Int()).String()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		return strings.Join(v.Interface().([]string), ",")
+	case reflect.Map:
+		m := v.Interface().(map[string]string)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+m[k])
+		}
+		return strings.Join(pairs, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// Redacted возвращает конфигурацию в формате .env (KEY=value), пригодном
+// для повторного использования как файл конфигурации. Секреты скрыты,
+// у строк подключения скрывается только пароль.
+func (c *Config) Redacted() string {
+	var sb strings.Builder
+	for _, f := range collectFields(reflect.ValueOf(c).Elem()) {
+		if f.env == "" {
+			continue
+		}
+		value := formatValue(f.value)
+		switch {
+		case value == "":
+		case f.secret == "url" && f.value.Kind() == reflect.Slice:
+			urls := make([]string, 0, f.value.Len())
+			for _, u := range f.value.Interface().([]string) {
+				urls = append(urls, redactURL(u))
+			}
+			value = strings.Join(urls, ",")
+		case f.secret == "url":
+			value = redactURL(value)
+		case f.secret != "":
+			value = redactedValue
+		}
+		if f.desc != "" {
+			fmt.Fprintf(&sb, "# %s\n", f.desc)
+		}
+		fmt.Fprintf(&sb, "%s=%s\n", f.env, strconv.Quote(value))
+	}
+	return sb.String()
+}
+
+// secretURLParams — параметры строки подключения, которые содержат пароли
+// (libpq принимает пароль и в query: ?password=...).
+var secretURLParams = []string{"password", "sslpassword"}
+
+// redactURL скрывает пароль в строке подключения, в том числе в параметрах
+// запроса; если строку не удаётся разобрать как URL, она скрывается целиком.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return redactedValue
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return redactedValue
+	}
+	redacted := false
+	for key := range query {
+		if slices.Contains(secretURLParams, strings.ToLower(key)) {
+			query.Set(key, "xxxxx")
+			redacted = true
+		}
+	}
+	if redacted {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// flagValue запоминает, был ли флаг передан явно, чтобы не перетирать
+// значения из окружения значениями флагов по умолчанию.
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *flagValue) String() string { return f.value }
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool { return f.isBool }
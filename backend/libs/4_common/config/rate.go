@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate — ограничение частоты запросов в виде "N/период", например "10/1m".
+// Нулевое значение означает «без ограничения».
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRate разбирает строку вида "10/1m". Пустая строка и "0" — без ограничения.
+func ParseRate(raw string) (Rate, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "0" {
+		return Rate{}, nil
+	}
+	requestsStr, perStr, ok := strings.Cut(raw, "/")
+	if !ok {
+		return Rate{}, fmt.Errorf("expected N/duration, got %q", raw)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(requestsStr))
+	if err != nil || requests <= 0 {
+		return Rate{}, fmt.Errorf("invalid request count in %q", raw)
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(perStr))
+	if err != nil || per <= 0 {
+		return Rate{}, fmt.Errorf("invalid period in %q", raw)
+	}
+	return Rate{Requests: requests, Per: per}, nil
+}
+
+// Unlimited сообщает, что ограничение не задано.
+func (r Rate) Unlimited() bool {
+	return r.Requests <= 0 || r.Per <= 0
+}
+
+func (r Rate) String() string {
+	if r.Unlimited() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", r.Requests, r.Per)
+}
+
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
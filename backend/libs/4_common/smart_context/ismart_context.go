@@ -36,6 +36,10 @@ type ISmartContext interface {
 	WithDB(db *gorm.DB) ISmartContext
 	GetDB() *gorm.DB
 
+	// RunInTx выполняет fn в транзакции. Внутри fn все репозитории должны брать
+	// соединение из переданного tx. Вложенный вызов RunInTx создаёт savepoint.
+	RunInTx(fn func(tx ISmartContext) error) error
+
 	// Метод для получения стандартного context.Context
 	WithContext(ctx context.Context) ISmartContext
 	GetContext() context.Context
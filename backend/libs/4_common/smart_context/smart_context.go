@@ -2,6 +2,7 @@ package smart_context
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"mdm/libs/4_common/types"
 	"os"
@@ -220,6 +221,19 @@ func (sc *SmartContext) GetDB() *gorm.DB {
 	return tx
 }
 
+// RunInTx открывает транзакцию на текущем DB и передаёт в fn контекст, в котором
+// GetDB возвращает эту транзакцию. Если контекст уже находится в транзакции,
+// GORM создаёт savepoint, и ошибка fn откатывает только вложенный шаг.
+func (sc *SmartContext) RunInTx(fn func(tx ISmartContext) error) error {
+	db := sc.GetDB()
+	if db == nil {
+		return errors.New("db is not set in smart context")
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(sc.WithDB(tx))
+	})
+}
+
 func getLogLevel() zapcore.Level {
 	logLevel := os.Getenv("LOG_LEVEL")
 	switch strings.ToLower(logLevel) {
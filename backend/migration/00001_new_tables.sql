@@ -1,4 +1,4 @@
-CREATE TABLE device (
+CREATE TABLE IF NOT EXISTS device (
     id TEXT PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
     device_id TEXT NOT NULL,
     camera_enabled BOOLEAN NOT NULL DEFAULT false,
@@ -11,7 +11,7 @@ CREATE TABLE device (
 	updated_at TIMESTAMP
 );
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id TEXT PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
     username VARCHAR(255) UNIQUE NOT NULL,
     password VARCHAR(255) NOT NULL,
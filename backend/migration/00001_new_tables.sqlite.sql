@@ -0,0 +1,24 @@
+-- Вариант 00001 для SQLite: без gen_random_uuid() (id генерируется в Go)
+-- и с TIMESTAMP вместо TIMESTAMPTZ, который драйвер не умеет читать как время.
+CREATE TABLE IF NOT EXISTS device (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id TEXT NOT NULL,
+    camera_enabled BOOLEAN NOT NULL DEFAULT false,
+    microphone_enabled BOOLEAN NOT NULL DEFAULT false,
+    bluetooth_enabled BOOLEAN NOT NULL DEFAULT false,
+    os_version TEXT,
+    battery_level INT,
+    last_heartbeat TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP
+);
+
+-- role: например, 'admin' или 'user'
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY NOT NULL,
+    username VARCHAR(255) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Версия настроек устройства для оптимистичной блокировки (If-Match / ETag).
+ALTER TABLE device ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- Вариант 00002 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
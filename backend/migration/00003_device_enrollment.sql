@@ -0,0 +1,20 @@
+-- Из-за гонки при регистрации могли появиться дубликаты device_id. Миграция
+-- их не удаляет, а останавливается со списком: какую запись оставить, решает
+-- администратор (DELETE лишних строк вручную), после чего миграция повторяется.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(device_id, ', ') INTO duplicates
+    FROM (SELECT device_id FROM device GROUP BY device_id HAVING COUNT(*) > 1) AS d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'duplicate device_id values, remove extra rows before migrating: %', duplicates;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS device_device_id_key ON device (device_id);
+
+-- Статус регистрации (active / wiped) и хеш токена, выданного устройству при регистрации.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64);
+ALTER TABLE device ADD COLUMN IF NOT EXISTS enrolled_at TIMESTAMP;
@@ -0,0 +1,8 @@
+-- Вариант 00003 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+-- Дубликаты device_id не удаляются: при их наличии создание уникального индекса
+-- завершится ошибкой UNIQUE constraint failed, и лишние строки нужно удалить вручную.
+CREATE UNIQUE INDEX IF NOT EXISTS device_device_id_key ON device (device_id);
+
+ALTER TABLE device ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'active';
+ALTER TABLE device ADD COLUMN token_hash VARCHAR(64);
+ALTER TABLE device ADD COLUMN enrolled_at TIMESTAMP;
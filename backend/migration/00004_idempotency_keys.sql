@@ -0,0 +1,17 @@
+-- Ответы на запросы с заголовком Idempotency-Key. status_code = 0 — запрос ещё выполняется.
+-- Ответ привязан к субъекту запроса (principal, пусто — анонимный), а не только
+-- к ключу: чужой ключ не отдаёт чужой ответ. expires_at у выполняющегося
+-- запроса — короткая аренда, у готового ответа — срок хранения.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    principal VARCHAR(255) NOT NULL DEFAULT '',
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    response_headers TEXT,
+    response_body TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (principal, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,3 @@
+-- Счётчик неудачных входов подряд и время, до которого вход заблокирован.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+-- Вариант 00005 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE users ADD COLUMN failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
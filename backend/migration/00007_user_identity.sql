@@ -0,0 +1,7 @@
+-- Источник учётной записи: local — вход по паролю из users.password,
+-- oidc — вход через корпоративный IdP (пароль пустой, локальный вход невозможен).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_provider VARCHAR(32) NOT NULL DEFAULT 'local';
+-- external_subject — claim sub пользователя у IdP, по нему учётная запись находится при следующем входе.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_subject VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_subject_key ON users (auth_provider, external_subject) WHERE auth_provider <> 'local';
@@ -0,0 +1,5 @@
+-- Вариант 00007 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE users ADD COLUMN auth_provider VARCHAR(32) NOT NULL DEFAULT 'local';
+ALTER TABLE users ADD COLUMN external_subject VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_subject_key ON users (auth_provider, external_subject) WHERE auth_provider <> 'local';
@@ -0,0 +1,13 @@
+-- Ключи подписи JWT (RS256/EdDSA). id — kid в заголовке токена.
+-- private_key зашифрован (AES-GCM, ключ выводится из DATA_ENCRYPTION_KEY), public_key — PEM
+-- для JWKS. Новый ключ публикуется заранее и начинает подписывать с activates_at;
+-- заменённый ключ остаётся в JWKS до expires_at, пока действуют подписанные им токены.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    id VARCHAR(64) PRIMARY KEY NOT NULL,
+    algorithm VARCHAR(16) NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
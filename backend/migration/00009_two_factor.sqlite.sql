@@ -0,0 +1,18 @@
+-- Вариант 00009 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+-- Двухфакторная аутентификация (TOTP). totp_secret зашифрован ключом из DATA_ENCRYPTION_KEY;
+-- секрет без totp_enabled — начатая, но не подтверждённая настройка.
+-- totp_last_step — шаг последнего принятого кода: повторно код не принимается.
+ALTER TABLE users ADD COLUMN totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+-- Одноразовые коды восстановления (хранится только SHA-256).
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id TEXT PRIMARY KEY NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
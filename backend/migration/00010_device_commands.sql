@@ -0,0 +1,17 @@
+-- Очередь команд устройствам. Команда доставляется в ответе на heartbeat, пока
+-- агент не сообщит результат (at-least-once: агент отбрасывает повторы по id).
+-- status: pending → delivered → succeeded | failed.
+CREATE TABLE IF NOT EXISTS device_commands (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL DEFAULT '{}',
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    result TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS device_commands_device_id_status_idx ON device_commands (device_id, status);
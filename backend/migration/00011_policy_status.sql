@@ -0,0 +1,6 @@
+-- Результат применения политики на устройстве: агент сообщает версию настроек,
+-- которую применил, и ошибки применения (policy_status: pending | applied | failed).
+ALTER TABLE device ADD COLUMN IF NOT EXISTS policy_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE device ADD COLUMN IF NOT EXISTS policy_status VARCHAR(32) NOT NULL DEFAULT 'pending';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS policy_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS policy_reported_at TIMESTAMP;
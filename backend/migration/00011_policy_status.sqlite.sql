@@ -0,0 +1,5 @@
+-- Вариант 00011 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN policy_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE device ADD COLUMN policy_status VARCHAR(32) NOT NULL DEFAULT 'pending';
+ALTER TABLE device ADD COLUMN policy_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN policy_reported_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Последняя телеметрия агента (JSON: заряд, ОС, uptime, диск, память) и время её получения.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS telemetry TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS telemetry_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Вариант 00012 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN telemetry TEXT NOT NULL DEFAULT '{}';
+ALTER TABLE device ADD COLUMN telemetry_at TIMESTAMP;
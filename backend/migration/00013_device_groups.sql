@@ -0,0 +1,13 @@
+-- Группы устройств. heartbeat_interval_seconds переопределяет период heartbeat,
+-- который сервер сообщает агентам группы (0 — период по умолчанию, HEARTBEAT_INTERVAL).
+CREATE TABLE IF NOT EXISTS device_groups (
+    id TEXT PRIMARY KEY NOT NULL,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    heartbeat_interval_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Пустой group_id — устройство вне групп.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS group_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS device_group_id_idx ON device (group_id);
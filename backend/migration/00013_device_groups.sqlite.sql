@@ -0,0 +1,11 @@
+-- Вариант 00013 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+CREATE TABLE IF NOT EXISTS device_groups (
+    id TEXT PRIMARY KEY NOT NULL,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    heartbeat_interval_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE device ADD COLUMN group_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS device_group_id_idx ON device (group_id);
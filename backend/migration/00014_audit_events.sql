@@ -0,0 +1,13 @@
+-- Журнал изменений через административный API: кто, что и с каким итогом.
+-- Тело запроса не сохраняется: в нём бывают пароли и ключи.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id TEXT PRIMARY KEY NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    method VARCHAR(16) NOT NULL,
+    path TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    remote_addr VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
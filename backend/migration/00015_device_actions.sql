@@ -0,0 +1,27 @@
+-- Действия над устройством: блокировка, разблокировка и стирание. Действие
+-- выполняется командой агенту (command_id); стирание с подтверждением вторым
+-- администратором сначала ждёт решения.
+-- status: pending_approval → issued | rejected | expired; lock/unlock сразу issued.
+-- Правило двух лиц сверяет пользователей, а не имена субъектов:
+-- requested_by_user_id — пользователь, запросивший действие сам или через свой ключ.
+CREATE TABLE IF NOT EXISTS device_actions (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    payload TEXT NOT NULL DEFAULT '{}',
+    status VARCHAR(32) NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    requested_by_user_id TEXT NOT NULL DEFAULT '',
+    approved_by VARCHAR(255) NOT NULL DEFAULT '',
+    command_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    decided_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS device_actions_device_id_idx ON device_actions (device_id, created_at);
+CREATE INDEX IF NOT EXISTS device_actions_status_idx ON device_actions (status);
+
+-- owner_id — пользователь, выпустивший API-ключ (ключ действует от его имени).
+-- Пусто — владелец неизвестен (ключи, выпущенные до этой миграции).
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Вывод устройства из эксплуатации: retired_at — когда и retired_by — кем
+-- (токен отозван, история сохраняется); deleted_at — мягкое удаление,
+-- после срока хранения запись удаляется окончательно вместе с историей.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP;
+ALTER TABLE device ADD COLUMN IF NOT EXISTS retired_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS device_deleted_at_idx ON device (deleted_at);
@@ -0,0 +1,5 @@
+-- Вариант 00016 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN retired_at TIMESTAMP;
+ALTER TABLE device ADD COLUMN retired_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS device_deleted_at_idx ON device (deleted_at);
@@ -0,0 +1,31 @@
+-- Вариант 00017 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN apps_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE device ADD COLUMN apps_reported_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS device_apps (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    package_name VARCHAR(255) NOT NULL,
+    version VARCHAR(128) NOT NULL DEFAULT '',
+    installer VARCHAR(64) NOT NULL DEFAULT '',
+    installed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS device_apps_device_package_idx ON device_apps (device_id, package_name);
+CREATE INDEX IF NOT EXISTS device_apps_package_idx ON device_apps (package_name);
+
+-- event: installed | updated | removed; previous_version — версия до обновления или удаления.
+CREATE TABLE IF NOT EXISTS device_app_events (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    package_name VARCHAR(255) NOT NULL,
+    event VARCHAR(16) NOT NULL,
+    version VARCHAR(128) NOT NULL DEFAULT '',
+    previous_version VARCHAR(128) NOT NULL DEFAULT '',
+    installer VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS device_app_events_device_idx ON device_app_events (device_id, created_at);
+CREATE INDEX IF NOT EXISTS device_app_events_package_idx ON device_app_events (package_name, created_at);
@@ -0,0 +1,5 @@
+-- Одноразовый токен перерегистрации, который выдаёт администратор: без него
+-- стёртое устройство или устройство без токена зарегистрировать заново нельзя.
+-- Хранится только SHA-256 хеш; enrollment_expires_at — до какого момента он действует.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS enrollment_token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN IF NOT EXISTS enrollment_expires_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Вариант 00018 для SQLite: ADD COLUMN IF NOT EXISTS не поддерживается.
+ALTER TABLE device ADD COLUMN enrollment_token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE device ADD COLUMN enrollment_expires_at TIMESTAMP;
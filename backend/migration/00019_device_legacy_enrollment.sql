@@ -0,0 +1,6 @@
+-- Устройства, зарегистрированные до появления токенов устройств (token_hash пустой),
+-- получают токен при следующей регистрации без токена перерегистрации, но только
+-- в течение 30 дней после обновления. Позже — только через токен от администратора.
+ALTER TABLE device ADD COLUMN IF NOT EXISTS legacy_enrollment_until TIMESTAMP;
+UPDATE device SET legacy_enrollment_until = CURRENT_TIMESTAMP + INTERVAL '30 days'
+WHERE (token_hash IS NULL OR token_hash = '') AND status <> 'wiped' AND retired_at IS NULL;
@@ -0,0 +1,4 @@
+-- Вариант 00019 для SQLite: ADD COLUMN IF NOT EXISTS и INTERVAL не поддерживаются.
+ALTER TABLE device ADD COLUMN legacy_enrollment_until TIMESTAMP;
+UPDATE device SET legacy_enrollment_until = datetime('now', '+30 days')
+WHERE (token_hash IS NULL OR token_hash = '') AND status <> 'wiped' AND retired_at IS NULL;
@@ -0,0 +1,3 @@
+-- Каждая аренда ключа получает свою метку reservation: Complete и Release
+-- запроса, аренда которого истекла и была передана повтору, не затрагивают чужую запись.
+ALTER TABLE idempotency_keys ADD COLUMN reservation VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Снимки телеметрии, снятые агентом без связи с сервером. Агент копит их в
+-- локальном состоянии и присылает с первым heartbeat после восстановления связи;
+-- последняя телеметрия по-прежнему хранится в device.telemetry. Уникальный индекс
+-- не даёт повтору heartbeat записать снимок дважды.
+CREATE TABLE IF NOT EXISTS device_telemetry_snapshots (
+    id TEXT PRIMARY KEY NOT NULL,
+    device_id VARCHAR(255) NOT NULL,
+    telemetry TEXT NOT NULL DEFAULT '{}',
+    collected_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS device_telemetry_snapshots_device_idx ON device_telemetry_snapshots (device_id, collected_at);
+CREATE INDEX IF NOT EXISTS device_telemetry_snapshots_collected_at_idx ON device_telemetry_snapshots (collected_at);
@@ -0,0 +1,11 @@
+// Package migration содержит SQL-миграции схемы БД.
+//
+// Файлы называются NNNNN_описание.sql и применяются по возрастанию номера.
+// Если для диалекта нужен другой синтаксис, рядом кладётся файл
+// NNNNN_описание.<диалект>.sql (например, .sqlite.sql) — он используется вместо общего.
+package migration
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,835 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+func devicesCommand() *command {
+	return &command{
+		name:    "devices",
+		summary: "Устройства: поиск, состояние, настройки",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "Список устройств с фильтрами",
+				define: func(fs *flag.FlagSet) action {
+					search := fs.String("search", "", "Подстрока идентификатора устройства")
+					camera := fs.String("camera", "", "Только с камерой on или off")
+					microphone := fs.String("microphone", "", "Только с микрофоном on или off")
+					bluetooth := fs.String("bluetooth", "", "Только с Bluetooth on или off")
+					seenSince := fs.String("seen-since", "", "Только приславшие heartbeat после момента: RFC 3339 или давность вроде 1h")
+					archived := fs.Bool("archived", false, "Выведенные из эксплуатации устройства вместо действующих")
+					limit := fs.Int("limit", 0, "Не больше стольких устройств")
+					offset := fs.Int("offset", 0, "Пропустить столько устройств (только вместе с --limit)")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 0, "none"); err != nil {
+							return err
+						}
+						filter := mdmclient.DeviceFilter{Search: *search, Archived: *archived, Limit: *limit, Offset: *offset}
+						var err error
+						if filter.CameraEnabled, err = parseSwitchFilter("camera", *camera); err != nil {
+							return err
+						}
+						if filter.MicrophoneEnabled, err = parseSwitchFilter("microphone", *microphone); err != nil {
+							return err
+						}
+						if filter.BluetoothEnabled, err = parseSwitchFilter("bluetooth", *bluetooth); err != nil {
+							return err
+						}
+						if filter.SeenSince, err = parseSince(*seenSince, a.now()); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						devices, err := client.ListDevices(ctx, filter)
+						if err != nil {
+							return err
+						}
+						return a.print(devices, devicesTable(devices))
+					}
+				},
+			},
+			{
+				name:    "get",
+				args:    "<device-id>",
+				summary: "Состояние устройства, политика и телеметрия",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						device, err := client.GetDevice(ctx, args[0])
+						if err != nil {
+							return err
+						}
+						return a.print(device, deviceTable(device))
+					}
+				},
+			},
+			{
+				name:    "set",
+				args:    "<device-id> <настройка>=on|off...",
+				summary: "Включить или выключить camera, microphone, bluetooth",
+				define: func(fs *flag.FlagSet) action {
+					ifVersion := fs.Int64("if-version", 0, "Применить, только если версия настроек устройства такая (иначе конфликт)")
+					return func(ctx context.Context, a *app, args []string) error {
+						if len(args) < 2 {
+							return usagef("expected arguments: <device-id> <setting>=on|off...")
+						}
+						changes, err := parseSettings(args[1:])
+						if err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						var device *mdmclient.Device
+						version := *ifVersion
+						for _, change := range changes {
+							if device, err = client.SetSetting(ctx, args[0], change.setting, change.enabled, version); err != nil {
+								return fmt.Errorf("set %s: %w", change.setting, err)
+							}
+							// Следующее изменение — поверх только что сделанного.
+							if version > 0 {
+								version = device.Version
+							}
+						}
+						return a.print(device, deviceTable(device))
+					}
+				},
+			},
+			{
+				name:    "lock",
+				args:    "<device-id>",
+				summary: "Заблокировать устройство",
+				define: func(fs *flag.FlagSet) action {
+					message := fs.String("message", "", "Текст на экране блокировки")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						created, err := client.LockDevice(ctx, args[0], *message)
+						if err != nil {
+							return err
+						}
+						return a.print(created, actionsTable([]*mdmclient.DeviceAction{created}))
+					}
+				},
+			},
+			{
+				name:    "unlock",
+				args:    "<device-id>",
+				summary: "Снять блокировку с устройства",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						created, err := client.UnlockDevice(ctx, args[0])
+						if err != nil {
+							return err
+						}
+						return a.print(created, actionsTable([]*mdmclient.DeviceAction{created}))
+					}
+				},
+			},
+			{
+				name:    "wipe",
+				args:    "<device-id>",
+				summary: "Стереть устройство: с повторным вводом пароля или подтверждением другим администратором",
+				define: func(fs *flag.FlagSet) action {
+					requestApproval := fs.Bool("request-approval", false, "Не вводить пароль, а отправить запрос на подтверждение другому администратору")
+					passwordStdin := fs.Bool("password-stdin", false, "Прочитать пароль из первой строки stdin")
+					otp := fs.String("otp", "", "Код из приложения-аутентификатора, если включена 2FA")
+					recoveryCode := fs.String("recovery-code", "", "Код восстановления вместо --otp")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						if *requestApproval && (*passwordStdin || *otp != "" || *recoveryCode != "") {
+							return usagef("--request-approval cannot be combined with --password-stdin, --otp or --recovery-code")
+						}
+						var password string
+						if !*requestApproval {
+							var err error
+							if *passwordStdin {
+								password, err = a.readLine()
+							} else {
+								password, err = a.promptSecret(fmt.Sprintf("Стирание %s необратимо. Пароль для подтверждения: ", args[0]))
+							}
+							if err != nil {
+								return err
+							}
+							if password == "" {
+								return errors.New("password is required; use --request-approval to ask another administrator instead")
+							}
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						created, err := client.WipeDevice(ctx, args[0], password, *otp, *recoveryCode)
+						if err != nil {
+							return err
+						}
+						if created.Status == mdmclient.ActionPendingApproval {
+							fmt.Fprintf(a.stderr, "Запрос %s ждёт подтверждения: mdmctl actions approve %s\n", created.ID, created.ID)
+						}
+						return a.print(created, actionsTable([]*mdmclient.DeviceAction{created}))
+					}
+				},
+			},
+			{
+				name:    "retire",
+				args:    "<device-id>",
+				summary: "Вывести устройство из эксплуатации: отозвать токен, оставить историю в архиве",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						device, err := client.RetireDevice(ctx, args[0])
+						if err != nil {
+							return err
+						}
+						return a.print(device, deviceTable(device))
+					}
+				},
+			},
+			{
+				name:    "enrollment-token",
+				args:    "<device-id>",
+				summary: "Выдать одноразовый токен перерегистрации стёртого устройства (--enrollment-token агента)",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						token, err := client.IssueEnrollmentToken(ctx, args[0])
+						if err != nil {
+							return err
+						}
+						return a.print(token, enrollmentTokenTable(token))
+					}
+				},
+			},
+			{
+				name:    "delete",
+				args:    "<device-id>",
+				summary: "Удалить устройство; история стирается после срока хранения",
+				define: func(fs *flag.FlagSet) action {
+					yes := fs.Bool("yes", false, "Не спрашивать подтверждение")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						if !*yes {
+							answer, err := a.prompt(fmt.Sprintf("Устройство %s будет выведено из эксплуатации и удалено. Введите его идентификатор для подтверждения: ", args[0]))
+							if err != nil {
+								return err
+							}
+							if answer != args[0] {
+								return errors.New("confirmation does not match the device id; nothing deleted")
+							}
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						return client.DeleteDevice(ctx, args[0])
+					}
+				},
+			},
+		},
+	}
+}
+
+// actionsCommand — история блокировок и стираний и подтверждение запросов на стирание.
+func actionsCommand() *command {
+	decide := func(name, summary string, call func(*mdmclient.Client, context.Context, string) (*mdmclient.DeviceAction, error)) *command {
+		return &command{
+			name:    name,
+			args:    "<action-id>",
+			summary: summary,
+			define: func(fs *flag.FlagSet) action {
+				return func(ctx context.Context, a *app, args []string) error {
+					if err := exactArgs(args, 1, "<action-id>"); err != nil {
+						return err
+					}
+					client, err := a.client(true)
+					if err != nil {
+						return err
+					}
+					decided, err := call(client, ctx, args[0])
+					if err != nil {
+						return err
+					}
+					return a.print(decided, actionsTable([]*mdmclient.DeviceAction{decided}))
+				}
+			},
+		}
+	}
+	return &command{
+		name:    "actions",
+		summary: "Блокировки и стирания устройств, подтверждение стирания",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "Действия над устройствами, новые первыми",
+				define: func(fs *flag.FlagSet) action {
+					deviceID := fs.String("device", "", "Только действия над этим устройством")
+					pending := fs.Bool("pending", false, "Только запросы, ждущие подтверждения")
+					limit := fs.Int("limit", 0, "Не больше стольких действий")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 0, "none"); err != nil {
+							return err
+						}
+						query := mdmclient.ActionQuery{DeviceID: *deviceID, Limit: *limit}
+						if *pending {
+							query.Status = mdmclient.ActionPendingApproval
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						actions, err := client.ListActions(ctx, query)
+						if err != nil {
+							return err
+						}
+						return a.print(actions, actionsTable(actions))
+					}
+				},
+			},
+			decide("approve", "Подтвердить чужой запрос на стирание", (*mdmclient.Client).ApproveAction),
+			decide("reject", "Отклонить запрос на стирание", (*mdmclient.Client).RejectAction),
+		},
+	}
+}
+
+type settingChange struct {
+	setting string
+	enabled bool
+}
+
+// parseSettings разбирает аргументы вида camera=off.
+func parseSettings(args []string) ([]settingChange, error) {
+	changes := make([]settingChange, 0, len(args))
+	for _, arg := range args {
+		setting, value, ok := strings.Cut(arg, "=")
+		switch setting {
+		case mdmclient.SettingCamera, mdmclient.SettingMicrophone, mdmclient.SettingBluetooth:
+		default:
+			ok = false
+		}
+		enabled, err := parseSwitch(value)
+		if !ok || err != nil {
+			return nil, usagef("invalid setting %q: expected camera, microphone or bluetooth =on|off", arg)
+		}
+		changes = append(changes, settingChange{setting: setting, enabled: enabled})
+	}
+	return changes, nil
+}
+
+// parseSwitch разбирает on/off (а также true/false, 1/0).
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// parseSwitchFilter — фильтр по настройке; пустое значение — без фильтра.
+func parseSwitchFilter(name, value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	enabled, err := parseSwitch(value)
+	if err != nil {
+		return nil, usagef("invalid --%s %q: expected on or off", name, value)
+	}
+	return &enabled, nil
+}
+
+// parseSince разбирает момент времени: RFC 3339 или давность относительно now ("15m").
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil && ago >= 0 {
+		return now.Add(-ago), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, usagef("invalid time %q: expected RFC 3339 or a duration like 1h", value)
+	}
+	return t, nil
+}
+
+func usersCommand() *command {
+	return &command{
+		name:    "users",
+		summary: "Пользователи с входом по паролю",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "Список пользователей",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 0, "none"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						users, err := client.ListUsers(ctx)
+						if err != nil {
+							return err
+						}
+						return a.print(users, usersTable(users))
+					}
+				},
+			},
+			{
+				name:    "create",
+				args:    "<username>",
+				summary: "Создать пользователя; пароль запрашивается",
+				define: func(fs *flag.FlagSet) action {
+					role := fs.String("role", "user", "Роль: admin или user")
+					passwordStdin := fs.Bool("password-stdin", false, "Прочитать пароль из первой строки stdin")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<username>"); err != nil {
+							return err
+						}
+						password, err := a.newPassword(*passwordStdin)
+						if err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						user, err := client.CreateUser(ctx, args[0], password, *role)
+						if err != nil {
+							return err
+						}
+						return a.print(user, usersTable([]*mdmclient.User{user}))
+					}
+				},
+			},
+		},
+	}
+}
+
+// newPassword читает пароль нового пользователя: из stdin или дважды с подтверждением.
+func (a *app) newPassword(fromStdin bool) (string, error) {
+	if fromStdin {
+		return a.readLine()
+	}
+	password, err := a.promptSecret("Пароль: ")
+	if err != nil {
+		return "", err
+	}
+	confirm, err := a.promptSecret("Пароль ещё раз: ")
+	if err != nil {
+		return "", err
+	}
+	if password != confirm {
+		return "", errors.New("passwords do not match")
+	}
+	return password, nil
+}
+
+func groupsCommand() *command {
+	return &command{
+		name:    "groups",
+		summary: "Группы устройств и их период heartbeat",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "Список групп",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 0, "none"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						groups, err := client.ListGroups(ctx)
+						if err != nil {
+							return err
+						}
+						return a.print(groups, groupsTable(groups))
+					}
+				},
+			},
+			{
+				name:    "create",
+				args:    "<name>",
+				summary: "Создать группу",
+				define: func(fs *flag.FlagSet) action {
+					interval := fs.Duration("heartbeat-interval", 0, "Период heartbeat устройств группы; 0 — по умолчанию сервера")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<name>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						group, err := client.CreateGroup(ctx, args[0], *interval)
+						if err != nil {
+							return err
+						}
+						return a.print(group, groupsTable([]*mdmclient.Group{group}))
+					}
+				},
+			},
+			{
+				name:    "set",
+				args:    "<group-id>",
+				summary: "Изменить период heartbeat группы",
+				define: func(fs *flag.FlagSet) action {
+					interval := fs.Duration("heartbeat-interval", 0, "Период heartbeat устройств группы; 0 — по умолчанию сервера")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<group-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						group, err := client.SetGroupHeartbeatInterval(ctx, args[0], *interval)
+						if err != nil {
+							return err
+						}
+						return a.print(group, groupsTable([]*mdmclient.Group{group}))
+					}
+				},
+			},
+			{
+				name:    "delete",
+				args:    "<group-id>",
+				summary: "Удалить группу; устройства остаются без группы",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<group-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						return client.DeleteGroup(ctx, args[0])
+					}
+				},
+			},
+			{
+				name:    "assign",
+				args:    "<device-id> [group-id]",
+				summary: "Перенести устройство в группу; без group-id — убрать из группы",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if len(args) != 1 && len(args) != 2 {
+							return usagef("expected arguments: <device-id> [group-id]")
+						}
+						groupID := ""
+						if len(args) == 2 {
+							groupID = args[1]
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						device, err := client.AssignGroup(ctx, args[0], groupID)
+						if err != nil {
+							return err
+						}
+						return a.print(device, deviceTable(device))
+					}
+				},
+			},
+		},
+	}
+}
+
+// appsCommand — установленные приложения: список, история и поиск устаревших версий.
+func appsCommand() *command {
+	return &command{
+		name:    "apps",
+		summary: "Установленные приложения устройств",
+		subcommands: []*command{
+			{
+				name:    "list",
+				args:    "<device-id>",
+				summary: "Приложения, установленные на устройстве",
+				define: func(fs *flag.FlagSet) action {
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						apps, err := client.ListApps(ctx, args[0])
+						if err != nil {
+							return err
+						}
+						return a.print(apps, appsTable(apps, false))
+					}
+				},
+			},
+			{
+				name:    "history",
+				args:    "<device-id>",
+				summary: "Установки, обновления и удаления приложений, новые первыми",
+				define: func(fs *flag.FlagSet) action {
+					packageName := fs.String("package", "", "Только это приложение")
+					limit := fs.Int("limit", 0, "Не больше стольких событий")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						events, err := client.AppHistory(ctx, args[0], *packageName, *limit)
+						if err != nil {
+							return err
+						}
+						return a.print(events, appEventsTable(events))
+					}
+				},
+			},
+			{
+				name:    "find",
+				args:    "<package>",
+				summary: "Устройства, на которых установлено приложение",
+				define: func(fs *flag.FlagSet) action {
+					below := fs.String("below", "", "Только версии ниже этой, например 115.0")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<package>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						apps, err := client.FindApp(ctx, args[0], *below)
+						if err != nil {
+							return err
+						}
+						return a.print(apps, appsTable(apps, true))
+					}
+				},
+			},
+		},
+	}
+}
+
+func commandsCommand() *command {
+	return &command{
+		name:    "commands",
+		summary: "Команды устройствам",
+		subcommands: []*command{
+			{
+				name:    "send",
+				args:    "<device-id> <type>",
+				summary: "Поставить команду устройству",
+				define: func(fs *flag.FlagSet) action {
+					payload := fs.String("payload", "", "Параметры команды — JSON-объект")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 2, "<device-id> <type>"); err != nil {
+							return err
+						}
+						var body any
+						if *payload != "" {
+							var object map[string]any
+							if err := json.Unmarshal([]byte(*payload), &object); err != nil {
+								return usagef("invalid --payload: expected a JSON object: %v", err)
+							}
+							body = object
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						cmd, err := client.SendCommand(ctx, args[0], args[1], body)
+						if err != nil {
+							return err
+						}
+						return a.print(cmd, commandsTable([]*mdmclient.Command{cmd}))
+					}
+				},
+			},
+			{
+				name:    "list",
+				args:    "<device-id>",
+				summary: "Команды устройства, новые первыми",
+				define: func(fs *flag.FlagSet) action {
+					limit := fs.Int("limit", 0, "Не больше стольких команд")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 1, "<device-id>"); err != nil {
+							return err
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						commands, err := client.ListCommands(ctx, args[0], *limit)
+						if err != nil {
+							return err
+						}
+						return a.print(commands, commandsTable(commands))
+					}
+				},
+			},
+		},
+	}
+}
+
+// maxAuditPage — предел выборки журнала на сервере: полная страница при
+// --follow означает, что за ней могут быть ещё события.
+const maxAuditPage = 1000
+
+func auditCommand() *command {
+	return &command{
+		name:    "audit",
+		summary: "Журнал изменений через административный API",
+		subcommands: []*command{
+			{
+				name:    "tail",
+				summary: "Последние события журнала; --follow — ждать новые",
+				define: func(fs *flag.FlagSet) action {
+					since := fs.String("since", "", "События после момента: RFC 3339 или давность вроде 1h")
+					actor := fs.String("actor", "", "Только события субъекта, например user:bob или api_key:ci")
+					limit := fs.Int("limit", 20, "Сколько событий вывести сначала")
+					follow := fs.Bool("follow", false, "Ждать и выводить новые события")
+					fs.BoolVar(follow, "f", false, "Сокращение для --follow")
+					interval := fs.Duration("interval", 2*time.Second, "Период опроса сервера при --follow")
+					return func(ctx context.Context, a *app, args []string) error {
+						if err := exactArgs(args, 0, "none"); err != nil {
+							return err
+						}
+						query := mdmclient.AuditQuery{Actor: *actor, Limit: *limit}
+						var err error
+						if query.Since, err = parseSince(*since, a.now()); err != nil {
+							return err
+						}
+						if *interval <= 0 {
+							return usagef("--interval must be positive")
+						}
+						client, err := a.client(true)
+						if err != nil {
+							return err
+						}
+						return a.tailAudit(ctx, client, query, *follow, *interval)
+					}
+				},
+			},
+		},
+	}
+}
+
+// tailAudit выводит события журнала, а с follow — опрашивает сервер, запрашивая
+// события после последнего выведенного, пока ctx не отменён.
+func (a *app) tailAudit(ctx context.Context, client *mdmclient.Client, query mdmclient.AuditQuery, follow bool, interval time.Duration) error {
+	events, err := client.ListAudit(ctx, query)
+	if err != nil {
+		return err
+	}
+	if !follow {
+		return a.print(events, func(w io.Writer) {
+			for _, e := range events {
+				auditLine(w, e)
+			}
+		})
+	}
+
+	for {
+		for _, e := range events {
+			if err := a.printAuditEvent(e); err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			query.Since = events[len(events)-1].CreatedAt
+		}
+		query.Limit = maxAuditPage
+		// Полная страница — события ещё есть, запрашиваем сразу.
+		if len(events) < maxAuditPage {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+		if events, err = client.ListAudit(ctx, query); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// Сбой сети или сервера — повторяем на следующем опросе; отказ в доступе — выходим.
+			var apiErr *mdmclient.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError && apiErr.StatusCode != http.StatusTooManyRequests {
+				return err
+			}
+			fmt.Fprintf(a.stderr, "mdmctl: %v\n", err)
+			events = nil
+		}
+	}
+}
+
+// printAuditEvent выводит одно событие в режиме --follow: строкой таблицы,
+// JSON-объектом на строку или отдельным YAML-документом.
+func (a *app) printAuditEvent(e *mdmclient.AuditEvent) error {
+	switch a.output {
+	case outputJSON:
+		return writeJSON(a.stdout, e, "")
+	case outputYAML:
+		fmt.Fprintln(a.stdout, "---")
+		return writeYAML(a.stdout, e)
+	}
+	auditLine(a.stdout, e)
+	return nil
+}
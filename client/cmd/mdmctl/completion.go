@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func completionCommand() *command {
+	return &command{
+		name:    "completion",
+		args:    "bash|zsh|fish",
+		values:  []string{"bash", "zsh", "fish"},
+		summary: "Скрипт автодополнения для оболочки",
+		define: func(fs *flag.FlagSet) action {
+			return func(ctx context.Context, a *app, args []string) error {
+				if err := exactArgs(args, 1, "bash|zsh|fish"); err != nil {
+					return err
+				}
+				nodes := completionNodes()
+				switch args[0] {
+				case "bash":
+					writeBashCompletion(a.stdout, nodes)
+				case "zsh":
+					// zsh понимает bash-скрипт через bashcompinit.
+					fmt.Fprintln(a.stdout, "autoload -U +X bashcompinit && bashcompinit")
+					writeBashCompletion(a.stdout, nodes)
+				case "fish":
+					writeFishCompletion(a.stdout, nodes)
+				default:
+					return usagef("unsupported shell %q: expected bash, zsh or fish", args[0])
+				}
+				return nil
+			}
+		},
+	}
+}
+
+// completionNode — команда дерева с тем, что можно дополнить после неё.
+type completionNode struct {
+	path    []string
+	summary string
+	// subcommands — подкоманды группы; flags — флаги команды с действием.
+	subcommands []*command
+	values      []string
+	flags       []*flag.Flag
+}
+
+// completionNodes обходит дерево команд; флаги берутся из тех же define,
+// что и при разборе, поэтому дополнение не расходится с командами.
+func completionNodes() []completionNode {
+	var nodes []completionNode
+	var walk func(cmd *command, path []string)
+	walk = func(cmd *command, path []string) {
+		node := completionNode{path: path, summary: cmd.summary, subcommands: cmd.subcommands, values: cmd.values}
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		newApp(nil, io.Discard, io.Discard).globalFlags(fs)
+		if cmd.define != nil {
+			cmd.define(fs)
+		}
+		fs.VisitAll(func(f *flag.Flag) { node.flags = append(node.flags, f) })
+		nodes = append(nodes, node)
+		for _, sub := range cmd.subcommands {
+			walk(sub, append(path[:len(path):len(path)], sub.name))
+		}
+	}
+	walk(root(), nil)
+	return nodes
+}
+
+func (n completionNode) words() []string {
+	words := append([]string(nil), n.values...)
+	for _, sub := range n.subcommands {
+		words = append(words, sub.name)
+	}
+	for _, f := range n.flags {
+		words = append(words, flagName(f))
+	}
+	return words
+}
+
+func flagName(f *flag.Flag) string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+func writeBashCompletion(w io.Writer, nodes []completionNode) {
+	var groups []string
+	for _, n := range nodes {
+		for _, sub := range n.subcommands {
+			groups = append(groups, fmt.Sprintf("%q", " "+strings.Join(append(n.path, sub.name), " ")))
+		}
+	}
+	fmt.Fprint(w, `_mdmctl() {
+    local cur prev word path="" words="" i
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    case "$prev" in
+        -o|--output) COMPREPLY=($(compgen -W "table json yaml" -- "$cur")); return ;;
+        --config) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+    esac
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        word="${COMP_WORDS[i]}"
+        case "$path $word" in
+`)
+	fmt.Fprintf(w, "            %s) path=\"$path $word\" ;;\n", strings.Join(groups, "|"))
+	fmt.Fprint(w, `        esac
+    done
+    case "$path" in
+`)
+	for _, n := range nodes {
+		fmt.Fprintf(w, "        %q) words=%q ;;\n", prefixed(n.path), strings.Join(n.words(), " "))
+	}
+	fmt.Fprint(w, `    esac
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -F _mdmctl mdmctl
+`)
+}
+
+// prefixed — путь команды в виде, который собирает скрипт bash: " devices list".
+func prefixed(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return " " + strings.Join(path, " ")
+}
+
+func writeFishCompletion(w io.Writer, nodes []completionNode) {
+	fmt.Fprintln(w, "complete -c mdmctl -f")
+	for _, n := range nodes {
+		condition := fishCondition(n)
+		for _, sub := range n.subcommands {
+			fmt.Fprintf(w, "complete -c mdmctl -n %s -a %s -d %s\n", fishQuote(condition), sub.name, fishQuote(sub.summary))
+		}
+		if len(n.values) > 0 {
+			fmt.Fprintf(w, "complete -c mdmctl -n %s -a %s\n", fishQuote(condition), fishQuote(strings.Join(n.values, " ")))
+		}
+		for _, f := range n.flags {
+			option := "-l " + f.Name
+			if len(f.Name) == 1 {
+				option = "-s " + f.Name
+			}
+			fmt.Fprintf(w, "complete -c mdmctl -n %s %s -d %s\n", fishQuote(condition), option, fishQuote(f.Usage))
+		}
+	}
+	fmt.Fprintln(w, "complete -c mdmctl -l output -s o -xa 'table json yaml'")
+}
+
+// fishCondition — условие fish «набран путь команд узла, но ещё не его подкоманда».
+func fishCondition(n completionNode) string {
+	var conditions []string
+	if len(n.path) == 0 {
+		conditions = append(conditions, "__fish_use_subcommand")
+	}
+	for _, name := range n.path {
+		conditions = append(conditions, "__fish_seen_subcommand_from "+name)
+	}
+	if len(n.subcommands) > 0 && len(n.path) > 0 {
+		names := make([]string, len(n.subcommands))
+		for i, sub := range n.subcommands {
+			names[i] = sub.name
+		}
+		conditions = append(conditions, "not __fish_seen_subcommand_from "+strings.Join(names, " "))
+	}
+	return strings.Join(conditions, "; and ")
+}
+
+// fishQuote заключает s в одинарные кавычки fish: без подстановки переменных.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
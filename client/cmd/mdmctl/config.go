@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// Config — файл настроек mdmctl: сервер и токен последнего входа.
+// Файл создаётся с правами 0600: токен даёт те же права, что и пароль.
+type Config struct {
+	Server   string `json:"server,omitempty"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// configFile — путь к файлу настроек: --config, $MDMCTL_CONFIG или
+// <каталог настроек пользователя>/mdmctl/config.json.
+func (a *app) configFile() (string, error) {
+	if a.configPath != "" {
+		return a.configPath, nil
+	}
+	if path := a.getenv("MDMCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config directory: %w (use --config)", err)
+	}
+	return filepath.Join(dir, "mdmctl", "config.json"), nil
+}
+
+// loadConfig читает файл настроек; отсутствующий файл — пустые настройки.
+func (a *app) loadConfig() (*Config, error) {
+	path, err := a.configFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// saveConfig атомарно записывает файл настроек.
+func (a *app) saveConfig(cfg *Config) error {
+	path, err := a.configFile()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// serverURL — адрес сервера: --server, $MDM_SERVER, сервер последнего входа
+// или defaultServer.
+func (a *app) serverURL(cfg *Config) string {
+	switch {
+	case a.server != "":
+		return a.server
+	case a.getenv("MDM_SERVER") != "":
+		return a.getenv("MDM_SERVER")
+	case cfg.Server != "":
+		return cfg.Server
+	}
+	return defaultServer
+}
+
+// client создаёт клиент API. Токен берётся из $MDM_TOKEN (например, API-ключ
+// для скриптов) или из файла настроек, если вход выполнялся на тот же сервер.
+// Если authenticated, без действующего токена возвращается ошибка.
+func (a *app) client(authenticated bool) (*mdmclient.Client, error) {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	server := a.serverURL(cfg)
+	token := a.getenv("MDM_TOKEN")
+	if token == "" && sameServer(cfg.Server, server) {
+		token = cfg.Token
+	}
+	if authenticated {
+		if token == "" {
+			return nil, fmt.Errorf("not logged in to %s: run \"mdmctl login\" or set MDM_TOKEN", server)
+		}
+		if exp, ok := tokenExpiry(token); ok && !a.now().Before(exp) {
+			return nil, fmt.Errorf("session expired at %s: run \"mdmctl login\" again", exp.Local().Format(time.DateTime))
+		}
+	}
+	return mdmclient.New(mdmclient.Config{
+		BaseURL:    server,
+		Token:      token,
+		Timeout:    a.timeout,
+		HTTPClient: a.httpClient,
+		UserAgent:  "mdmctl",
+	})
+}
+
+func sameServer(a, b string) bool {
+	return strings.TrimRight(a, "/") == strings.TrimRight(b, "/")
+}
+
+// tokenExpiry читает срок действия JWT (claim exp) без проверки подписи —
+// только чтобы не отправлять заведомо просроченный токен. Для API-ключей
+// и прочих токенов ok = false.
+func tokenExpiry(token string) (exp time.Time, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(*claims.Exp), 0), true
+}
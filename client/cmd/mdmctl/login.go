@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func loginCommand() *command {
+	return &command{
+		name:    "login",
+		summary: "Вход по имени и паролю; токен сохраняется в файле настроек",
+		define: func(fs *flag.FlagSet) action {
+			username := fs.String("username", "", "Имя пользователя (по умолчанию — имя последнего входа или запрос)")
+			passwordStdin := fs.Bool("password-stdin", false, "Прочитать пароль из первой строки stdin")
+			otp := fs.String("otp", "", "Код из приложения-аутентификатора, если включена 2FA")
+			recoveryCode := fs.String("recovery-code", "", "Код восстановления вместо кода из приложения")
+			return func(ctx context.Context, a *app, args []string) error {
+				if err := exactArgs(args, 0, "none"); err != nil {
+					return err
+				}
+				return a.login(ctx, *username, *passwordStdin, *otp, *recoveryCode)
+			}
+		},
+	}
+}
+
+func (a *app) login(ctx context.Context, username string, passwordStdin bool, otp, recoveryCode string) error {
+	cfg, err := a.loadConfig()
+	if err != nil {
+		return err
+	}
+	server := a.serverURL(cfg)
+	if username == "" && sameServer(cfg.Server, server) {
+		username = cfg.Username
+	}
+	if username == "" {
+		if username, err = a.prompt("Имя пользователя: "); err != nil {
+			return err
+		}
+	}
+	var password string
+	if passwordStdin {
+		password, err = a.readLine()
+	} else {
+		password, err = a.promptSecret("Пароль: ")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Вход идёт на выбранный сервер, даже если в настройках сохранён другой.
+	a.server = server
+	client, err := a.client(false)
+	if err != nil {
+		return err
+	}
+	response, err := client.Login(ctx, username, password)
+	if err != nil {
+		return err
+	}
+	switch {
+	case response.EnrollmentRequired:
+		return errors.New("two-factor authentication is required for this account but not set up yet: " +
+			"enroll through POST /login/2fa/enroll and /login/2fa/confirm, then log in again")
+	case response.MFARequired:
+		if otp == "" && recoveryCode == "" {
+			if otp, err = a.promptSecret("Код 2FA (или код восстановления): "); err != nil {
+				return err
+			}
+			// Коды восстановления (XXXXX-XXXXX) длиннее шести цифр из приложения.
+			if otp = strings.TrimSpace(otp); len(otp) > 8 {
+				otp, recoveryCode = "", otp
+			}
+		}
+		if response, err = client.CompleteLogin(ctx, response.MFAToken, otp, recoveryCode); err != nil {
+			return err
+		}
+	}
+	if response.Token == "" {
+		return errors.New("server did not return a token")
+	}
+
+	cfg.Server, cfg.Username, cfg.Token = server, username, response.Token
+	if err := a.saveConfig(cfg); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+	message := fmt.Sprintf("Вход выполнен: %s на %s", username, server)
+	if exp, ok := tokenExpiry(response.Token); ok {
+		message += ", сессия до " + exp.Local().Format(time.DateTime)
+	}
+	fmt.Fprintln(a.stderr, message)
+	return nil
+}
+
+func logoutCommand() *command {
+	return &command{
+		name:    "logout",
+		summary: "Удалить сохранённый токен",
+		define: func(fs *flag.FlagSet) action {
+			return func(ctx context.Context, a *app, args []string) error {
+				if err := exactArgs(args, 0, "none"); err != nil {
+					return err
+				}
+				cfg, err := a.loadConfig()
+				if err != nil {
+					return err
+				}
+				if cfg.Token == "" {
+					return nil
+				}
+				cfg.Token = ""
+				return a.saveConfig(cfg)
+			}
+		},
+	}
+}
@@ -0,0 +1,265 @@
+// Команда mdmctl — административный клиент сервера MDM: вход, устройства,
+// блокировка и стирание, пользователи, группы, команды и журнал аудита.
+// Работает через тот же пакет mdmclient, что и агент.
+//
+//	mdmctl login --username admin
+//	mdmctl devices list --camera=on -o json
+//	mdmctl devices set android-1 camera=off bluetooth=on
+//	mdmctl devices wipe android-1 --request-approval
+//	mdmctl audit tail --follow
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a := newApp(os.Stdin, os.Stdout, os.Stderr)
+	if err := a.run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "mdmctl: %v\n", err)
+		var usage usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// defaultServer — адрес сервера, если он не задан ни флагом, ни окружением, ни при входе.
+const defaultServer = "http://localhost:4000"
+
+// Форматы вывода.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// app — состояние одного запуска: глобальные флаги, ввод-вывод и окружение.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// reader — буфер stdin, общий для всех запросов ввода.
+	reader *bufio.Reader
+	// getenv и now подменяются в тестах.
+	getenv func(string) string
+	now    func() time.Time
+	// httpClient — транспорт для mdmclient; nil — по умолчанию.
+	httpClient *http.Client
+
+	// Глобальные флаги.
+	server     string
+	output     string
+	configPath string
+	timeout    time.Duration
+}
+
+func newApp(stdin io.Reader, stdout, stderr io.Writer) *app {
+	return &app{
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+		getenv:  os.Getenv,
+		now:     time.Now,
+		output:  outputTable,
+		timeout: 15 * time.Second,
+	}
+}
+
+// action — действие команды; args — позиционные аргументы после разбора флагов.
+type action func(ctx context.Context, a *app, args []string) error
+
+// command — узел дерева команд: либо группа подкоманд, либо команда с действием.
+type command struct {
+	name    string
+	args    string
+	summary string
+	// values — варианты позиционного аргумента для автодополнения.
+	values []string
+	// define объявляет флаги команды и возвращает её действие.
+	define      func(fs *flag.FlagSet) action
+	subcommands []*command
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// usageError — ошибка в аргументах командной строки (код выхода 2).
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// root — дерево команд mdmctl.
+func root() *command {
+	return &command{
+		name: "mdmctl",
+		subcommands: []*command{
+			loginCommand(),
+			logoutCommand(),
+			devicesCommand(),
+			usersCommand(),
+			groupsCommand(),
+			commandsCommand(),
+			actionsCommand(),
+			appsCommand(),
+			auditCommand(),
+			completionCommand(),
+		},
+	}
+}
+
+// globalFlags объявляет флаги, допустимые на любом уровне команды. Значения по
+// умолчанию — текущие, чтобы повторное объявление не сбрасывало уже разобранное.
+func (a *app) globalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.server, "server", a.server, "URL сервера MDM (по умолчанию $MDM_SERVER или сервер последнего входа)")
+	fs.StringVar(&a.output, "output", a.output, "Формат вывода: table, json или yaml")
+	fs.StringVar(&a.output, "o", a.output, "Сокращение для --output")
+	fs.StringVar(&a.configPath, "config", a.configPath, "Файл настроек с токеном (по умолчанию $MDMCTL_CONFIG или каталог настроек пользователя)")
+	fs.DurationVar(&a.timeout, "timeout", a.timeout, "Таймаут одного запроса")
+}
+
+// run разбирает аргументы, спускаясь по дереву команд, и выполняет найденную команду.
+// Флаги можно указывать в любом месте, в том числе после позиционных аргументов.
+func (a *app) run(ctx context.Context, args []string) error {
+	cmd := root()
+	path := []string{cmd.name}
+	for {
+		fs := a.flagSet(path, cmd)
+		var run action
+		if cmd.define != nil {
+			run = cmd.define(fs)
+		}
+		if run == nil {
+			// Группа подкоманд: глобальные флаги до имени подкоманды.
+			if err := fs.Parse(args); err != nil {
+				return a.parseError(fs, err)
+			}
+			args = fs.Args()
+			if len(args) == 0 {
+				fs.Usage()
+				return usagef("%s: command is required", strings.Join(path, " "))
+			}
+			sub := cmd.find(args[0])
+			if sub == nil {
+				fs.Usage()
+				return usagef("%s: unknown command %q", strings.Join(path, " "), args[0])
+			}
+			cmd, path, args = sub, append(path, sub.name), args[1:]
+			continue
+		}
+		positional, err := parseInterspersed(fs, args)
+		if err != nil {
+			return a.parseError(fs, err)
+		}
+		if err := a.checkOutput(); err != nil {
+			return err
+		}
+		return run(ctx, a, positional)
+	}
+}
+
+// flagSet создаёт набор флагов команды cmd со справкой по ней.
+func (a *app) flagSet(path []string, cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	a.globalFlags(fs)
+	fs.Usage = func() {
+		w := a.stderr
+		synopsis := strings.Join(path, " ")
+		if len(cmd.subcommands) > 0 {
+			synopsis += " <команда>"
+		}
+		if cmd.args != "" {
+			synopsis += " " + cmd.args
+		}
+		fmt.Fprintf(w, "Использование: %s [флаги]\n", synopsis)
+		if cmd.summary != "" {
+			fmt.Fprintf(w, "\n%s\n", cmd.summary)
+		}
+		if len(cmd.subcommands) > 0 {
+			fmt.Fprintln(w, "\nКоманды:")
+			for _, sub := range cmd.subcommands {
+				fmt.Fprintf(w, "  %-12s %s\n", sub.name, sub.summary)
+			}
+		}
+		fmt.Fprintln(w, "\nФлаги:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+		fs.SetOutput(io.Discard)
+	}
+	return fs
+}
+
+// parseError превращает ошибку разбора флагов в usageError; -h выводит справку.
+func (a *app) parseError(fs *flag.FlagSet, err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		fs.Usage()
+		return nil
+	}
+	fs.Usage()
+	return usageError{msg: err.Error()}
+}
+
+func (a *app) checkOutput() error {
+	switch a.output {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return usagef("unknown output format %q: expected table, json or yaml", a.output)
+}
+
+// parseInterspersed разбирает флаги вперемешку с позиционными аргументами;
+// всё после "--" — позиционные аргументы.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// exactArgs проверяет число позиционных аргументов.
+func exactArgs(args []string, n int, names string) error {
+	if len(args) != n {
+		return usagef("expected arguments: %s", names)
+	}
+	return nil
+}
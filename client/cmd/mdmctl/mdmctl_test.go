@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// fakeServer — административный API в объёме, нужном командам mdmctl.
+type fakeServer struct {
+	t     *testing.T
+	token string
+
+	mu       sync.Mutex
+	device   mdmclient.Device
+	ifMatch  []string
+	audit    []*mdmclient.AuditEvent
+	queries  []string
+	requests int
+	// wipePasswords — пароли из запросов на стирание ("" — запрос подтверждения).
+	wipePasswords []string
+	// wipeOTPs — коды 2FA из тех же запросов.
+	wipeOTPs []string
+	deleted  bool
+}
+
+func newFakeServer(t *testing.T, token string) *httptest.Server {
+	f := &fakeServer{t: t, token: token, device: mdmclient.Device{DeviceID: "android-1", Version: 3, Status: "active"}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	if r.URL.Path == "/login" {
+		switch {
+		case body["username"] == "bob" && body["password"] == "secret":
+			writeJSON(w, map[string]any{"mfa_required": true, "mfa_token": "challenge"}, "")
+		case body["mfa_token"] == "challenge" && body["otp"] == "123456":
+			writeJSON(w, map[string]any{"token": f.token}, "")
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			writeJSON(w, map[string]string{"error": "invalid credentials"}, "")
+		}
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		writeJSON(w, map[string]string{"error": "unauthorized"}, "")
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/devices":
+		f.queries = append(f.queries, r.URL.RawQuery)
+		writeJSON(w, []*mdmclient.Device{&f.device}, "")
+	case r.Method == http.MethodDelete && r.URL.Path == "/devices/android-1":
+		f.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && r.URL.Path == "/devices/android-1/wipe":
+		password, _ := body["password"].(string)
+		f.wipePasswords = append(f.wipePasswords, password)
+		otp, _ := body["otp"].(string)
+		f.wipeOTPs = append(f.wipeOTPs, otp)
+		action := mdmclient.DeviceAction{ID: "act-1", DeviceID: "android-1", Action: "wipe", Status: mdmclient.ActionIssued}
+		status := http.StatusCreated
+		if password == "" {
+			action.Status, status = mdmclient.ActionPendingApproval, http.StatusAccepted
+		}
+		w.WriteHeader(status)
+		writeJSON(w, action, "")
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/devices/android-1/"):
+		f.ifMatch = append(f.ifMatch, r.Header.Get("If-Match"))
+		enabled, _ := body["enabled"].(bool)
+		switch strings.TrimPrefix(r.URL.Path, "/devices/android-1/") {
+		case "camera":
+			f.device.CameraEnabled = enabled
+		case "bluetooth":
+			f.device.BluetoothEnabled = enabled
+		}
+		f.device.Version++
+		writeJSON(w, &f.device, "")
+	case r.Method == http.MethodGet && r.URL.Path == "/apps":
+		f.queries = append(f.queries, r.URL.RawQuery)
+		writeJSON(w, []*mdmclient.App{{DeviceID: "android-1", PackageName: "firefox", Version: "102.9", Installer: "dpkg"}}, "")
+	case r.Method == http.MethodGet && r.URL.Path == "/audit":
+		f.queries = append(f.queries, r.URL.RawQuery)
+		var events []*mdmclient.AuditEvent
+		since, _ := time.Parse(time.RFC3339Nano, r.URL.Query().Get("since"))
+		for _, e := range f.audit {
+			if e.CreatedAt.After(since) {
+				events = append(events, e)
+			}
+		}
+		writeJSON(w, events, "")
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]string{"error": "not found"}, "")
+	}
+}
+
+// jwt собирает неподписанный JWT с заданным exp — mdmctl читает только exp.
+func jwt(exp time.Time) string {
+	payload, _ := json.Marshal(map[string]int64{"exp": exp.Unix()})
+	return "e30." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+type testApp struct {
+	*app
+	stdout, stderr bytes.Buffer
+	env            map[string]string
+}
+
+func newTestApp(t *testing.T, stdin string) *testApp {
+	ta := &testApp{env: map[string]string{}}
+	ta.app = newApp(strings.NewReader(stdin), &ta.stdout, &ta.stderr)
+	ta.getenv = func(key string) string { return ta.env[key] }
+	ta.configPath = filepath.Join(t.TempDir(), "mdmctl", "config.json")
+	return ta
+}
+
+func TestLoginWithTwoFactorAndDevices(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	srv := newFakeServer(t, token)
+	ctx := context.Background()
+
+	// Имя — флагом, пароль и код 2FA — из stdin (не терминал: без stty).
+	login := newTestApp(t, "secret\n123456\n")
+	if err := login.run(ctx, []string{"login", "--server", srv.URL, "--username", "bob"}); err != nil {
+		t.Fatalf("login: %v (stderr: %s)", err, login.stderr.String())
+	}
+	info, err := os.Stat(login.configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("config mode = %v, want 0600", info.Mode().Perm())
+	}
+	cfg, err := login.loadConfig()
+	if err != nil || cfg.Token != token || cfg.Server != srv.URL || cfg.Username != "bob" {
+		t.Fatalf("config = %+v, %v", cfg, err)
+	}
+
+	// Следующие команды берут сервер и токен из файла настроек.
+	list := newTestApp(t, "")
+	list.configPath = login.configPath
+	if err := list.run(ctx, []string{"devices", "list", "--camera=off", "-o", "json"}); err != nil {
+		t.Fatalf("devices list: %v", err)
+	}
+	var devices []mdmclient.Device
+	if err := json.Unmarshal(list.stdout.Bytes(), &devices); err != nil || len(devices) != 1 || devices[0].DeviceID != "android-1" {
+		t.Fatalf("devices list output %q: %v", list.stdout.String(), err)
+	}
+
+	// Флаги после позиционных аргументов; каждое изменение — поверх предыдущего.
+	set := newTestApp(t, "")
+	set.configPath = login.configPath
+	if err := set.run(ctx, []string{"devices", "set", "android-1", "camera=on", "bluetooth=on", "--if-version", "3"}); err != nil {
+		t.Fatalf("devices set: %v", err)
+	}
+	if !strings.Contains(set.stdout.String(), "Settings version:  5") {
+		t.Errorf("devices set output:\n%s", set.stdout.String())
+	}
+
+	logout := newTestApp(t, "")
+	logout.configPath = login.configPath
+	if err := logout.run(ctx, []string{"logout"}); err != nil {
+		t.Fatal(err)
+	}
+	after := newTestApp(t, "")
+	after.configPath = login.configPath
+	if err := after.run(ctx, []string{"devices", "list"}); err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Errorf("devices list after logout: %v", err)
+	}
+}
+
+func TestSetSendsIfMatchChain(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	f := &fakeServer{t: t, token: token, device: mdmclient.Device{DeviceID: "android-1", Version: 3}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "set", "--if-version=3", "android-1", "camera=on", "bluetooth=off"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`"3"`, `"4"`}; fmt.Sprint(f.ifMatch) != fmt.Sprint(want) {
+		t.Errorf("If-Match = %v, want %v", f.ifMatch, want)
+	}
+}
+
+func TestWipeRequiresPasswordOrApproval(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	f := &fakeServer{t: t, token: token}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "hunter22\n")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "wipe", "--password-stdin", "android-1", "-o", "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.stdout.String(), `"status": "issued"`) {
+		t.Errorf("stdout = %q, want issued action", a.stdout.String())
+	}
+
+	a = newTestApp(t, "")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "wipe", "android-1", "--request-approval"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(a.stderr.String(), "mdmctl actions approve act-1") {
+		t.Errorf("stderr = %q, want approval hint", a.stderr.String())
+	}
+
+	a = newTestApp(t, "hunter22\n")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "wipe", "--password-stdin", "--otp=123456", "android-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"hunter22", "", "hunter22"}; fmt.Sprint(f.wipePasswords) != fmt.Sprint(want) {
+		t.Errorf("wipe passwords = %q, want %q", f.wipePasswords, want)
+	}
+	if want := []string{"", "", "123456"}; fmt.Sprint(f.wipeOTPs) != fmt.Sprint(want) {
+		t.Errorf("wipe otps = %q, want %q", f.wipeOTPs, want)
+	}
+
+	// Пустой пароль не превращается молча в запрос подтверждения.
+	a = newTestApp(t, "\n")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "wipe", "--password-stdin", "android-1"}); err == nil {
+		t.Errorf("expected an error for an empty password")
+	}
+}
+
+func TestDeleteAsksForDeviceID(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	f := &fakeServer{t: t, token: token}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "android-2\n")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "delete", "android-1"}); err == nil || f.deleted {
+		t.Fatalf("expected mismatched confirmation to abort, got %v (deleted %v)", err, f.deleted)
+	}
+
+	a = newTestApp(t, "android-1\n")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "delete", "android-1"}); err != nil || !f.deleted {
+		t.Fatalf("devices delete: %v (deleted %v)", err, f.deleted)
+	}
+
+	a = newTestApp(t, "")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"devices", "list", "--archived"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.queries[len(f.queries)-1]; got != "archived=true" {
+		t.Errorf("devices list query = %q, want archived=true", got)
+	}
+}
+
+func TestAppsFindBelowVersion(t *testing.T) {
+	token := jwt(time.Now().Add(time.Hour))
+	f := &fakeServer{t: t, token: token}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	if err := a.run(context.Background(), []string{"apps", "find", "firefox", "--below", "115.0"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "below=115.0&package=firefox"; len(f.queries) != 1 || f.queries[0] != want {
+		t.Errorf("queries = %q, want %q", f.queries, want)
+	}
+	if out := a.stdout.String(); !strings.Contains(out, "android-1") || !strings.Contains(out, "102.9") {
+		t.Errorf("apps find output:\n%s", out)
+	}
+}
+
+func TestExpiredSessionIsNotSent(t *testing.T) {
+	f := &fakeServer{t: t, token: "unused"}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "")
+	if err := a.saveConfig(&Config{Server: srv.URL, Token: jwt(time.Now().Add(-time.Minute))}); err != nil {
+		t.Fatal(err)
+	}
+	err := a.run(context.Background(), []string{"devices", "list"})
+	if err == nil || !strings.Contains(err.Error(), "session expired") {
+		t.Fatalf("err = %v, want session expired", err)
+	}
+	if f.requests != 0 {
+		t.Errorf("requests = %d, want none", f.requests)
+	}
+}
+
+func TestAuditTailFollow(t *testing.T) {
+	token := "mdm_0123456789abcdef_secret"
+	f := &fakeServer{t: t, token: token}
+	started := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	f.audit = []*mdmclient.AuditEvent{
+		{ID: "1", Actor: "user:bob", Method: "POST", Path: "/devices/a/camera", Status: 200, CreatedAt: started},
+	}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+
+	a := newTestApp(t, "")
+	a.env["MDM_SERVER"] = srv.URL
+	a.env["MDM_TOKEN"] = token
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		f.mu.Lock()
+		f.audit = append(f.audit, &mdmclient.AuditEvent{ID: "2", Actor: "api_key:ci", Method: "DELETE", Path: "/device-groups/g", Status: 204, CreatedAt: started.Add(time.Second)})
+		f.mu.Unlock()
+		time.Sleep(60 * time.Millisecond)
+		cancel()
+	}()
+	if err := a.run(ctx, []string{"audit", "tail", "-f", "--interval", "10ms", "-o", "json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, line := range strings.Split(strings.TrimSpace(a.stdout.String()), "\n") {
+		var e mdmclient.AuditEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Errorf("events = %v, want [1 2] each once", ids)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.queries[0] != "limit=20" || !strings.Contains(f.queries[len(f.queries)-1], "since=2026-10-01T12%3A00%3A01Z") {
+		t.Errorf("queries = %v", f.queries)
+	}
+}
+
+func TestWriteYAMLKeepsFieldOrderAndTypes(t *testing.T) {
+	var out bytes.Buffer
+	value := struct {
+		Name    string   `json:"name"`
+		Enabled bool     `json:"enabled"`
+		Version string   `json:"version"`
+		Tags    []string `json:"tags"`
+	}{Name: "android-1", Enabled: true, Version: "14", Tags: []string{"a", "b"}}
+	if err := writeYAML(&out, value); err != nil {
+		t.Fatal(err)
+	}
+	want := "name: android-1\nenabled: true\nversion: \"14\"\ntags:\n  - a\n  - b\n"
+	if out.String() != want {
+		t.Errorf("yaml:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"devices"},
+		{"devices", "remove"},
+		{"devices", "set", "android-1", "wifi=on"},
+		{"devices", "list", "--camera", "maybe"},
+		{"devices", "list", "-o", "xml"},
+		{"commands", "send", "android-1", "reboot", "--payload", "[1]"},
+	} {
+		a := newTestApp(t, "")
+		err := a.run(context.Background(), args)
+		if _, ok := err.(usageError); !ok {
+			t.Errorf("%v: err = %v, want usage error", args, err)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"":                     {},
+		"90m":                  now.Add(-90 * time.Minute),
+		"2026-09-30T08:00:00Z": time.Date(2026, 9, 30, 8, 0, 0, 0, time.UTC),
+	} {
+		got, err := parseSince(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("parseSince(yesterday) succeeded")
+	}
+}
+
+func TestCompletionCoversCommands(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		a := newTestApp(t, "")
+		if err := a.run(context.Background(), []string{"completion", shell}); err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		for _, word := range []string{"devices", "tail", "seen-since", "heartbeat-interval"} {
+			if !strings.Contains(a.stdout.String(), word) {
+				t.Errorf("%s completion lacks %q", shell, word)
+			}
+		}
+	}
+}
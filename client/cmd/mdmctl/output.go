@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"mdm-client/mdmclient"
+)
+
+// print выводит v в выбранном формате; table печатает таблицу для --output=table.
+func (a *app) print(v any, table func(w io.Writer)) error {
+	switch a.output {
+	case outputJSON:
+		return writeJSON(a.stdout, v, "  ")
+	case outputYAML:
+		return writeYAML(a.stdout, v)
+	}
+	tw := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(v)
+}
+
+// writeYAML выводит v в YAML с теми же именами и порядком полей, что и в JSON API:
+// JSON — подмножество YAML, поэтому разобранный документ остаётся перевести
+// в блочный стиль.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle снимает JSON-оформление: скобки и кавычки там, где они не нужны.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// Форматирование значений в таблицах.
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return formatTime(*t)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatBytes — объём в двоичных единицах: 512 MiB, 7.8 GiB.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatUint(n, 10) + " B"
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func devicesTable(devices []*mdmclient.Device) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "DEVICE ID\tSTATUS\tCAMERA\tMICROPHONE\tBLUETOOTH\tOS\tBATTERY\tPOLICY\tLAST HEARTBEAT")
+		for _, d := range devices {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d%%\t%s\t%s\n",
+				d.DeviceID, d.Status, onOff(d.CameraEnabled), onOff(d.MicrophoneEnabled), onOff(d.BluetoothEnabled),
+				orDash(d.OsVersion), d.BatteryLevel, d.PolicyStatus, formatTime(d.LastHeartbeat))
+		}
+	}
+}
+
+// deviceTable — одно устройство подробно, «поле: значение».
+func deviceTable(d *mdmclient.Device) func(io.Writer) {
+	return func(w io.Writer) {
+		rows := [][2]string{
+			{"Device ID", d.DeviceID},
+			{"ID", d.ID},
+			{"Status", d.Status},
+			{"Group", orDash(d.GroupID)},
+			{"Camera", onOff(d.CameraEnabled)},
+			{"Microphone", onOff(d.MicrophoneEnabled)},
+			{"Bluetooth", onOff(d.BluetoothEnabled)},
+			{"Settings version", strconv.FormatInt(d.Version, 10)},
+			{"Policy", policySummary(d)},
+			{"OS version", orDash(d.OsVersion)},
+			{"Battery", fmt.Sprintf("%d%%", d.BatteryLevel)},
+			{"Last heartbeat", formatTime(d.LastHeartbeat)},
+			{"Enrolled", formatTime(d.EnrolledAt)},
+		}
+		t := d.Telemetry
+		if t.OSName != "" {
+			rows = append(rows, [2]string{"OS", strings.TrimSpace(t.OSName + " " + t.OSVersion)})
+		}
+		if t.UptimeSeconds != nil {
+			rows = append(rows, [2]string{"Uptime", (time.Duration(*t.UptimeSeconds) * time.Second).String()})
+		}
+		if t.MemoryTotalBytes != nil && t.MemoryAvailableBytes != nil {
+			rows = append(rows, [2]string{"Memory", formatBytes(*t.MemoryAvailableBytes) + " free of " + formatBytes(*t.MemoryTotalBytes)})
+		}
+		if t.DiskTotalBytes != nil && t.DiskFreeBytes != nil {
+			rows = append(rows, [2]string{"Disk", formatBytes(*t.DiskFreeBytes) + " free of " + formatBytes(*t.DiskTotalBytes)})
+		}
+		if d.TelemetryAt != nil {
+			rows = append(rows, [2]string{"Telemetry at", formatTimePtr(d.TelemetryAt)})
+		}
+		if d.RetiredAt != nil {
+			rows = append(rows, [2]string{"Retired", formatTimePtr(d.RetiredAt) + " by " + orDash(d.RetiredBy)})
+		}
+		if d.DeletedAt != nil {
+			rows = append(rows, [2]string{"Deleted", formatTimePtr(d.DeletedAt)})
+		}
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s:\t%s\n", row[0], row[1])
+		}
+	}
+}
+
+// policySummary — состояние применения политики: "applied v3", "failed v2: ...".
+func policySummary(d *mdmclient.Device) string {
+	summary := d.PolicyStatus
+	if d.PolicyVersion > 0 {
+		summary += " v" + strconv.FormatInt(d.PolicyVersion, 10)
+	}
+	if d.PolicyError != "" {
+		summary += ": " + d.PolicyError
+	}
+	return summary
+}
+
+func usersTable(users []*mdmclient.User) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "USERNAME\tROLE\tPROVIDER\t2FA\tCREATED")
+		for _, u := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.Username, u.Role, u.AuthProvider, onOff(u.TotpEnabled), formatTime(u.CreatedAt))
+		}
+	}
+}
+
+func groupsTable(groups []*mdmclient.Group) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tHEARTBEAT\tCREATED")
+		for _, g := range groups {
+			interval := "default"
+			if g.HeartbeatIntervalSeconds > 0 {
+				interval = (time.Duration(g.HeartbeatIntervalSeconds) * time.Second).String()
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", g.ID, g.Name, interval, formatTime(g.CreatedAt))
+		}
+	}
+}
+
+func enrollmentTokenTable(t *mdmclient.EnrollmentToken) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "DEVICE ID\tENROLLMENT TOKEN\tEXPIRES")
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.DeviceID, t.EnrollmentToken, formatTime(t.ExpiresAt))
+	}
+}
+
+func commandsTable(commands []*mdmclient.Command) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tCREATED BY\tCREATED\tCOMPLETED\tRESULT")
+		for _, c := range commands {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				c.ID, c.Type, c.Status, orDash(c.CreatedBy), formatTime(c.CreatedAt), formatTimePtr(c.CompletedAt), orDash(c.Result))
+		}
+	}
+}
+
+func actionsTable(actions []*mdmclient.DeviceAction) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tDEVICE ID\tACTION\tSTATUS\tREQUESTED BY\tAPPROVED BY\tCREATED\tEXPIRES")
+		for _, act := range actions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				act.ID, act.DeviceID, act.Action, act.Status, orDash(act.RequestedBy), orDash(act.ApprovedBy),
+				formatTime(act.CreatedAt), formatTimePtr(act.ExpiresAt))
+		}
+	}
+}
+
+// appsTable — приложения; withDevice — с колонкой устройства (поиск по всем устройствам).
+func appsTable(apps []*mdmclient.App, withDevice bool) func(io.Writer) {
+	return func(w io.Writer) {
+		if withDevice {
+			fmt.Fprint(w, "DEVICE ID\t")
+		}
+		fmt.Fprintln(w, "PACKAGE\tVERSION\tINSTALLER\tINSTALLED")
+		for _, app := range apps {
+			if withDevice {
+				fmt.Fprintf(w, "%s\t", app.DeviceID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", app.PackageName, orDash(app.Version), orDash(app.Installer), formatTimePtr(app.InstalledAt))
+		}
+	}
+}
+
+func appEventsTable(events []*mdmclient.AppEvent) func(io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "TIME\tPACKAGE\tEVENT\tVERSION\tPREVIOUS")
+		for _, e := range events {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				formatTime(e.CreatedAt), e.PackageName, e.Event, orDash(e.Version), orDash(e.PreviousVersion))
+		}
+	}
+}
+
+// auditLine — событие журнала одной строкой; ширина колонок постоянная,
+// чтобы строки audit tail --follow, выведенные в разное время, были выровнены.
+func auditLine(w io.Writer, e *mdmclient.AuditEvent) {
+	fmt.Fprintf(w, "%s  %-24s %-6s %-40s %3d  %s\n",
+		e.CreatedAt.Local().Format("2006-01-02 15:04:05.000"), e.Actor, e.Method, e.Path, e.Status, e.RemoteAddr)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// readLine читает строку ввода без перевода строки.
+func (a *app) readLine() (string, error) {
+	if a.reader == nil {
+		a.reader = bufio.NewReader(a.stdin)
+	}
+	line, err := a.reader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("unexpected end of input")
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// prompt выводит приглашение и читает ответ.
+func (a *app) prompt(label string) (string, error) {
+	fmt.Fprint(a.stderr, label)
+	return a.readLine()
+}
+
+// promptSecret читает пароль или код, не отображая ввод, если stdin — терминал.
+func (a *app) promptSecret(label string) (string, error) {
+	fmt.Fprint(a.stderr, label)
+	if restore := a.disableEcho(); restore != nil {
+		defer func() {
+			restore()
+			fmt.Fprintln(a.stderr)
+		}()
+	}
+	return a.readLine()
+}
+
+// isTerminal сообщает, что stdin — терминал.
+func (a *app) isTerminal() bool {
+	f, ok := a.stdin.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// disableEcho отключает эхо терминала через stty и возвращает функцию,
+// которая его включает; nil — stdin не терминал или stty недоступен.
+func (a *app) disableEcho() func() {
+	if !a.isTerminal() {
+		return nil
+	}
+	stty := func(arg string) error {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = a.stdin.(*os.File)
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return nil
+	}
+	return func() { _ = stty("echo") }
+}
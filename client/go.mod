@@ -1,3 +1,5 @@
 module mdm-client
 
 go 1.23.0
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -1,145 +1,153 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
-)
-
-// Device соответствует JSON-структуре, возвращаемой сервером (см. модель Device в базе)
-type Device struct {
-	ID            string    `json:"id"`
-	DeviceID      string    `json:"device_id"`
-	CameraEnabled bool      `json:"camera_enabled"`
-	LastHeartbeat time.Time `json:"last_heartbeat"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-}
 
-// registerDevice отправляет запрос на регистрацию устройства (POST /devices/register)
-func registerDevice(server, deviceID string) (*Device, error) {
-	url := fmt.Sprintf("%s/devices/register", server)
-	payload := map[string]string{
-		"device_id": deviceID,
-	}
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	"mdm-client/mdmclient"
+	"mdm-client/mdmclient/actuators"
+	"mdm-client/mdmclient/collectors"
+)
 
-	// Если сервер вернул не OK, читаем тело ответа для диагностики
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("registration failed: %s", body)
+func main() {
+	// mdm-client simulate [флаги] — нагрузка на сервер виртуальными агентами.
+	if len(os.Args) > 1 && os.Args[1] == "simulate" {
+		simulate(os.Args[2:])
+		return
 	}
 
-	var device Device
-	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
-		return nil, err
-	}
-	return &device, nil
-}
+	// Парсинг флагов командной строки
+	deviceID := flag.String("device-id", "", "Уникальный идентификатор устройства")
+	serverURL := flag.String("server", "http://localhost:4000", "URL сервера MDM")
+	deviceToken := flag.String("device-token", "", "Токен устройства, выданный при прошлой регистрации")
+	enrollmentToken := flag.String("enrollment-token", "", "Токен перерегистрации от администратора (mdmctl devices enrollment-token) для стёртого устройства")
+	interval := flag.Duration("interval", 10*time.Second, "Период heartbeat, пока сервер не сообщил свой")
+	timeout := flag.Duration("timeout", 15*time.Second, "Таймаут одного запроса к серверу")
+	actuatorKind := flag.String("actuators", "dry-run", "Исполнители политики: dry-run, linux или none")
+	hook := flag.String("hook", "", "Команда для камеры и микрофона (linux); получает MDM_SETTING и MDM_ENABLED")
+	lockHook := flag.String("lock-hook", "", "Команда блокировки и разблокировки (linux); получает MDM_ACTION (lock/unlock) и MDM_MESSAGE")
+	wipeHook := flag.String("wipe-hook", "", "Команда стирания устройства (linux); без неё команда wipe отклоняется")
+	telemetry := flag.Bool("telemetry", true, "Отправлять с heartbeat телеметрию: заряд, ОС, uptime, диск, память")
+	appsInterval := flag.Duration("apps-interval", time.Hour, "Период проверки установленных приложений (dpkg); 0 — не отправлять список приложений")
+	statePath := flag.String("state", "", "Файл состояния агента (токен, политика, очередь отчётов); по умолчанию в каталоге настроек пользователя, \"none\" — не сохранять")
+	flag.Parse()
 
-// sendHeartbeat отправляет запрос heartbeat (POST /devices/{device_id}/heartbeat)
-func sendHeartbeat(server, deviceID string) (*Device, error) {
-	url := fmt.Sprintf("%s/devices/%s/heartbeat", server, deviceID)
-	resp, err := http.Post(url, "application/json", nil)
-	if err != nil {
-		return nil, err
+	if *deviceID == "" {
+		fmt.Println("Параметр --device-id обязателен")
+		os.Exit(1)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("heartbeat failed: %s", body)
+	var store mdmclient.StateStore
+	switch *statePath {
+	case "none":
+	case "":
+		if dir, err := os.UserConfigDir(); err == nil {
+			store = mdmclient.NewFileStore(filepath.Join(dir, "mdm-agent", url.PathEscape(*deviceID)+".json"))
+		} else {
+			log.Printf("Состояние не будет сохраняться: %v", err)
+		}
+	default:
+		store = mdmclient.NewFileStore(*statePath)
 	}
 
-	var device Device
-	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
-		return nil, err
+	var registry *mdmclient.Actuators
+	// actions — обработчики команд lock, unlock и wipe по типу команды.
+	actions := map[string]mdmclient.CommandHandler{}
+	switch *actuatorKind {
+	case "dry-run":
+		dryRun := &actuators.DryRun{Logger: log.Default()}
+		registry = actuators.DryRunAll(dryRun)
+		for _, action := range []string{mdmclient.CommandLock, mdmclient.CommandUnlock, mdmclient.CommandWipe} {
+			actions[action] = dryRun.Handle
+		}
+	case "linux":
+		registry = actuators.Linux(*hook)
+		if *lockHook != "" {
+			lock := &actuators.ActionHook{Command: *lockHook}
+			actions[mdmclient.CommandLock] = lock.Handle
+			actions[mdmclient.CommandUnlock] = lock.Handle
+		}
+		if *wipeHook != "" {
+			actions[mdmclient.CommandWipe] = (&actuators.ActionHook{Command: *wipeHook}).Handle
+		}
+	case "none":
+	default:
+		fmt.Printf("Неизвестные исполнители %q: ожидается dry-run, linux или none\n", *actuatorKind)
+		os.Exit(1)
 	}
-	return &device, nil
-}
 
-// getDeviceStatus получает статус устройства (GET /devices/{device_id}/status)
-func getDeviceStatus(server, deviceID string) (*Device, error) {
-	url := fmt.Sprintf("%s/devices/%s/status", server, deviceID)
-	resp, err := http.Get(url)
+	client, err := mdmclient.New(mdmclient.Config{
+		BaseURL:         *serverURL,
+		DeviceID:        *deviceID,
+		DeviceToken:     *deviceToken,
+		EnrollmentToken: *enrollmentToken,
+		Timeout:         *timeout,
+		UserAgent:       "mdm-client",
+	})
 	if err != nil {
-		return nil, err
+		log.Fatalf("Ошибка настройки клиента: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("get status failed: %s", body)
+	var telemetryCollectors []mdmclient.Collector
+	if *telemetry {
+		telemetryCollectors = collectors.Linux()
 	}
 
-	var device Device
-	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
-		return nil, err
+	var apps mdmclient.AppSource
+	if *appsInterval > 0 {
+		apps = &collectors.Dpkg{}
 	}
-	return &device, nil
-}
-
-func main() {
-	// Парсинг флагов командной строки
-	deviceID := flag.String("device-id", "", "Уникальный идентификатор устройства")
-	serverURL := flag.String("server", "http://localhost:4000", "URL сервера MDM")
-	flag.Parse()
 
-	if *deviceID == "" {
-		fmt.Println("Параметр --device-id обязателен")
-		os.Exit(1)
+	agent := mdmclient.NewAgent(client, mdmclient.AgentOptions{
+		Interval:     *interval,
+		Actuators:    registry,
+		Collectors:   telemetryCollectors,
+		Apps:         apps,
+		AppsInterval: *appsInterval,
+		Store:        store,
+	})
+	for action, handler := range actions {
+		agent.OnCommand(action, handler)
 	}
-
-	// Регистрируем устройство
-	device, err := registerDevice(*serverURL, *deviceID)
-	if err != nil {
-		log.Printf("Ошибка регистрации устройства: %v", err)
-		// Если устройство уже зарегистрировано, попробуем получить его статус
-		log.Printf("Пытаемся получить статус устройства")
-		device, err = getDeviceStatus(*serverURL, *deviceID)
-		if err != nil {
-			log.Fatalf("Не удалось получить статус устройства: %v", err)
+	agent.OnRegister(func(ctx context.Context, registration *mdmclient.Registration) {
+		log.Printf("Устройство зарегистрировано: %+v", registration.Device)
+		// Сам токен в лог не пишется: он хранится в файле состояния (0600).
+		if registration.DeviceToken != "" && store == nil {
+			log.Printf("Выдан токен устройства, но состояние не сохраняется (--state=none): после перезапуска понадобится токен перерегистрации")
 		}
-	}
-	log.Printf("Устройство зарегистрировано: %+v", device)
-
-	// Сохраним текущее состояние камеры для отслеживания изменений
-	currentCameraState := device.CameraEnabled
-
-	// Периодически отправляем heartbeat (например, каждые 10 секунд)
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		updatedDevice, err := sendHeartbeat(*serverURL, *deviceID)
-		if err != nil {
-			log.Printf("Ошибка отправки heartbeat: %v", err)
-			continue
+	})
+	agent.OnPolicyChange(func(ctx context.Context, change mdmclient.PolicyChange) {
+		// При первом получении политики только запоминаем состояние.
+		if change.Previous == nil {
+			log.Printf("Текущая политика: %+v", change.Current)
+			return
 		}
-		log.Printf("Получен heartbeat: %+v", updatedDevice)
-
-		// Если изменилось состояние камеры, логируем событие
-		if updatedDevice.CameraEnabled != currentCameraState {
-			currentCameraState = updatedDevice.CameraEnabled
-			if currentCameraState {
+		if change.Current.CameraEnabled != change.Previous.CameraEnabled {
+			if change.Current.CameraEnabled {
 				log.Printf("Команда: Включить камеру для устройства %s", *deviceID)
 			} else {
 				log.Printf("Команда: Выключить камеру для устройства %s", *deviceID)
 			}
 		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = agent.Run(ctx)
+	if errors.Is(err, mdmclient.ErrDeviceWiped) {
+		log.Printf("Устройство %s стёрто; для повторной регистрации запустите агент снова", *deviceID)
+		return
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatalf("Агент остановлен: %v", err)
 	}
 }
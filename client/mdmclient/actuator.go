@@ -0,0 +1,142 @@
+package mdmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Настройки устройства, которыми управляет политика.
+const (
+	SettingCamera     = "camera"
+	SettingMicrophone = "microphone"
+	SettingBluetooth  = "bluetooth"
+)
+
+// Settings возвращает значения настроек политики по именам.
+func (p Policy) Settings() map[string]bool {
+	return map[string]bool{
+		SettingCamera:     p.CameraEnabled,
+		SettingMicrophone: p.MicrophoneEnabled,
+		SettingBluetooth:  p.BluetoothEnabled,
+	}
+}
+
+// Actuator применяет настройку на устройстве (включает или выключает камеру,
+// Bluetooth и т. п.). Реализации — в пакете mdmclient/actuators.
+type Actuator interface {
+	Apply(ctx context.Context, setting string, enabled bool) error
+}
+
+// ActuatorFunc — функция-исполнитель.
+type ActuatorFunc func(ctx context.Context, setting string, enabled bool) error
+
+func (f ActuatorFunc) Apply(ctx context.Context, setting string, enabled bool) error {
+	return f(ctx, setting, enabled)
+}
+
+// Simulator — исполнитель, который не меняет настройки на устройстве
+// (например, actuators.DryRun). Его результаты не считаются применёнными.
+type Simulator interface {
+	Simulated() bool
+}
+
+// ErrNoActuator — для настройки не зарегистрирован исполнитель.
+var ErrNoActuator = errors.New("no actuator")
+
+// SettingResult — результат применения одной настройки.
+type SettingResult struct {
+	Setting string `json:"setting"`
+	Enabled bool   `json:"enabled"`
+	// Enforced — настройка действительно применена на устройстве: без ошибки
+	// и не исполнителем-имитатором (Simulator).
+	Enforced bool `json:"enforced"`
+	// Error — пусто, если настройку удалось применить; ErrNoActuator, если применить её нечем.
+	Error string `json:"error,omitempty"`
+}
+
+// PolicyReport — отчёт агента о применении версии настроек.
+type PolicyReport struct {
+	Version int64           `json:"version"`
+	Results []SettingResult `json:"results"`
+}
+
+// Failed сообщает, что хотя бы одну настройку применить не удалось.
+func (r *PolicyReport) Failed() bool {
+	for _, result := range r.Results {
+		if result.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable сообщает, что применение стоит повторить: хотя бы одна настройка
+// не применилась, и дело не в отсутствии исполнителя (он не появится до перезапуска).
+func (r *PolicyReport) retryable() bool {
+	for _, result := range r.Results {
+		if result.Error != "" && result.Error != ErrNoActuator.Error() {
+			return true
+		}
+	}
+	return false
+}
+
+// Actuators — реестр исполнителей по настройкам. Настройки без исполнителя попадают
+// в результаты с ошибкой ErrNoActuator: сервер видит, что политика не применена.
+type Actuators struct {
+	mu        sync.RWMutex
+	actuators map[string]Actuator
+}
+
+// NewActuators создаёт пустой реестр.
+func NewActuators() *Actuators {
+	return &Actuators{actuators: make(map[string]Actuator)}
+}
+
+// Register задаёт исполнителя настройки setting.
+func (r *Actuators) Register(setting string, actuator Actuator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actuators[setting] = actuator
+}
+
+// Apply применяет политику и возвращает результаты по всем настройкам в порядке имён.
+func (r *Actuators) Apply(ctx context.Context, policy Policy) []SettingResult {
+	settings := policy.Settings()
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var results []SettingResult
+	for _, name := range names {
+		result := SettingResult{Setting: name, Enabled: settings[name]}
+		actuator, ok := r.actuators[name]
+		if !ok {
+			result.Error = ErrNoActuator.Error()
+		} else if err := applySafely(ctx, actuator, name, settings[name]); err != nil {
+			result.Error = err.Error()
+		} else {
+			simulator, ok := actuator.(Simulator)
+			result.Enforced = !ok || !simulator.Simulated()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// applySafely не даёт панике в исполнителе остановить агента.
+func applySafely(ctx context.Context, actuator Actuator, setting string, enabled bool) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("actuator panic: %v", r)
+		}
+	}()
+	return actuator.Apply(ctx, setting, enabled)
+}
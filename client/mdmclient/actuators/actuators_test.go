@@ -0,0 +1,92 @@
+package actuators
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mdm-client/mdmclient"
+)
+
+func TestRFKill(t *testing.T) {
+	root := t.TempDir()
+	for name, kind := range map[string]string{"rfkill0": "bluetooth", "rfkill1": "wlan"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.WriteFile(filepath.Join(dir, "type"), []byte(kind+"\n"), 0o644)
+		_ = os.WriteFile(filepath.Join(dir, "soft"), []byte("0"), 0o644)
+	}
+
+	rfkill := &RFKill{Type: "bluetooth", Root: root}
+	if err := rfkill.Apply(context.Background(), mdmclient.SettingBluetooth, false); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if soft, _ := os.ReadFile(filepath.Join(root, "rfkill0", "soft")); string(soft) != "1" {
+		t.Errorf("Expected bluetooth to be blocked, got %q", soft)
+	}
+	if soft, _ := os.ReadFile(filepath.Join(root, "rfkill1", "soft")); string(soft) != "0" {
+		t.Errorf("Expected wlan to stay unblocked, got %q", soft)
+	}
+
+	missing := &RFKill{Type: "nfc", Root: root}
+	if err := missing.Apply(context.Background(), "nfc", true); err == nil {
+		t.Errorf("Expected an error without matching devices")
+	}
+}
+
+func TestShellHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	hook := &ShellHook{Command: `echo "$MDM_SETTING=$MDM_ENABLED" > ` + out}
+	if err := hook.Apply(context.Background(), mdmclient.SettingCamera, false); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if data, _ := os.ReadFile(out); strings.TrimSpace(string(data)) != "camera=false" {
+		t.Errorf("Unexpected hook environment: %q", data)
+	}
+
+	failing := &ShellHook{Command: "echo denied >&2; exit 3"}
+	err := failing.Apply(context.Background(), mdmclient.SettingCamera, true)
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected hook failure with its output, got %v", err)
+	}
+}
+
+func TestActionHook(t *testing.T) {
+	hook := &ActionHook{Command: `echo "$MDM_ACTION:$MDM_MESSAGE"`}
+	result, err := hook.Handle(context.Background(), &mdmclient.Command{Type: mdmclient.CommandLock, Payload: `{"message":"Lost device"}`})
+	if err != nil || result != "lock:Lost device" {
+		t.Fatalf("Expected hook output as result, got %q, %v", result, err)
+	}
+
+	failing := &ActionHook{Command: "echo no disk >&2; exit 1"}
+	if _, err := failing.Handle(context.Background(), &mdmclient.Command{Type: mdmclient.CommandWipe}); err == nil || !strings.Contains(err.Error(), "no disk") {
+		t.Errorf("Expected hook failure with its output, got %v", err)
+	}
+}
+
+func TestDryRunRegistry(t *testing.T) {
+	dryRun := &DryRun{}
+	dryRun.Fail(mdmclient.SettingBluetooth, errors.New("busy"))
+	results := DryRunAll(dryRun).Apply(context.Background(), mdmclient.Policy{CameraEnabled: true})
+
+	if len(results) != 3 {
+		t.Fatalf("Expected a result per setting, got %+v", results)
+	}
+	if enabled, ok := dryRun.Applied(mdmclient.SettingCamera); !ok || !enabled {
+		t.Errorf("Expected camera=true to be applied")
+	}
+	report := mdmclient.PolicyReport{Results: results}
+	if !report.Failed() {
+		t.Errorf("Expected the bluetooth failure in the report, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Enforced {
+			t.Errorf("Expected dry-run results not to be enforced, got %+v", result)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package actuators
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"mdm-client/mdmclient"
+)
+
+// DryRun ничего не меняет на устройстве: записывает и логирует применённые
+// значения. Подходит для тестов и запуска агента вне реального устройства.
+type DryRun struct {
+	// Logger — nil, чтобы не логировать.
+	Logger *log.Logger
+
+	mu      sync.Mutex
+	applied map[string]bool
+	errs    map[string]error
+	actions []string
+}
+
+// Fail заставляет Apply для setting возвращать err (nil — снова успешно).
+func (d *DryRun) Fail(setting string, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.errs == nil {
+		d.errs = make(map[string]error)
+	}
+	d.errs[setting] = err
+}
+
+// Applied возвращает последнее применённое значение настройки.
+func (d *DryRun) Applied(setting string) (enabled bool, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	enabled, ok = d.applied[setting]
+	return enabled, ok
+}
+
+// Simulated отмечает результаты DryRun как неприменённые (см. mdmclient.Simulator).
+func (d *DryRun) Simulated() bool {
+	return true
+}
+
+func (d *DryRun) Apply(ctx context.Context, setting string, enabled bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.errs[setting]; err != nil {
+		return err
+	}
+	if d.applied == nil {
+		d.applied = make(map[string]bool)
+	}
+	d.applied[setting] = enabled
+	if d.Logger != nil {
+		d.Logger.Printf("[dry-run] %s=%t", setting, enabled)
+	}
+	return nil
+}
+
+// Handle записывает и логирует действие (lock, unlock, wipe), ничего не делая;
+// подходит как обработчик Agent.OnCommand. Ошибку можно задать через Fail(cmd.Type, ...).
+func (d *DryRun) Handle(ctx context.Context, cmd *mdmclient.Command) (string, error) {
+	var payload mdmclient.ActionPayload
+	_ = mdmclient.DecodePayload(cmd, &payload)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.errs[cmd.Type]; err != nil {
+		return "", err
+	}
+	d.actions = append(d.actions, cmd.Type)
+	if d.Logger != nil {
+		d.Logger.Printf("[dry-run] %s %s", cmd.Type, payload.Message)
+	}
+	return "dry-run", nil
+}
+
+// Actions возвращает выполненные действия в порядке выполнения.
+func (d *DryRun) Actions() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.actions...)
+}
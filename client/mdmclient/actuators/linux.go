@@ -0,0 +1,26 @@
+package actuators
+
+import "mdm-client/mdmclient"
+
+// Linux возвращает исполнителей для Linux: Bluetooth — через rfkill, камера и
+// микрофон — через hook (для них в ядре нет общего выключателя). Пустой hook —
+// камера и микрофон не применяются.
+func Linux(hook string) *mdmclient.Actuators {
+	registry := mdmclient.NewActuators()
+	registry.Register(mdmclient.SettingBluetooth, &RFKill{Type: "bluetooth"})
+	if hook != "" {
+		shell := &ShellHook{Command: hook}
+		registry.Register(mdmclient.SettingCamera, shell)
+		registry.Register(mdmclient.SettingMicrophone, shell)
+	}
+	return registry
+}
+
+// DryRunAll возвращает реестр, где все настройки политики применяет dryRun.
+func DryRunAll(dryRun *DryRun) *mdmclient.Actuators {
+	registry := mdmclient.NewActuators()
+	for setting := range (mdmclient.Policy{}).Settings() {
+		registry.Register(setting, dryRun)
+	}
+	return registry
+}
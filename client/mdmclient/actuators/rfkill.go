@@ -0,0 +1,55 @@
+// Package actuators — исполнители политики для агента (см. mdmclient.Actuator).
+package actuators
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultRFKillRoot — каталог rfkill в sysfs Linux.
+const defaultRFKillRoot = "/sys/class/rfkill"
+
+// RFKill включает и выключает радиомодули (Bluetooth, Wi-Fi) через программную
+// блокировку rfkill в sysfs. Нужны права на запись в /sys/class/rfkill/*/soft.
+type RFKill struct {
+	// Type — тип модулей rfkill: "bluetooth", "wlan", ...
+	Type string
+	// Root — каталог rfkill; пусто — /sys/class/rfkill.
+	Root string
+}
+
+// Apply снимает или ставит блокировку на все модули типа Type.
+func (r *RFKill) Apply(ctx context.Context, setting string, enabled bool) error {
+	root := r.Root
+	if root == "" {
+		root = defaultRFKillRoot
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return fmt.Errorf("rfkill: %w", err)
+	}
+
+	soft := "1"
+	if enabled {
+		soft = "0"
+	}
+	found := 0
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		kind, err := os.ReadFile(filepath.Join(dir, "type"))
+		if err != nil || strings.TrimSpace(string(kind)) != r.Type {
+			continue
+		}
+		found++
+		if err := os.WriteFile(filepath.Join(dir, "soft"), []byte(soft), 0o644); err != nil {
+			return fmt.Errorf("rfkill %s: %w", entry.Name(), err)
+		}
+	}
+	if found == 0 {
+		return fmt.Errorf("rfkill: no %s devices in %s", r.Type, root)
+	}
+	return nil
+}
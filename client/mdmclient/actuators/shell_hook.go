@@ -0,0 +1,72 @@
+package actuators
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// defaultHookTimeout — сколько ждать завершения хука, если ShellHook.Timeout не задан.
+const defaultHookTimeout = 30 * time.Second
+
+// ShellHook применяет настройку внешней командой: например, выгружает модуль
+// uvcvideo для камеры или выключает микрофон через amixer. Команда выполняется
+// через sh -c с переменными окружения MDM_SETTING (camera, microphone, ...) и
+// MDM_ENABLED (true/false); ненулевой код выхода считается ошибкой.
+type ShellHook struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (h *ShellHook) Apply(ctx context.Context, setting string, enabled bool) error {
+	_, err := runHook(ctx, h.Command, h.Timeout, "MDM_SETTING="+setting, "MDM_ENABLED="+strconv.FormatBool(enabled))
+	return err
+}
+
+// ActionHook выполняет действие над устройством (lock, unlock, wipe) внешней
+// командой: например, loginctl lock-sessions или скриптом стирания данных.
+// Команда выполняется через sh -c с переменными MDM_ACTION (тип команды) и
+// MDM_MESSAGE (текст для экрана блокировки); её вывод уходит на сервер результатом.
+type ActionHook struct {
+	Command string
+	Timeout time.Duration
+}
+
+// Handle — обработчик команд для Agent.OnCommand.
+func (h *ActionHook) Handle(ctx context.Context, cmd *mdmclient.Command) (string, error) {
+	var payload mdmclient.ActionPayload
+	if err := mdmclient.DecodePayload(cmd, &payload); err != nil {
+		return "", fmt.Errorf("invalid payload: %w", err)
+	}
+	return runHook(ctx, h.Command, h.Timeout, "MDM_ACTION="+cmd.Type, "MDM_MESSAGE="+payload.Message)
+}
+
+// runHook выполняет command через sh -c с дополнительными переменными окружения
+// и возвращает её вывод; при ненулевом коде выхода вывод попадает в ошибку.
+func runHook(ctx context.Context, command string, timeout time.Duration, env ...string) (string, error) {
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return "", fmt.Errorf("hook: %w: %s", err, message)
+		}
+		return "", fmt.Errorf("hook: %w", err)
+	}
+	return strings.TrimSpace(output.String()), nil
+}
@@ -0,0 +1,394 @@
+package mdmclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Административные вызовы: нужен Config.Token — JWT пользователя (см. Login)
+// или API-ключ с соответствующими правами.
+
+// LoginResponse — ответ на вход. Если включена 2FA, вместо Token приходит
+// MFAToken: вход завершает CompleteLogin с кодом.
+type LoginResponse struct {
+	Token string `json:"token,omitempty"`
+	// MFARequired — нужен код из приложения-аутентификатора или код восстановления.
+	MFARequired bool `json:"mfa_required,omitempty"`
+	// EnrollmentRequired — роль требует 2FA, а она ещё не подключена.
+	EnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken           string `json:"mfa_token,omitempty"`
+	ExpiresIn          int    `json:"expires_in,omitempty"`
+}
+
+// Login входит по имени и паролю. Токен из ответа передаётся в Config.Token
+// нового клиента.
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	var response LoginResponse
+	body := map[string]string{"username": username, "password": password}
+	if err := c.request(ctx, http.MethodPost, "/login", "", nil, body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CompleteLogin завершает вход с 2FA: otp — код из приложения или recoveryCode —
+// код восстановления (одно из двух).
+func (c *Client) CompleteLogin(ctx context.Context, mfaToken, otp, recoveryCode string) (*LoginResponse, error) {
+	body := map[string]string{"mfa_token": mfaToken}
+	if otp != "" {
+		body["otp"] = otp
+	}
+	if recoveryCode != "" {
+		body["recovery_code"] = recoveryCode
+	}
+	var response LoginResponse
+	if err := c.request(ctx, http.MethodPost, "/login", "", nil, body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// DeviceFilter — параметры поиска устройств; пустые поля не применяются.
+type DeviceFilter struct {
+	// Search — подстрока device_id.
+	Search            string
+	CameraEnabled     *bool
+	MicrophoneEnabled *bool
+	BluetoothEnabled  *bool
+	// SeenSince — только устройства, присылавшие heartbeat после этого момента.
+	SeenSince time.Time
+	// Archived — архив выведенных из эксплуатации устройств вместо действующих.
+	Archived bool
+	// IncludeDeleted — показывать в архиве и удалённые устройства.
+	IncludeDeleted bool
+	Limit          int
+	Offset         int
+}
+
+func (f DeviceFilter) query() url.Values {
+	values := url.Values{}
+	if f.Search != "" {
+		values.Set("search", f.Search)
+	}
+	for name, value := range map[string]*bool{
+		"camera_enabled":     f.CameraEnabled,
+		"microphone_enabled": f.MicrophoneEnabled,
+		"bluetooth_enabled":  f.BluetoothEnabled,
+	} {
+		if value != nil {
+			values.Set(name, strconv.FormatBool(*value))
+		}
+	}
+	if f.Archived {
+		values.Set("archived", "true")
+	}
+	if f.IncludeDeleted {
+		values.Set("include_deleted", "true")
+	}
+	if !f.SeenSince.IsZero() {
+		values.Set("seen_since", f.SeenSince.UTC().Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		values.Set("offset", strconv.Itoa(f.Offset))
+	}
+	return values
+}
+
+// ListDevices ищет устройства (право devices:read).
+func (c *Client) ListDevices(ctx context.Context, filter DeviceFilter) ([]*Device, error) {
+	var devices []*Device
+	if err := c.do(ctx, http.MethodGet, withQuery("/devices", filter.query()), nil, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// GetDevice возвращает устройство deviceID (право devices:read).
+func (c *Client) GetDevice(ctx context.Context, deviceID string) (*Device, error) {
+	var device Device
+	if err := c.do(ctx, http.MethodGet, "/devices/"+url.PathEscape(deviceID), nil, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// RetireDevice выводит устройство из эксплуатации: его токен отзывается, а
+// история остаётся в архиве (право devices:retire).
+func (c *Client) RetireDevice(ctx context.Context, deviceID string) (*Device, error) {
+	var device Device
+	if err := c.do(ctx, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/retire", nil, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// IssueEnrollmentToken выдаёт одноразовый токен, с которым агент стёртого
+// устройства или устройства, потерявшего токен, регистрируется заново (право devices:write).
+func (c *Client) IssueEnrollmentToken(ctx context.Context, deviceID string) (*EnrollmentToken, error) {
+	var token EnrollmentToken
+	if err := c.do(ctx, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/enrollment-token", nil, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteDevice удаляет устройство; запись и история стираются окончательно
+// после срока хранения на сервере (право devices:retire).
+func (c *Client) DeleteDevice(ctx context.Context, deviceID string) error {
+	return c.do(ctx, http.MethodDelete, "/devices/"+url.PathEscape(deviceID), nil, nil)
+}
+
+// SetSetting включает или выключает настройку (SettingCamera, SettingMicrophone,
+// SettingBluetooth) устройства (право devices:write). version > 0 — изменение
+// применяется только к этой версии настроек (If-Match), иначе 409.
+func (c *Client) SetSetting(ctx context.Context, deviceID, setting string, enabled bool, version int64) (*Device, error) {
+	var header http.Header
+	if version > 0 {
+		header = http.Header{"If-Match": {strconv.Quote(strconv.FormatInt(version, 10))}}
+	}
+	var device Device
+	path := "/devices/" + url.PathEscape(deviceID) + "/" + url.PathEscape(setting)
+	if err := c.request(ctx, http.MethodPost, path, c.token, header, map[string]bool{"enabled": enabled}, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ListCommands возвращает команды устройства, новые первыми (право devices:read).
+func (c *Client) ListCommands(ctx context.Context, deviceID string, limit int) ([]*Command, error) {
+	values := url.Values{}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	var commands []*Command
+	if err := c.do(ctx, http.MethodGet, withQuery("/devices/"+url.PathEscape(deviceID)+"/commands", values), nil, &commands); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// ListApps возвращает приложения, установленные на устройстве (право devices:read).
+func (c *Client) ListApps(ctx context.Context, deviceID string) ([]*App, error) {
+	var apps []*App
+	if err := c.do(ctx, http.MethodGet, "/devices/"+url.PathEscape(deviceID)+"/apps", nil, &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// AppHistory возвращает установки, обновления и удаления приложений устройства,
+// новые первыми; пустой packageName — все приложения (право devices:read).
+func (c *Client) AppHistory(ctx context.Context, deviceID, packageName string, limit int) ([]*AppEvent, error) {
+	values := url.Values{}
+	if packageName != "" {
+		values.Set("package", packageName)
+	}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	var events []*AppEvent
+	if err := c.do(ctx, http.MethodGet, withQuery("/devices/"+url.PathEscape(deviceID)+"/apps/history", values), nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// FindApp возвращает установки приложения packageName на действующих устройствах;
+// непустой below оставляет только версии ниже него (право devices:read).
+func (c *Client) FindApp(ctx context.Context, packageName, below string) ([]*App, error) {
+	values := url.Values{"package": {packageName}}
+	if below != "" {
+		values.Set("below", below)
+	}
+	var apps []*App
+	if err := c.do(ctx, http.MethodGet, withQuery("/apps", values), nil, &apps); err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// LockDevice блокирует устройство; message показывается на экране блокировки
+// (право devices:write).
+func (c *Client) LockDevice(ctx context.Context, deviceID, message string) (*DeviceAction, error) {
+	return c.deviceAction(ctx, deviceID, CommandLock, map[string]string{"message": message})
+}
+
+// UnlockDevice снимает блокировку с устройства (право devices:write).
+func (c *Client) UnlockDevice(ctx context.Context, deviceID string) (*DeviceAction, error) {
+	return c.deviceAction(ctx, deviceID, CommandUnlock, map[string]string{})
+}
+
+// WipeDevice стирает устройство (право devices:wipe). С паролем текущего
+// пользователя (и при включённой 2FA — otp или recoveryCode) стирание выдаётся
+// сразу; с пустым password запрос ждёт подтверждения другим администратором
+// (статус ActionPendingApproval).
+func (c *Client) WipeDevice(ctx context.Context, deviceID, password, otp, recoveryCode string) (*DeviceAction, error) {
+	body := map[string]string{}
+	if password != "" {
+		body["password"] = password
+	}
+	if otp != "" {
+		body["otp"] = otp
+	}
+	if recoveryCode != "" {
+		body["recovery_code"] = recoveryCode
+	}
+	return c.deviceAction(ctx, deviceID, CommandWipe, body)
+}
+
+func (c *Client) deviceAction(ctx context.Context, deviceID, action string, body map[string]string) (*DeviceAction, error) {
+	var created DeviceAction
+	if err := c.do(ctx, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/"+action, body, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ActionQuery — выборка действий над устройствами. Пустые поля не применяются.
+type ActionQuery struct {
+	DeviceID string
+	// Status — например, ActionPendingApproval.
+	Status string
+	Limit  int
+}
+
+// ListActions возвращает блокировки и стирания, новые первыми (право devices:read).
+func (c *Client) ListActions(ctx context.Context, q ActionQuery) ([]*DeviceAction, error) {
+	values := url.Values{}
+	if q.DeviceID != "" {
+		values.Set("device_id", q.DeviceID)
+	}
+	if q.Status != "" {
+		values.Set("status", q.Status)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var actions []*DeviceAction
+	if err := c.do(ctx, http.MethodGet, withQuery("/device-actions", values), nil, &actions); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// ApproveAction подтверждает чужой запрос на стирание (право devices:wipe).
+func (c *Client) ApproveAction(ctx context.Context, actionID string) (*DeviceAction, error) {
+	var action DeviceAction
+	if err := c.do(ctx, http.MethodPost, "/device-actions/"+url.PathEscape(actionID)+"/approve", nil, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// RejectAction отклоняет запрос на стирание (право devices:wipe).
+func (c *Client) RejectAction(ctx context.Context, actionID string) (*DeviceAction, error) {
+	var action DeviceAction
+	if err := c.do(ctx, http.MethodPost, "/device-actions/"+url.PathEscape(actionID)+"/reject", nil, &action); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// ListGroups возвращает группы устройств (право devices:read).
+func (c *Client) ListGroups(ctx context.Context) ([]*Group, error) {
+	var groups []*Group
+	if err := c.do(ctx, http.MethodGet, "/device-groups", nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// CreateGroup создаёт группу (право devices:write); heartbeatInterval 0 — период по умолчанию.
+func (c *Client) CreateGroup(ctx context.Context, name string, heartbeatInterval time.Duration) (*Group, error) {
+	body := map[string]string{"name": name, "heartbeat_interval": heartbeatInterval.String()}
+	var group Group
+	if err := c.do(ctx, http.MethodPost, "/device-groups", body, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// SetGroupHeartbeatInterval меняет период heartbeat группы (право devices:write).
+func (c *Client) SetGroupHeartbeatInterval(ctx context.Context, groupID string, heartbeatInterval time.Duration) (*Group, error) {
+	body := map[string]string{"heartbeat_interval": heartbeatInterval.String()}
+	var group Group
+	if err := c.do(ctx, http.MethodPut, "/device-groups/"+url.PathEscape(groupID), body, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteGroup удаляет группу; её устройства остаются без группы (право devices:write).
+func (c *Client) DeleteGroup(ctx context.Context, groupID string) error {
+	return c.do(ctx, http.MethodDelete, "/device-groups/"+url.PathEscape(groupID), nil, nil)
+}
+
+// AssignGroup переносит устройство в группу; пустой groupID убирает его из группы
+// (право devices:write).
+func (c *Client) AssignGroup(ctx context.Context, deviceID, groupID string) (*Device, error) {
+	var device Device
+	if err := c.do(ctx, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/group", map[string]string{"group_id": groupID}, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ListUsers возвращает пользователей (право users:manage).
+func (c *Client) ListUsers(ctx context.Context) ([]*User, error) {
+	var users []*User
+	if err := c.do(ctx, http.MethodGet, "/users", nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateUser создаёт пользователя с входом по паролю (право users:manage).
+func (c *Client) CreateUser(ctx context.Context, username, password, role string) (*User, error) {
+	body := map[string]string{"username": username, "password": password, "role": role}
+	var user User
+	if err := c.do(ctx, http.MethodPost, "/users", body, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// AuditQuery — выборка журнала аудита.
+type AuditQuery struct {
+	// Since — только события после этого момента; нулевое — последние Limit событий.
+	Since time.Time
+	// Actor — только события субъекта вида "user:bob" или "api_key:ci".
+	Actor string
+	Limit int
+}
+
+// ListAudit возвращает события журнала аудита по возрастанию времени (право audit:read).
+func (c *Client) ListAudit(ctx context.Context, q AuditQuery) ([]*AuditEvent, error) {
+	values := url.Values{}
+	if !q.Since.IsZero() {
+		values.Set("since", q.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if q.Actor != "" {
+		values.Set("actor", q.Actor)
+	}
+	if q.Limit > 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var events []*AuditEvent
+	if err := c.do(ctx, http.MethodGet, withQuery("/audit", values), nil, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func withQuery(path string, values url.Values) string {
+	if len(values) == 0 {
+		return path
+	}
+	return path + "?" + values.Encode()
+}
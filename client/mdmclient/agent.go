@@ -0,0 +1,646 @@
+package mdmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultInterval — период heartbeat, если AgentOptions.Interval не задан.
+	defaultInterval = 10 * time.Second
+	// minInterval — нижняя граница периода heartbeat, откуда бы он ни пришёл.
+	minInterval = time.Second
+)
+
+// ErrDeviceWiped возвращает Run, когда устройство стёрто по команде сервера:
+// токен отозван, сохранённое состояние очищено, для возврата под управление
+// агент нужно запустить снова — он зарегистрирует устройство заново.
+var ErrDeviceWiped = errors.New("mdmclient: device has been wiped")
+
+// PolicyChange — событие изменения политики устройства.
+type PolicyChange struct {
+	// Previous — nil при первом получении политики после запуска.
+	Previous *Policy
+	Current  Policy
+}
+
+// PolicyHandler вызывается, когда политика устройства изменилась.
+type PolicyHandler func(ctx context.Context, change PolicyChange)
+
+// CommandHandler выполняет команду. Текст результата (или ошибки) отправляется на сервер.
+type CommandHandler func(ctx context.Context, cmd *Command) (string, error)
+
+// RegisterHandler вызывается после успешной регистрации устройства.
+type RegisterHandler func(ctx context.Context, registration *Registration)
+
+// AgentOptions — настройки агента.
+type AgentOptions struct {
+	// Interval — период heartbeat, пока сервер не сообщил свой
+	// (heartbeat_interval_seconds в ответе на heartbeat).
+	Interval time.Duration
+	// Backoff — паузы между повторами после ошибок регистрации и heartbeat;
+	// незаданные поля берутся из DefaultBackoff. Retry-After сервера соблюдается всегда.
+	Backoff Backoff
+	// Logger — журнал агента; по умолчанию log.Default().
+	Logger *log.Logger
+	// Actuators применяют политику на устройстве; агент сообщает результат серверу.
+	// nil — политика только передаётся обработчикам OnPolicyChange.
+	Actuators *Actuators
+	// Collectors собирают телеметрию, которая отправляется с каждым heartbeat.
+	Collectors []Collector
+	// Apps — источник списка установленных приложений; nil — список не отправляется.
+	// Сервер получает полный список один раз, дальше только изменения.
+	Apps AppSource
+	// AppsInterval — как часто проверять установленные приложения; по умолчанию час.
+	AppsInterval time.Duration
+	// Store сохраняет токен устройства, последнюю политику и неотправленные отчёты.
+	// С ним агент после перезапуска сразу применяет последнюю политику и продолжает
+	// работать без сервера. nil — состояние только в памяти.
+	Store StateStore
+}
+
+// Agent — цикл агента устройства: регистрация, периодический heartbeat,
+// события изменения политики и выполнение команд сервера.
+// Обработчики регистрируются до вызова Run и выполняются в горутине Run по очереди.
+type Agent struct {
+	client     *Client
+	interval   time.Duration
+	backoff    Backoff
+	logger     *log.Logger
+	actuators  *Actuators
+	collectors []Collector
+	store      StateStore
+	apps       AppSource
+	// appsInterval — период проверки приложений.
+	appsInterval time.Duration
+
+	mu               sync.Mutex
+	registerHandlers []RegisterHandler
+	policyHandlers   []PolicyHandler
+	commandHandlers  map[string]CommandHandler
+	policy           *Policy
+	// device — последнее состояние устройства, полученное от сервера.
+	device *Device
+	// serverInterval — период heartbeat из последнего ответа сервера, 0 — не задан.
+	serverInterval time.Duration
+	// unreported — результаты выполненных команд, которые не удалось отправить:
+	// повторно такие команды не выполняются, отправка повторяется.
+	unreported map[string]CommandResult
+	// queuedTelemetry — снимки телеметрии, снятые без связи, от старых к новым.
+	queuedTelemetry []TelemetrySnapshot
+	// applied — последний результат применения политики; policyReported —
+	// дошёл ли он до сервера.
+	applied        *PolicyReport
+	policyReported bool
+	// wipeCommandID — выполненное стирание, результат которого ещё не принят
+	// сервером; wiped — сервер принял его, работа агента закончена.
+	wipeCommandID string
+	wiped         bool
+	// reportedApps — приложения, которые сервер принял как версию инвентаря
+	// appsVersion; nil — следующий отчёт полный. appsCheckedAt — время последней проверки.
+	reportedApps  map[string]InstalledApp
+	appsVersion   int64
+	appsCheckedAt time.Time
+}
+
+// NewAgent создаёт агента для устройства client.DeviceID().
+func NewAgent(client *Client, opts AgentOptions) *Agent {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	appsInterval := opts.AppsInterval
+	if appsInterval <= 0 {
+		appsInterval = defaultAppsInterval
+	}
+	return &Agent{
+		client:          client,
+		interval:        interval,
+		backoff:         opts.Backoff.withDefaults(),
+		logger:          logger,
+		actuators:       opts.Actuators,
+		collectors:      opts.Collectors,
+		store:           opts.Store,
+		apps:            opts.Apps,
+		appsInterval:    appsInterval,
+		commandHandlers: make(map[string]CommandHandler),
+		unreported:      make(map[string]CommandResult),
+	}
+}
+
+// OnRegister добавляет обработчик успешной регистрации.
+func (a *Agent) OnRegister(handler RegisterHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.registerHandlers = append(a.registerHandlers, handler)
+}
+
+// OnPolicyChange добавляет обработчик изменения политики.
+func (a *Agent) OnPolicyChange(handler PolicyHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policyHandlers = append(a.policyHandlers, handler)
+}
+
+// OnCommand задаёт обработчик команд типа commandType. Команды без обработчика
+// завершаются с ошибкой "unsupported command".
+func (a *Agent) OnCommand(commandType string, handler CommandHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.commandHandlers[commandType] = handler
+}
+
+// Policy возвращает последнюю полученную политику.
+func (a *Agent) Policy() (Policy, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.policy == nil {
+		return Policy{}, false
+	}
+	return *a.policy, true
+}
+
+// Run регистрирует устройство и отправляет heartbeat, пока не отменён ctx.
+// Период heartbeat задаёт сервер, с разбросом ±10%. После ошибки запросы
+// повторяются с нарастающей паузой (AgentOptions.Backoff), но не раньше
+// Retry-After; ошибки только логируются. Если есть сохранённое состояние, его
+// политика применяется сразу, иначе агент ждёт регистрации, повторяя её.
+func (a *Agent) Run(ctx context.Context) error {
+	err := a.start(ctx)
+	for attempt := 0; err != nil; attempt++ {
+		delay := a.backoff.RetryDelay(err, attempt)
+		a.logger.Printf("Ошибка регистрации, повтор через %s: %v", delay.Round(time.Millisecond), err)
+		if !sleep(ctx, delay) {
+			return ctx.Err()
+		}
+		err = a.connect(ctx)
+	}
+
+	delay := a.nextInterval()
+	for failures := 0; ; {
+		if !sleep(ctx, delay) {
+			return ctx.Err()
+		}
+		if err := a.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, ErrDeviceWiped) {
+				a.forget()
+				return err
+			}
+			delay = a.backoff.RetryDelay(err, failures)
+			failures++
+			a.logger.Printf("Ошибка отправки heartbeat, повтор через %s: %v", delay.Round(time.Millisecond), err)
+			continue
+		}
+		failures = 0
+		delay = a.nextInterval()
+	}
+}
+
+// nextInterval — пауза до следующего heartbeat: период сервера или
+// AgentOptions.Interval, с разбросом.
+func (a *Agent) nextInterval() time.Duration {
+	a.mu.Lock()
+	interval := a.serverInterval
+	a.mu.Unlock()
+	if interval <= 0 {
+		interval = a.interval
+	}
+	return jitter(max(interval, minInterval))
+}
+
+// start восстанавливает состояние, применяет сохранённую политику и регистрирует
+// устройство. Без сохранённого состояния ошибка регистрации возвращается.
+func (a *Agent) start(ctx context.Context) error {
+	a.restore()
+	if cached := a.lastDevice(); cached != nil {
+		a.updatePolicy(ctx, PolicyOf(cached))
+		a.applyPolicy(ctx, cached, true)
+	}
+
+	err := a.connect(ctx)
+	if err != nil && a.lastDevice() == nil {
+		return err
+	}
+	if err != nil {
+		a.logger.Printf("Сервер недоступен, агент работает по сохранённой политике: %v", err)
+		a.save()
+	}
+	return nil
+}
+
+// connect регистрирует устройство и применяет полученное состояние.
+func (a *Agent) connect(ctx context.Context) error {
+	device, err := a.register(ctx)
+	if err != nil {
+		return err
+	}
+	a.accept(ctx, device)
+	a.save()
+	return nil
+}
+
+// register регистрирует устройство; если регистрация не удалась (например,
+// устройство зарегистрировано без токена), агент продолжает по текущему статусу.
+func (a *Agent) register(ctx context.Context) (*Device, error) {
+	registration, err := a.client.Register(ctx)
+	if err == nil {
+		a.mu.Lock()
+		handlers := append([]RegisterHandler(nil), a.registerHandlers...)
+		a.mu.Unlock()
+		for _, handler := range handlers {
+			handler(ctx, registration)
+		}
+		return registration.Device, nil
+	}
+
+	a.logger.Printf("Ошибка регистрации устройства: %v", err)
+	device, statusErr := a.client.Status(ctx)
+	if statusErr != nil {
+		return nil, fmt.Errorf("register device: %w; get status: %v", err, statusErr)
+	}
+	return device, nil
+}
+
+// Sync выполняет один heartbeat: отправляет телеметрию, обновляет политику,
+// досылает накопленные отчёты, выполняет полученные команды и, когда подошёл
+// срок, сообщает об изменениях в установленных приложениях. Если сервер
+// недоступен, агент повторяет неудавшееся применение последней политики и
+// откладывает снимок телеметрии до восстановления связи.
+// ErrDeviceWiped означает, что сервер принял отчёт о стирании устройства.
+func (a *Agent) Sync(ctx context.Context) error {
+	defer a.save()
+
+	collectedAt := time.Now()
+	telemetry := a.telemetry(ctx)
+	a.mu.Lock()
+	queued := slices.Clone(a.queuedTelemetry)
+	a.mu.Unlock()
+	response, err := a.client.Heartbeat(ctx, telemetry, queued...)
+	if err != nil && a.isWiped(err) {
+		return ErrDeviceWiped
+	}
+	if err != nil {
+		a.queueTelemetry(telemetry, collectedAt, len(queued), err)
+		if cached := a.lastDevice(); cached != nil {
+			a.applyPolicy(ctx, cached, false)
+		}
+		return err
+	}
+	a.mu.Lock()
+	a.serverInterval = response.HeartbeatInterval()
+	// Отложенные снимки приняты вместе с heartbeat.
+	a.queuedTelemetry = a.queuedTelemetry[len(queued):]
+	a.mu.Unlock()
+	// Результат, досланный только что, сервер ещё не учёл в этом ответе:
+	// такие команды приходят повторно, но уже выполнены.
+	flushed := a.flushResults(ctx)
+	a.accept(ctx, response.Device)
+	for _, cmd := range response.Commands {
+		if a.isWiped(nil) {
+			break
+		}
+		if _, done := flushed[cmd.ID]; !done {
+			a.runCommand(ctx, cmd)
+		}
+	}
+	if a.isWiped(nil) {
+		return ErrDeviceWiped
+	}
+	a.reportApps(ctx)
+	return nil
+}
+
+// reportApps раз в appsInterval сравнивает установленные приложения с принятыми
+// сервером и отправляет изменения. Если сервер потерял версию инвентаря (409),
+// сразу отправляется полный список; при другой ошибке отчёт повторяется
+// со следующим heartbeat.
+func (a *Agent) reportApps(ctx context.Context) {
+	a.mu.Lock()
+	due := a.apps != nil && time.Since(a.appsCheckedAt) >= a.appsInterval
+	if due {
+		a.appsCheckedAt = time.Now()
+	}
+	reported, version := a.reportedApps, a.appsVersion
+	a.mu.Unlock()
+	if !due {
+		return
+	}
+	list, err := a.apps.InstalledApps(ctx)
+	if err != nil {
+		a.logger.Printf("Не удалось получить список приложений: %v", err)
+		return
+	}
+	current := appsByName(list)
+
+	report := &AppInventoryReport{BaseVersion: version}
+	if reported == nil {
+		report.Full, report.Installed = true, sortedApps(current)
+	} else if report.Installed, report.Removed = appsDelta(reported, current); len(report.Installed)+len(report.Removed) == 0 {
+		return
+	}
+	version, err = a.client.ReportApps(ctx, report)
+	if !report.Full && IsStatus(err, http.StatusConflict) {
+		a.logger.Printf("Сервер не принял изменения приложений от версии %d, отправляем полный список", report.BaseVersion)
+		version, err = a.client.ReportApps(ctx, &AppInventoryReport{Full: true, Installed: sortedApps(current)})
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		a.logger.Printf("Не удалось отправить список приложений: %v", err)
+		a.appsCheckedAt = time.Time{}
+		return
+	}
+	a.reportedApps, a.appsVersion = current, version
+}
+
+// isWiped сообщает, закончено ли стирание: сервер принял его результат или,
+// после выполненного стирания, отклонил токен устройства (err — ошибка heartbeat).
+func (a *Agent) isWiped(err error) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.wipeCommandID != "" && IsStatus(err, http.StatusUnauthorized) {
+		a.wiped = true
+	}
+	return a.wiped
+}
+
+// forget очищает токен и сохранённое состояние стёртого устройства.
+func (a *Agent) forget() {
+	a.client.SetDeviceToken("")
+	a.mu.Lock()
+	a.device, a.policy, a.applied, a.policyReported = nil, nil, nil, false
+	a.unreported = make(map[string]CommandResult)
+	a.wipeCommandID = ""
+	a.reportedApps, a.appsVersion = nil, 0
+	a.mu.Unlock()
+	a.save()
+	a.logger.Printf("Устройство стёрто; токен и состояние агента удалены")
+}
+
+// accept запоминает полученное от сервера состояние устройства и применяет его политику.
+func (a *Agent) accept(ctx context.Context, device *Device) {
+	a.mu.Lock()
+	a.device = device
+	a.mu.Unlock()
+	a.updatePolicy(ctx, PolicyOf(device))
+	a.applyPolicy(ctx, device, false)
+	a.reportPolicy(ctx)
+}
+
+func (a *Agent) lastDevice() *Device {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.device
+}
+
+// telemetry собирает телеметрию; ошибки сборщиков только логируются.
+func (a *Agent) telemetry(ctx context.Context) *Telemetry {
+	if len(a.collectors) == 0 {
+		return nil
+	}
+	telemetry, err := CollectTelemetry(ctx, a.collectors)
+	if err != nil {
+		a.logger.Printf("Телеметрия собрана не полностью: %v", err)
+	}
+	return telemetry
+}
+
+// queueTelemetry откладывает снимок, с которым heartbeat не удался, до
+// восстановления связи; при переполнении вытесняются самые старые. Ответ 400
+// означает, что сервер доступен, но отклонил запрос: снимок не откладывается,
+// а отправленные с ним sent отложенных снимков отбрасываются, чтобы не
+// повторять отклонённый запрос бесконечно.
+func (a *Agent) queueTelemetry(telemetry *Telemetry, collectedAt time.Time, sent int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if IsStatus(err, http.StatusBadRequest) {
+		if sent > 0 {
+			a.logger.Printf("Сервер отклонил %d снимков телеметрии, снятых без связи: %v", sent, err)
+			a.queuedTelemetry = a.queuedTelemetry[sent:]
+		}
+		return
+	}
+	if telemetry == nil {
+		return
+	}
+	a.queuedTelemetry = append(a.queuedTelemetry, TelemetrySnapshot{CollectedAt: collectedAt, Telemetry: *telemetry})
+	if extra := len(a.queuedTelemetry) - MaxQueuedTelemetry; extra > 0 {
+		a.queuedTelemetry = slices.Delete(a.queuedTelemetry, 0, extra)
+	}
+}
+
+func (a *Agent) updatePolicy(ctx context.Context, current Policy) {
+	a.mu.Lock()
+	previous := a.policy
+	if previous != nil && *previous == current {
+		a.mu.Unlock()
+		return
+	}
+	a.policy = &current
+	handlers := append([]PolicyHandler(nil), a.policyHandlers...)
+	a.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, PolicyChange{Previous: previous, Current: current})
+	}
+}
+
+// applyPolicy применяет политику исполнителями, если пришла новая версия настроек,
+// прошлое применение не удалось или force (при запуске: настройки могли сброситься).
+func (a *Agent) applyPolicy(ctx context.Context, device *Device, force bool) {
+	if a.actuators == nil {
+		return
+	}
+	a.mu.Lock()
+	previous := a.applied
+	reapply := force || previous == nil || previous.Version != device.Version || previous.retryable()
+	a.mu.Unlock()
+	if !reapply {
+		return
+	}
+
+	report := &PolicyReport{Version: device.Version, Results: a.actuators.Apply(ctx, PolicyOf(device))}
+	for _, result := range report.Results {
+		if result.Error != "" {
+			a.logger.Printf("Не удалось применить %s=%t: %s", result.Setting, result.Enabled, result.Error)
+		}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// Такой же отчёт, уже принятый сервером, повторно не отправляется.
+	if !a.policyReported || !sameReport(a.applied, report) {
+		a.policyReported = false
+	}
+	a.applied = report
+}
+
+// reportPolicy отправляет результат применения политики, пока сервер его не примет.
+// 409 означает, что сервер уже принял отчёт о более новой версии: этот больше не нужен.
+func (a *Agent) reportPolicy(ctx context.Context) {
+	a.mu.Lock()
+	report, reported := a.applied, a.policyReported
+	a.mu.Unlock()
+	if report == nil || reported {
+		return
+	}
+	if _, err := a.client.ReportPolicyStatus(ctx, report); err != nil {
+		if !IsStatus(err, http.StatusConflict) {
+			a.logger.Printf("Не удалось отправить статус применения политики: %v", err)
+			return
+		}
+		a.logger.Printf("Статус применения политики устарел и не отправлен: %v", err)
+	}
+	a.mu.Lock()
+	if a.applied == report {
+		a.policyReported = true
+	}
+	a.mu.Unlock()
+}
+
+func sameReport(a, b *PolicyReport) bool {
+	return a != nil && b != nil && a.Version == b.Version && slices.Equal(a.Results, b.Results)
+}
+
+// runCommand выполняет команду и отправляет результат. Сервер повторяет доставку,
+// пока не получит результат, поэтому уже выполненные команды не запускаются снова.
+func (a *Agent) runCommand(ctx context.Context, cmd *Command) {
+	a.mu.Lock()
+	_, done := a.unreported[cmd.ID]
+	handler := a.commandHandlers[cmd.Type]
+	a.mu.Unlock()
+	if done {
+		return
+	}
+
+	var outcome CommandResult
+	if handler == nil {
+		outcome.Result = fmt.Sprintf("unsupported command %q", cmd.Type)
+	} else {
+		result, err := handler(ctx, cmd)
+		outcome = CommandResult{Succeeded: err == nil, Result: result}
+		if err != nil {
+			outcome.Result = err.Error()
+		}
+	}
+	a.logger.Printf("Команда %s (%s) выполнена: succeeded=%t %s", cmd.ID, cmd.Type, outcome.Succeeded, outcome.Result)
+
+	a.mu.Lock()
+	a.unreported[cmd.ID] = outcome
+	if cmd.Type == CommandWipe && outcome.Succeeded {
+		a.wipeCommandID = cmd.ID
+	}
+	a.mu.Unlock()
+	// Результат сохраняется до отправки: после сбоя команда не выполнится повторно.
+	a.save()
+	a.report(ctx, cmd.ID, outcome)
+}
+
+// flushResults повторяет отправку результатов, которые не дошли до сервера,
+// и возвращает их.
+func (a *Agent) flushResults(ctx context.Context) map[string]CommandResult {
+	a.mu.Lock()
+	pending := maps.Clone(a.unreported)
+	a.mu.Unlock()
+	for id, outcome := range pending {
+		a.report(ctx, id, outcome)
+	}
+	return pending
+}
+
+func (a *Agent) report(ctx context.Context, commandID string, outcome CommandResult) {
+	if _, err := a.client.ReportCommandResult(ctx, commandID, outcome.Succeeded, outcome.Result); err != nil {
+		a.logger.Printf("Не удалось отправить результат команды %s: %v", commandID, err)
+		// Повторять имеет смысл только сетевые ошибки, 5xx и 429.
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests {
+			return
+		}
+	}
+	a.mu.Lock()
+	delete(a.unreported, commandID)
+	if commandID == a.wipeCommandID {
+		a.wiped = true
+	}
+	a.mu.Unlock()
+}
+
+// restore загружает сохранённое состояние. Токен из хранилища используется,
+// только если клиенту не передали свой.
+func (a *Agent) restore() {
+	if a.store == nil {
+		return
+	}
+	state, err := a.store.Load()
+	if err != nil {
+		a.logger.Printf("Не удалось прочитать состояние агента: %v", err)
+		return
+	}
+	if state == nil {
+		return
+	}
+	if state.DeviceID != a.client.DeviceID() {
+		a.logger.Printf("Сохранённое состояние относится к устройству %q, пропускаем", state.DeviceID)
+		return
+	}
+	if a.client.DeviceToken() == "" {
+		a.client.SetDeviceToken(state.DeviceToken)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.device = state.Device
+	a.applied = state.Applied
+	a.policyReported = state.PolicyReported
+	a.wipeCommandID = state.WipeCommandID
+	if state.Apps != nil {
+		a.reportedApps, a.appsVersion = appsByName(state.Apps), state.AppsVersion
+	}
+	for id, outcome := range state.PendingResults {
+		a.unreported[id] = outcome
+	}
+	a.queuedTelemetry = state.PendingTelemetry
+	if extra := len(a.queuedTelemetry) - MaxQueuedTelemetry; extra > 0 {
+		a.queuedTelemetry = a.queuedTelemetry[extra:]
+	}
+}
+
+// save сохраняет состояние; ошибка записи только логируется.
+func (a *Agent) save() {
+	if a.store == nil {
+		return
+	}
+	a.mu.Lock()
+	state := &State{
+		DeviceID:         a.client.DeviceID(),
+		DeviceToken:      a.client.DeviceToken(),
+		Device:           a.device,
+		Applied:          a.applied,
+		PolicyReported:   a.policyReported,
+		PendingResults:   maps.Clone(a.unreported),
+		PendingTelemetry: slices.Clone(a.queuedTelemetry),
+		WipeCommandID:    a.wipeCommandID,
+		AppsVersion:      a.appsVersion,
+		SavedAt:          time.Now(),
+	}
+	if a.reportedApps != nil {
+		state.Apps = sortedApps(a.reportedApps)
+	}
+	a.mu.Unlock()
+	if err := a.store.Save(state); err != nil {
+		a.logger.Printf("Не удалось сохранить состояние агента: %v", err)
+	}
+}
@@ -0,0 +1,553 @@
+package mdmclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer имитирует API устройства: политику и очередь команд.
+type fakeServer struct {
+	mu       sync.Mutex
+	device   Device
+	commands []*Command
+	results  map[string]string
+	// failResults — сколько отчётов о результатах отклонить с 503.
+	failResults int
+	reports     []PolicyReport
+	telemetry   []*Telemetry
+	// queued — снимки телеметрии, снятые агентом без связи.
+	queued []TelemetrySnapshot
+	// down — сервер отвечает 503 на все запросы.
+	down bool
+	// interval — heartbeat_interval_seconds в ответе на heartbeat.
+	interval int64
+	// revoked — токен устройства отозван (устройство стёрто).
+	revoked bool
+	// appReports — принятые отчёты о приложениях; appsVersion — версия инвентаря.
+	appReports  []AppInventoryReport
+	appsVersion int64
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, `{"error":"unavailable"}`)
+		return
+	}
+	if r.URL.Path != "/devices/register" && (s.revoked || r.Header.Get("Authorization") != "Bearer tok") {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error":"Invalid device token"}`)
+		return
+	}
+	switch {
+	case r.URL.Path == "/devices/register":
+		_ = json.NewEncoder(w).Encode(Registration{Device: &s.device, Registration: "registered", DeviceToken: "tok"})
+	case r.URL.Path == "/devices/dev-1/heartbeat":
+		var body struct {
+			Telemetry *Telemetry          `json:"telemetry"`
+			Queued    []TelemetrySnapshot `json:"queued_telemetry"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.telemetry = append(s.telemetry, body.Telemetry)
+		s.queued = append(s.queued, body.Queued...)
+		var pending []*Command
+		for _, cmd := range s.commands {
+			if _, done := s.results[cmd.ID]; !done {
+				pending = append(pending, cmd)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(HeartbeatResponse{Device: &s.device, Commands: pending, HeartbeatIntervalSeconds: s.interval})
+	case r.URL.Path == "/devices/dev-1/policy-status":
+		var report PolicyReport
+		_ = json.NewDecoder(r.Body).Decode(&report)
+		s.reports = append(s.reports, report)
+		_ = json.NewEncoder(w).Encode(s.device)
+	case r.URL.Path == "/devices/dev-1/apps":
+		var report AppInventoryReport
+		_ = json.NewDecoder(r.Body).Decode(&report)
+		if !report.Full && report.BaseVersion != s.appsVersion {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = io.WriteString(w, `{"error":"app inventory version mismatch"}`)
+			return
+		}
+		s.appReports = append(s.appReports, report)
+		s.appsVersion++
+		_ = json.NewEncoder(w).Encode(map[string]int64{"apps_version": s.appsVersion})
+	case r.Method == http.MethodPost && len(r.URL.Path) > len("/devices/dev-1/commands/"):
+		if s.failResults > 0 {
+			s.failResults--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = io.WriteString(w, `{"error":"unavailable"}`)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		id := r.URL.Path[len("/devices/dev-1/commands/") : len(r.URL.Path)-len("/result")]
+		s.results[id] = body["status"] + ":" + body["result"]
+		_ = json.NewEncoder(w).Encode(Command{ID: id, Status: body["status"]})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":"not found"}`)
+	}
+}
+
+func TestAgentPolicyAndCommands(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, err := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0)})
+
+	var changes []PolicyChange
+	agent.OnPolicyChange(func(ctx context.Context, change PolicyChange) {
+		changes = append(changes, change)
+	})
+	executed := 0
+	agent.OnCommand("sync", func(ctx context.Context, cmd *Command) (string, error) {
+		executed++
+		return "done", nil
+	})
+	agent.OnCommand("broken", func(ctx context.Context, cmd *Command) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	ctx := context.Background()
+	device, err := agent.register(ctx)
+	if err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if client.DeviceToken() != "tok" {
+		t.Errorf("Expected the issued device token to be kept, got %q", client.DeviceToken())
+	}
+	agent.updatePolicy(ctx, PolicyOf(device))
+
+	server.mu.Lock()
+	server.device.CameraEnabled = true
+	server.commands = []*Command{{ID: "c1", Type: "sync"}, {ID: "c2", Type: "broken"}, {ID: "c3", Type: "unknown"}}
+	server.failResults = 1
+	server.mu.Unlock()
+
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Previous != nil || changes[1].Previous == nil || !changes[1].Current.CameraEnabled {
+		t.Fatalf("Expected initial and camera policy events, got %+v", changes)
+	}
+
+	// Результат c1 не дошёл (503): команда доставляется снова, но не выполняется повторно.
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if executed != 1 {
+		t.Errorf("Expected the command to run once, got %d", executed)
+	}
+	want := map[string]string{"c1": "succeeded:done", "c2": "failed:boom", "c3": `failed:unsupported command "unknown"`}
+	for id, result := range want {
+		if server.results[id] != result {
+			t.Errorf("Expected result %q for %s, got %q", result, id, server.results[id])
+		}
+	}
+	if len(changes) != 2 {
+		t.Errorf("Expected no event without a policy change, got %d", len(changes))
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"error":"too many requests"}`)
+	}))
+	defer ts.Close()
+
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+	_, err := client.Heartbeat(context.Background(), nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter.Seconds() != 7 || apiErr.Message != "too many requests" {
+		t.Fatalf("Expected APIError with Retry-After, got %#v", err)
+	}
+	if !IsStatus(err, http.StatusTooManyRequests) {
+		t.Errorf("Expected IsStatus to match")
+	}
+	if _, err := New(Config{BaseURL: "localhost:4000"}); err == nil {
+		t.Errorf("Expected an error for a base URL without scheme")
+	}
+}
+
+func TestAgentEnforcesPolicy(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1", Version: 1}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	applied := map[string]bool{}
+	failBluetooth := true
+	registry := NewActuators()
+	registry.Register(SettingCamera, ActuatorFunc(func(ctx context.Context, setting string, enabled bool) error {
+		applied[setting] = enabled
+		return nil
+	}))
+	registry.Register(SettingBluetooth, ActuatorFunc(func(ctx context.Context, setting string, enabled bool) error {
+		if failBluetooth {
+			return errors.New("rfkill: permission denied")
+		}
+		applied[setting] = enabled
+		return nil
+	}))
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Actuators: registry})
+
+	ctx := context.Background()
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(server.reports) != 1 || !server.reports[0].Failed() || server.reports[0].Version != 1 {
+		t.Fatalf("Expected a failed report for version 1, got %+v", server.reports)
+	}
+
+	// Неудачное применение повторяется на следующем heartbeat.
+	failBluetooth = false
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if _, ok := applied[SettingBluetooth]; len(server.reports) != 2 || !ok {
+		t.Fatalf("Expected a report after retry, got %+v", server.reports)
+	}
+	// Настройка без исполнителя остаётся в отчёте с ошибкой, остальные применены.
+	for _, result := range server.reports[1].Results {
+		wantError := ""
+		if result.Setting == SettingMicrophone {
+			wantError = ErrNoActuator.Error()
+		}
+		if result.Error != wantError || result.Enforced != (wantError == "") {
+			t.Errorf("Unexpected result after retry: %+v", result)
+		}
+	}
+
+	// Без изменений политика не применяется и не отправляется повторно,
+	// в том числе из-за настройки без исполнителя.
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	server.mu.Lock()
+	server.device.Version = 2
+	server.device.CameraEnabled = true
+	server.mu.Unlock()
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(server.reports) != 3 || server.reports[2].Version != 2 || !applied[SettingCamera] {
+		t.Errorf("Expected the new version to be applied once, got %+v, %v", server.reports, applied)
+	}
+}
+
+func TestAgentSendsTelemetry(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	level := int32(80)
+	collectors := []Collector{
+		CollectorFunc(func(ctx context.Context, t *Telemetry) error {
+			t.BatteryLevel = &level
+			return nil
+		}),
+		CollectorFunc(func(ctx context.Context, t *Telemetry) error {
+			return errors.New("os-release: not found")
+		}),
+		CollectorFunc(func(ctx context.Context, t *Telemetry) error {
+			t.OSName = "Debian"
+			return nil
+		}),
+	}
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Collectors: collectors})
+	if err := agent.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(server.telemetry) != 1 || server.telemetry[0] == nil {
+		t.Fatalf("Expected telemetry in the heartbeat, got %+v", server.telemetry)
+	}
+	got := server.telemetry[0]
+	if got.BatteryLevel == nil || *got.BatteryLevel != 80 || got.OSName != "Debian" {
+		t.Errorf("Expected telemetry of the working collectors, got %+v", got)
+	}
+
+	// Без токена устройства сервер отклоняет heartbeat.
+	client.SetDeviceToken("")
+	if err := agent.Sync(context.Background()); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Expected 401 without a device token, got %v", err)
+	}
+}
+
+// TestAgentQueuesTelemetryOffline проверяет, что снимки телеметрии, снятые без
+// связи, переживают перезапуск агента, не копятся сверх MaxQueuedTelemetry и
+// уходят с первым удачным heartbeat.
+func TestAgentQueuesTelemetryOffline(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	level := int32(0)
+	newAgent := func() *Agent {
+		client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+		collector := CollectorFunc(func(ctx context.Context, t *Telemetry) error {
+			level++
+			current := level
+			t.BatteryLevel = &current
+			return nil
+		})
+		return NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Collectors: []Collector{collector}, Store: store})
+	}
+	setDown := func(down bool) {
+		server.mu.Lock()
+		server.down = down
+		server.mu.Unlock()
+	}
+
+	ctx := context.Background()
+	first := newAgent()
+	if err := first.start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	setDown(true)
+	for range 2 {
+		if err := first.Sync(ctx); err == nil {
+			t.Fatalf("Expected Sync to fail while the server is down")
+		}
+	}
+
+	// Очередь переживает перезапуск и ограничена MaxQueuedTelemetry.
+	second := newAgent()
+	second.restore()
+	for range MaxQueuedTelemetry {
+		_ = second.Sync(ctx)
+	}
+	state, err := store.Load()
+	if err != nil || len(state.PendingTelemetry) != MaxQueuedTelemetry {
+		t.Fatalf("Expected %d queued snapshots in the state, got %+v, %v", MaxQueuedTelemetry, state, err)
+	}
+	// Два самых старых снимка вытеснены.
+	if oldest := state.PendingTelemetry[0]; *oldest.Telemetry.BatteryLevel != 3 || oldest.CollectedAt.IsZero() {
+		t.Errorf("Expected the oldest snapshots to be dropped, got %+v", oldest)
+	}
+
+	setDown(false)
+	if err := second.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(server.queued) != MaxQueuedTelemetry {
+		t.Errorf("Expected the queue to be flushed with the heartbeat, got %d snapshots", len(server.queued))
+	}
+	if last := server.telemetry[len(server.telemetry)-1]; *last.BatteryLevel != level {
+		t.Errorf("Expected fresh telemetry with the flush, got %+v", last)
+	}
+	if state, _ := store.Load(); len(state.PendingTelemetry) != 0 {
+		t.Errorf("Expected an empty telemetry queue after the flush, got %d", len(state.PendingTelemetry))
+	}
+}
+
+func TestAgentReportsAppChanges(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	installed := []InstalledApp{
+		{PackageName: "firefox", Version: "115.0", Installer: "dpkg"},
+		{PackageName: "vim", Version: "9.0", Installer: "dpkg"},
+	}
+	source := AppSourceFunc(func(ctx context.Context) ([]InstalledApp, error) { return installed, nil })
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1", DeviceToken: "tok"})
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Apps: source, AppsInterval: time.Nanosecond})
+	sync := func() {
+		t.Helper()
+		if err := agent.Sync(context.Background()); err != nil {
+			t.Fatalf("Sync failed: %v", err)
+		}
+	}
+
+	sync()
+	sync()
+	if len(server.appReports) != 1 || !server.appReports[0].Full || len(server.appReports[0].Installed) != 2 {
+		t.Fatalf("Expected a single full report, got %+v", server.appReports)
+	}
+
+	installed = []InstalledApp{
+		{PackageName: "curl", Version: "8.0", Installer: "dpkg"},
+		{PackageName: "firefox", Version: "128.0", Installer: "dpkg"},
+	}
+	sync()
+	delta := server.appReports[len(server.appReports)-1]
+	if delta.Full || delta.BaseVersion != 1 || len(delta.Installed) != 2 || delta.Installed[0].PackageName != "curl" ||
+		len(delta.Removed) != 1 || delta.Removed[0] != "vim" {
+		t.Fatalf("Expected delta from version 1, got %+v", delta)
+	}
+
+	// Сервер потерял инвентарь (например, устройство удалили и зарегистрировали снова).
+	server.appsVersion = 0
+	installed = installed[:1]
+	sync()
+	full := server.appReports[len(server.appReports)-1]
+	if len(server.appReports) != 3 || !full.Full || len(full.Installed) != 1 || full.Installed[0].PackageName != "curl" {
+		t.Errorf("Expected a full report after 409, got %+v", server.appReports)
+	}
+}
+
+func TestAgentStateSurvivesRestart(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1", Version: 1, CameraEnabled: true}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	store := NewFileStore(filepath.Join(t.TempDir(), "agent", "state.json"))
+
+	var applied []Policy
+	executed := 0
+	newAgent := func() (*Agent, *Client) {
+		client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+		registry := NewActuators()
+		registry.Register(SettingCamera, ActuatorFunc(func(ctx context.Context, setting string, enabled bool) error {
+			applied = append(applied, Policy{CameraEnabled: enabled})
+			return nil
+		}))
+		agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Actuators: registry, Store: store})
+		agent.OnCommand("sync", func(ctx context.Context, cmd *Command) (string, error) {
+			executed++
+			return "done", nil
+		})
+		return agent, client
+	}
+
+	ctx := context.Background()
+	first, _ := newAgent()
+	if err := first.start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	// Результат команды не доходит до сервера, затем сервер пропадает.
+	server.mu.Lock()
+	server.commands = []*Command{{ID: "c1", Type: "sync"}}
+	server.failResults = 1
+	server.mu.Unlock()
+	if err := first.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if info, err := os.Stat(store.Path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected a private state file, got %v, %v", info, err)
+	}
+
+	server.mu.Lock()
+	server.down = true
+	server.mu.Unlock()
+	applied = nil
+
+	// После перезапуска без сервера агент берёт токен и политику из состояния.
+	second, client := newAgent()
+	if err := second.start(ctx); err != nil {
+		t.Fatalf("Expected offline start with saved state, got %v", err)
+	}
+	if client.DeviceToken() != "tok" {
+		t.Errorf("Expected the saved device token, got %q", client.DeviceToken())
+	}
+	if len(applied) != 1 || !applied[0].CameraEnabled {
+		t.Errorf("Expected the saved policy to be enforced offline, got %+v", applied)
+	}
+	if err := second.Sync(ctx); err == nil {
+		t.Fatalf("Expected Sync to fail while the server is down")
+	}
+
+	// Связь вернулась: накопленный результат досылается, команда не выполняется снова.
+	server.mu.Lock()
+	server.down = false
+	server.mu.Unlock()
+	if err := second.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if executed != 1 || server.results["c1"] != "succeeded:done" {
+		t.Errorf("Expected the queued result to be flushed once, got executed=%d results=%v", executed, server.results)
+	}
+	state, err := store.Load()
+	if err != nil || state == nil || len(state.PendingResults) != 0 || !state.PolicyReported {
+		t.Errorf("Expected an empty queue in the saved state, got %+v, %v", state, err)
+	}
+}
+
+func TestAgentWipe(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Store: store})
+	var actions []string
+	for _, action := range []string{CommandLock, CommandWipe} {
+		agent.OnCommand(action, func(ctx context.Context, cmd *Command) (string, error) {
+			var payload ActionPayload
+			_ = DecodePayload(cmd, &payload)
+			actions = append(actions, cmd.Type+":"+payload.Message)
+			return "ok", nil
+		})
+	}
+
+	ctx := context.Background()
+	if err := agent.start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	// Отчёт о стирании не доходит: агент продолжает работу, пока сервер его не примет.
+	server.mu.Lock()
+	server.commands = []*Command{{ID: "c1", Type: CommandLock, Payload: `{"message":"lost"}`}, {ID: "c2", Type: CommandWipe}}
+	server.failResults = 2
+	server.mu.Unlock()
+	if err := agent.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if len(actions) != 2 || actions[0] != "lock:lost" || actions[1] != "wipe:" {
+		t.Fatalf("Expected lock and wipe to run, got %v", actions)
+	}
+	if state, _ := store.Load(); state == nil || state.WipeCommandID != "c2" {
+		t.Fatalf("Expected the pending wipe to be saved, got %+v", state)
+	}
+
+	// Сервер принял отчёт, но ответ потерян: отказ в токене означает, что стирание завершено.
+	server.mu.Lock()
+	server.revoked = true
+	server.mu.Unlock()
+	if err := agent.Sync(ctx); !errors.Is(err, ErrDeviceWiped) {
+		t.Fatalf("Expected ErrDeviceWiped, got %v", err)
+	}
+	agent.forget()
+	state, err := store.Load()
+	if err != nil || state == nil || state.DeviceToken != "" || state.Device != nil || state.WipeCommandID != "" {
+		t.Errorf("Expected the wiped state to be cleared, got %+v, %v", state, err)
+	}
+	if len(actions) != 2 {
+		t.Errorf("Expected commands not to run again, got %v", actions)
+	}
+}
+
+func TestAgentStartWithoutStateFails(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}, down: true}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, _ := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+	agent := NewAgent(client, AgentOptions{Logger: log.New(io.Discard, "", 0), Store: NewFileStore(filepath.Join(t.TempDir(), "state.json"))})
+	if err := agent.start(context.Background()); err == nil {
+		t.Errorf("Expected start to fail without a server and saved state")
+	}
+}
@@ -0,0 +1,65 @@
+package mdmclient
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultAppsInterval — как часто агент проверяет установленные приложения,
+// если AgentOptions.AppsInterval не задан.
+const defaultAppsInterval = time.Hour
+
+// AppSource перечисляет установленные приложения. Реализация для dpkg —
+// в пакете mdmclient/collectors.
+type AppSource interface {
+	InstalledApps(ctx context.Context) ([]InstalledApp, error)
+}
+
+// AppSourceFunc — функция-источник списка приложений.
+type AppSourceFunc func(ctx context.Context) ([]InstalledApp, error)
+
+func (f AppSourceFunc) InstalledApps(ctx context.Context) ([]InstalledApp, error) {
+	return f(ctx)
+}
+
+// appsByName индексирует приложения по имени пакета; из повторяющихся имён
+// остаётся первое, чтобы сервер не отклонил отчёт целиком.
+func appsByName(apps []InstalledApp) map[string]InstalledApp {
+	byName := make(map[string]InstalledApp, len(apps))
+	for _, app := range apps {
+		if _, ok := byName[app.PackageName]; !ok && app.PackageName != "" {
+			byName[app.PackageName] = app
+		}
+	}
+	return byName
+}
+
+// sortedApps возвращает приложения по имени пакета.
+func sortedApps(apps map[string]InstalledApp) []InstalledApp {
+	list := make([]InstalledApp, 0, len(apps))
+	for _, app := range apps {
+		list = append(list, app)
+	}
+	slices.SortFunc(list, func(a, b InstalledApp) int { return strings.Compare(a.PackageName, b.PackageName) })
+	return list
+}
+
+// appsDelta — изменения current относительно reported: новые и изменённые
+// приложения и имена удалённых.
+func appsDelta(reported, current map[string]InstalledApp) (installed []InstalledApp, removed []string) {
+	for name, app := range current {
+		if previous, ok := reported[name]; !ok || previous.Version != app.Version || previous.Installer != app.Installer {
+			installed = append(installed, app)
+		}
+	}
+	for name := range reported {
+		if _, ok := current[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.SortFunc(installed, func(a, b InstalledApp) int { return strings.Compare(a.PackageName, b.PackageName) })
+	slices.Sort(removed)
+	return installed, removed
+}
@@ -0,0 +1,83 @@
+package mdmclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultBackoff — паузы агента между повторами по умолчанию: 1s, 2s, 4s... до 5 минут.
+var DefaultBackoff = Backoff{Initial: time.Second, Max: 5 * time.Minute, Multiplier: 2}
+
+// Backoff — экспоненциально растущая пауза между повторами со случайным разбросом,
+// чтобы устройства, потерявшие связь одновременно (например, при перезапуске
+// сервера), не возвращались все разом.
+type Backoff struct {
+	// Initial — пауза перед первым повтором.
+	Initial time.Duration
+	// Max — предел паузы.
+	Max time.Duration
+	// Multiplier — во сколько раз растёт пауза с каждой неудачей.
+	Multiplier float64
+}
+
+// withDefaults заполняет незаданные поля значениями DefaultBackoff.
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = DefaultBackoff.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = max(DefaultBackoff.Max, b.Initial)
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultBackoff.Multiplier
+	}
+	return b
+}
+
+// Delay возвращает паузу перед повтором номер attempt (с нуля): случайную
+// величину от d/2 до d, где d = Initial·Multiplier^attempt, но не больше Max.
+func (b Backoff) Delay(attempt int) time.Duration {
+	b = b.withDefaults()
+	d := float64(b.Initial) * math.Pow(b.Multiplier, float64(max(attempt, 0)))
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	half := time.Duration(d / 2)
+	return half + rand.N(half+1)
+}
+
+// RetryDelay — пауза после ошибки err: Delay(attempt), но не меньше Retry-After,
+// если сервер попросил подождать.
+func (b Backoff) RetryDelay(err error, attempt int) time.Duration {
+	delay := b.Delay(attempt)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		return apiErr.RetryAfter
+	}
+	return delay
+}
+
+// jitter разбрасывает период d на ±10%: агенты, запущенные одновременно,
+// со временем расходятся по времени heartbeat.
+func jitter(d time.Duration) time.Duration {
+	spread := d / 10
+	if spread <= 0 {
+		return d
+	}
+	return d - spread + rand.N(2*spread+1)
+}
+
+// sleep ждёт d или отмены ctx; false — ctx отменён.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,104 @@
+package mdmclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	for attempt := 0; attempt < 8; attempt++ {
+		ceiling := min(100*time.Millisecond<<attempt, time.Second)
+		for i := 0; i < 50; i++ {
+			if d := b.Delay(attempt); d < ceiling/2 || d > ceiling {
+				t.Fatalf("Delay(%d) = %s, expected between %s and %s", attempt, d, ceiling/2, ceiling)
+			}
+		}
+	}
+	if d := (Backoff{}).Delay(0); d < DefaultBackoff.Initial/2 || d > DefaultBackoff.Initial {
+		t.Errorf("Expected zero Backoff to use defaults, got %s", d)
+	}
+
+	throttled := &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 7 * time.Second}
+	if d := b.RetryDelay(throttled, 0); d != 7*time.Second {
+		t.Errorf("Expected Retry-After to win over a shorter backoff, got %s", d)
+	}
+	if d := b.RetryDelay(errors.New("connection refused"), 0); d > 100*time.Millisecond {
+		t.Errorf("Expected plain backoff for a network error, got %s", d)
+	}
+
+	for i := 0; i < 50; i++ {
+		if d := jitter(time.Minute); d < 54*time.Second || d > 66*time.Second {
+			t.Fatalf("jitter(1m) = %s, expected within 10%%", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Duration{
+		"7":                             7 * time.Second,
+		"":                              0,
+		"soon":                          0,
+		"Thu, 01 Jan 2026 12:00:30 GMT": 30 * time.Second,
+		"Thu, 01 Jan 2026 11:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, expected %s", value, got, want)
+		}
+	}
+}
+
+func TestAgentRunRetriesAndFollowsServerInterval(t *testing.T) {
+	server := &fakeServer{device: Device{DeviceID: "dev-1"}, results: map[string]string{}, down: true, interval: 3600}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, err := New(Config{BaseURL: ts.URL, DeviceID: "dev-1"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	agent := NewAgent(client, AgentOptions{
+		Interval: 10 * time.Millisecond,
+		Backoff:  Backoff{Initial: 5 * time.Millisecond, Max: 20 * time.Millisecond},
+		Logger:   log.New(io.Discard, "", 0),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- agent.Run(ctx) }()
+
+	// Пока сервер недоступен, Run не завершается, а повторяет регистрацию.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Run to keep retrying, got %v", err)
+	default:
+	}
+	server.mu.Lock()
+	server.down = false
+	server.mu.Unlock()
+
+	// После первого heartbeat агент переходит на период сервера.
+	deadline := time.Now().Add(2 * time.Second)
+	for agent.nextInterval() < 54*time.Minute {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the server heartbeat interval to be used, got %s", agent.nextInterval())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if _, ok := agent.Policy(); !ok {
+		t.Errorf("Expected policy after registration")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
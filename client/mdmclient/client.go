@@ -0,0 +1,315 @@
+// Package mdmclient — клиент API сервера MDM и агент устройства, который можно
+// встроить в своё приложение.
+package mdmclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout — таймаут одного запроса, если Config.Timeout не задан.
+const defaultTimeout = 15 * time.Second
+
+// Config — настройки клиента.
+type Config struct {
+	// BaseURL — адрес сервера MDM, например http://localhost:4000.
+	BaseURL string
+	// DeviceID — идентификатор устройства для вызовов агента.
+	DeviceID string
+	// DeviceToken — токен, выданный при прошлой регистрации устройства.
+	DeviceToken string
+	// EnrollmentToken — токен перерегистрации от администратора; нужен стёртому
+	// устройству и устройству, потерявшему свой токен.
+	EnrollmentToken string
+	// Token — JWT пользователя или API-ключ для административных вызовов.
+	Token string
+	// Timeout — таймаут одного запроса; не применяется, если задан HTTPClient.
+	Timeout time.Duration
+	// HTTPClient — свой клиент (прокси, TLS); по умолчанию http.Client с Timeout.
+	HTTPClient *http.Client
+	// UserAgent — заголовок User-Agent запросов.
+	UserAgent string
+}
+
+// Client — клиент API сервера MDM. Безопасен для использования из нескольких горутин.
+type Client struct {
+	baseURL    string
+	deviceID   string
+	token      string
+	userAgent  string
+	httpClient *http.Client
+	// enrollmentToken — токен перерегистрации из Config.
+	enrollmentToken string
+
+	mu          sync.RWMutex
+	deviceToken string
+}
+
+// New создаёт клиент.
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("mdmclient: invalid base URL %q", cfg.BaseURL)
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = "mdmclient"
+	}
+	return &Client{
+		baseURL:         strings.TrimRight(cfg.BaseURL, "/"),
+		deviceID:        cfg.DeviceID,
+		token:           cfg.Token,
+		userAgent:       userAgent,
+		httpClient:      httpClient,
+		deviceToken:     cfg.DeviceToken,
+		enrollmentToken: cfg.EnrollmentToken,
+	}, nil
+}
+
+// DeviceID возвращает идентификатор устройства клиента.
+func (c *Client) DeviceID() string {
+	return c.deviceID
+}
+
+// DeviceToken возвращает текущий токен устройства.
+func (c *Client) DeviceToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.deviceToken
+}
+
+// SetDeviceToken заменяет токен устройства (например, восстановленный из хранилища).
+func (c *Client) SetDeviceToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deviceToken = token
+}
+
+// APIError — ответ сервера с кодом ошибки.
+type APIError struct {
+	StatusCode int
+	// Message — поле "error" ответа или его тело.
+	Message string
+	// RetryAfter — сколько подождать по заголовку Retry-After (секунды или
+	// HTTP-дата), если сервер его прислал.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("mdm api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsStatus сообщает, что err — ответ сервера с кодом status.
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// Register регистрирует устройство. Выданный сервером токен сохраняется в клиенте.
+func (c *Client) Register(ctx context.Context) (*Registration, error) {
+	payload := map[string]string{
+		"device_id":    c.deviceID,
+		"device_token": c.DeviceToken(),
+	}
+	if c.enrollmentToken != "" {
+		payload["enrollment_token"] = c.enrollmentToken
+	}
+	var registration Registration
+	if err := c.do(ctx, http.MethodPost, "/devices/register", payload, &registration); err != nil {
+		return nil, err
+	}
+	if registration.DeviceToken != "" {
+		c.SetDeviceToken(registration.DeviceToken)
+	}
+	return &registration, nil
+}
+
+// Heartbeat отмечает, что устройство на связи, передаёт телеметрию (nil — без неё)
+// и снимки queued, снятые без связи (не больше MaxQueuedTelemetry), и получает
+// политику и команды.
+func (c *Client) Heartbeat(ctx context.Context, telemetry *Telemetry, queued ...TelemetrySnapshot) (*HeartbeatResponse, error) {
+	var body any
+	if telemetry != nil || len(queued) > 0 {
+		payload := map[string]any{}
+		if telemetry != nil {
+			payload["telemetry"] = telemetry
+		}
+		if len(queued) > 0 {
+			payload["queued_telemetry"] = queued
+		}
+		body = payload
+	}
+	var response HeartbeatResponse
+	if err := c.deviceDo(ctx, http.MethodPost, c.devicePath("heartbeat"), body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// Status возвращает состояние устройства.
+func (c *Client) Status(ctx context.Context) (*Device, error) {
+	var device Device
+	if err := c.deviceDo(ctx, http.MethodGet, c.devicePath("status"), nil, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ReportPolicyStatus сообщает серверу, какую версию настроек агент применил и с какими ошибками.
+// Отчёт о версии старше уже принятой отклоняется с 409, если это не текущая версия устройства.
+func (c *Client) ReportPolicyStatus(ctx context.Context, report *PolicyReport) (*Device, error) {
+	var device Device
+	if err := c.deviceDo(ctx, http.MethodPost, c.devicePath("policy-status"), report, &device); err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// ReportApps отправляет отчёт об установленных приложениях и возвращает новую
+// версию инвентаря на сервере. Отчёт с изменениями от устаревшей BaseVersion
+// отклоняется с 409 (IsStatus(err, http.StatusConflict)): нужен полный отчёт.
+func (c *Client) ReportApps(ctx context.Context, report *AppInventoryReport) (int64, error) {
+	var response struct {
+		AppsVersion int64 `json:"apps_version"`
+	}
+	if err := c.deviceDo(ctx, http.MethodPost, c.devicePath("apps"), report, &response); err != nil {
+		return 0, err
+	}
+	return response.AppsVersion, nil
+}
+
+// ReportCommandResult сообщает серверу результат выполнения команды.
+func (c *Client) ReportCommandResult(ctx context.Context, commandID string, succeeded bool, result string) (*Command, error) {
+	status := CommandFailed
+	if succeeded {
+		status = CommandSucceeded
+	}
+	payload := map[string]string{"status": status, "result": result}
+	var command Command
+	if err := c.deviceDo(ctx, http.MethodPost, c.devicePath("commands", commandID, "result"), payload, &command); err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+// SendCommand ставит команду устройству deviceID (нужен Config.Token с правом devices:write).
+// payload — JSON-объект с параметрами команды или nil.
+func (c *Client) SendCommand(ctx context.Context, deviceID, commandType string, payload any) (*Command, error) {
+	body := map[string]any{"type": commandType}
+	if payload != nil {
+		body["payload"] = payload
+	}
+	var command Command
+	if err := c.do(ctx, http.MethodPost, "/devices/"+url.PathEscape(deviceID)+"/commands", body, &command); err != nil {
+		return nil, err
+	}
+	return &command, nil
+}
+
+func (c *Client) devicePath(parts ...string) string {
+	path := "/devices/" + url.PathEscape(c.deviceID)
+	for _, part := range parts {
+		path += "/" + url.PathEscape(part)
+	}
+	return path
+}
+
+// do выполняет запрос от имени пользователя или API-ключа (Config.Token).
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	return c.request(ctx, method, path, c.token, nil, body, out)
+}
+
+// deviceDo выполняет запрос агента: сервер проверяет токен устройства.
+func (c *Client) deviceDo(ctx context.Context, method, path string, body, out any) error {
+	return c.request(ctx, method, path, c.DeviceToken(), nil, body, out)
+}
+
+// request выполняет запрос к API: body кодируется в JSON, ответ 2xx декодируется в out.
+// header — дополнительные заголовки запроса или nil.
+func (c *Client) request(ctx context.Context, method, path, token string, header http.Header, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("mdm api: decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		apiErr.Message = body.Error
+	}
+	apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return apiErr
+}
+
+// parseRetryAfter разбирает Retry-After: число секунд или HTTP-дату.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package mdmclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Collector собирает часть телеметрии устройства (заряд, ОС, память и т. п.) и
+// заполняет свои поля t. Реализации для Linux — в пакете mdmclient/collectors.
+type Collector interface {
+	Collect(ctx context.Context, t *Telemetry) error
+}
+
+// CollectorFunc — функция-сборщик.
+type CollectorFunc func(ctx context.Context, t *Telemetry) error
+
+func (f CollectorFunc) Collect(ctx context.Context, t *Telemetry) error {
+	return f(ctx, t)
+}
+
+// CollectTelemetry запускает сборщики по очереди. Ошибка одного сборщика не мешает
+// остальным: возвращается собранная часть телеметрии и объединённая ошибка.
+func CollectTelemetry(ctx context.Context, collectors []Collector) (*Telemetry, error) {
+	var telemetry Telemetry
+	var errs []error
+	for _, collector := range collectors {
+		if err := collectSafely(ctx, collector, &telemetry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return &telemetry, errors.Join(errs...)
+}
+
+// collectSafely не даёт панике в сборщике остановить агента.
+func collectSafely(ctx context.Context, collector Collector, t *Telemetry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("collector panic: %v", r)
+		}
+	}()
+	return collector.Collect(ctx, t)
+}
@@ -0,0 +1,69 @@
+// Package collectors — сборщики телеметрии для агента (см. mdmclient.Collector).
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mdm-client/mdmclient"
+)
+
+// defaultPowerSupplyRoot — каталог источников питания в sysfs Linux.
+const defaultPowerSupplyRoot = "/sys/class/power_supply"
+
+// ErrNoBattery — на устройстве нет батареи (стационарный компьютер, виртуальная машина).
+var ErrNoBattery = errors.New("no battery found")
+
+// Battery читает заряд и состояние первой батареи из power_supply.
+type Battery struct {
+	// Root — каталог power_supply; пусто — /sys/class/power_supply.
+	Root string
+	// IgnoreMissing — не считать ошибкой отсутствие батареи.
+	IgnoreMissing bool
+}
+
+func (b *Battery) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	root := b.Root
+	if root == "" {
+		root = defaultPowerSupplyRoot
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("battery: %w", err)
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(root, entry.Name())
+		if kind, _ := readTrimmed(filepath.Join(dir, "type")); kind != "Battery" {
+			continue
+		}
+		capacity, err := readTrimmed(filepath.Join(dir, "capacity"))
+		if err != nil {
+			return fmt.Errorf("battery %s: %w", entry.Name(), err)
+		}
+		level, err := strconv.ParseInt(capacity, 10, 32)
+		if err != nil || level < 0 || level > 100 {
+			return fmt.Errorf("battery %s: invalid capacity %q", entry.Name(), capacity)
+		}
+		level32 := int32(level)
+		t.BatteryLevel = &level32
+		t.BatteryStatus, _ = readTrimmed(filepath.Join(dir, "status"))
+		return nil
+	}
+	if b.IgnoreMissing {
+		return nil
+	}
+	return fmt.Errorf("battery: %w in %s", ErrNoBattery, root)
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,138 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBattery(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "AC", "type"), "Mains\n")
+	writeFile(t, filepath.Join(root, "BAT0", "type"), "Battery\n")
+	writeFile(t, filepath.Join(root, "BAT0", "capacity"), "73\n")
+	writeFile(t, filepath.Join(root, "BAT0", "status"), "Discharging\n")
+
+	var telemetry mdmclient.Telemetry
+	if err := (&Battery{Root: root}).Collect(context.Background(), &telemetry); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if telemetry.BatteryLevel == nil || *telemetry.BatteryLevel != 73 || telemetry.BatteryStatus != "Discharging" {
+		t.Errorf("Unexpected battery telemetry: %+v", telemetry)
+	}
+
+	empty := t.TempDir()
+	if err := (&Battery{Root: empty}).Collect(context.Background(), &mdmclient.Telemetry{}); !errors.Is(err, ErrNoBattery) {
+		t.Errorf("Expected ErrNoBattery, got %v", err)
+	}
+	if err := (&Battery{Root: empty, IgnoreMissing: true}).Collect(context.Background(), &mdmclient.Telemetry{}); err != nil {
+		t.Errorf("Expected a missing battery to be ignored, got %v", err)
+	}
+}
+
+func TestOSRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	writeFile(t, path, "# comment\nNAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nPRETTY_NAME=\"Ubuntu 22.04.3 LTS\"\n")
+
+	var telemetry mdmclient.Telemetry
+	if err := (&OSRelease{Path: path}).Collect(context.Background(), &telemetry); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if telemetry.OSName != "Ubuntu" || telemetry.OSVersion != "22.04" {
+		t.Errorf("Unexpected OS telemetry: %+v", telemetry)
+	}
+
+	rolling := filepath.Join(t.TempDir(), "os-release")
+	writeFile(t, rolling, "NAME=Arch Linux\nBUILD_ID=rolling\n")
+	telemetry = mdmclient.Telemetry{}
+	if err := (&OSRelease{Path: rolling}).Collect(context.Background(), &telemetry); err != nil || telemetry.OSVersion != "rolling" {
+		t.Errorf("Expected BUILD_ID as version, got %+v, %v", telemetry, err)
+	}
+}
+
+func TestProcCollectors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "uptime"), "12345.67 54321.00\n")
+	writeFile(t, filepath.Join(dir, "meminfo"), "MemTotal:       16318412 kB\nMemFree:         1000000 kB\nMemAvailable:    8159206 kB\n")
+
+	var telemetry mdmclient.Telemetry
+	if err := (&Uptime{Path: filepath.Join(dir, "uptime")}).Collect(context.Background(), &telemetry); err != nil {
+		t.Fatalf("Uptime failed: %v", err)
+	}
+	if err := (&Memory{Path: filepath.Join(dir, "meminfo")}).Collect(context.Background(), &telemetry); err != nil {
+		t.Fatalf("Memory failed: %v", err)
+	}
+	if telemetry.UptimeSeconds == nil || *telemetry.UptimeSeconds != 12345 {
+		t.Errorf("Unexpected uptime: %+v", telemetry.UptimeSeconds)
+	}
+	if telemetry.MemoryTotalBytes == nil || *telemetry.MemoryTotalBytes != 16318412*1024 ||
+		telemetry.MemoryAvailableBytes == nil || *telemetry.MemoryAvailableBytes != 8159206*1024 {
+		t.Errorf("Unexpected memory telemetry: %+v", telemetry)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	var telemetry mdmclient.Telemetry
+	if err := (&Disk{Path: t.TempDir()}).Collect(context.Background(), &telemetry); err != nil {
+		t.Skipf("statfs is not available: %v", err)
+	}
+	if telemetry.DiskTotalBytes == nil || telemetry.DiskFreeBytes == nil || *telemetry.DiskFreeBytes > *telemetry.DiskTotalBytes {
+		t.Errorf("Unexpected disk telemetry: %+v", telemetry)
+	}
+}
+
+func TestDpkg(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "status"), `Package: firefox-esr
+Status: install ok installed
+Architecture: amd64
+Version: 115.6.0esr-1~deb12u1
+Description: Mozilla Firefox web browser
+ Firefox ESR is a powerful, extensible web browser.
+
+Package: libc6
+Status: install ok installed
+Multi-Arch: same
+Architecture: amd64
+Version: 2.36-9
+
+Package: vim
+Status: deinstall ok config-files
+Version: 2:9.0.1378-2
+`)
+	writeFile(t, filepath.Join(dir, "info", "firefox-esr.list"), "/usr/bin/firefox-esr\n")
+	installedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "info", "firefox-esr.list"), installedAt, installedAt); err != nil {
+		t.Fatal(err)
+	}
+
+	apps, err := (&Dpkg{AdminDir: dir}).InstalledApps(context.Background())
+	if err != nil {
+		t.Fatalf("InstalledApps failed: %v", err)
+	}
+	if len(apps) != 2 {
+		t.Fatalf("Expected 2 installed packages, got %+v", apps)
+	}
+	if apps[0].PackageName != "firefox-esr" || apps[0].Version != "115.6.0esr-1~deb12u1" || apps[0].Installer != "dpkg" ||
+		apps[0].InstalledAt == nil || !apps[0].InstalledAt.Equal(installedAt) {
+		t.Errorf("Unexpected firefox entry: %+v", apps[0])
+	}
+	if apps[1].PackageName != "libc6:amd64" || apps[1].InstalledAt != nil {
+		t.Errorf("Unexpected libc6 entry: %+v", apps[1])
+	}
+}
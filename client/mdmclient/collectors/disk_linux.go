@@ -0,0 +1,23 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+
+	"mdm-client/mdmclient"
+)
+
+// Collect читает размер и свободное место файловой системы через statfs.
+func (d *Disk) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(d.path(), &stat); err != nil {
+		return fmt.Errorf("disk %s: %w", d.path(), err)
+	}
+	total := stat.Blocks * uint64(stat.Bsize)
+	// Bavail — место, доступное непривилегированным процессам.
+	free := stat.Bavail * uint64(stat.Bsize)
+	t.DiskTotalBytes = &total
+	t.DiskFreeBytes = &free
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package collectors
+
+import (
+	"context"
+	"errors"
+
+	"mdm-client/mdmclient"
+)
+
+func (d *Disk) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	return errors.New("disk: only supported on linux")
+}
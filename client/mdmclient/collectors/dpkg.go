@@ -0,0 +1,77 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// defaultDpkgAdminDir — каталог базы dpkg (см. dpkg(1), --admindir).
+const defaultDpkgAdminDir = "/var/lib/dpkg"
+
+// Dpkg перечисляет пакеты, установленные через dpkg (Debian, Ubuntu), из файла
+// status. Пакеты Multi-Arch: same называются, как в dpkg-query, с архитектурой
+// (libc6:amd64). Время установки — время изменения info/<пакет>.list.
+type Dpkg struct {
+	// AdminDir — каталог базы dpkg; пусто — /var/lib/dpkg.
+	AdminDir string
+}
+
+func (d *Dpkg) InstalledApps(ctx context.Context) ([]mdmclient.InstalledApp, error) {
+	dir := d.AdminDir
+	if dir == "" {
+		dir = defaultDpkgAdminDir
+	}
+	file, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, fmt.Errorf("dpkg: %w", err)
+	}
+	defer file.Close()
+
+	var apps []mdmclient.InstalledApp
+	fields := make(map[string]string)
+	flush := func() {
+		defer clear(fields)
+		if fields["Package"] == "" || !strings.HasSuffix(fields["Status"], " installed") {
+			return
+		}
+		name := fields["Package"]
+		if fields["Multi-Arch"] == "same" && fields["Architecture"] != "" {
+			name += ":" + fields["Architecture"]
+		}
+		app := mdmclient.InstalledApp{PackageName: name, Version: fields["Version"], Installer: "dpkg"}
+		if info, err := os.Stat(filepath.Join(dir, "info", name+".list")); err == nil {
+			installedAt := info.ModTime().UTC().Truncate(time.Second)
+			app.InstalledAt = &installedAt
+		}
+		apps = append(apps, app)
+	}
+	scanner := bufio.NewScanner(file)
+	// Описания пакетов бывают длиннее стандартного буфера строки.
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		// Продолжения многострочных полей (Description, Conffiles) начинаются с пробела.
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("dpkg: %w", err)
+	}
+	flush()
+	return apps, nil
+}
@@ -0,0 +1,27 @@
+package collectors
+
+import "mdm-client/mdmclient"
+
+// Disk собирает размер и свободное место файловой системы.
+type Disk struct {
+	// Path — любой путь на файловой системе; пусто — "/".
+	Path string
+}
+
+func (d *Disk) path() string {
+	if d.Path == "" {
+		return "/"
+	}
+	return d.Path
+}
+
+// Linux возвращает все сборщики для Linux. Отсутствие батареи не считается ошибкой.
+func Linux() []mdmclient.Collector {
+	return []mdmclient.Collector{
+		&Battery{IgnoreMissing: true},
+		&OSRelease{},
+		&Uptime{},
+		&Disk{},
+		&Memory{},
+	}
+}
@@ -0,0 +1,62 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"mdm-client/mdmclient"
+)
+
+// defaultOSReleasePath — описание дистрибутива Linux (см. os-release(5)).
+const defaultOSReleasePath = "/etc/os-release"
+
+// OSRelease читает имя и версию ОС из os-release: NAME и VERSION_ID
+// (если VERSION_ID нет, например в rolling-дистрибутивах, — BUILD_ID).
+type OSRelease struct {
+	// Path — файл os-release; пусто — /etc/os-release.
+	Path string
+}
+
+func (o *OSRelease) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	path := o.Path
+	if path == "" {
+		path = defaultOSReleasePath
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os-release: %w", err)
+	}
+	defer file.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `'"`)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("os-release: %w", err)
+	}
+
+	t.OSName = values["NAME"]
+	t.OSVersion = values["VERSION_ID"]
+	if t.OSVersion == "" {
+		t.OSVersion = values["BUILD_ID"]
+	}
+	if t.OSName == "" {
+		return fmt.Errorf("os-release: NAME is missing in %s", path)
+	}
+	return nil
+}
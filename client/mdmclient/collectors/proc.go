@@ -0,0 +1,91 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"mdm-client/mdmclient"
+)
+
+// Uptime читает время работы системы из /proc/uptime.
+type Uptime struct {
+	// Path — файл uptime; пусто — /proc/uptime.
+	Path string
+}
+
+func (u *Uptime) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	path := u.Path
+	if path == "" {
+		path = "/proc/uptime"
+	}
+	data, err := readTrimmed(path)
+	if err != nil {
+		return fmt.Errorf("uptime: %w", err)
+	}
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return fmt.Errorf("uptime: empty %s", path)
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("uptime: invalid value %q", fields[0])
+	}
+	uptime := int64(seconds)
+	t.UptimeSeconds = &uptime
+	return nil
+}
+
+// Memory читает объём памяти из /proc/meminfo: MemTotal и MemAvailable.
+type Memory struct {
+	// Path — файл meminfo; пусто — /proc/meminfo.
+	Path string
+}
+
+func (m *Memory) Collect(ctx context.Context, t *mdmclient.Telemetry) error {
+	path := m.Path
+	if path == "" {
+		path = "/proc/meminfo"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("meminfo: %w", err)
+	}
+	defer file.Close()
+
+	var total, available *uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Строки вида "MemTotal:       16318412 kB".
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (key != "MemTotal" && key != "MemAvailable") {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return fmt.Errorf("meminfo: empty %s", key)
+		}
+		kib, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("meminfo: invalid %s %q", key, fields[0])
+		}
+		bytes := kib * 1024
+		if key == "MemTotal" {
+			total = &bytes
+		} else {
+			available = &bytes
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("meminfo: %w", err)
+	}
+	if total == nil {
+		return fmt.Errorf("meminfo: MemTotal is missing in %s", path)
+	}
+	t.MemoryTotalBytes = total
+	t.MemoryAvailableBytes = available
+	return nil
+}
@@ -0,0 +1,261 @@
+package mdmclient
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Модели API. Поля и JSON-теги повторяют ответы сервера (backend/libs/2_generated_models/model);
+// служебные поля, которые сервер не отдаёт (хеши токенов, пароли, секреты), опущены.
+// Клиент не зависит от модуля backend: новое поле сервера нужно добавить сюда вручную,
+// а неизвестные поля ответа при разборе игнорируются.
+
+// Device — устройство и его политика.
+type Device struct {
+	ID                  string     `json:"id"`
+	DeviceID            string     `json:"device_id"`
+	CameraEnabled       bool       `json:"camera_enabled"`
+	MicrophoneEnabled   bool       `json:"microphone_enabled"`
+	BluetoothEnabled    bool       `json:"bluetooth_enabled"`
+	OsVersion           string     `json:"os_version"`
+	BatteryLevel        int32      `json:"battery_level"`
+	LastHeartbeat       time.Time  `json:"last_heartbeat"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Version             int64      `json:"version"`
+	Status              string     `json:"status"`
+	EnrolledAt          time.Time  `json:"enrolled_at"`
+	PolicyVersion       int64      `json:"policy_version"`
+	PolicyStatus        string     `json:"policy_status"`
+	PolicyError         string     `json:"policy_error"`
+	PolicyReportedAt    *time.Time `json:"policy_reported_at"`
+	Telemetry           Telemetry  `json:"telemetry"`
+	TelemetryAt         *time.Time `json:"telemetry_at"`
+	GroupID             string     `json:"group_id"`
+	RetiredAt           *time.Time `json:"retired_at"`
+	RetiredBy           string     `json:"retired_by"`
+	DeletedAt           *time.Time `json:"deleted_at"`
+	AppsVersion         int64      `json:"apps_version"`
+	AppsReportedAt      *time.Time `json:"apps_reported_at"`
+	EnrollmentExpiresAt *time.Time `json:"enrollment_expires_at"`
+}
+
+// Telemetry — показатели устройства, которые агент присылает с heartbeat.
+// Показатель, который агент не смог собрать, отсутствует (nil или пустая строка).
+type Telemetry struct {
+	// BatteryLevel — заряд в процентах (0–100).
+	BatteryLevel *int32 `json:"battery_level,omitempty"`
+	// BatteryStatus — состояние из power_supply: Charging, Discharging, Full и т. п.
+	BatteryStatus string `json:"battery_status,omitempty"`
+	OSName        string `json:"os_name,omitempty"`
+	OSVersion     string `json:"os_version,omitempty"`
+	UptimeSeconds *int64 `json:"uptime_seconds,omitempty"`
+	// DiskTotalBytes и DiskFreeBytes — корневая файловая система.
+	DiskTotalBytes       *uint64 `json:"disk_total_bytes,omitempty"`
+	DiskFreeBytes        *uint64 `json:"disk_free_bytes,omitempty"`
+	MemoryTotalBytes     *uint64 `json:"memory_total_bytes,omitempty"`
+	MemoryAvailableBytes *uint64 `json:"memory_available_bytes,omitempty"`
+}
+
+// TelemetrySnapshot — телеметрия, снятая без связи с сервером.
+type TelemetrySnapshot struct {
+	CollectedAt time.Time `json:"collected_at"`
+	Telemetry   Telemetry `json:"telemetry"`
+}
+
+// Command — команда устройству.
+type Command struct {
+	ID          string     `json:"id"`
+	DeviceID    string     `json:"device_id"`
+	Type        string     `json:"type"`
+	Payload     string     `json:"payload"`
+	Status      string     `json:"status"`
+	Result      string     `json:"result"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// Group — группа устройств со своим периодом heartbeat.
+type Group struct {
+	ID                       string    `json:"id"`
+	Name                     string    `json:"name"`
+	HeartbeatIntervalSeconds int32     `json:"heartbeat_interval_seconds"`
+	CreatedAt                time.Time `json:"created_at"`
+	UpdatedAt                time.Time `json:"updated_at"`
+}
+
+// User — пользователь сервера.
+type User struct {
+	ID                  string    `json:"id"`
+	Username            string    `json:"username"`
+	Role                string    `json:"role"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	FailedLoginAttempts int32     `json:"failed_login_attempts"`
+	LockedUntil         time.Time `json:"locked_until"`
+	AuthProvider        string    `json:"auth_provider"`
+	ExternalSubject     string    `json:"external_subject"`
+	Email               string    `json:"email"`
+	TotpEnabled         bool      `json:"totp_enabled"`
+}
+
+// AuditEvent — запись журнала аудита.
+type AuditEvent struct {
+	ID         string    `json:"id"`
+	Actor      string    `json:"actor"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int32     `json:"status"`
+	RemoteAddr string    `json:"remote_addr"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeviceAction — блокировка или стирание устройства и его подтверждение.
+type DeviceAction struct {
+	ID                string     `json:"id"`
+	DeviceID          string     `json:"device_id"`
+	Action            string     `json:"action"`
+	Payload           string     `json:"payload"`
+	Status            string     `json:"status"`
+	RequestedBy       string     `json:"requested_by"`
+	ApprovedBy        string     `json:"approved_by"`
+	CommandID         string     `json:"command_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	DecidedAt         *time.Time `json:"decided_at"`
+	RequestedByUserID string     `json:"requested_by_user_id"`
+}
+
+// InstalledApp — приложение в отчёте агента об установленных приложениях.
+type InstalledApp struct {
+	// PackageName — имя пакета: org.mozilla.firefox, firefox-esr и т. п.
+	PackageName string `json:"package_name"`
+	Version     string `json:"version"`
+	// Installer — откуда установлено: dpkg, rpm, flatpak, snap, play_store...
+	Installer string `json:"installer,omitempty"`
+	// InstalledAt — время установки, если пакетный менеджер его сообщает.
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+}
+
+// AppInventoryReport — отчёт агента об установленных приложениях.
+// Полный отчёт (Full) заменяет список на сервере целиком, остальные содержат
+// только изменения относительно версии инвентаря BaseVersion.
+type AppInventoryReport struct {
+	Full        bool  `json:"full"`
+	BaseVersion int64 `json:"base_version"`
+	// Installed — установленные и обновлённые приложения (в полном отчёте — все).
+	Installed []InstalledApp `json:"installed,omitempty"`
+	// Removed — имена удалённых пакетов; в полном отчёте не используется.
+	Removed []string `json:"removed,omitempty"`
+}
+
+// App — приложение на устройстве.
+type App struct {
+	ID          string     `json:"id"`
+	DeviceID    string     `json:"device_id"`
+	PackageName string     `json:"package_name"`
+	Version     string     `json:"version"`
+	Installer   string     `json:"installer"`
+	InstalledAt *time.Time `json:"installed_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AppEvent — установка, обновление или удаление приложения на устройстве.
+type AppEvent struct {
+	ID              string    `json:"id"`
+	DeviceID        string    `json:"device_id"`
+	PackageName     string    `json:"package_name"`
+	Event           string    `json:"event"`
+	Version         string    `json:"version"`
+	PreviousVersion string    `json:"previous_version"`
+	Installer       string    `json:"installer"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// Registration — ответ на регистрацию устройства.
+type Registration struct {
+	*Device
+	// Registration — "registered", "already_registered" или "reenrolled".
+	Registration string `json:"registration"`
+	// DeviceToken выдаётся только при первой регистрации и перерегистрации.
+	DeviceToken string `json:"device_token,omitempty"`
+}
+
+// EnrollmentToken — одноразовый токен перерегистрации устройства, выданный администратором.
+type EnrollmentToken struct {
+	DeviceID        string    `json:"device_id"`
+	EnrollmentToken string    `json:"enrollment_token"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// HeartbeatResponse — состояние устройства, команды, ожидающие выполнения,
+// и период следующего heartbeat.
+type HeartbeatResponse struct {
+	*Device
+	Commands []*Command `json:"commands"`
+	// HeartbeatIntervalSeconds — через сколько секунд сервер ждёт следующий
+	// heartbeat (с учётом группы устройства); 0 — сервер период не задаёт.
+	HeartbeatIntervalSeconds int64 `json:"heartbeat_interval_seconds,omitempty"`
+}
+
+// HeartbeatInterval возвращает период heartbeat, заданный сервером, или 0.
+func (r *HeartbeatResponse) HeartbeatInterval() time.Duration {
+	return time.Duration(r.HeartbeatIntervalSeconds) * time.Second
+}
+
+// Статусы команды. Агент сообщает CommandSucceeded или CommandFailed.
+const (
+	CommandPending   = "pending"
+	CommandDelivered = "delivered"
+	CommandSucceeded = "succeeded"
+	CommandFailed    = "failed"
+)
+
+// Действия над устройством, которые сервер выдаёт командами этих типов.
+// После успешного CommandWipe, принятого сервером, токен устройства отозван:
+// агент останавливается (ErrDeviceWiped) и должен зарегистрироваться заново.
+const (
+	CommandLock   = "lock"
+	CommandUnlock = "unlock"
+	CommandWipe   = "wipe"
+)
+
+// Статусы действия над устройством.
+const (
+	ActionPendingApproval = "pending_approval"
+	ActionIssued          = "issued"
+	ActionRejected        = "rejected"
+	ActionExpired         = "expired"
+)
+
+// ActionPayload — параметры команд lock, unlock и wipe.
+type ActionPayload struct {
+	// Message — текст для экрана блокировки.
+	Message string `json:"message,omitempty"`
+}
+
+// Policy — настройки устройства, которые применяет агент.
+type Policy struct {
+	CameraEnabled     bool `json:"camera_enabled"`
+	MicrophoneEnabled bool `json:"microphone_enabled"`
+	BluetoothEnabled  bool `json:"bluetooth_enabled"`
+}
+
+// PolicyOf извлекает политику из состояния устройства.
+func PolicyOf(device *Device) Policy {
+	return Policy{
+		CameraEnabled:     device.CameraEnabled,
+		MicrophoneEnabled: device.MicrophoneEnabled,
+		BluetoothEnabled:  device.BluetoothEnabled,
+	}
+}
+
+// DecodePayload разбирает JSON-параметры команды в v.
+func DecodePayload(cmd *Command, v any) error {
+	if cmd.Payload == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(cmd.Payload), v)
+}
@@ -0,0 +1,109 @@
+package mdmclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// State — локальное состояние агента, которое переживает перезапуск и потерю связи.
+type State struct {
+	DeviceID string `json:"device_id"`
+	// DeviceToken — токен, выданный сервером при регистрации.
+	DeviceToken string `json:"device_token,omitempty"`
+	// Device — последнее полученное от сервера состояние: по нему агент применяет
+	// политику, пока сервер недоступен.
+	Device *Device `json:"device,omitempty"`
+	// Applied — последний результат применения политики; PolicyReported — дошёл ли он до сервера.
+	Applied        *PolicyReport `json:"applied,omitempty"`
+	PolicyReported bool          `json:"policy_reported"`
+	// PendingResults — результаты команд, которые ещё не дошли до сервера.
+	PendingResults map[string]CommandResult `json:"pending_results,omitempty"`
+	// PendingTelemetry — снимки телеметрии, снятые без связи (не больше
+	// MaxQueuedTelemetry, самые старые вытесняются); уходят с первым удачным heartbeat.
+	PendingTelemetry []TelemetrySnapshot `json:"pending_telemetry,omitempty"`
+	// WipeCommandID — выполненная команда стирания, результат которой сервер
+	// ещё не принял; после отчёта о ней агент останавливается.
+	WipeCommandID string `json:"wipe_command_id,omitempty"`
+	// Apps — установленные приложения, принятые сервером как версия инвентаря
+	// AppsVersion: следующий отчёт содержит только изменения относительно них.
+	Apps        []InstalledApp `json:"apps,omitempty"`
+	AppsVersion int64          `json:"apps_version,omitempty"`
+	SavedAt     time.Time      `json:"saved_at"`
+}
+
+// MaxQueuedTelemetry — сколько снимков телеметрии агент хранит без связи с сервером;
+// столько же сервер принимает с одним heartbeat.
+const MaxQueuedTelemetry = 100
+
+// CommandResult — результат выполнения команды.
+type CommandResult struct {
+	Succeeded bool   `json:"succeeded"`
+	Result    string `json:"result"`
+}
+
+// StateStore сохраняет состояние агента между запусками.
+type StateStore interface {
+	// Load возвращает сохранённое состояние или nil, если его ещё нет.
+	Load() (*State, error)
+	Save(state *State) error
+}
+
+// FileStore хранит состояние в JSON-файле. Файл содержит токен устройства,
+// поэтому создаётся с правами 0600.
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore создаёт хранилище в файле path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Load() (*State, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("mdmclient: corrupted state file %s: %w", s.Path, err)
+	}
+	return &state, nil
+}
+
+// Save записывает состояние атомарно: во временный файл рядом и переименованием,
+// чтобы сбой посреди записи не оставил обрезанный файл.
+func (s *FileStore) Save(state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"mdm-client/simulator"
+)
+
+// simulate — команда "simulate": нагрузка на сервер виртуальными агентами.
+// Прерывание (Ctrl+C) завершает прогон досрочно, отчёт всё равно выводится.
+func simulate(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Использование: mdm-client simulate [флаги]")
+		flags.PrintDefaults()
+	}
+	serverURL := flags.String("server", "http://localhost:4000", "URL сервера MDM")
+	devices := flags.Int("devices", 10, "Число виртуальных устройств")
+	prefix := flags.String("prefix", "sim", "Префикс идентификаторов устройств")
+	interval := flags.Duration("interval", 0, "Период heartbeat; 0 — период, который сообщает сервер")
+	duration := flags.Duration("duration", time.Minute, "Длительность прогона")
+	rampUp := flags.Duration("ramp-up", 10*time.Second, "За какое время запускаются все устройства")
+	churn := flags.Float64("churn", 0, "Вероятность уйти из сети после каждого heartbeat, от 0 до 1")
+	offline := flags.Duration("offline", 30*time.Second, "Наибольшее время вне сети")
+	commandLatency := flags.Duration("command-latency", 500*time.Millisecond, "Наибольшее время выполнения команды")
+	timeout := flags.Duration("timeout", 15*time.Second, "Таймаут одного запроса")
+	seed := flags.Uint64("seed", 0, "Зерно генератора случайных чисел; 0 — случайное")
+	_ = flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("Запуск %d виртуальных устройств на %s", *devices, *duration)
+	report, err := simulator.Run(ctx, simulator.Config{
+		BaseURL:        *serverURL,
+		Devices:        *devices,
+		Prefix:         *prefix,
+		Interval:       *interval,
+		Duration:       *duration,
+		RampUp:         *rampUp,
+		Churn:          *churn,
+		Offline:        *offline,
+		CommandLatency: *commandLatency,
+		Timeout:        *timeout,
+		Seed:           *seed,
+	})
+	if err != nil {
+		log.Fatalf("Ошибка симуляции: %v", err)
+	}
+	report.Print(os.Stdout)
+}
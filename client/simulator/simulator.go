@@ -0,0 +1,315 @@
+// Package simulator — нагрузочный стенд для сервера MDM: N виртуальных агентов
+// регистрируются, шлют heartbeat со случайной телеметрией, выполняют команды,
+// уходят из сети и возвращаются. По итогам — перцентили задержек и доля ошибок
+// по каждой операции API.
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// defaultInterval — период heartbeat, если его не задали ни Config, ни сервер.
+const defaultInterval = 10 * time.Second
+
+// Config — параметры прогона.
+type Config struct {
+	// BaseURL — адрес сервера MDM.
+	BaseURL string
+	// Devices — число виртуальных устройств.
+	Devices int
+	// Prefix — префикс идентификаторов устройств: <prefix>-0001, <prefix>-0002...
+	Prefix string
+	// Interval — период heartbeat; 0 — период, который сообщает сервер.
+	Interval time.Duration
+	// Duration — длительность прогона.
+	Duration time.Duration
+	// RampUp — за какое время запускаются все устройства (равномерно).
+	RampUp time.Duration
+	// Churn — вероятность уйти из сети после каждого heartbeat, от 0 до 1.
+	Churn float64
+	// Offline — наибольшее время вне сети; устройство отсутствует от Offline/2 до Offline,
+	// а вернувшись, регистрируется заново, как перезапущенный агент.
+	Offline time.Duration
+	// CommandLatency — наибольшее время выполнения команды (случайное от 0).
+	CommandLatency time.Duration
+	// Timeout — таймаут одного запроса.
+	Timeout time.Duration
+	// Seed — зерно генератора случайных чисел; 0 — случайное.
+	Seed uint64
+	// Backoff — паузы между повторами после ошибок; Retry-After соблюдается.
+	Backoff mdmclient.Backoff
+}
+
+func (c *Config) validate() error {
+	var errs []error
+	if c.BaseURL == "" {
+		errs = append(errs, errors.New("base URL is required"))
+	}
+	if c.Devices < 1 {
+		errs = append(errs, errors.New("devices must be at least 1"))
+	}
+	if c.Duration <= 0 {
+		errs = append(errs, errors.New("duration must be positive"))
+	}
+	if c.Churn < 0 || c.Churn > 1 {
+		errs = append(errs, errors.New("churn must be between 0 and 1"))
+	}
+	if c.Interval < 0 || c.RampUp < 0 || c.Offline < 0 || c.CommandLatency < 0 || c.Timeout < 0 {
+		errs = append(errs, errors.New("intervals and latencies must not be negative"))
+	}
+	if c.Churn > 0 && c.Offline == 0 {
+		errs = append(errs, errors.New("offline must be positive when churn is set"))
+	}
+	return errors.Join(errs...)
+}
+
+// Run запускает виртуальные устройства на cfg.Duration (или до отмены ctx)
+// и возвращает отчёт.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("simulator: %w", err)
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "sim"
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	recorder := NewRecorder(nil)
+	httpClient := &http.Client{Transport: recorder, Timeout: cfg.Timeout}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+	run := &run{cfg: cfg}
+	started := time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Devices; i++ {
+		client, err := mdmclient.New(mdmclient.Config{
+			BaseURL:    cfg.BaseURL,
+			DeviceID:   fmt.Sprintf("%s-%04d", cfg.Prefix, i+1),
+			HTTPClient: httpClient,
+			UserAgent:  "mdm-simulator",
+		})
+		if err != nil {
+			return nil, err
+		}
+		d := &device{
+			run:     run,
+			client:  client,
+			rng:     rand.New(rand.NewPCG(seed, uint64(i))),
+			applied: -1,
+		}
+		d.initTelemetry(started)
+		delay := time.Duration(0)
+		if cfg.Devices > 1 {
+			delay = cfg.RampUp * time.Duration(i) / time.Duration(cfg.Devices)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sleep(ctx, delay) {
+				d.live(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return &Report{
+		Devices:     cfg.Devices,
+		Registered:  int(run.registered.Load()),
+		Disconnects: int(run.disconnects.Load()),
+		Commands:    int(run.commands.Load()),
+		Duration:    time.Since(started),
+		Ops:         recorder.Stats(),
+	}, nil
+}
+
+// run — общие для устройств настройки и счётчики.
+type run struct {
+	cfg         Config
+	registered  atomic.Int64
+	disconnects atomic.Int64
+	commands    atomic.Int64
+}
+
+// device — виртуальное устройство. Используется только своей горутиной.
+type device struct {
+	run    *run
+	client *mdmclient.Client
+	rng    *rand.Rand
+	// applied — версия настроек, о применении которой устройство сообщило.
+	applied int64
+	// registered — устройство уже регистрировалось (для счётчика Registered).
+	registered bool
+
+	telemetry mdmclient.Telemetry
+	battery   int32
+	bootedAt  time.Time
+}
+
+// live — жизнь устройства до отмены ctx: регистрация, heartbeat, команды, уход из сети.
+func (d *device) live(ctx context.Context) {
+	cfg := d.run.cfg
+	for {
+		if !d.connect(ctx) {
+			return
+		}
+		for failures := 0; ; {
+			response, err := d.client.Heartbeat(ctx, d.nextTelemetry())
+			if ctx.Err() != nil {
+				return
+			}
+			var delay time.Duration
+			if err != nil {
+				delay = cfg.Backoff.RetryDelay(err, failures)
+				failures++
+			} else {
+				failures = 0
+				d.handle(ctx, response)
+				delay = d.interval(response)
+			}
+			if err == nil && cfg.Churn > 0 && d.rng.Float64() < cfg.Churn {
+				d.run.disconnects.Add(1)
+				if !sleep(ctx, d.between(cfg.Offline/2, cfg.Offline)) {
+					return
+				}
+				// Вернувшись, устройство регистрируется заново, как перезапущенный агент.
+				break
+			}
+			if !sleep(ctx, delay) {
+				return
+			}
+		}
+	}
+}
+
+// connect регистрирует устройство, повторяя с нарастающей паузой; false — ctx отменён.
+func (d *device) connect(ctx context.Context) bool {
+	for attempt := 0; ; attempt++ {
+		_, err := d.client.Register(ctx)
+		if err == nil {
+			if !d.registered {
+				d.registered = true
+				d.run.registered.Add(1)
+			}
+			return true
+		}
+		if ctx.Err() != nil || !sleep(ctx, d.run.cfg.Backoff.RetryDelay(err, attempt)) {
+			return false
+		}
+	}
+}
+
+// handle сообщает о применении новой политики и выполняет команды из ответа на heartbeat.
+func (d *device) handle(ctx context.Context, response *mdmclient.HeartbeatResponse) {
+	if response.Device != nil && response.Version != d.applied {
+		report := &mdmclient.PolicyReport{Version: response.Version}
+		for setting, enabled := range mdmclient.PolicyOf(response.Device).Settings() {
+			report.Results = append(report.Results, mdmclient.SettingResult{Setting: setting, Enabled: enabled})
+		}
+		sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].Setting < report.Results[j].Setting })
+		if _, err := d.client.ReportPolicyStatus(ctx, report); err == nil {
+			d.applied = response.Version
+		}
+	}
+	for _, cmd := range response.Commands {
+		if !sleep(ctx, d.between(0, d.run.cfg.CommandLatency)) {
+			return
+		}
+		if _, err := d.client.ReportCommandResult(ctx, cmd.ID, true, "simulated "+cmd.Type); err == nil {
+			d.run.commands.Add(1)
+		}
+	}
+}
+
+// interval — пауза до следующего heartbeat с разбросом ±10%.
+func (d *device) interval(response *mdmclient.HeartbeatResponse) time.Duration {
+	interval := d.run.cfg.Interval
+	if interval <= 0 {
+		interval = response.HeartbeatInterval()
+	}
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return d.between(interval-interval/10, interval+interval/10)
+}
+
+// between возвращает случайную длительность от lo до hi.
+func (d *device) between(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(d.rng.Int64N(int64(hi-lo)+1))
+}
+
+var (
+	simulatedOS     = []struct{ name, version string }{{"Android", "14"}, {"Android", "13"}, {"Ubuntu", "22.04"}, {"Debian GNU/Linux", "12"}, {"Windows", "11"}}
+	simulatedMemory = []uint64{2 << 30, 4 << 30, 8 << 30, 16 << 30}
+	simulatedDisk   = []uint64{32 << 30, 64 << 30, 128 << 30, 256 << 30}
+)
+
+// initTelemetry выбирает постоянные параметры устройства: ОС, объём памяти и диска.
+func (d *device) initTelemetry(now time.Time) {
+	system := simulatedOS[d.rng.IntN(len(simulatedOS))]
+	memory := simulatedMemory[d.rng.IntN(len(simulatedMemory))]
+	disk := simulatedDisk[d.rng.IntN(len(simulatedDisk))]
+	d.telemetry = mdmclient.Telemetry{
+		OSName:           system.name,
+		OSVersion:        system.version,
+		MemoryTotalBytes: &memory,
+		DiskTotalBytes:   &disk,
+	}
+	d.battery = 20 + d.rng.Int32N(81)
+	d.bootedAt = now.Add(-d.between(0, 72*time.Hour))
+}
+
+// nextTelemetry — снимок телеметрии: заряд понемногу садится и восстанавливается
+// на зарядке, память и диск меняются случайно.
+func (d *device) nextTelemetry() *mdmclient.Telemetry {
+	status := "Discharging"
+	d.battery -= d.rng.Int32N(2)
+	if d.battery < 5 {
+		d.battery = 100
+		status = "Charging"
+	}
+	battery := d.battery
+	uptime := int64(time.Since(d.bootedAt) / time.Second)
+	available := *d.telemetry.MemoryTotalBytes / 100 * uint64(20+d.rng.IntN(61))
+	free := *d.telemetry.DiskTotalBytes / 100 * uint64(10+d.rng.IntN(81))
+
+	telemetry := d.telemetry
+	telemetry.BatteryLevel = &battery
+	telemetry.BatteryStatus = status
+	telemetry.UptimeSeconds = &uptime
+	telemetry.MemoryAvailableBytes = &available
+	telemetry.DiskFreeBytes = &free
+	return &telemetry
+}
+
+// sleep ждёт d или отмены ctx; false — ctx отменён.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mdm-client/mdmclient"
+)
+
+// fakeServer — минимальный API устройств: каждому устройству одна команда,
+// каждый третий heartbeat отвечает 503.
+type fakeServer struct {
+	mu         sync.Mutex
+	heartbeats map[string]int
+	results    map[string]bool
+	reports    int
+	telemetry  int
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/devices/register":
+		_ = json.NewEncoder(w).Encode(mdmclient.Registration{Device: &mdmclient.Device{}, DeviceToken: "tok"})
+	case strings.HasSuffix(r.URL.Path, "/heartbeat"):
+		id := parts[1]
+		s.heartbeats[id]++
+		if s.heartbeats[id]%3 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Telemetry *mdmclient.Telemetry `json:"telemetry"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Telemetry != nil && body.Telemetry.BatteryLevel != nil && body.Telemetry.OSName != "" {
+			s.telemetry++
+		}
+		response := mdmclient.HeartbeatResponse{Device: &mdmclient.Device{DeviceID: id, Version: 1}}
+		if !s.results[id] {
+			response.Commands = []*mdmclient.Command{{ID: id + "-c1", Type: "sync"}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	case strings.HasSuffix(r.URL.Path, "/policy-status"):
+		s.reports++
+		_ = json.NewEncoder(w).Encode(mdmclient.Device{})
+	case strings.HasSuffix(r.URL.Path, "/result"):
+		s.results[parts[1]] = true
+		_ = json.NewEncoder(w).Encode(mdmclient.Command{})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := &fakeServer{heartbeats: map[string]int{}, results: map[string]bool{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	report, err := Run(context.Background(), Config{
+		BaseURL:        ts.URL,
+		Devices:        5,
+		Interval:       10 * time.Millisecond,
+		Duration:       300 * time.Millisecond,
+		RampUp:         20 * time.Millisecond,
+		Churn:          0.2,
+		Offline:        20 * time.Millisecond,
+		CommandLatency: 5 * time.Millisecond,
+		Seed:           1,
+		Backoff:        mdmclient.Backoff{Initial: 5 * time.Millisecond, Max: 10 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Registered != 5 || report.Commands != 5 || report.Disconnects == 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	ops := map[string]OpStats{}
+	for _, s := range report.Ops {
+		ops[s.Operation] = s
+	}
+	heartbeat := ops[OpHeartbeat]
+	if heartbeat.Requests == 0 || heartbeat.Errors == 0 || heartbeat.ErrorRate() > 0.5 {
+		t.Errorf("Expected heartbeats with about a third of 503s, got %+v", heartbeat)
+	}
+	if heartbeat.P50 > heartbeat.P90 || heartbeat.P90 > heartbeat.P99 || heartbeat.P99 > heartbeat.Max {
+		t.Errorf("Expected ordered percentiles, got %+v", heartbeat)
+	}
+	if ops[OpRegister].Requests < 5 || ops[OpCommandResult].Requests != 5 || ops[OpPolicyStatus].Requests < 5 {
+		t.Errorf("Unexpected operation counts: %+v", ops)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.telemetry == 0 {
+		t.Errorf("Expected heartbeats to carry telemetry")
+	}
+
+	var out strings.Builder
+	report.Print(&out)
+	if !strings.Contains(out.String(), "heartbeat") || !strings.Contains(out.String(), "p99") {
+		t.Errorf("Unexpected printed report:\n%s", out.String())
+	}
+
+	if _, err := Run(context.Background(), Config{BaseURL: ts.URL, Churn: 2}); err == nil {
+		t.Errorf("Expected an invalid config to be rejected")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(nil)
+	for i := 1; i <= 100; i++ {
+		recorder.Record(OpHeartbeat, time.Duration(i)*time.Millisecond, http.StatusOK, nil)
+	}
+	recorder.Record(OpHeartbeat, time.Second, http.StatusTooManyRequests, nil)
+	recorder.Record(OpHeartbeat, time.Second, 0, errors.New("connection refused"))
+	recorder.Record(OpRegister, time.Millisecond, http.StatusInternalServerError, nil)
+
+	stats := recorder.Stats()
+	if len(stats) != 2 || stats[0].Operation != OpHeartbeat || stats[1].Operation != OpRegister {
+		t.Fatalf("Expected heartbeat and register stats, got %+v", stats)
+	}
+	heartbeat := stats[0]
+	if heartbeat.Requests != 102 || heartbeat.Errors != 1 || heartbeat.Throttled != 1 {
+		t.Errorf("Unexpected heartbeat counts: %+v", heartbeat)
+	}
+	if heartbeat.P50 != 51*time.Millisecond || heartbeat.P90 != 92*time.Millisecond || heartbeat.Max != time.Second {
+		t.Errorf("Unexpected heartbeat percentiles: %+v", heartbeat)
+	}
+	if stats[1].ErrorRate() != 1 {
+		t.Errorf("Expected 5xx to count as errors, got %+v", stats[1])
+	}
+
+	for path, want := range map[string]string{
+		"/devices/register":             OpRegister,
+		"/devices/a/heartbeat":          OpHeartbeat,
+		"/devices/a/status":             OpStatus,
+		"/devices/a/policy-status":      OpPolicyStatus,
+		"/devices/a/commands/c1/result": OpCommandResult,
+	} {
+		if got := operation(path); got != want {
+			t.Errorf("operation(%q) = %q, expected %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Операции API, по которым считается статистика.
+const (
+	OpRegister      = "register"
+	OpHeartbeat     = "heartbeat"
+	OpStatus        = "status"
+	OpPolicyStatus  = "policy-status"
+	OpCommandResult = "command-result"
+	OpOther         = "other"
+)
+
+// operation определяет операцию по пути запроса.
+func operation(path string) string {
+	switch {
+	case path == "/devices/register":
+		return OpRegister
+	case strings.HasSuffix(path, "/heartbeat"):
+		return OpHeartbeat
+	case strings.HasSuffix(path, "/policy-status"):
+		return OpPolicyStatus
+	case strings.HasSuffix(path, "/result"):
+		return OpCommandResult
+	case strings.HasSuffix(path, "/status"):
+		return OpStatus
+	default:
+		return OpOther
+	}
+}
+
+// Recorder — http.RoundTripper, который замеряет время и исход каждого запроса.
+// Безопасен для использования из нескольких горутин.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	ops map[string]*opSamples
+}
+
+type opSamples struct {
+	latencies []time.Duration
+	// errors — сетевые ошибки и ответы 5xx; statuses — число ответов по коду.
+	errors   int
+	statuses map[int]int
+}
+
+// NewRecorder оборачивает транспорт next (nil — http.DefaultTransport).
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next, ops: make(map[string]*opSamples)}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := r.next.RoundTrip(req)
+	if err != nil && req.Context().Err() != nil {
+		// Запросы, прерванные окончанием прогона, в статистику не попадают.
+		return resp, err
+	}
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	r.Record(operation(req.URL.Path), time.Since(started), status, err)
+	return resp, err
+}
+
+// Record учитывает запрос операции op: время ответа, код ответа и ошибку транспорта.
+func (r *Recorder) Record(op string, latency time.Duration, status int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	samples := r.ops[op]
+	if samples == nil {
+		samples = &opSamples{statuses: make(map[int]int)}
+		r.ops[op] = samples
+	}
+	samples.latencies = append(samples.latencies, latency)
+	if err != nil || status >= 500 {
+		samples.errors++
+	}
+	if status != 0 {
+		samples.statuses[status]++
+	}
+}
+
+// OpStats — итог по одной операции.
+type OpStats struct {
+	Operation string
+	Requests  int
+	// Errors — сетевые ошибки и ответы 5xx.
+	Errors int
+	// Throttled — ответы 429.
+	Throttled int
+	// Rejected — прочие ответы 4xx.
+	Rejected           int
+	P50, P90, P99, Max time.Duration
+}
+
+// ErrorRate — доля ошибок среди запросов.
+func (s OpStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Stats возвращает итоги по операциям, упорядоченные по имени.
+func (r *Recorder) Stats() []OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := make([]OpStats, 0, len(r.ops))
+	for op, samples := range r.ops {
+		sorted := slices.Clone(samples.latencies)
+		slices.Sort(sorted)
+		s := OpStats{
+			Operation: op,
+			Requests:  len(sorted),
+			Errors:    samples.errors,
+			P50:       percentile(sorted, 50),
+			P90:       percentile(sorted, 90),
+			P99:       percentile(sorted, 99),
+			Max:       percentile(sorted, 100),
+		}
+		for status, count := range samples.statuses {
+			switch {
+			case status == http.StatusTooManyRequests:
+				s.Throttled += count
+			case status >= 400 && status < 500:
+				s.Rejected += count
+			}
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b OpStats) int { return strings.Compare(a.Operation, b.Operation) })
+	return stats
+}
+
+// percentile возвращает p-й перцентиль (nearest rank) отсортированной выборки.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.999999)
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// Report — итог прогона симулятора.
+type Report struct {
+	Devices int
+	// Registered — сколько устройств успешно зарегистрировались.
+	Registered int
+	// Disconnects — сколько раз устройства уходили из сети.
+	Disconnects int
+	// Commands — сколько команд выполнили устройства.
+	Commands int
+	Duration time.Duration
+	Ops      []OpStats
+}
+
+// Print выводит отчёт таблицей.
+func (r *Report) Print(w io.Writer) {
+	total := 0
+	for _, s := range r.Ops {
+		total += s.Requests
+	}
+	rate := 0.0
+	if r.Duration > 0 {
+		rate = float64(total) / r.Duration.Seconds()
+	}
+	fmt.Fprintf(w, "devices: %d (registered %d), duration: %s, requests: %d (%.1f/s), disconnects: %d, commands: %d\n\n",
+		r.Devices, r.Registered, r.Duration.Round(time.Millisecond), total, rate, r.Disconnects, r.Commands)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\trequests\terrors\terror rate\t429\t4xx\tp50\tp90\tp99\tmax\t")
+	for _, s := range r.Ops {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f%%\t%d\t%d\t%s\t%s\t%s\t%s\t\n",
+			s.Operation, s.Requests, s.Errors, 100*s.ErrorRate(), s.Throttled, s.Rejected,
+			roundLatency(s.P50), roundLatency(s.P90), roundLatency(s.P99), roundLatency(s.Max))
+	}
+	_ = tw.Flush()
+}
+
+func roundLatency(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Microsecond)
+}
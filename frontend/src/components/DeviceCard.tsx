@@ -26,7 +26,7 @@ const DeviceCard: React.FC<DeviceCardProps> = ({ deviceId, serverUrl }) => {
   // Функция для получения статуса устройства через REST API
   const fetchStatus = async () => {
     try {
-      const response = await axios.get<Device>(`${serverUrl}/devices/${deviceId}/status`);
+      const response = await axios.get<Device>(`${serverUrl}/devices/${deviceId}`);
       setDevice(response.data);
     } catch (error) {
       console.error('Error fetching device status:', error);
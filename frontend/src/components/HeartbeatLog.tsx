@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import { List } from 'antd';
 import axios from 'axios';
 
@@ -7,15 +7,26 @@ interface HeartbeatLogProps {
   serverUrl: string;
 }
 
+interface DeviceHeartbeat {
+  last_heartbeat: string;
+}
+
 const HeartbeatLog: React.FC<HeartbeatLogProps> = ({ deviceId, serverUrl }) => {
   const [logs, setLogs] = useState<string[]>([]);
+  // Последний показанный heartbeat: повторный опрос без нового heartbeat лог не пополняет
+  const lastSeen = useRef<string | null>(null);
 
-  // Функция для получения heartbeat через REST API
+  // Heartbeat отправляет только агент (с токеном устройства); панель читает
+  // время последнего heartbeat из GET /devices/{id}
   const fetchHeartbeat = async () => {
     try {
-      // Предполагаем, что endpoint heartbeat возвращает данные устройства
-      const response = await axios.post(`${serverUrl}/devices/${deviceId}/heartbeat`);
-      const logEntry = `Heartbeat: ${new Date(response.data.last_heartbeat).toLocaleString()}`;
+      const response = await axios.get<DeviceHeartbeat>(`${serverUrl}/devices/${deviceId}`);
+      const lastHeartbeat = response.data.last_heartbeat;
+      if (!lastHeartbeat || lastHeartbeat === lastSeen.current) {
+        return;
+      }
+      lastSeen.current = lastHeartbeat;
+      const logEntry = `Heartbeat: ${new Date(lastHeartbeat).toLocaleString()}`;
       setLogs((prev) => [logEntry, ...prev]);
     } catch (error) {
       console.error('Error fetching heartbeat:', error);
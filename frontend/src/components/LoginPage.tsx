@@ -1,5 +1,5 @@
-import React, { useState } from "react";
-import { Form, Input, Button, message } from "antd";
+import React, { useEffect, useState } from "react";
+import { Form, Input, Button, QRCode, Typography, message } from "antd";
 import axios from "axios";
 
 interface LoginFormValues {
@@ -7,23 +7,96 @@ interface LoginFormValues {
   password: string;
 }
 
+interface SecondFactorValues {
+  otp?: string;
+  recovery_code?: string;
+}
+
+interface LoginResponse {
+  token?: string;
+  mfa_required?: boolean;
+  mfa_enrollment_required?: boolean;
+  mfa_token?: string;
+}
+
+interface Enrollment {
+  secret: string;
+  provisioning_uri: string;
+}
+
 interface LoginPageProps {
   serverUrl: string;
   onLogin: (token: string) => void;
 }
 
+// Шаги входа: пароль, код 2FA, подключение 2FA и показ кодов восстановления.
+type Step = "password" | "otp" | "enroll" | "recovery_codes";
+
 const LoginPage: React.FC<LoginPageProps> = ({ serverUrl, onLogin }) => {
   const [loading, setLoading] = useState(false);
+  const [step, setStep] = useState<Step>("password");
+  const [mfaToken, setMfaToken] = useState("");
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+  const [enrollment, setEnrollment] = useState<Enrollment | null>(null);
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([]);
+  const [pendingToken, setPendingToken] = useState("");
+
+  // После входа через SSO backend возвращает сюда с #token=... или #error=...
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get("token");
+    const error = params.get("error");
+    if (!token && !error) {
+      return;
+    }
+    window.history.replaceState(null, "", window.location.pathname + window.location.search);
+    if (token) {
+      message.success("Вход выполнен успешно");
+      onLogin(token);
+    } else {
+      console.error("SSO login error:", error);
+      message.error("Ошибка входа через SSO");
+    }
+  }, [onLogin]);
+
+  const loginWithSSO = () => {
+    window.location.href = `${serverUrl}/auth/oidc/login`;
+  };
+
+  const post = async <T,>(path: string, body: object): Promise<T> => {
+    const response = await axios.post<T>(`${serverUrl}${path}`, body, {
+      headers: { "Content-Type": "application/json" },
+    });
+    return response.data;
+  };
+
+  const restart = () => {
+    setStep("password");
+    setMfaToken("");
+    setEnrollment(null);
+    setUseRecoveryCode(false);
+  };
+
+  const handleLoginResponse = async (data: LoginResponse) => {
+    if (data.token) {
+      message.success("Вход выполнен успешно");
+      onLogin(data.token);
+      return;
+    }
+    setMfaToken(data.mfa_token ?? "");
+    if (data.mfa_enrollment_required) {
+      // Роль требует 2FA, а приложение-аутентификатор ещё не подключено.
+      setEnrollment(await post<Enrollment>("/login/2fa/enroll", { mfa_token: data.mfa_token }));
+      setStep("enroll");
+    } else {
+      setStep("otp");
+    }
+  };
 
   const onFinish = async (values: LoginFormValues) => {
     setLoading(true);
     try {
-      const response = await axios.post(`${serverUrl}/login`, values, {
-        headers: { "Content-Type": "application/json" },
-      });
-      const token = response.data.token;
-      message.success("Вход выполнен успешно");
-      onLogin(token);
+      await handleLoginResponse(await post<LoginResponse>("/login", values));
     } catch (error: unknown) {
       console.error("Login error:", error);
       message.error("Ошибка входа");
@@ -32,6 +105,129 @@ const LoginPage: React.FC<LoginPageProps> = ({ serverUrl, onLogin }) => {
     }
   };
 
+  const onSecondFactor = async (values: SecondFactorValues) => {
+    setLoading(true);
+    try {
+      await handleLoginResponse(await post<LoginResponse>("/login", { mfa_token: mfaToken, ...values }));
+    } catch (error: unknown) {
+      console.error("2FA error:", error);
+      if (axios.isAxiosError(error) && error.response?.data?.error?.includes("mfa_token")) {
+        message.error("Время на ввод кода истекло, войдите заново");
+        restart();
+      } else {
+        message.error("Неверный код");
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const onConfirmEnrollment = async (values: SecondFactorValues) => {
+    setLoading(true);
+    try {
+      const data = await post<{ token: string; recovery_codes: string[] }>("/login/2fa/confirm", {
+        mfa_token: mfaToken,
+        otp: values.otp,
+      });
+      setPendingToken(data.token);
+      setRecoveryCodes(data.recovery_codes);
+      setStep("recovery_codes");
+    } catch (error: unknown) {
+      console.error("2FA enrollment error:", error);
+      message.error("Неверный код");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const finishEnrollment = () => {
+    message.success("Двухфакторная аутентификация подключена");
+    onLogin(pendingToken);
+  };
+
+  if (step === "otp") {
+    return (
+      <div style={{ maxWidth: 400, margin: "100px auto" }}>
+        <h2>Подтверждение входа</h2>
+        <Form name="otp" onFinish={onSecondFactor}>
+          {useRecoveryCode ? (
+            <Form.Item
+              name="recovery_code"
+              rules={[{ required: true, message: "Введите код восстановления" }]}
+            >
+              <Input placeholder="Код восстановления" autoFocus />
+            </Form.Item>
+          ) : (
+            <Form.Item
+              name="otp"
+              rules={[{ required: true, message: "Введите код из приложения" }]}
+            >
+              <Input placeholder="Код из приложения-аутентификатора" inputMode="numeric" autoComplete="one-time-code" autoFocus />
+            </Form.Item>
+          )}
+          <Form.Item>
+            <Button type="primary" htmlType="submit" loading={loading} style={{ width: "100%" }}>
+              Подтвердить
+            </Button>
+          </Form.Item>
+          <Button type="link" onClick={() => setUseRecoveryCode(!useRecoveryCode)}>
+            {useRecoveryCode ? "Ввести код из приложения" : "Использовать код восстановления"}
+          </Button>
+          <Button type="link" onClick={restart}>
+            Назад
+          </Button>
+        </Form>
+      </div>
+    );
+  }
+
+  if (step === "enroll" && enrollment) {
+    return (
+      <div style={{ maxWidth: 400, margin: "100px auto" }}>
+        <h2>Подключение двухфакторной аутентификации</h2>
+        <Typography.Paragraph>
+          Для вашей роли вход требует второго фактора. Отсканируйте QR-код в приложении-аутентификаторе
+          или введите ключ вручную, затем введите код из приложения.
+        </Typography.Paragraph>
+        <QRCode value={enrollment.provisioning_uri} style={{ margin: "0 auto 16px" }} />
+        <Typography.Paragraph copyable code>
+          {enrollment.secret}
+        </Typography.Paragraph>
+        <Form name="enroll" onFinish={onConfirmEnrollment}>
+          <Form.Item name="otp" rules={[{ required: true, message: "Введите код из приложения" }]}>
+            <Input placeholder="Код из приложения-аутентификатора" inputMode="numeric" autoComplete="one-time-code" />
+          </Form.Item>
+          <Form.Item>
+            <Button type="primary" htmlType="submit" loading={loading} style={{ width: "100%" }}>
+              Подключить
+            </Button>
+          </Form.Item>
+          <Button type="link" onClick={restart}>
+            Назад
+          </Button>
+        </Form>
+      </div>
+    );
+  }
+
+  if (step === "recovery_codes") {
+    return (
+      <div style={{ maxWidth: 400, margin: "100px auto" }}>
+        <h2>Коды восстановления</h2>
+        <Typography.Paragraph>
+          Сохраните коды в надёжном месте: каждый из них один раз заменяет код из приложения,
+          если телефон недоступен. Больше они показаны не будут.
+        </Typography.Paragraph>
+        <Typography.Paragraph copyable={{ text: recoveryCodes.join("\n") }}>
+          <pre>{recoveryCodes.join("\n")}</pre>
+        </Typography.Paragraph>
+        <Button type="primary" onClick={finishEnrollment} style={{ width: "100%" }}>
+          Я сохранил коды
+        </Button>
+      </div>
+    );
+  }
+
   return (
     <div style={{ maxWidth: 400, margin: "100px auto" }}>
       <h2>Вход в систему</h2>
@@ -58,6 +254,11 @@ const LoginPage: React.FC<LoginPageProps> = ({ serverUrl, onLogin }) => {
             Войти
           </Button>
         </Form.Item>
+        <Form.Item>
+          <Button onClick={loginWithSSO} style={{ width: "100%" }}>
+            Войти через SSO
+          </Button>
+        </Form.Item>
       </Form>
     </div>
   );